@@ -85,14 +85,30 @@ var (
 	r_byte       = reflect.TypeOf(byte(0))
 )
 
-// Unpack output in v according to the abi specification
+// Unpack output in v according to the abi specification. The name may refer to
+// a method, in which case its return values are unpacked, or to an event, in
+// which case its non-indexed fields are unpacked from the log data.
 func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
-	var method = abi.Methods[name]
-
 	if len(output) == 0 {
 		return fmt.Errorf("abi: unmarshalling empty output")
 	}
+	// Contracts can't have methods and events with colliding names, so the
+	// name alone decides what we're unpacking
+	if method, ok := abi.Methods[name]; ok {
+		return unpackArguments(v, method.Outputs, output)
+	}
+	if event, ok := abi.Events[name]; ok {
+		return unpackArguments(v, event.nonIndexed(), output)
+	}
+	return fmt.Errorf("abi: could not locate named method or event '%s'", name)
+}
 
+// unpackArguments unpacks the output according to the given argument list into
+// v. Multiple arguments are unpacked into a struct (matched by capitalised field
+// names) or an interface slice, a single one directly into v, unless v is a
+// struct in which case the named field is set. A single tuple argument is always
+// unpacked directly into v.
+func unpackArguments(v interface{}, args []Argument, output []byte) error {
 	// make sure the passed value is a pointer
 	valueOf := reflect.ValueOf(v)
 	if reflect.Ptr != valueOf.Kind() {
@@ -102,15 +118,22 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 	var (
 		value = valueOf.Elem()
 		typ   = value.Type()
+
+		offsets = headOffsets(args)
 	)
+	// A single tuple is unpacked directly, other structs gather the arguments
+	single := len(args) == 1 && args[0].Type.T == TupleTy
 
-	if len(method.Outputs) > 1 {
+	if len(args) > 1 || (value.Kind() == reflect.Struct && !single) {
 		switch value.Kind() {
 		// struct will match named return values to the struct's field
 		// names
 		case reflect.Struct:
-			for i := 0; i < len(method.Outputs); i++ {
-				marshalledValue, err := toGoType(i, method.Outputs[i], output)
+			for i := 0; i < len(args); i++ {
+				if args[i].Name == "" {
+					return fmt.Errorf("abi: cannot unmarshal unnamed argument %d in to %v", i, typ)
+				}
+				marshalledValue, err := toGoType(offsets[i], args[i].Type, output)
 				if err != nil {
 					return err
 				}
@@ -119,8 +142,8 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 				for j := 0; j < typ.NumField(); j++ {
 					field := typ.Field(j)
 					// TODO read tags: `abi:"fieldName"`
-					if field.Name == strings.ToUpper(method.Outputs[i].Name[:1])+method.Outputs[i].Name[1:] {
-						if err := set(value.Field(j), reflectValue, method.Outputs[i]); err != nil {
+					if field.Name == strings.ToUpper(args[i].Name[:1])+args[i].Name[1:] {
+						if err := set(value.Field(j), reflectValue, args[i].Type); err != nil {
 							return err
 						}
 					}
@@ -133,17 +156,17 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 
 			// if the slice already contains values, set those instead of the interface slice itself.
 			if value.Len() > 0 {
-				if len(method.Outputs) > value.Len() {
-					return fmt.Errorf("abi: cannot marshal in to slices of unequal size (require: %v, got: %v)", len(method.Outputs), value.Len())
+				if len(args) > value.Len() {
+					return fmt.Errorf("abi: cannot marshal in to slices of unequal size (require: %v, got: %v)", len(args), value.Len())
 				}
 
-				for i := 0; i < len(method.Outputs); i++ {
-					marshalledValue, err := toGoType(i, method.Outputs[i], output)
+				for i := 0; i < len(args); i++ {
+					marshalledValue, err := toGoType(offsets[i], args[i].Type, output)
 					if err != nil {
 						return err
 					}
 					reflectValue := reflect.ValueOf(marshalledValue)
-					if err := set(value.Index(i).Elem(), reflectValue, method.Outputs[i]); err != nil {
+					if err := set(value.Index(i).Elem(), reflectValue, args[i].Type); err != nil {
 						return err
 					}
 				}
@@ -152,9 +175,9 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 
 			// create a new slice and start appending the unmarshalled
 			// values to the new interface slice.
-			z := reflect.MakeSlice(typ, 0, len(method.Outputs))
-			for i := 0; i < len(method.Outputs); i++ {
-				marshalledValue, err := toGoType(i, method.Outputs[i], output)
+			z := reflect.MakeSlice(typ, 0, len(args))
+			for i := 0; i < len(args); i++ {
+				marshalledValue, err := toGoType(offsets[i], args[i].Type, output)
 				if err != nil {
 					return err
 				}
@@ -166,11 +189,14 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 		}
 
 	} else {
-		marshalledValue, err := toGoType(0, method.Outputs[0], output)
+		if len(args) == 0 {
+			return fmt.Errorf("abi: no arguments to unmarshal in to %v", typ)
+		}
+		marshalledValue, err := toGoType(0, args[0].Type, output)
 		if err != nil {
 			return err
 		}
-		if err := set(value, reflect.ValueOf(marshalledValue), method.Outputs[0]); err != nil {
+		if err := set(value, reflect.ValueOf(marshalledValue), args[0].Type); err != nil {
 			return err
 		}
 	}
@@ -178,6 +204,16 @@ func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
 	return nil
 }
 
+// headOffsets returns the positions of the arguments in the head section of the
+// output. Static arrays and tuples are stored in place, spanning multiple words.
+func headOffsets(args []Argument) []int {
+	offsets := make([]int, len(args))
+	for i := 1; i < len(args); i++ {
+		offsets[i] = offsets[i-1] + args[i-1].Type.headSize()
+	}
+	return offsets
+}
+
 func (abi *ABI) UnmarshalJSON(data []byte) error {
 	var fields []struct {
 		Type      string
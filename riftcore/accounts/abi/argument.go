@@ -19,6 +19,7 @@ package abi
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Argument holds the name of the argument and the corresponding type.
@@ -29,18 +30,24 @@ type Argument struct {
 	Indexed bool // indexed is only used by events
 }
 
+// ArgumentMarshaling is the JSON representation of an argument, recursively
+// holding the components of tuple types.
+type ArgumentMarshaling struct {
+	Name         string
+	Type         string
+	InternalType string
+	Components   []ArgumentMarshaling
+	Indexed      bool
+}
+
 func (a *Argument) UnmarshalJSON(data []byte) error {
-	var extarg struct {
-		Name    string
-		Type    string
-		Indexed bool
-	}
+	var extarg ArgumentMarshaling
 	err := json.Unmarshal(data, &extarg)
 	if err != nil {
 		return fmt.Errorf("argument json err: %v", err)
 	}
 
-	a.Type, err = NewType(extarg.Type)
+	a.Type, err = newArgumentType(extarg)
 	if err != nil {
 		return err
 	}
@@ -49,3 +56,28 @@ func (a *Argument) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// newArgumentType creates the type of a JSON argument, naming any tuple it
+// contains after the source struct declared in its internal type.
+func newArgumentType(arg ArgumentMarshaling) (Type, error) {
+	typ, err := NewType(arg.Type, arg.Components...)
+	if err != nil {
+		return Type{}, err
+	}
+	// Internal types of structs look like "struct Contract.Name[2][]"
+	if name := arg.InternalType; strings.HasPrefix(name, "struct ") {
+		if i := strings.Index(name, "["); i != -1 {
+			name = name[:i]
+		}
+		name = strings.Replace(name[len("struct "):], ".", "", -1)
+
+		elem := &typ
+		for elem.Elem != nil && elem.T != TupleTy {
+			elem = elem.Elem
+		}
+		if elem.T == TupleTy {
+			elem.TupleRawName = name
+		}
+	}
+	return typ, nil
+}
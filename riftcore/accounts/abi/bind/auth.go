@@ -60,3 +60,32 @@ func NewKeyedTransactor(key *ecdsa.PrivateKey) *TransactOpts {
 		},
 	}
 }
+
+// NewSigner is a utility method to easily create a structured data signer from
+// an encrypted json key stream and the associated passphrase.
+func NewSigner(keyin io.Reader, passphrase string) (*SignOpts, error) {
+	json, err := ioutil.ReadAll(keyin)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(json, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyedSigner(key.PrivateKey), nil
+}
+
+// NewKeyedSigner is a utility method to easily create a structured data signer
+// from a single private key.
+func NewKeyedSigner(key *ecdsa.PrivateKey) *SignOpts {
+	keyAddr := crypto.PubkeyToAddress(key.PublicKey)
+	return &SignOpts{
+		From: keyAddr,
+		Signer: func(address common.Address, hash []byte) ([]byte, error) {
+			if address != keyAddr {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			return crypto.Sign(hash, key)
+		},
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/common/math"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
+	"github.com/cryptorift/riftcore/core/state"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/rift/downloader"
+	"github.com/cryptorift/riftcore/rift/filters"
+	"github.com/cryptorift/riftcore/riftdb"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// APIs returns the RPC services offered by the simulated backend. These are the
+// blockchain, transaction and filter APIs of a full node, all operating on the
+// simulated chain and its pending block.
+func (b *SimulatedBackend) APIs() []rpc.API {
+	apiBackend := &simulatedApiBackend{sim: b, am: accounts.NewManager()}
+
+	return []rpc.API{
+		{
+			Namespace: "rift",
+			Version:   "1.0",
+			Service:   &PublicSimulatedAPI{b},
+			Public:    true,
+		}, {
+			Namespace: "rift",
+			Version:   "1.0",
+			Service:   riftapi.NewPublicBlockChainAPI(apiBackend),
+			Public:    true,
+		}, {
+			Namespace: "rift",
+			Version:   "1.0",
+			Service:   riftapi.NewPublicTransactionPoolAPI(apiBackend, new(riftapi.AddrLocker)),
+			Public:    true,
+		}, {
+			Namespace: "rift",
+			Version:   "1.0",
+			Service:   filters.NewPublicFilterAPI(apiBackend, false),
+			Public:    true,
+		},
+	}
+}
+
+// RPCServer creates an RPC server serving the APIs of the simulated backend. It
+// is meant to be attached to in-process with rpc.DialInProc, allowing clients
+// such as riftclient.Client to talk to the simulator as if it was a real node.
+func (b *SimulatedBackend) RPCServer() (*rpc.Server, error) {
+	server := rpc.NewServer()
+	for _, api := range b.APIs() {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
+
+// PublicSimulatedAPI provides the general node information that is otherwise
+// sourced from the network protocol, which the simulator doesn't run.
+type PublicSimulatedAPI struct {
+	b *SimulatedBackend
+}
+
+// GasPrice returns a suggestion for a gas price.
+func (s *PublicSimulatedAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := s.b.SuggestGasPrice(ctx)
+	return (*hexutil.Big)(price), err
+}
+
+// Syncing returns false, the simulated chain is always up to date.
+func (s *PublicSimulatedAPI) Syncing() (interface{}, error) {
+	return false, nil
+}
+
+// simulatedApiBackend implements riftapi.Backend and filters.Backend on top of
+// a simulated backend, with the pending block taking the place of the pool.
+type simulatedApiBackend struct {
+	sim *SimulatedBackend
+	am  *accounts.Manager
+}
+
+func (b *simulatedApiBackend) ChainConfig() *params.ChainConfig {
+	return b.sim.config
+}
+
+func (b *simulatedApiBackend) CurrentBlock() *types.Block {
+	return b.sim.blockchain.CurrentBlock()
+}
+
+func (b *simulatedApiBackend) SetHead(number uint64) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	b.sim.blockchain.SetHead(number)
+	b.sim.rollback()
+}
+
+func (b *simulatedApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *simulatedApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		b.sim.mu.Lock()
+		defer b.sim.mu.Unlock()
+
+		return b.sim.pendingBlock, nil
+	case rpc.LatestBlockNumber:
+		return b.sim.blockchain.CurrentBlock(), nil
+	}
+	return b.sim.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+}
+
+func (b *simulatedApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	// Hand out a copy of the pending state, callers are free to modify it
+	if blockNr == rpc.PendingBlockNumber {
+		b.sim.mu.Lock()
+		defer b.sim.mu.Unlock()
+
+		return b.sim.pendingState.Copy(), b.sim.pendingBlock.Header(), nil
+	}
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := b.sim.blockchain.StateAt(header.Root)
+	return stateDb, header, err
+}
+
+func (b *simulatedApiBackend) StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	// Hand out a copy of the pending state, callers are free to modify it
+	if blockHash == b.sim.pendingBlock.Hash() {
+		return b.sim.pendingState.Copy(), b.sim.pendingBlock.Header(), nil
+	}
+	header := b.sim.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	stateDb, err := b.sim.blockchain.StateAt(header.Root)
+	return stateDb, header, err
+}
+
+func (b *simulatedApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	if blockHash == b.sim.pendingBlock.Hash() {
+		return b.sim.pendingBlock, nil
+	}
+	return b.sim.blockchain.GetBlockByHash(blockHash), nil
+}
+
+func (b *simulatedApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(b.sim.database, blockHash, core.GetBlockNumber(b.sim.database, blockHash)), nil
+}
+
+func (b *simulatedApiBackend) GetTd(blockHash common.Hash) *big.Int {
+	return b.sim.blockchain.GetTdByHash(blockHash)
+}
+
+func (b *simulatedApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	vmError := func() error { return nil }
+
+	context := core.NewEVMContext(msg, header, b.sim.blockchain, nil)
+	return vm.NewEVM(context, state, b.sim.config, vmCfg), vmError, nil
+}
+
+func (b *simulatedApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.sendTransaction(signedTx)
+}
+
+func (b *simulatedApiBackend) RemoveTx(txHash common.Hash) {
+	b.TxPoolEvict(txHash)
+}
+
+func (b *simulatedApiBackend) GetPoolTransactions() (types.Transactions, error) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.pendingBlock.Transactions(), nil
+}
+
+func (b *simulatedApiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.pendingBlock.Transaction(hash)
+}
+
+func (b *simulatedApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return b.sim.PendingNonceAt(ctx, addr)
+}
+
+func (b *simulatedApiBackend) Stats() (pending int, queued int) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return len(b.sim.pendingBlock.Transactions()), 0
+}
+
+func (b *simulatedApiBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	pending := make(map[common.Address]types.Transactions)
+	for _, tx := range b.sim.pendingBlock.Transactions() {
+		from := b.sim.sender(tx)
+		pending[from] = append(pending[from], tx)
+	}
+	return pending, make(map[common.Address]types.Transactions)
+}
+
+func (b *simulatedApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	pending, _ := b.TxPoolContent()
+	return pending[addr], nil
+}
+
+func (b *simulatedApiBackend) TxPoolEvict(txHash common.Hash) bool {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	tx := b.sim.pendingBlock.Transaction(txHash)
+	if tx == nil {
+		return false
+	}
+	from := b.sim.sender(tx)
+	b.sim.evict(func(pending *types.Transaction) bool {
+		return b.sim.sender(pending) == from && pending.Nonce() >= tx.Nonce()
+	})
+	return true
+}
+
+func (b *simulatedApiBackend) TxPoolEvictAccount(addr common.Address) int {
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.evict(func(pending *types.Transaction) bool {
+		return b.sim.sender(pending) == addr
+	})
+}
+
+func (b *simulatedApiBackend) SubscribeTxPoolEvent(ch chan<- core.TxPoolEvent) event.Subscription {
+	// The simulated pool doesn't track transaction promotions, nothing to report
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *simulatedApiBackend) Downloader() *downloader.Downloader {
+	return nil // The simulated chain is never synchronised from the network
+}
+
+func (b *simulatedApiBackend) ProtocolVersion() int {
+	return 0 // The simulated chain doesn't speak any network protocol
+}
+
+func (b *simulatedApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return b.sim.SuggestGasPrice(ctx)
+}
+
+func (b *simulatedApiBackend) ChainDb() riftdb.Database {
+	return b.sim.database
+}
+
+func (b *simulatedApiBackend) EventMux() *event.TypeMux {
+	return b.sim.mux
+}
+
+func (b *simulatedApiBackend) AccountManager() *accounts.Manager {
+	return b.am
+}
+
+func (b *simulatedApiBackend) BloomStatus() (uint64, uint64) {
+	return params.BloomBitsBlocks, 0
+}
+
+func (b *simulatedApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	panic("not supported")
+}
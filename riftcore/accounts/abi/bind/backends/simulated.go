@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/cryptorift/riftcore"
 	"github.com/cryptorift/riftcore/accounts/abi/bind"
@@ -29,18 +30,26 @@ import (
 	"github.com/cryptorift/riftcore/common/math"
 	"github.com/cryptorift/riftcore/consensus/rifthash"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
 	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/rift/filters"
+	"github.com/cryptorift/riftcore/rpc"
 )
 
 // This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
 
-var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
+var (
+	errBlockDoesNotExist       = errors.New("block does not exist in blockchain")
+	errTransactionDoesNotExist = errors.New("transaction does not exist")
+	errNegativeTimeAdjustment  = errors.New("SimulatedBackend cannot adjust time backwards")
+)
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
@@ -51,6 +60,10 @@ type SimulatedBackend struct {
 	mu           sync.Mutex
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
 	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	timeOffset   int64          // Seconds the pending block's timestamp is shifted forward by
+
+	mux    *event.TypeMux       // Event multiplexer the blockchain posts its events to
+	events *filters.EventSystem // Event system for filtering log events live
 
 	config *params.ChainConfig
 }
@@ -61,8 +74,15 @@ func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
 	database, _ := riftdb.NewMemDatabase()
 	genesis := core.Genesis{Config: params.AllProtocolChanges, Alloc: alloc}
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, genesis.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
-	backend := &SimulatedBackend{database: database, blockchain: blockchain, config: genesis.Config}
+	mux := new(event.TypeMux)
+	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, rifthash.NewFaker(), mux, vm.Config{})
+	backend := &SimulatedBackend{
+		database:   database,
+		blockchain: blockchain,
+		config:     genesis.Config,
+		mux:        mux,
+		events:     filters.NewEventSystem(mux, &filterBackend{database, blockchain, mux}, false),
+	}
 	backend.rollback()
 	return backend
 }
@@ -79,7 +99,8 @@ func (b *SimulatedBackend) Commit() {
 	b.rollback()
 }
 
-// Rollback aborts all pending transactions, reverting to the last committed state.
+// Rollback aborts all pending transactions and time adjustments, reverting to
+// the last committed state.
 func (b *SimulatedBackend) Rollback() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -88,9 +109,78 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, func(int, *core.BlockGen) {})
+	b.timeOffset = 0
+	b.regenerate(nil)
+}
+
+// regenerate rebuilds the pending block on top of the current chain head from
+// the given transactions, applying any pending time adjustment. If any of the
+// transactions fails to apply, the pending block is left untouched.
+func (b *SimulatedBackend) regenerate(txs types.Transactions) (err error) {
+	// The block generator panics on invalid transactions, turn that into an error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid transaction: %v", r)
+		}
+	}()
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, func(number int, block *core.BlockGen) {
+		// Shift the time first, the transactions must execute with the final timestamp
+		if b.timeOffset != 0 {
+			block.OffsetTime(b.timeOffset)
+		}
+		for _, tx := range txs {
+			block.AddTx(tx)
+		}
+	})
 	b.pendingBlock = blocks[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), state.NewDatabase(b.database))
+	return nil
+}
+
+// AdjustTime moves the timestamp of the pending block forward by the given
+// duration, keeping all pending transactions. Blocks committed afterwards are
+// built on top of the adjusted one, so the shift persists on the chain.
+func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
+	if adjustment < 0 {
+		return errNegativeTimeAdjustment
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offset := int64(adjustment / time.Second)
+
+	b.timeOffset += offset
+	if err := b.regenerate(b.pendingBlock.Transactions()); err != nil {
+		b.timeOffset -= offset
+		return err
+	}
+	return nil
+}
+
+// blockByNumber retrieves a canonical block from the chain, or the current head
+// if number is nil.
+func (b *SimulatedBackend) blockByNumber(number *big.Int) (*types.Block, error) {
+	if number == nil {
+		return b.blockchain.CurrentBlock(), nil
+	}
+	if !number.IsUint64() {
+		return nil, errBlockDoesNotExist
+	}
+	block := b.blockchain.GetBlockByNumber(number.Uint64())
+	if block == nil {
+		return nil, errBlockDoesNotExist
+	}
+	return block, nil
+}
+
+// stateByNumber retrieves the state of the chain after the given block, or the
+// state of the current head if number is nil.
+func (b *SimulatedBackend) stateByNumber(number *big.Int) (*state.StateDB, error) {
+	block, err := b.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return b.blockchain.StateAt(block.Root())
 }
 
 // CodeAt returns the code associated with a certain account in the blockchain.
@@ -98,10 +188,10 @@ func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetCode(contract), nil
 }
 
@@ -110,10 +200,10 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetBalance(contract), nil
 }
 
@@ -122,10 +212,10 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return 0, errBlockNumberUnsupported
+	statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return 0, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetNonce(contract), nil
 }
 
@@ -134,10 +224,10 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	val := statedb.GetState(contract, key)
 	return val[:], nil
 }
@@ -148,6 +238,88 @@ func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common
 	return receipt, nil
 }
 
+// BlockByHash retrieves a block based on the block hash, including the pending
+// one.
+func (b *SimulatedBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if hash == b.pendingBlock.Hash() {
+		return b.pendingBlock, nil
+	}
+	if block := b.blockchain.GetBlockByHash(hash); block != nil {
+		return block, nil
+	}
+	return nil, errBlockDoesNotExist
+}
+
+// BlockByNumber retrieves a block from the canonical chain. If number is nil,
+// the latest known block is returned.
+func (b *SimulatedBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockByNumber(number)
+}
+
+// HeaderByHash returns a block header based on the block hash, including the
+// pending one.
+func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	block, err := b.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+// HeaderByNumber returns a block header from the canonical chain. If number is
+// nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+// TransactionByHash returns the transaction with the given hash, either from
+// the pending block or from the canonical chain.
+func (b *SimulatedBackend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if tx := b.pendingBlock.Transaction(txHash); tx != nil {
+		return tx, true, nil
+	}
+	if tx, _, _, _ := core.GetTransaction(b.database, txHash); tx != nil {
+		return tx, false, nil
+	}
+	return nil, false, cryptorift.NotFound
+}
+
+// TransactionCount returns the number of transactions in the given block.
+func (b *SimulatedBackend) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	block, err := b.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	return uint(len(block.Transactions())), nil
+}
+
+// TransactionInBlock returns the transaction at the given index in the given
+// block.
+func (b *SimulatedBackend) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	block, err := b.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if uint64(index) >= uint64(len(txs)) {
+		return nil, errTransactionDoesNotExist
+	}
+	return txs[index], nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	b.mu.Lock()
@@ -161,14 +333,15 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call cryptorift.Cal
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	block, err := b.blockByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	state, err := b.blockchain.State()
+	state, err := b.blockchain.StateAt(block.Root())
 	if err != nil {
 		return nil, err
 	}
-	rval, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	rval, _, _, err := b.callContract(ctx, call, block, state)
 	return rval, err
 }
 
@@ -178,7 +351,7 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call cryptor
 	defer b.mu.Unlock()
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
-	rval, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	rval, _, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
 	return rval, err
 }
 
@@ -216,11 +389,11 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call cryptorift.Call
 		call.Gas = new(big.Int).SetUint64(mid)
 
 		snapshot := b.pendingState.Snapshot()
-		_, gas, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+		_, _, failed, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
 		b.pendingState.RevertToSnapshot(snapshot)
 
-		// If the transaction became invalid or used all the gas (failed), raise the gas limit
-		if err != nil || gas.Cmp(call.Gas) == 0 {
+		// If the transaction became invalid or execution failed, raise the gas limit
+		if err != nil || failed {
 			lo = mid
 			continue
 		}
@@ -232,7 +405,7 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call cryptorift.Call
 
 // callContract implemens common code between normal and pending contract calls.
 // state is modified during execution, make sure to copy it if necessary.
-func (b *SimulatedBackend) callContract(ctx context.Context, call cryptorift.CallMsg, block *types.Block, statedb *state.StateDB) ([]byte, *big.Int, error) {
+func (b *SimulatedBackend) callContract(ctx context.Context, call cryptorift.CallMsg, block *types.Block, statedb *state.StateDB) ([]byte, *big.Int, bool, error) {
 	// Ensure message is initialized properly.
 	if call.GasPrice == nil {
 		call.GasPrice = big.NewInt(1)
@@ -254,8 +427,8 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call cryptorift.Cal
 	// about the transaction and calling mechanisms.
 	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{})
 	gaspool := new(core.GasPool).AddGas(math.MaxBig256)
-	ret, gasUsed, _, err := core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
-	return ret, gasUsed, err
+	ret, gasUsed, _, failed, err := core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
+	return ret, gasUsed, failed, err
 }
 
 // SendTransaction updates the pending block to include the given transaction.
@@ -264,24 +437,149 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	if err := b.sendTransaction(tx); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// sendTransaction validates the given transaction against the pending state and
+// adds it to the pending block. The caller must hold the lock.
+func (b *SimulatedBackend) sendTransaction(tx *types.Transaction) error {
+	sender, err := types.Sender(types.MakeSigner(b.config, b.pendingBlock.Number()), tx)
 	if err != nil {
-		panic(fmt.Errorf("invalid transaction: %v", err))
+		return fmt.Errorf("invalid transaction: %v", err)
 	}
 	nonce := b.pendingState.GetNonce(sender)
 	if tx.Nonce() != nonce {
-		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
+		return fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce)
 	}
+	// Execute the transaction on top of a copy of the pending state, rejecting it
+	// if it can't be included (insufficient balance, gas limits, etc)
+	header := b.pendingBlock.Header()
+	gaspool := new(core.GasPool).AddGas(new(big.Int).Sub(header.GasLimit, header.GasUsed))
+	if _, _, err := core.ApplyTransaction(b.config, nil, &header.Coinbase, gaspool, b.pendingState.Copy(), header, tx, new(big.Int).Set(header.GasUsed), vm.Config{}); err != nil {
+		return fmt.Errorf("invalid transaction: %v", err)
+	}
+	return b.regenerate(append(b.pendingBlock.Transactions(), tx))
+}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
+// sender returns the sender of a transaction already accepted into the pending
+// block. The caller must hold the lock.
+func (b *SimulatedBackend) sender(tx *types.Transaction) common.Address {
+	from, _ := types.Sender(types.MakeSigner(b.config, b.pendingBlock.Number()), tx)
+	return from
+}
+
+// evict drops all transactions matched by the filter from the pending block,
+// returning the number of transactions dropped. The caller must hold the lock.
+func (b *SimulatedBackend) evict(match func(*types.Transaction) bool) int {
+	var keep types.Transactions
+	for _, tx := range b.pendingBlock.Transactions() {
+		if !match(tx) {
+			keep = append(keep, tx)
 		}
-		block.AddTx(tx)
-	})
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), state.NewDatabase(b.database))
-	return nil
+	}
+	dropped := len(b.pendingBlock.Transactions()) - len(keep)
+	if dropped > 0 {
+		// Remaining transactions may depend on the dropped ones, keep all if so
+		if err := b.regenerate(keep); err != nil {
+			log.Warn("Failed to evict simulated transactions", "err", err)
+			return 0
+		}
+	}
+	return dropped
+}
+
+// FilterLogs executes a log filter operation, blocking during execution and
+// returning all the results in one batch.
+func (b *SimulatedBackend) FilterLogs(ctx context.Context, query cryptorift.FilterQuery) ([]types.Log, error) {
+	// Initialize unset filter boundaries to run from genesis to chain head
+	from := int64(0)
+	if query.FromBlock != nil {
+		from = query.FromBlock.Int64()
+	}
+	to := int64(-1)
+	if query.ToBlock != nil {
+		to = query.ToBlock.Int64()
+	}
+	// Construct and execute the filter
+	filter := filters.New(&filterBackend{b.database, b.blockchain, nil})
+	filter.SetBeginBlock(from)
+	filter.SetEndBlock(to)
+	filter.SetAddresses(query.Addresses)
+	filter.SetTopics(query.Topics)
+
+	logs, err := filter.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]types.Log, len(logs))
+	for i, log := range logs {
+		res[i] = *log
+	}
+	return res, nil
+}
+
+// SubscribeFilterLogs creates a background log filtering operation, returning a
+// subscription immediately, which can be used to stream the found events.
+func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query cryptorift.FilterQuery, ch chan<- types.Log) (cryptorift.Subscription, error) {
+	// Subscribe to contract events
+	sink := make(chan []*types.Log)
+
+	sub, err := b.events.SubscribeLogs(filters.FilterCriteria(query), sink)
+	if err != nil {
+		return nil, err
+	}
+	// Since we're getting logs in batches, we need to flatten them into a plain stream
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case logs := <-sink:
+				for _, log := range logs {
+					select {
+					case ch <- *log:
+					case err := <-sub.Err():
+						return err
+					case <-quit:
+						return nil
+					}
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// SubscribeNewHead creates a background subscription to the headers of newly
+// committed blocks.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (cryptorift.Subscription, error) {
+	sink := make(chan *types.Header)
+	sub := b.events.SubscribeNewHeads(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case head := <-sink:
+				select {
+				case ch <- head:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
 }
 
 // callmsg implements core.Message to allow passing it as a transaction simulator.
@@ -297,3 +595,31 @@ func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
 func (m callmsg) Gas() *big.Int        { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
 func (m callmsg) Data() []byte         { return m.CallMsg.Data }
+
+// filterBackend implements filters.Backend to support filtering for logs without
+// taking bloom-bits acceleration structures into account.
+type filterBackend struct {
+	db  riftdb.Database
+	bc  *core.BlockChain
+	mux *event.TypeMux
+}
+
+func (fb *filterBackend) ChainDb() riftdb.Database { return fb.db }
+func (fb *filterBackend) EventMux() *event.TypeMux { return fb.mux }
+
+func (fb *filterBackend) HeaderByNumber(ctx context.Context, block rpc.BlockNumber) (*types.Header, error) {
+	if block == rpc.LatestBlockNumber {
+		return fb.bc.CurrentHeader(), nil
+	}
+	return fb.bc.GetHeaderByNumber(uint64(block.Int64())), nil
+}
+
+func (fb *filterBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(fb.db, hash, core.GetBlockNumber(fb.db, hash)), nil
+}
+
+func (fb *filterBackend) BloomStatus() (uint64, uint64) { return params.BloomBitsBlocks, 0 }
+
+func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
+	panic("not supported")
+}
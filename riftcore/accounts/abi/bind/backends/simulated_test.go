@@ -0,0 +1,378 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore"
+	"github.com/cryptorift/riftcore/accounts/abi/bind/backends"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/riftclient"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(10000000000)
+
+	// testLogCode deploys a contract emitting a single log with topic 0x2a and
+	// no data whenever it is called.
+	testLogCode  = common.FromHex("6008600c60003960086000f3602a60006000a100")
+	testLogTopic = common.BigToHash(big.NewInt(0x2a))
+)
+
+func newTestBackend() *backends.SimulatedBackend {
+	return backends.NewSimulatedBackend(core.GenesisAlloc{testAddr: {Balance: testBalance}})
+}
+
+// sendTestTx signs the given transaction with the test key and sends it to the
+// simulated backend.
+func sendTestTx(t *testing.T, sim *backends.SimulatedBackend, tx *types.Transaction) *types.Transaction {
+	tx, err := types.SignTx(tx, types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	return tx
+}
+
+func TestSimulatedAdjustTime(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	sim.Commit()
+	prev, _ := sim.HeaderByNumber(ctx, nil)
+
+	if err := sim.AdjustTime(-time.Second); err == nil {
+		t.Fatalf("negative time adjustment accepted")
+	}
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sim.Commit()
+
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	if diff := new(big.Int).Sub(head.Time, prev.Time); diff.Cmp(big.NewInt(3600)) < 0 {
+		t.Errorf("block time not adjusted: have %v seconds between blocks, want at least 3600", diff)
+	}
+	// Adjustments must survive transactions arriving afterwards
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sendTestTx(t, sim, types.NewTransaction(0, common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil))
+	sim.Commit()
+
+	prev = head
+	head, _ = sim.HeaderByNumber(ctx, nil)
+	if diff := new(big.Int).Sub(head.Time, prev.Time); diff.Cmp(big.NewInt(3600)) < 0 {
+		t.Errorf("time adjustment lost on new transaction: have %v seconds between blocks, want at least 3600", diff)
+	}
+	// Rollbacks must discard pending adjustments
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sim.Rollback()
+	sim.Commit()
+
+	prev = head
+	head, _ = sim.HeaderByNumber(ctx, nil)
+	if diff := new(big.Int).Sub(head.Time, prev.Time); diff.Cmp(big.NewInt(3600)) >= 0 {
+		t.Errorf("time adjustment survived rollback: have %v seconds between blocks, want less than 3600", diff)
+	}
+}
+
+// Tests that pending transactions depending on the block time execute with the
+// adjusted timestamp, both when sent before and after the adjustment.
+func TestSimulatedAdjustTimeContract(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	// Contract creation storing TIMESTAMP into slot 0 (TIMESTAMP PUSH1 0 SSTORE)
+	code := common.FromHex("4260005500")
+
+	deploy := func(nonce uint64) common.Address {
+		tx, _ := types.SignTx(types.NewContractCreation(nonce, big.NewInt(0), big.NewInt(100000), big.NewInt(1), code), types.HomesteadSigner{}, testKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+		return crypto.CreateAddress(testAddr, nonce)
+	}
+	check := func(addr common.Address) {
+		head, _ := sim.HeaderByNumber(ctx, nil)
+		stored, err := sim.StorageAt(ctx, addr, common.Hash{}, nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve storage: %v", err)
+		}
+		if have := new(big.Int).SetBytes(stored); have.Cmp(head.Time) != 0 {
+			t.Errorf("stored timestamp mismatch: have %v, want %v", have, head.Time)
+		}
+	}
+	// Transaction pending while the time is adjusted
+	addr := deploy(0)
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sim.Commit()
+	check(addr)
+
+	// Transaction arriving after the time was adjusted
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	addr = deploy(1)
+	sim.Commit()
+	check(addr)
+}
+
+func TestSimulatedChainAccess(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	genesis, err := sim.BlockByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("failed to retrieve genesis block: %v", err)
+	}
+	tx := sendTestTx(t, sim, types.NewTransaction(0, common.Address{1}, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil))
+
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || !pending {
+		t.Fatalf("pending transaction lookup mismatch: pending %v, err %v", pending, err)
+	}
+	sim.Commit()
+
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || pending {
+		t.Fatalf("mined transaction lookup mismatch: pending %v, err %v", pending, err)
+	}
+	block, err := sim.BlockByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve head block: %v", err)
+	}
+	if block.NumberU64() != 1 || block.ParentHash() != genesis.Hash() {
+		t.Fatalf("head block mismatch: have #%d (parent %x), want #1 (parent %x)", block.NumberU64(), block.ParentHash(), genesis.Hash())
+	}
+	if header, err := sim.HeaderByHash(ctx, block.Hash()); err != nil || header.Hash() != block.Hash() {
+		t.Errorf("header by hash mismatch: have %v, err %v", header, err)
+	}
+	if header, err := sim.HeaderByNumber(ctx, big.NewInt(1)); err != nil || header.Hash() != block.Hash() {
+		t.Errorf("header by number mismatch: have %v, err %v", header, err)
+	}
+	if count, err := sim.TransactionCount(ctx, block.Hash()); err != nil || count != 1 {
+		t.Errorf("transaction count mismatch: have %d, want 1, err %v", count, err)
+	}
+	if have, err := sim.TransactionInBlock(ctx, block.Hash(), 0); err != nil || have.Hash() != tx.Hash() {
+		t.Errorf("transaction in block mismatch: have %v, err %v", have, err)
+	}
+	if _, err := sim.TransactionInBlock(ctx, block.Hash(), 1); err == nil {
+		t.Errorf("out of bounds transaction index accepted")
+	}
+	if _, err := sim.BlockByNumber(ctx, big.NewInt(2)); err == nil {
+		t.Errorf("future block retrieved")
+	}
+	// Ensure that historical state is accessible
+	if balance, err := sim.BalanceAt(ctx, common.Address{1}, big.NewInt(0)); err != nil || balance.Sign() != 0 {
+		t.Errorf("genesis balance mismatch: have %v, want 0, err %v", balance, err)
+	}
+	if balance, err := sim.BalanceAt(ctx, common.Address{1}, big.NewInt(1)); err != nil || balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("block #1 balance mismatch: have %v, want 1000, err %v", balance, err)
+	}
+}
+
+func TestSimulatedLogs(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	deploy := sendTestTx(t, sim, types.NewContractCreation(0, new(big.Int), big.NewInt(100000), big.NewInt(1), testLogCode))
+	sim.Commit()
+
+	receipt, _ := sim.TransactionReceipt(ctx, deploy.Hash())
+	query := cryptorift.FilterQuery{Addresses: []common.Address{receipt.ContractAddress}}
+
+	logs := make(chan types.Log, 1)
+	sub, err := sim.SubscribeFilterLogs(ctx, query, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe to logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	heads := make(chan *types.Header, 1)
+	headSub, err := sim.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		t.Fatalf("failed to subscribe to heads: %v", err)
+	}
+	defer headSub.Unsubscribe()
+
+	sendTestTx(t, sim, types.NewTransaction(1, receipt.ContractAddress, new(big.Int), big.NewInt(100000), big.NewInt(1), nil))
+	sim.Commit()
+
+	select {
+	case log := <-logs:
+		if log.BlockNumber != 2 || len(log.Topics) != 1 || log.Topics[0] != testLogTopic {
+			t.Errorf("log mismatch: have %v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("log subscription timed out")
+	}
+	// Head events are posted asynchronously, the previous block's may still arrive
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case head := <-heads:
+			done = head.Number.Uint64() == 2
+		case <-timeout:
+			t.Fatalf("head subscription timed out")
+		}
+	}
+	found, err := sim.FilterLogs(ctx, query)
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if len(found) != 1 || found[0].Topics[0] != testLogTopic {
+		t.Errorf("filtered logs mismatch: have %v", found)
+	}
+}
+
+func TestSimulatedRPC(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	server, err := sim.RPCServer()
+	if err != nil {
+		t.Fatalf("failed to create RPC server: %v", err)
+	}
+	defer server.Stop()
+
+	conn := rpc.DialInProc(server)
+	defer conn.Close()
+
+	client := riftclient.NewClient(conn)
+
+	// Deploy the log contract through the client, signing for the chain
+	signer := types.NewEIP155Signer(params.AllProtocolChanges.ChainId)
+	deploy, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), big.NewInt(100000), big.NewInt(1), testLogCode), signer, testKey)
+	if err := client.SendTransaction(ctx, deploy); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	if _, pending, err := client.TransactionByHash(ctx, deploy.Hash()); err != nil || !pending {
+		t.Fatalf("pending transaction lookup mismatch: pending %v, err %v", pending, err)
+	}
+	sim.Commit()
+
+	receipt, err := client.TransactionReceipt(ctx, deploy.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve receipt: %v", err)
+	}
+	if code, err := client.CodeAt(ctx, receipt.ContractAddress, nil); err != nil || len(code) == 0 {
+		t.Fatalf("contract not deployed: code %x, err %v", code, err)
+	}
+	nonce, err := client.PendingNonceAt(ctx, testAddr)
+	if err != nil || nonce != 1 {
+		t.Fatalf("pending nonce mismatch: have %d, want 1, err %v", nonce, err)
+	}
+	// Emit a log and ensure both subscriptions and queries see it
+	query := cryptorift.FilterQuery{Addresses: []common.Address{receipt.ContractAddress}}
+
+	logs := make(chan types.Log, 1)
+	sub, err := client.SubscribeFilterLogs(ctx, query, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe to logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	call, _ := types.SignTx(types.NewTransaction(nonce, receipt.ContractAddress, new(big.Int), big.NewInt(100000), big.NewInt(1), nil), signer, testKey)
+	if err := client.SendTransaction(ctx, call); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	select {
+	case log := <-logs:
+		if log.TxHash != call.Hash() || log.Topics[0] != testLogTopic {
+			t.Errorf("log mismatch: have %v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("log subscription timed out")
+	}
+	found, err := client.FilterLogs(ctx, query)
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if len(found) != 1 || found[0].TxHash != call.Hash() {
+		t.Errorf("filtered logs mismatch: have %v", found)
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil || head.Number.Uint64() != 2 {
+		t.Fatalf("head mismatch: have %v, err %v", head, err)
+	}
+	if block, err := client.BlockByHash(ctx, head.Hash()); err != nil || len(block.Transactions()) != 1 {
+		t.Errorf("block by hash mismatch: have %v, err %v", block, err)
+	}
+	if balance, err := client.BalanceAt(ctx, testAddr, big.NewInt(0)); err != nil || balance.Cmp(testBalance) != 0 {
+		t.Errorf("genesis balance mismatch: have %v, want %v, err %v", balance, testBalance, err)
+	}
+}
+
+// Tests that invalid transactions sent over RPC are rejected with an error,
+// leaving the pending block untouched.
+func TestSimulatedRPCInvalidTx(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	server, err := sim.RPCServer()
+	if err != nil {
+		t.Fatalf("failed to create RPC server: %v", err)
+	}
+	defer server.Stop()
+
+	conn := rpc.DialInProc(server)
+	defer conn.Close()
+
+	client := riftclient.NewClient(conn)
+	signer := types.NewEIP155Signer(params.AllProtocolChanges.ChainId)
+
+	invalid := map[string]*types.Transaction{
+		"insufficient balance": types.NewTransaction(0, common.Address{}, testBalance, big.NewInt(21000), big.NewInt(1), nil),
+		"intrinsic gas":        types.NewTransaction(0, common.Address{}, new(big.Int), big.NewInt(20000), big.NewInt(1), nil),
+		"block gas limit":      types.NewTransaction(0, common.Address{}, new(big.Int), new(big.Int).Mul(params.GenesisGasLimit, big.NewInt(2)), big.NewInt(0), nil),
+	}
+	for name, tx := range invalid {
+		tx, _ = types.SignTx(tx, signer, testKey)
+		if err := client.SendTransaction(ctx, tx); err == nil {
+			t.Errorf("%s: transaction accepted", name)
+		}
+	}
+	if count, err := client.PendingTransactionCount(ctx); err != nil || count != 0 {
+		t.Fatalf("pending transaction count mismatch: have %d, want 0, err %v", count, err)
+	}
+	// Ensure the simulator is still operational and accepts valid transactions
+	valid, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, testKey)
+	if err := client.SendTransaction(ctx, valid); err != nil {
+		t.Fatalf("failed to send valid transaction: %v", err)
+	}
+	if count, err := client.PendingTransactionCount(ctx); err != nil || count != 1 {
+		t.Fatalf("pending transaction count mismatch: have %d, want 1, err %v", count, err)
+	}
+}
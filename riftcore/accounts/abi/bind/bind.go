@@ -22,11 +22,12 @@ package bind
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
-	"unicode"
 
 	"github.com/cryptorift/riftcore/accounts/abi"
 	"golang.org/x/tools/imports"
@@ -45,7 +46,12 @@ const (
 // to be used as is in client code, but rather as an intermediate struct which
 // enforces compile time type safety and naming convention opposed to having to
 // manually maintain hard coded strings that break on runtime.
-func Bind(types []string, abis []string, bytecodes []string, pkg string, lang Lang) (string, error) {
+//
+// The optional metadata holds the solc metadata of each contract. The libs map
+// the link placeholders solc leaves in the bytecodes to the fully qualified names
+// of the libraries they stand for, the addresses of which are taken by the deploy
+// methods of all contracts linked against them.
+func Bind(types []string, abis []string, bytecodes []string, metadata []string, pkg string, lang Lang, libs map[string]string) (string, error) {
 	// Process each individual contract requested binding
 	contracts := make(map[string]*tmplContract)
 
@@ -55,13 +61,12 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 		if err != nil {
 			return "", err
 		}
-		// Strip any whitespace from the JSON ABI
-		strippedABI := strings.Map(func(r rune) rune {
-			if unicode.IsSpace(r) {
-				return -1
-			}
-			return r
-		}, abis[i])
+		// Strip any whitespace from the JSON ABI, leaving the contents of strings
+		stripped := new(bytes.Buffer)
+		if err := json.Compact(stripped, []byte(abis[i])); err != nil {
+			return "", err
+		}
+		strippedABI := stripped.String()
 
 		// Extract the call and transact methods, and sort them alphabetically
 		var (
@@ -94,24 +99,85 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 				transacts[original.Name] = &tmplMethod{Original: original, Normalized: normalized, Structured: structured(original)}
 			}
 		}
+		// Extract the events, skipping anonymous ones as they can't be filtered on
+		events := make(map[string]*tmplEvent)
+		for _, original := range evmABI.Events {
+			if original.Anonymous {
+				continue
+			}
+			// Normalize the event for capital cases and non-anonymous fields
+			normalized := original
+			normalized.Name = methodNormalizer[lang](original.Name)
+
+			normalized.Inputs = make([]abi.Argument, len(original.Inputs))
+			copy(normalized.Inputs, original.Inputs)
+			for j, input := range normalized.Inputs {
+				if input.Name == "" {
+					normalized.Inputs[j].Name = fmt.Sprintf("arg%d", j)
+				}
+			}
+			events[original.Name] = &tmplEvent{Original: original, Normalized: normalized}
+		}
+		// Gather the libraries the contract needs to be linked against
+		libraries, err := linkLibraries(types[i], bytecodes[i], libs, evmABI.Constructor)
+		if err != nil {
+			return "", err
+		}
+		if lang != LangGo && len(libraries) > 0 {
+			return "", fmt.Errorf("library linking of %s is only supported in Go bindings", types[i])
+		}
+		// Strip any whitespace from the metadata too, and dig out the source hash
+		var inputMetadata, sourceHash string
+		if metadata != nil && metadata[i] != "" {
+			stripped := new(bytes.Buffer)
+			if err := json.Compact(stripped, []byte(metadata[i])); err != nil {
+				return "", fmt.Errorf("invalid metadata of %s: %v", types[i], err)
+			}
+			inputMetadata, sourceHash = stripped.String(), metadataSourceHash(metadata[i])
+		}
 		contracts[types[i]] = &tmplContract{
-			Type:        capitalise(types[i]),
-			InputABI:    strings.Replace(strippedABI, "\"", "\\\"", -1),
-			InputBin:    strings.TrimSpace(bytecodes[i]),
-			Constructor: evmABI.Constructor,
-			Calls:       calls,
-			Transacts:   transacts,
+			Type:          capitalise(types[i]),
+			InputABI:      strings.Replace(strippedABI, "\"", "\\\"", -1),
+			InputBin:      strings.TrimSpace(bytecodes[i]),
+			InputMetadata: inputMetadata,
+			SourceHash:    sourceHash,
+			Libraries:     libraries,
+			Constructor:   evmABI.Constructor,
+			Calls:         calls,
+			Transacts:     transacts,
+			Events:        events,
+		}
+	}
+	// Map the tuples of all contracts to structs, visiting them in a fixed order
+	// so the generated struct names are stable across runs
+	structs := make(map[string]*tmplStruct)
+
+	names := append([]string{}, types...)
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, arg := range contractArguments(contracts[name]) {
+			if lang != LangGo && hasTuple(arg.Type) {
+				return "", fmt.Errorf("tuple argument %s of %s is only supported in Go bindings", arg.Name, name)
+			}
+			bindType[lang](arg.Type, structs)
 		}
 	}
 	// Generate the contract template data content and render it
 	data := &tmplData{
 		Package:   pkg,
 		Contracts: contracts,
+		Structs:   structs,
 	}
 	buffer := new(bytes.Buffer)
 
 	funcs := map[string]interface{}{
-		"bindtype":     bindType[lang],
+		"bindtype": func(kind abi.Type) string {
+			return bindType[lang](kind, structs)
+		},
+		"bindtopictype": func(kind abi.Type) string {
+			return bindTopicType[lang](kind, structs)
+		},
 		"namedtype":    namedType[lang],
 		"capitalise":   capitalise,
 		"decapitalise": decapitalise,
@@ -133,8 +199,9 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 }
 
 // bindType is a set of type binders that convert Solidity types to some supported
-// programming language.
-var bindType = map[Lang]func(kind abi.Type) string{
+// programming language. Tuples are mapped to the structs collected in the given
+// map.
+var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTypeGo,
 	LangJava: bindTypeJava,
 }
@@ -142,7 +209,16 @@ var bindType = map[Lang]func(kind abi.Type) string{
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
 // from all Solidity types to Go ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. *big.Int).
-func bindTypeGo(kind abi.Type) string {
+func bindTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	// Lists and tuples are converted recursively, nesting their element types
+	switch {
+	case kind.T == abi.TupleTy:
+		return bindStructTypeGo(kind, structs)
+	case isList(kind) && kind.IsSlice:
+		return "[]" + bindTypeGo(*kind.Elem, structs)
+	case isList(kind):
+		return fmt.Sprintf("[%d]%s", kind.SliceSize, bindTypeGo(*kind.Elem, structs))
+	}
 	stringKind := kind.String()
 
 	switch {
@@ -183,10 +259,37 @@ func bindTypeGo(kind abi.Type) string {
 	}
 }
 
+// bindTopicType is a set of type binders that convert Solidity types to some
+// supported programming language topic types.
+var bindTopicType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
+	LangGo:   bindTopicTypeGo,
+	LangJava: bindTopicTypeJava,
+}
+
+// bindTopicTypeGo converts a Solidity topic type to a Go one. It is almost the
+// same functionality as for simple types, but dynamic types get converted to
+// hashes, as indexed dynamic values are stored as the Keccak256 of their value.
+func bindTopicTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	if kind.T == abi.StringTy || kind.T == abi.BytesTy || kind.T == abi.TupleTy || strings.Contains(kind.String(), "[") {
+		return "common.Hash"
+	}
+	return bindTypeGo(kind, structs)
+}
+
+// bindTopicTypeJava converts a Solidity topic type to a Java one. It is almost the
+// same functionality as for simple types, but dynamic types get converted to
+// hashes, as indexed dynamic values are stored as the Keccak256 of their value.
+func bindTopicTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
+	if kind.T == abi.StringTy || kind.T == abi.BytesTy || strings.Contains(kind.String(), "[") {
+		return "Hash"
+	}
+	return bindTypeJava(kind, structs)
+}
+
 // bindTypeJava converts a Solidity type to a Java one. Since there is no clear mapping
 // from all Solidity types to Java ones (e.g. uint17), those that cannot be exactly
-// mapped will use an upscaled type (e.g. BigDecimal).
-func bindTypeJava(kind abi.Type) string {
+// mapped will use an upscaled type (e.g. BigDecimal). Tuples are not supported.
+func bindTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	stringKind := kind.String()
 
 	switch {
@@ -254,6 +357,90 @@ func bindTypeJava(kind abi.Type) string {
 	}
 }
 
+// bindStructTypeGo converts a Solidity tuple type to a Go struct, recording it
+// in the given map. Tuples with the same fields share a single struct, named
+// after the source struct if the ABI declares it.
+func bindStructTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	fields := make([]*tmplField, len(kind.TupleElems))
+	decls := make([]string, len(kind.TupleElems))
+	for i, elem := range kind.TupleElems {
+		fields[i] = &tmplField{
+			Type:    bindTypeGo(*elem, structs),
+			Name:    abi.ToCamelCase(kind.TupleRawNames[i]),
+			SolKind: *elem,
+		}
+		decls[i] = fields[i].Name + " " + fields[i].Type
+	}
+	id := kind.TupleRawName + "{" + strings.Join(decls, ";") + "}"
+	if s, ok := structs[id]; ok {
+		return s.Name
+	}
+	// Suffix the name with a counter if it's anonymous or already taken
+	name := "Struct"
+	if kind.TupleRawName != "" {
+		name = capitalise(kind.TupleRawName)
+	}
+	taken := func(name string) bool {
+		for _, s := range structs {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	if kind.TupleRawName == "" || taken(name) {
+		for i := 0; ; i++ {
+			if candidate := fmt.Sprintf("%s%d", name, i); !taken(candidate) {
+				name = candidate
+				break
+			}
+		}
+	}
+	structs[id] = &tmplStruct{Name: name, Fields: fields}
+	return name
+}
+
+// isList returns whether the type is an array or slice of other types, as
+// opposed to the byte based types that are flagged the same way.
+func isList(kind abi.Type) bool {
+	return (kind.IsSlice || kind.IsArray) && kind.T != abi.BytesTy && kind.T != abi.FixedBytesTy && kind.T != abi.FunctionTy
+}
+
+// hasTuple returns whether the type is or contains a tuple.
+func hasTuple(kind abi.Type) bool {
+	if kind.Elem != nil && isList(kind) {
+		return hasTuple(*kind.Elem)
+	}
+	return kind.T == abi.TupleTy
+}
+
+// contractArguments returns all the arguments of the contract's constructor,
+// methods and events, the latter ones ordered by name.
+func contractArguments(contract *tmplContract) []abi.Argument {
+	args := append([]abi.Argument{}, contract.Constructor.Inputs...)
+
+	for _, methods := range []map[string]*tmplMethod{contract.Calls, contract.Transacts} {
+		names := make([]string, 0, len(methods))
+		for name := range methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			args = append(args, methods[name].Normalized.Inputs...)
+			args = append(args, methods[name].Normalized.Outputs...)
+		}
+	}
+	names := make([]string, 0, len(contract.Events))
+	for name := range contract.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, contract.Events[name].Normalized.Inputs...)
+	}
+	return args
+}
+
 // namedType is a set of functions that transform language specific types to
 // named versions that my be used inside method names.
 var namedType = map[Lang]func(string, abi.Type) string{
@@ -309,6 +496,81 @@ func capitalise(input string) string {
 	return strings.ToUpper(input[:1]) + input[1:]
 }
 
+// linkLibraries gathers the libraries a contract's bytecode needs to be linked
+// against, naming the deploy parameters taking their addresses so they clash
+// neither with each other nor with the constructor arguments. Placeholders that
+// don't belong to any known library are reported as an error.
+func linkLibraries(kind string, bytecode string, libs map[string]string, constructor abi.Method) ([]*tmplLibrary, error) {
+	// Collect the placeholders of each library present in the bytecode
+	found := make(map[string]*tmplLibrary)
+	for pattern, name := range libs {
+		if !strings.Contains(bytecode, pattern) {
+			continue
+		}
+		if found[name] == nil {
+			found[name] = &tmplLibrary{Name: name}
+		}
+		found[name].Patterns = append(found[name].Patterns, pattern)
+		bytecode = strings.Replace(bytecode, pattern, "", -1)
+	}
+	if idx := strings.Index(bytecode, "__"); idx >= 0 {
+		placeholder := bytecode[idx:]
+		if len(placeholder) > 40 {
+			placeholder = placeholder[:40]
+		}
+		return nil, fmt.Errorf("unresolved library placeholder %s in bytecode of %s", placeholder, kind)
+	}
+	// Name the parameters in a stable order, avoiding all other identifiers
+	taken := map[string]bool{"auth": true, "backend": true, "parsed": true, "bin": true, "address": true, "tx": true, "contract": true, "err": true}
+	for _, input := range constructor.Inputs {
+		taken[input.Name] = true
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	libraries := make([]*tmplLibrary, 0, len(names))
+	for _, name := range names {
+		lib := found[name]
+		sort.Strings(lib.Patterns)
+
+		parts := strings.Split(lib.Name, ":")
+		base := decapitalise(parts[len(parts)-1]) + "Addr"
+
+		lib.Param = base
+		for j := 1; taken[lib.Param]; j++ {
+			lib.Param = fmt.Sprintf("%s%d", base, j)
+		}
+		taken[lib.Param] = true
+
+		libraries = append(libraries, lib)
+	}
+	return libraries, nil
+}
+
+// metadataSourceHash extracts the keccak256 hash of the source file a contract
+// was compiled from out of its solc metadata, or returns an empty string if the
+// metadata doesn't contain it.
+func metadataSourceHash(metadata string) string {
+	var meta struct {
+		Settings struct {
+			CompilationTarget map[string]string `json:"compilationTarget"`
+		} `json:"settings"`
+		Sources map[string]struct {
+			Keccak256 string `json:"keccak256"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		return ""
+	}
+	for file := range meta.Settings.CompilationTarget {
+		return meta.Sources[file].Keccak256
+	}
+	return ""
+}
+
 // decapitalise makes the first character of a string lower case.
 func decapitalise(input string) string {
 	return strings.ToLower(input[:1]) + input[1:]
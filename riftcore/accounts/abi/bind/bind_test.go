@@ -447,6 +447,242 @@ var bindTests = []struct {
 			}
 		`,
 	},
+	// Tests that events can be filtered and watched through the generated bindings.
+	// The bytecode is hand assembled to emit Received with the arguments of any call.
+	{
+		`Eventer`,
+		`
+			contract Eventer {
+				event Received(address indexed from, int indexed delta, uint amount, bool flag);
+
+				function raise(address from, int delta, uint amount, bool flag) {
+					Received(from, delta, amount, flag);
+				}
+			}
+		`,
+		`603480600b6000396000f3604060446000376024356004357fe3cf73e421e005f81db68e41576d7804d9077d1083c9973fb57a8b2be8bade8460406000a300`,
+		`[{"constant":false,"inputs":[{"name":"from","type":"address"},{"name":"delta","type":"int256"},{"name":"amount","type":"uint256"},{"name":"flag","type":"bool"}],"name":"raise","outputs":[],"type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"delta","type":"int256"},{"indexed":false,"name":"amount","type":"uint256"},{"indexed":false,"name":"flag","type":"bool"}],"name":"Received","type":"event"}]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}})
+
+			// Deploy an event tester contract and start watching for events from a single sender
+			_, _, eventer, err := DeployEventer(auth, sim)
+			if err != nil {
+				t.Fatalf("Failed to deploy eventer contract: %v", err)
+			}
+			sim.Commit()
+
+			sink := make(chan *EventerReceived, 4)
+			sub, err := eventer.WatchReceived(nil, sink, []common.Address{common.Address{1}}, nil)
+			if err != nil {
+				t.Fatalf("Failed to watch for events: %v", err)
+			}
+			defer sub.Unsubscribe()
+
+			// Raise a few events and check that they can all be filtered
+			deltas := []int64{-2, 1, 3}
+			for i, delta := range deltas {
+				if _, err := eventer.Raise(auth, common.Address{byte(i%2 + 1)}, big.NewInt(delta), big.NewInt(int64(i)), i == 1); err != nil {
+					t.Fatalf("Failed to raise event %d: %v", i, err)
+				}
+			}
+			sim.Commit()
+
+			it, err := eventer.FilterReceived(nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to filter events: %v", err)
+			}
+			var found int
+			for ; it.Next(); found++ {
+				if want := (common.Address{byte(found%2 + 1)}); it.Event.From != want {
+					t.Errorf("Event %d: sender mismatch: have %x, want %x", found, it.Event.From, want)
+				}
+				if it.Event.Delta.Cmp(big.NewInt(deltas[found])) != 0 {
+					t.Errorf("Event %d: delta mismatch: have %v, want %v", found, it.Event.Delta, deltas[found])
+				}
+				if it.Event.Amount.Cmp(big.NewInt(int64(found))) != 0 {
+					t.Errorf("Event %d: amount mismatch: have %v, want %v", found, it.Event.Amount, found)
+				}
+				if it.Event.Flag != (found == 1) {
+					t.Errorf("Event %d: flag mismatch: have %v, want %v", found, it.Event.Flag, found == 1)
+				}
+				if it.Event.Raw.BlockNumber != 2 {
+					t.Errorf("Event %d: block number mismatch: have %d, want 2", found, it.Event.Raw.BlockNumber)
+				}
+			}
+			if err := it.Error(); err != nil {
+				t.Fatalf("Failed to iterate events: %v", err)
+			}
+			if found != len(deltas) {
+				t.Fatalf("Filtered event count mismatch: have %d, want %d", found, len(deltas))
+			}
+			it.Close()
+
+			// Check that the indexed fields can be filtered on
+			filters := []struct {
+				from  []common.Address
+				delta []*big.Int
+				count int
+			}{
+				{[]common.Address{{2}}, nil, 1},
+				{nil, []*big.Int{big.NewInt(-2), big.NewInt(3)}, 2},
+				{[]common.Address{{1}}, []*big.Int{big.NewInt(1)}, 0},
+			}
+			for i, filter := range filters {
+				it, err := eventer.FilterReceived(nil, filter.from, filter.delta)
+				if err != nil {
+					t.Fatalf("Filter %d: failed to filter events: %v", i, err)
+				}
+				var found int
+				for ; it.Next(); found++ {
+				}
+				if found != filter.count {
+					t.Errorf("Filter %d: event count mismatch: have %d, want %d", i, found, filter.count)
+				}
+			}
+			// Check that the watcher received the events of its sender only
+			for i, want := range []int64{-2, 3} {
+				select {
+				case event := <-sink:
+					if event.From != (common.Address{1}) || event.Delta.Cmp(big.NewInt(want)) != 0 {
+						t.Errorf("Watched event %d: mismatch: have %x/%v, want %x/%v", i, event.From, event.Delta, common.Address{1}, want)
+					}
+				case err := <-sub.Err():
+					t.Fatalf("Watched event %d: subscription failed: %v", i, err)
+				case <-time.After(time.Second):
+					t.Fatalf("Watched event %d: timeout", i)
+				}
+			}
+		`,
+	},
+	// Tests that tuples are mapped to Go structs and survive a round trip through
+	// the simulated chain. The bytecode is hand assembled to return its call data.
+	{
+		`Tupler`,
+		`
+			contract Tupler {
+				struct Item { uint8 id; string[] tags; bytes32[2] data; }
+
+				function echo(Item[] items, (uint256 num, address owner) pair) constant returns (Item[] items, (uint256 num, address owner) pair);
+			}
+		`,
+		`600e80600b6000396000f336600490038060046000376000f3`,
+		`[{"constant":true,"inputs":[{"name":"items","type":"tuple[]","internalType":"struct Tupler.Item[]","components":[{"name":"id","type":"uint8"},{"name":"tags","type":"string[]"},{"name":"data","type":"bytes32[2]"}]},{"name":"pair","type":"tuple","components":[{"name":"num","type":"uint256"},{"name":"owner","type":"address"}]}],"name":"echo","outputs":[{"name":"items","type":"tuple[]","internalType":"struct Tupler.Item[]","components":[{"name":"id","type":"uint8"},{"name":"tags","type":"string[]"},{"name":"data","type":"bytes32[2]"}]},{"name":"pair","type":"tuple","components":[{"name":"num","type":"uint256"},{"name":"owner","type":"address"}]}],"type":"function"}]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}})
+
+			// Deploy an echoing contract and call it with nested tuples
+			_, _, tupler, err := DeployTupler(auth, sim)
+			if err != nil {
+				t.Fatalf("Failed to deploy tupler contract: %v", err)
+			}
+			sim.Commit()
+
+			items := []TuplerItem{
+				{Id: 1, Tags: []string{"foo", "bar"}, Data: [2][32]byte{{1}, {2}}},
+				{Id: 2, Tags: []string{}},
+			}
+			pair := Struct0{Num: big.NewInt(42), Owner: common.Address{0x42}}
+
+			res, err := tupler.Echo(nil, items, pair)
+			if err != nil {
+				t.Fatalf("Failed to call echo: %v", err)
+			}
+			if !reflect.DeepEqual(res.Items, items) {
+				t.Errorf("Items mismatch: have %+v, want %+v", res.Items, items)
+			}
+			if res.Pair.Num.Cmp(pair.Num) != 0 || res.Pair.Owner != pair.Owner {
+				t.Errorf("Pair mismatch: have %+v, want %+v", res.Pair, pair)
+			}
+		`,
+	},
+	// Tests that contracts calling into libraries get linked on deployment. The
+	// bytecode is hand assembled to return the library address it embeds.
+	{
+		`Linked`,
+		`
+			library Math {}
+
+			contract Linked {
+				function lib() constant returns (address) { return Math; }
+			}
+		`,
+		`601d600c600039601d6000f373__linked.sol:Math_______________________60005260206000f3`,
+		`[{"constant":true,"inputs":[],"name":"lib","outputs":[{"name":"","type":"address"}],"type":"function"}]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}})
+
+			// Deploy the contract linked against an arbitrary library address
+			lib := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+			_, _, linked, err := DeployLinked(auth, sim, lib)
+			if err != nil {
+				t.Fatalf("Failed to deploy linked contract: %v", err)
+			}
+			sim.Commit()
+
+			if have, err := linked.Lib(nil); err != nil {
+				t.Fatalf("Failed to retrieve library address: %v", err)
+			} else if have != lib {
+				t.Fatalf("Library address mismatch: have %x, want %x", have, lib)
+			}
+		`,
+	},
+	// Tests that libraries of the same name from different sources are linked
+	// separately, without clashing with the constructor arguments.
+	{
+		`DoubleLinked`,
+		`
+			import {Math as MathA} from "a.sol";
+			import {Math as MathB} from "b.sol";
+
+			contract DoubleLinked {
+				function DoubleLinked(address mathAddr) {}
+				function libs() constant returns (address a, address b) { return (MathA, MathB); }
+			}
+		`,
+		`6035600c60003960356000f373__a.sol:Math____________________________60005273__b.sol:Math____________________________60205260406000f3`,
+		`[{"constant":true,"inputs":[],"name":"libs","outputs":[{"name":"a","type":"address"},{"name":"b","type":"address"}],"type":"function"},{"inputs":[{"name":"mathAddr","type":"address"}],"type":"constructor"}]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}})
+
+			// Deploy the contract linked against two distinct library addresses
+			libA := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+			libB := common.HexToAddress("0x1415161718191a1b1c1d1e1f2021222324252627")
+
+			_, _, linked, err := DeployDoubleLinked(auth, sim, libA, libB, common.Address{})
+			if err != nil {
+				t.Fatalf("Failed to deploy linked contract: %v", err)
+			}
+			sim.Commit()
+
+			if have, err := linked.Libs(nil); err != nil {
+				t.Fatalf("Failed to retrieve library addresses: %v", err)
+			} else if have.A != libA || have.B != libB {
+				t.Fatalf("Library addresses mismatch: have %x/%x, want %x/%x", have.A, have.B, libA, libB)
+			}
+		`,
+	},
+}
+
+// bindTestLibraries maps the link placeholders of the libraries used by the
+// binding tests to their fully qualified names.
+var bindTestLibraries = map[string]string{
+	"__linked.sol:Math_______________________": "linked.sol:Math",
+	"__a.sol:Math____________________________": "a.sol:Math",
+	"__b.sol:Math____________________________": "b.sol:Math",
 }
 
 // Tests that packages generated by the binder can be successfully compiled and
@@ -480,7 +716,7 @@ func TestBindings(t *testing.T) {
 	// Generate the test suite for all the contracts
 	for i, tt := range bindTests {
 		// Generate the binding and create a Go source file in the workspace
-		bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, "bindtest", LangGo)
+		bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, nil, "bindtest", LangGo, bindTestLibraries)
 		if err != nil {
 			t.Fatalf("test %d: failed to generate binding: %v", i, err)
 		}
@@ -504,3 +740,41 @@ func TestBindings(t *testing.T) {
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that the solc metadata of contracts is exposed by the generated bindings
+// along with the hash of the source they were compiled from.
+func TestBindMetadata(t *testing.T) {
+	metadata := `{
+		"compiler": {"version": "0.4.18"},
+		"settings": {"compilationTarget": {"linked.sol": "Linked"}},
+		"sources": {"linked.sol": {"keccak256": "0x5e1d7a4e2cd0ca9c8ab7bfd54b8e0c7e1e5e0d07ab5e3eb2bb1dd58b20e2b75c"}}
+	}`
+	code, err := Bind([]string{"Linked"}, []string{`[]`}, []string{``}, []string{metadata}, "bindtest", LangGo, nil)
+	if err != nil {
+		t.Fatalf("failed to generate binding: %v", err)
+	}
+	if want := "const LinkedSourceHash = \"0x5e1d7a4e2cd0ca9c8ab7bfd54b8e0c7e1e5e0d07ab5e3eb2bb1dd58b20e2b75c\""; !strings.Contains(code, want) {
+		t.Errorf("source hash missing from binding, want %s:\n%s", want, code)
+	}
+	if want := `const LinkedMetadata = "{\"compiler\":{\"version\":\"0.4.18\"},`; !strings.Contains(code, want) {
+		t.Errorf("metadata missing from binding, want %s:\n%s", want, code)
+	}
+	// Library linking is not available in the other languages
+	if _, err := Bind([]string{"Linked"}, []string{`[]`}, []string{bindTests[len(bindTests)-1].bytecode}, nil, "bindtest", LangJava, bindTestLibraries); err == nil {
+		t.Errorf("library linking accepted for java binding")
+	}
+}
+
+// Tests that bytecodes with link placeholders of unknown libraries are rejected
+// instead of generating bindings that deploy broken code.
+func TestBindUnresolvedLibrary(t *testing.T) {
+	linked := bindTests[len(bindTests)-1].bytecode
+
+	libs := map[string]string{"__a.sol:Math____________________________": "a.sol:Math"}
+	if _, err := Bind([]string{"DoubleLinked"}, []string{`[]`}, []string{linked}, nil, "bindtest", LangGo, libs); err == nil || !strings.Contains(err.Error(), "__b.sol:Math____________________________") {
+		t.Errorf("unresolved placeholder error mismatch: have %v", err)
+	}
+	if _, err := Bind([]string{"DoubleLinked"}, []string{`[]`}, []string{linked}, nil, "bindtest", LangGo, bindTestLibraries); err != nil {
+		t.Errorf("failed to generate fully linked binding: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+
+	"github.com/cryptorift/riftcore/accounts/abi"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// SignDataFn is a signer function callback when structured data needs to be
+// signed off-chain. It must return a [R || S || V] signature where V is 0 or 1.
+type SignDataFn func(common.Address, []byte) ([]byte, error)
+
+// SignOpts is the collection of authorization data required to sign off-chain
+// structured data (EIP-712), such as meta-transactions or orders verified by a
+// contract.
+type SignOpts struct {
+	From   common.Address // CryptoRift account to sign the data with
+	Signer SignDataFn     // Method to use for signing the data hash (mandatory)
+}
+
+// SignTypedData signs the structured data with the account of the options. The
+// returned signature has a V value of 27 or 28, as expected by ecrecover.
+func SignTypedData(opts *SignOpts, data *abi.TypedData) ([]byte, error) {
+	if opts.Signer == nil {
+		return nil, errors.New("no signer to authorize the structured data with")
+	}
+	hash, err := data.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := opts.Signer(opts.From, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != 65 {
+		return nil, errors.New("invalid signature length")
+	}
+	sig := make([]byte, 65)
+	copy(sig, signature)
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return sig, nil
+}
+
+// RecoverTypedData returns the address of the account that signed the structured
+// data. The V value of the signature must be 27 or 28, as produced by both the
+// SignTypedData method and the rift_signTypedData RPC call.
+func RecoverTypedData(data *abi.TypedData, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, errors.New("signature must be 65 bytes long")
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		return common.Address{}, errors.New("invalid CryptoRift signature (V is not 27 or 28)")
+	}
+	hash, err := data.SigHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	rsv := make([]byte, 65)
+	copy(rsv, sig)
+	rsv[64] -= 27 // Transform yellow paper V from 27/28 to 0/1
+
+	pubkey, err := crypto.SigToPub(hash[:], rsv)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts/abi"
+	"github.com/cryptorift/riftcore/accounts/abi/bind"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// Tests that structured data signed with a keyed signer can be verified, and
+// that any modification of the data invalidates the signature.
+func TestSignTypedData(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	opts := bind.NewKeyedSigner(key)
+
+	exchange := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	order := &abi.TypedData{
+		Types: abi.TypedDataTypes{
+			"Order": {
+				{Name: "maker", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+				{Name: "nonce", Type: "uint64"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: abi.TypedDataDomain{
+			Name:              "Exchange",
+			ChainId:           big.NewInt(1),
+			VerifyingContract: &exchange,
+		},
+		Message: map[string]interface{}{
+			"maker":  opts.From,
+			"amount": big.NewInt(1000000),
+			"nonce":  uint64(1),
+		},
+	}
+	sig, err := bind.SignTypedData(opts, order)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		t.Fatalf("invalid V value: %d", sig[64])
+	}
+	if signer, err := bind.RecoverTypedData(order, sig); err != nil || signer != opts.From {
+		t.Fatalf("signer mismatch: have %x, want %x (err %v)", signer, opts.From, err)
+	}
+	// Tamper with the order and ensure the signer no longer matches
+	order.Message["amount"] = big.NewInt(2000000)
+	if signer, err := bind.RecoverTypedData(order, sig); err == nil && signer == opts.From {
+		t.Fatalf("tampered order verified")
+	}
+	// Ensure signing with an unauthorized account is rejected
+	opts.From = exchange
+	if _, err := bind.SignTypedData(opts, order); err == nil {
+		t.Fatalf("unauthorized account signed order")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/cryptorift/riftcore/accounts/abi"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/math"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// errNoEventSignature is returned when unpacking a log without any topics, which
+// therefore can't have been emitted by a non-anonymous event.
+var errNoEventSignature = errors.New("no event signature")
+
+// makeTopics converts a filter query argument list into a filter topic set.
+func makeTopics(query ...[]interface{}) ([][]common.Hash, error) {
+	topics := make([][]common.Hash, len(query))
+	for i, filter := range query {
+		for _, rule := range filter {
+			var topic common.Hash
+
+			// Try to generate the topic based on simple types
+			switch rule := rule.(type) {
+			case common.Hash:
+				copy(topic[:], rule[:])
+			case common.Address:
+				copy(topic[common.HashLength-common.AddressLength:], rule[:])
+			case *big.Int:
+				topic = intTopic(rule)
+			case bool:
+				if rule {
+					topic[common.HashLength-1] = 1
+				}
+			case int8:
+				topic = intTopic(big.NewInt(int64(rule)))
+			case int16:
+				topic = intTopic(big.NewInt(int64(rule)))
+			case int32:
+				topic = intTopic(big.NewInt(int64(rule)))
+			case int64:
+				topic = intTopic(big.NewInt(rule))
+			case uint8:
+				topic = intTopic(new(big.Int).SetUint64(uint64(rule)))
+			case uint16:
+				topic = intTopic(new(big.Int).SetUint64(uint64(rule)))
+			case uint32:
+				topic = intTopic(new(big.Int).SetUint64(uint64(rule)))
+			case uint64:
+				topic = intTopic(new(big.Int).SetUint64(rule))
+			case string:
+				topic = crypto.Keccak256Hash([]byte(rule))
+			case []byte:
+				topic = crypto.Keccak256Hash(rule)
+
+			default:
+				// Attempt to generate the topic from fixed size byte arrays
+				val := reflect.ValueOf(rule)
+				if val.Kind() != reflect.Array || val.Type().Elem().Kind() != reflect.Uint8 || val.Len() > common.HashLength {
+					return nil, fmt.Errorf("unsupported indexed type: %T", rule)
+				}
+				reflect.Copy(reflect.ValueOf(topic[:val.Len()]), val)
+			}
+			topics[i] = append(topics[i], topic)
+		}
+	}
+	return topics, nil
+}
+
+// intTopic encodes an integer as a topic, using the EVM's 256 bit two's complement
+// representation for negative numbers.
+func intTopic(num *big.Int) common.Hash {
+	return common.BytesToHash(math.PaddedBigBytes(math.U256(new(big.Int).Set(num)), common.HashLength))
+}
+
+// Big batch of reflect types for topic reconstruction.
+var (
+	reflectHash    = reflect.TypeOf(common.Hash{})
+	reflectAddress = reflect.TypeOf(common.Address{})
+	reflectBigInt  = reflect.TypeOf(new(big.Int))
+)
+
+// parseTopics converts the indexed topic fields into actual log field values.
+//
+// Note, dynamic types cannot be reconstructed since they get mapped to Keccak256
+// hashes as the topic value!
+func parseTopics(out interface{}, fields []abi.Argument, topics []common.Hash) error {
+	// Sanity check that the fields and topics match up
+	if len(fields) != len(topics) {
+		return errors.New("topic/field count mismatch")
+	}
+	// Iterate over all the fields and reconstruct them from topics
+	for i, arg := range fields {
+		if !arg.Indexed {
+			return errors.New("non-indexed field in topic reconstruction")
+		}
+		if arg.Name == "" {
+			return fmt.Errorf("unnamed indexed field %d in topic reconstruction", i)
+		}
+		field := reflect.ValueOf(out).Elem().FieldByName(capitalise(arg.Name))
+		if !field.IsValid() {
+			return fmt.Errorf("missing field %s for indexed argument", capitalise(arg.Name))
+		}
+		topic := topics[i]
+
+		// Try to parse the topic back into the fields based on primitive types.
+		// Signed integers are sign extended to 256 bits, so truncating the low
+		// order bytes restores the original value.
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(topic[common.HashLength-1] == 1)
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(int64(new(big.Int).SetBytes(topic[common.HashLength-8:]).Uint64()))
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(new(big.Int).SetBytes(topic[common.HashLength-8:]).Uint64())
+		default:
+			// Ran out of plain primitive types, try custom types
+			switch field.Type() {
+			case reflectHash: // Also covers all dynamic types
+				field.Set(reflect.ValueOf(topic))
+			case reflectAddress:
+				field.Set(reflect.ValueOf(common.BytesToAddress(topic[common.HashLength-common.AddressLength:])))
+			case reflectBigInt:
+				num := new(big.Int).SetBytes(topic[:])
+				if arg.Type.T == abi.IntTy {
+					num = math.S256(num)
+				}
+				field.Set(reflect.ValueOf(num))
+			default:
+				// Ran out of custom types, try fixed size byte arrays
+				if arg.Type.T != abi.FixedBytesTy || field.Kind() != reflect.Array {
+					return fmt.Errorf("unsupported indexed type: %v", arg.Type)
+				}
+				reflect.Copy(field, reflect.ValueOf(topic[:arg.Type.SliceSize]))
+			}
+		}
+	}
+	return nil
+}
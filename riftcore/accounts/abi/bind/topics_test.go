@@ -0,0 +1,129 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts/abi"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// Tests that filter rules are converted into the correct topics.
+func TestMakeTopics(t *testing.T) {
+	var (
+		addr  = common.HexToAddress("0x0102030405060708091011121314151617181920")
+		hash  = common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132")
+		fixed = [4]byte{0xde, 0xad, 0xbe, 0xef}
+	)
+	tests := []struct {
+		rule  interface{}
+		topic common.Hash
+	}{
+		{addr, common.BytesToHash(addr[:])},
+		{hash, hash},
+		{true, common.BigToHash(big.NewInt(1))},
+		{false, common.Hash{}},
+		{big.NewInt(1000), common.BigToHash(big.NewInt(1000))},
+		{big.NewInt(-1), common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")},
+		{int8(-2), common.HexToHash("0xfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe")},
+		{int64(3), common.BigToHash(big.NewInt(3))},
+		{uint32(4), common.BigToHash(big.NewInt(4))},
+		{"hello", crypto.Keccak256Hash([]byte("hello"))},
+		{[]byte("hello"), crypto.Keccak256Hash([]byte("hello"))},
+		{fixed, common.HexToHash("0xdeadbeef00000000000000000000000000000000000000000000000000000000")},
+	}
+	for i, tt := range tests {
+		topics, err := makeTopics([]interface{}{tt.rule})
+		if err != nil {
+			t.Errorf("test %d: failed to make topics: %v", i, err)
+			continue
+		}
+		if len(topics) != 1 || len(topics[0]) != 1 || topics[0][0] != tt.topic {
+			t.Errorf("test %d: topic mismatch: have %x, want %x", i, topics, tt.topic)
+		}
+	}
+	// Empty rules should result in wildcard topics
+	topics, err := makeTopics([]interface{}{addr}, nil)
+	if err != nil {
+		t.Fatalf("failed to make wildcard topics: %v", err)
+	}
+	if len(topics) != 2 || len(topics[1]) != 0 {
+		t.Errorf("wildcard topic mismatch: have %x", topics)
+	}
+	if _, err := makeTopics([]interface{}{struct{}{}}); err == nil {
+		t.Errorf("unsupported rule type accepted")
+	}
+}
+
+// Tests that indexed event fields can be reconstructed from log topics.
+func TestParseTopics(t *testing.T) {
+	const definition = `[{"anonymous":false,"inputs":[
+		{"indexed":true,"name":"from","type":"address"},
+		{"indexed":true,"name":"delta","type":"int256"},
+		{"indexed":true,"name":"small","type":"int8"},
+		{"indexed":true,"name":"flag","type":"bool"},
+		{"indexed":true,"name":"id","type":"bytes4"},
+		{"indexed":true,"name":"memo","type":"string"}
+	],"name":"Event","type":"event"}]`
+
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatalf("failed to parse abi: %v", err)
+	}
+	type event struct {
+		From  common.Address
+		Delta *big.Int
+		Small int8
+		Flag  bool
+		Id    [4]byte
+		Memo  common.Hash
+	}
+	want := event{
+		From:  common.HexToAddress("0x0102030405060708091011121314151617181920"),
+		Delta: big.NewInt(-1000),
+		Small: -5,
+		Flag:  true,
+		Id:    [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Memo:  crypto.Keccak256Hash([]byte("memo")),
+	}
+	topics, err := makeTopics(
+		[]interface{}{want.From}, []interface{}{want.Delta}, []interface{}{want.Small},
+		[]interface{}{want.Flag}, []interface{}{want.Id}, []interface{}{"memo"},
+	)
+	if err != nil {
+		t.Fatalf("failed to make topics: %v", err)
+	}
+	flat := make([]common.Hash, len(topics))
+	for i, topic := range topics {
+		flat[i] = topic[0]
+	}
+	var have event
+	if err := parseTopics(&have, parsed.Events["Event"].Inputs, flat); err != nil {
+		t.Fatalf("failed to parse topics: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("parsed event mismatch: have %+v, want %+v", have, want)
+	}
+	if err := parseTopics(&have, parsed.Events["Event"].Inputs, flat[1:]); err == nil {
+		t.Errorf("mismatching topic count accepted")
+	}
+}
@@ -44,3 +44,28 @@ func (e Event) Id() common.Hash {
 	}
 	return common.BytesToHash(crypto.Keccak256([]byte(fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ",")))))
 }
+
+// String returns the human readable signature of the event, marking the fields
+// stored in the log topics as indexed.
+func (e Event) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Name, input.Type)
+		if input.Indexed {
+			inputs[i] = fmt.Sprintf("%v indexed %v", input.Name, input.Type)
+		}
+	}
+	return fmt.Sprintf("event %v(%v)", e.Name, strings.Join(inputs, ", "))
+}
+
+// nonIndexed returns the event fields that are stored in the log data, in the
+// order they are encoded there. Indexed fields are only present as topics.
+func (e Event) nonIndexed() []Argument {
+	var args []Argument
+	for _, input := range e.Inputs {
+		if !input.Indexed {
+			args = append(args, input)
+		}
+	}
+	return args
+}
@@ -17,6 +17,7 @@
 package abi
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 
@@ -54,3 +55,67 @@ func TestEventId(t *testing.T) {
 		}
 	}
 }
+
+func TestEventUnpack(t *testing.T) {
+	const definition = `[
+	{ "type" : "event", "name" : "received", "anonymous" : false, "inputs" : [
+		{ "indexed" : true, "name" : "sender", "type" : "address" },
+		{ "indexed" : false, "name" : "amount", "type" : "uint256" },
+		{ "indexed" : false, "name" : "memo", "type" : "bytes" }
+	] },
+	{ "type" : "event", "name" : "single", "anonymous" : false, "inputs" : [
+		{ "indexed" : true, "name" : "sender", "type" : "address" },
+		{ "indexed" : false, "name" : "amount", "type" : "uint256" }
+	] }
+	]`
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Log data only contains the non-indexed fields: amount, memo offset, memo length, memo
+	data := common.Hex2Bytes("" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000040" +
+		"0000000000000000000000000000000000000000000000000000000000000003" +
+		"6162630000000000000000000000000000000000000000000000000000000000")
+
+	var received struct {
+		Sender common.Address
+		Amount *big.Int
+		Memo   []byte
+	}
+	if err := abi.Unpack(&received, "received", data); err != nil {
+		t.Fatalf("failed to unpack event into struct: %v", err)
+	}
+	if received.Amount == nil || received.Amount.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("amount mismatch: have %v, want 1", received.Amount)
+	}
+	if string(received.Memo) != "abc" {
+		t.Errorf("memo mismatch: have %q, want %q", received.Memo, "abc")
+	}
+	if received.Sender != (common.Address{}) {
+		t.Errorf("indexed sender unpacked from data: %x", received.Sender)
+	}
+
+	var values []interface{}
+	if err := abi.Unpack(&values, "received", data); err != nil {
+		t.Fatalf("failed to unpack event into slice: %v", err)
+	}
+	if len(values) != 2 {
+		t.Errorf("unpacked value count mismatch: have %d, want 2", len(values))
+	}
+
+	var single struct {
+		Amount *big.Int
+	}
+	if err := abi.Unpack(&single, "single", data[:32]); err != nil {
+		t.Fatalf("failed to unpack single field event into struct: %v", err)
+	}
+	if single.Amount == nil || single.Amount.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("single amount mismatch: have %v, want 1", single.Amount)
+	}
+
+	if err := abi.Unpack(&single, "missing", data); err == nil {
+		t.Errorf("unpacking unknown event succeeded")
+	}
+}
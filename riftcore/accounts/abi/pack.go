@@ -31,6 +31,31 @@ func packBytesSlice(bytes []byte, l int) []byte {
 	return append(len, common.RightPadBytes(bytes, (l+31)/32*32)...)
 }
 
+// packTuple packs the given values as a tuple of the given types. Static values
+// are stored in place in the head section, dynamic ones are appended to the tail
+// section and referenced from the head by their offset.
+func packTuple(types []Type, values []reflect.Value) ([]byte, error) {
+	offset := 0
+	for _, typ := range types {
+		offset += typ.headSize()
+	}
+	var head, tail []byte
+	for i, typ := range types {
+		packed, err := typ.pack(values[i])
+		if err != nil {
+			return nil, err
+		}
+		if typ.isDynamic() {
+			head = append(head, packNum(reflect.ValueOf(offset))...)
+			tail = append(tail, packed...)
+			offset += len(packed)
+		} else {
+			head = append(head, packed...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
 // packElement packs the given reflect value according to the abi specification in
 // t.
 func packElement(t Type, reflectValue reflect.Value) []byte {
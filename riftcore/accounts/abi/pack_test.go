@@ -319,6 +319,49 @@ func TestPack(t *testing.T) {
 			"foobar",
 			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000006666f6f6261720000000000000000000000000000000000000000000000000000"),
 		},
+		{
+			"string[]",
+			[]string{"foo", "bar"},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+				"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+				"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to i = 1
+				"0000000000000000000000000000000000000000000000000000000000000003" + // len(str[0]) = 3
+				"666f6f0000000000000000000000000000000000000000000000000000000000" + // str[0]
+				"0000000000000000000000000000000000000000000000000000000000000003" + // len(str[1]) = 3
+				"6261720000000000000000000000000000000000000000000000000000000000"), // str[1]
+		},
+		{
+			"uint8[2][]",
+			[][2]uint8{{1, 2}, {3, 4}},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+				"0000000000000000000000000000000000000000000000000000000000000001" + // array[0][0]
+				"0000000000000000000000000000000000000000000000000000000000000002" + // array[0][1]
+				"0000000000000000000000000000000000000000000000000000000000000003" + // array[1][0]
+				"0000000000000000000000000000000000000000000000000000000000000004"), // array[1][1]
+		},
+		{
+			"uint8[][2]",
+			[2][]uint8{{1}, {2, 3}},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+				"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to i = 1
+				"0000000000000000000000000000000000000000000000000000000000000001" + // len(array[0]) = 1
+				"0000000000000000000000000000000000000000000000000000000000000001" + // array[0][0]
+				"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[1]) = 2
+				"0000000000000000000000000000000000000000000000000000000000000002" + // array[1][0]
+				"0000000000000000000000000000000000000000000000000000000000000003"), // array[1][1]
+		},
+		{
+			"bytes[][]",
+			[][][]byte{{{0xaa}}, {}},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+				"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+				"00000000000000000000000000000000000000000000000000000000000000c0" + // offset 192 to i = 1
+				"0000000000000000000000000000000000000000000000000000000000000001" + // len(array[0]) = 1
+				"0000000000000000000000000000000000000000000000000000000000000020" + // offset 32 to array[0][0]
+				"0000000000000000000000000000000000000000000000000000000000000001" + // len(array[0][0]) = 1
+				"aa00000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
+				"0000000000000000000000000000000000000000000000000000000000000000"), // len(array[1]) = 0
+		},
 	} {
 		typ, err := NewType(test.typ)
 		if err != nil {
@@ -439,3 +482,43 @@ func TestPackNumber(t *testing.T) {
 		t.Errorf("expected 'string' to pack to nil. got %x instead", packed)
 	}
 }
+
+func TestTuplePack(t *testing.T) {
+	const definition = `[{"name": "tuple", "inputs": [
+		{"name": "a", "type": "uint8"},
+		{"name": "t", "type": "tuple", "components": [
+			{"name": "num", "type": "uint256"},
+			{"name": "some_text", "type": "string"}
+		]},
+		{"name": "b", "type": "uint8"}
+	]}]`
+
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := abi.Methods["tuple"].Sig(); sig != "tuple(uint8,(uint256,string),uint8)" {
+		t.Errorf("signature mismatch: have %s, want tuple(uint8,(uint256,string),uint8)", sig)
+	}
+	type tuple struct {
+		Num      *big.Int
+		SomeText string
+	}
+	packed, err := abi.Pack("tuple", uint8(1), tuple{big.NewInt(2), "foo"}, uint8(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // a
+		"0000000000000000000000000000000000000000000000000000000000000060" + // offset 96 to t
+		"0000000000000000000000000000000000000000000000000000000000000003" + // b
+		"0000000000000000000000000000000000000000000000000000000000000002" + // t.num
+		"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to t.some_text
+		"0000000000000000000000000000000000000000000000000000000000000003" + // len(t.some_text) = 3
+		"666f6f0000000000000000000000000000000000000000000000000000000000") // t.some_text
+	if !bytes.Equal(packed[4:], want) {
+		t.Errorf("packed tuple mismatch:\nhave %x\nwant %x", packed[4:], want)
+	}
+	if _, err := abi.Pack("tuple", uint8(1), struct{ Num *big.Int }{big.NewInt(2)}, uint8(3)); err == nil {
+		t.Error("expected error for struct with missing field")
+	}
+}
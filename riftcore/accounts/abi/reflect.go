@@ -19,6 +19,7 @@ package abi
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // indirect recursively dereferences the value until it either gets the value
@@ -69,8 +70,9 @@ func mustArrayToByteSlice(value reflect.Value) reflect.Value {
 // set attempts to assign src to dst by either setting, copying or otherwise.
 //
 // set is a bit more lenient when it comes to assignment and doesn't force an as
-// strict ruleset as bare `reflect` does.
-func set(dst, src reflect.Value, output Argument) error {
+// strict ruleset as bare `reflect` does. Lists and tuples are converted element
+// by element, so unpacked values can be assigned to user defined types.
+func set(dst, src reflect.Value, typ Type) error {
 	dstType := dst.Type()
 	srcType := src.Type()
 
@@ -78,16 +80,55 @@ func set(dst, src reflect.Value, output Argument) error {
 	case dstType.AssignableTo(src.Type()):
 		dst.Set(src)
 	case dstType.Kind() == reflect.Array && srcType.Kind() == reflect.Slice:
-		if dst.Len() < output.Type.SliceSize {
-			return fmt.Errorf("abi: cannot unmarshal src (len=%d) in to dst (len=%d)", output.Type.SliceSize, dst.Len())
+		if dst.Len() < typ.SliceSize {
+			return fmt.Errorf("abi: cannot unmarshal src (len=%d) in to dst (len=%d)", typ.SliceSize, dst.Len())
+		}
+		if dstType.Elem() == srcType.Elem() || typ.Elem == nil {
+			reflect.Copy(dst, src)
+			break
+		}
+		for i := 0; i < src.Len() && i < dst.Len(); i++ {
+			if err := set(dst.Index(i), src.Index(i), *typ.Elem); err != nil {
+				return err
+			}
+		}
+	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice && typ.isList():
+		slice := reflect.MakeSlice(dstType, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := set(slice.Index(i), src.Index(i), *typ.Elem); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case dstType.Kind() == reflect.Struct && srcType.Kind() == reflect.Struct && typ.T == TupleTy:
+		for i, elem := range typ.TupleElems {
+			name := ToCamelCase(typ.TupleRawNames[i])
+			field := dst.FieldByName(name)
+			if !field.IsValid() {
+				return fmt.Errorf("abi: cannot unmarshal %v in to %v: missing field %s", src.Type(), dst.Type(), name)
+			}
+			if err := set(field, src.Field(i), *elem); err != nil {
+				return err
+			}
 		}
-		reflect.Copy(dst, src)
 	case dstType.Kind() == reflect.Interface:
 		dst.Set(src)
 	case dstType.Kind() == reflect.Ptr:
-		return set(dst.Elem(), src, output)
+		return set(dst.Elem(), src, typ)
 	default:
 		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
 	return nil
 }
+
+// ToCamelCase converts an under_score separated name to CamelCase, the way the
+// fields of tuples are named in Go.
+func ToCamelCase(input string) string {
+	parts := strings.Split(input, "_")
+	for i, part := range parts {
+		if len(part) > 0 {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
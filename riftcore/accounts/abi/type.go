@@ -21,6 +21,8 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 const (
@@ -35,6 +37,7 @@ const (
 	HashTy
 	FixedPointTy
 	FunctionTy
+	TupleTy
 )
 
 // Type is the reflection of the supported argument type
@@ -49,15 +52,19 @@ type Type struct {
 	Size int
 	T    byte // Our own type checking
 
+	TupleElems    []*Type  // Types of the tuple fields
+	TupleRawNames []string // Names of the tuple fields as declared in the ABI
+	TupleRawName  string   // Name of the source struct of the tuple, if known
+
 	stringKind string // holds the unparsed string for deriving signatures
 }
 
 var (
-	// fullTypeRegex parses the abi types
+	// typeRegex parses the abi types
 	//
 	// Types can be in the format of:
 	//
-	// 	Input  = Type [ "[" [ Number ] "]" ] Name .
+	// 	Input  = Type { "[" [ Number ] "]" } Name .
 	// 	Type   = [ "u" ] "int" [ Number ] [ x ] [ Number ].
 	//
 	// Examples:
@@ -65,42 +72,49 @@ var (
 	//      string     int       uint       fixed
 	//      string32   int8      uint8      uint[]
 	//      address    int256    uint256    fixed128x128[2]
-	fullTypeRegex = regexp.MustCompile(`([a-zA-Z0-9]+)(\[([0-9]*)\])?`)
-	// typeRegex parses the abi sub types
-	typeRegex = regexp.MustCompile("([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?")
+	//      tuple      tuple[]   uint[2][]  bytes[][3]
+	typeRegex = regexp.MustCompile("^([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?$")
+	// dimensionRegex parses the outermost dimension of array and slice types
+	dimensionRegex = regexp.MustCompile(`^\[([0-9]*)\]$`)
 )
 
-// NewType creates a new reflection type of abi type given in t.
-func NewType(t string) (typ Type, err error) {
-	res := fullTypeRegex.FindAllStringSubmatch(t, -1)[0]
-	// check if type is slice and parse type.
-	switch {
-	case res[3] != "":
-		// err is ignored. Already checked for number through the regexp
-		typ.SliceSize, _ = strconv.Atoi(res[3])
-		typ.IsArray = true
-	case res[2] != "":
-		typ.IsSlice, typ.SliceSize = true, -1
-	case res[0] == "":
-		return Type{}, fmt.Errorf("abi: type parse error: %s", t)
-	}
-	if typ.IsArray || typ.IsSlice {
-		sliceType, err := NewType(res[1])
+// NewType creates a new reflection type of abi type given in t. The components
+// describe the fields of tuple types and are ignored for all others.
+func NewType(t string, components ...ArgumentMarshaling) (typ Type, err error) {
+	// check if type is slice or array and parse the element type. The outermost
+	// dimension is the last one, i.e. uint[2][] is a slice of uint[2] arrays.
+	if i := strings.LastIndex(t, "["); i != -1 {
+		dimension := dimensionRegex.FindStringSubmatch(t[i:])
+		if dimension == nil {
+			return Type{}, fmt.Errorf("abi: type parse error: %s", t)
+		}
+		if dimension[1] == "" {
+			typ.IsSlice, typ.SliceSize = true, -1
+		} else {
+			// err is ignored. Already checked for number through the regexp
+			typ.SliceSize, _ = strconv.Atoi(dimension[1])
+			typ.IsArray = true
+		}
+		elem, err := NewType(t[:i], components...)
 		if err != nil {
 			return Type{}, err
 		}
-		typ.Elem = &sliceType
-		typ.stringKind = sliceType.stringKind + t[len(res[1]):]
-		// Although we know that this is an array, we cannot return
-		// as we don't know the type of the element, however, if it
-		// is still an array, then don't determine the type.
-		if typ.Elem.IsArray || typ.Elem.IsSlice {
-			return typ, nil
+		typ.Elem = &elem
+		typ.stringKind = elem.stringKind + t[i:]
+
+		// Lists of basic types carry the kind of their elements for type checking,
+		// nested lists, byte based types and tuples are checked element by element.
+		if !elem.IsSlice && !elem.IsArray && elem.T != TupleTy {
+			typ.Kind, typ.Type, typ.Size, typ.T = elem.Kind, elem.Type, elem.Size, elem.T
 		}
+		return typ, nil
 	}
 
 	// parse the type and size of the abi-type.
-	parsedType := typeRegex.FindAllStringSubmatch(res[1], -1)[0]
+	parsedType := typeRegex.FindStringSubmatch(t)
+	if parsedType == nil {
+		return Type{}, fmt.Errorf("abi: type parse error: %s", t)
+	}
 	// varSize is the size of the variable
 	var varSize int
 	if len(parsedType[3]) > 0 {
@@ -117,12 +131,7 @@ func NewType(t string) (typ Type, err error) {
 		varSize = 256
 		t += "256"
 	}
-
-	// only set stringKind if not array or slice, as for those,
-	// the correct string type has been set
-	if !(typ.IsArray || typ.IsSlice) {
-		typ.stringKind = t
-	}
+	typ.stringKind = t
 
 	switch varType {
 	case "int":
@@ -163,6 +172,43 @@ func NewType(t string) (typ Type, err error) {
 		typ.IsArray = true
 		typ.T = FunctionTy
 		typ.SliceSize = 24
+	case "tuple":
+		if len(components) == 0 {
+			return Type{}, fmt.Errorf("abi: tuple without components: %s", t)
+		}
+		var (
+			fields = make([]reflect.StructField, len(components))
+			kinds  = make([]string, len(components))
+		)
+		for i, component := range components {
+			elem, err := newArgumentType(component)
+			if err != nil {
+				return Type{}, err
+			}
+			// Tuple fields are mapped to exported struct fields, so they need names
+			name := ToCamelCase(component.Name)
+			if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+				return Type{}, fmt.Errorf("abi: unsupported tuple field name: %q", component.Name)
+			}
+			for _, field := range fields[:i] {
+				if field.Name == name {
+					return Type{}, fmt.Errorf("abi: duplicate tuple field name: %q", component.Name)
+				}
+			}
+			fields[i] = reflect.StructField{
+				Name: name,
+				Type: elem.goType(),
+				Tag:  reflect.StructTag(fmt.Sprintf("json:%q", component.Name)),
+			}
+			kinds[i] = elem.stringKind
+
+			typ.TupleElems = append(typ.TupleElems, &elem)
+			typ.TupleRawNames = append(typ.TupleRawNames, component.Name)
+		}
+		typ.Kind = reflect.Struct
+		typ.Type = reflect.StructOf(fields)
+		typ.T = TupleTy
+		typ.stringKind = "(" + strings.Join(kinds, ",") + ")"
 	default:
 		return Type{}, fmt.Errorf("unsupported arg type: %s", t)
 	}
@@ -183,28 +229,106 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 		return nil, err
 	}
 
-	if (t.IsSlice || t.IsArray) && t.T != BytesTy && t.T != FixedBytesTy && t.T != FunctionTy {
-		var packed []byte
+	switch {
+	case t.isList():
+		types := make([]Type, v.Len())
+		values := make([]reflect.Value, v.Len())
+		for i := range values {
+			types[i], values[i] = *t.Elem, v.Index(i)
+		}
+		packed, err := packTuple(types, values)
+		if err != nil {
+			return nil, err
+		}
+		if t.IsSlice {
+			return append(packNum(reflect.ValueOf(v.Len())), packed...), nil
+		}
+		return packed, nil
 
-		for i := 0; i < v.Len(); i++ {
-			val, err := t.Elem.pack(v.Index(i))
-			if err != nil {
-				return nil, err
+	case t.T == TupleTy:
+		types := make([]Type, len(t.TupleElems))
+		values := make([]reflect.Value, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			name := ToCamelCase(t.TupleRawNames[i])
+			if values[i] = v.FieldByName(name); !values[i].IsValid() {
+				return nil, fmt.Errorf("abi: cannot use %v as type %v as argument: missing field %s", v.Type(), t, name)
 			}
-			packed = append(packed, val...)
+			types[i] = *elem
 		}
-		if t.IsSlice {
-			return packBytesSlice(packed, v.Len()), nil
-		} else if t.IsArray {
-			return packed, nil
+		return packTuple(types, values)
+	}
+	return packElement(t, v), nil
+}
+
+// isList returns whether the type is an array or slice of other abi types. The
+// byte based types are flagged as arrays or slices too, but they are encoded as
+// a single value.
+func (t Type) isList() bool {
+	return (t.IsSlice || t.IsArray) && t.T != BytesTy && t.T != FixedBytesTy && t.T != FunctionTy
+}
+
+// isDynamic returns whether the type is encoded in the tail section of its
+// enclosing tuple, with only an offset to it stored in the head section.
+func (t Type) isDynamic() bool {
+	switch {
+	case t.isList():
+		return t.IsSlice || t.Elem.isDynamic()
+	case t.T == TupleTy:
+		for _, elem := range t.TupleElems {
+			if elem.isDynamic() {
+				return true
+			}
 		}
+		return false
 	}
+	return t.T == StringTy || t.T == BytesTy
+}
 
-	return packElement(t, v), nil
+// headSize returns the number of bytes the type occupies in the head section of
+// its enclosing tuple. Static arrays and tuples are stored in place, all other
+// types take up a single word.
+func (t Type) headSize() int {
+	if t.isDynamic() {
+		return 32
+	}
+	switch {
+	case t.isList():
+		return t.SliceSize * t.Elem.headSize()
+	case t.T == TupleTy:
+		size := 0
+		for _, elem := range t.TupleElems {
+			size += elem.headSize()
+		}
+		return size
+	}
+	return 32
 }
 
-// requireLengthPrefix returns whether the type requires any sort of length
-// prefixing.
-func (t Type) requiresLengthPrefix() bool {
-	return t.T != FixedBytesTy && (t.T == StringTy || t.T == BytesTy || t.IsSlice)
+// goType returns the Go type that values of the abi type are unpacked in to.
+// Arrays are unpacked in to slices and fixed bytes in to byte slices, leaving it
+// to the assignment to convert them to the type of the destination.
+func (t Type) goType() reflect.Type {
+	switch {
+	case t.isList():
+		return reflect.SliceOf(t.Elem.goType())
+	case t.T == TupleTy:
+		return t.Type
+	}
+	switch t.T {
+	case IntTy, UintTy:
+		kind, typ := reflectIntKindAndType(t.T == UintTy, t.Size)
+		if kind == reflect.Ptr {
+			return reflect.PtrTo(typ)
+		}
+		return typ
+	case BoolTy:
+		return reflect.TypeOf(false)
+	case StringTy:
+		return reflect.TypeOf("")
+	case AddressTy:
+		return address_t
+	case HashTy:
+		return hash_t
+	}
+	return byte_ts
 }
@@ -78,6 +78,8 @@ func TestTypeRegexp(t *testing.T) {
 		{"address", Type{Kind: reflect.Array, Type: address_t, Size: 20, T: AddressTy, stringKind: "address"}},
 		{"address[]", Type{IsSlice: true, SliceSize: -1, Kind: reflect.Array, Type: address_t, T: AddressTy, Size: 20, Elem: &Type{Kind: reflect.Array, Type: address_t, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[]"}},
 		{"address[2]", Type{IsArray: true, SliceSize: 2, Kind: reflect.Array, Type: address_t, T: AddressTy, Size: 20, Elem: &Type{Kind: reflect.Array, Type: address_t, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[2]"}},
+		{"int8[][2]", Type{IsArray: true, SliceSize: 2, Elem: &Type{IsSlice: true, SliceSize: -1, Kind: reflect.Int8, Type: int8_t, Size: 8, T: IntTy, Elem: &Type{Kind: reflect.Int8, Type: int8_t, Size: 8, T: IntTy, stringKind: "int8"}, stringKind: "int8[]"}, stringKind: "int8[][2]"}},
+		{"bytes[2][]", Type{IsSlice: true, SliceSize: -1, Elem: &Type{IsArray: true, SliceSize: 2, Elem: &Type{IsSlice: true, SliceSize: -1, Elem: &Type{Kind: reflect.Uint8, Type: uint8_t, Size: 8, T: UintTy, stringKind: "uint8"}, T: BytesTy, stringKind: "bytes"}, stringKind: "bytes[2]"}, stringKind: "bytes[2][]"}},
 
 		// TODO when fixed types are implemented properly
 		// {"fixed", Type{}},
@@ -132,6 +134,8 @@ func TestTypeCheck(t *testing.T) {
 		{"string", "hello world", ""},
 		{"bytes32[]", [][32]byte{{}}, ""},
 		{"function", [24]byte{}, ""},
+		{"uint8[][2]", [2][]uint8{{1}, {2, 3}}, ""},
+		{"bytes32[]", [][32]byte{}, ""},
 	} {
 		typ, err := NewType(test.typ)
 		if err != nil {
@@ -153,3 +157,46 @@ func TestTypeCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestTupleType(t *testing.T) {
+	typ, err := NewType("tuple[2]", []ArgumentMarshaling{
+		{Name: "amount", Type: "uint256"},
+		{Name: "recipients", Type: "address[]"},
+		{Name: "inner_data", Type: "tuple", InternalType: "struct Token.Data", Components: []ArgumentMarshaling{
+			{Name: "flag", Type: "bool"},
+		}},
+	}...)
+	if err != nil {
+		t.Fatal("unexpected parse error:", err)
+	}
+	if typ.String() != "(uint256,address[],(bool))[2]" {
+		t.Errorf("type string mismatch: have %s, want (uint256,address[],(bool))[2]", typ)
+	}
+	if !typ.IsArray || typ.Elem.T != TupleTy {
+		t.Fatalf("expected array of tuples, got %+v", typeWithoutStringer(typ))
+	}
+	if !reflect.DeepEqual(typ.Elem.TupleRawNames, []string{"amount", "recipients", "inner_data"}) {
+		t.Errorf("tuple field names mismatch: have %v", typ.Elem.TupleRawNames)
+	}
+	if name := typ.Elem.TupleElems[2].TupleRawName; name != "TokenData" {
+		t.Errorf("tuple struct name mismatch: have %s, want TokenData", name)
+	}
+	// The dynamic address slice makes the whole array dynamic
+	if !typ.isDynamic() || typ.headSize() != 32 {
+		t.Errorf("expected dynamic type taking a single head word")
+	}
+	if inner := *typ.Elem.TupleElems[2]; inner.isDynamic() || inner.headSize() != 32 {
+		t.Errorf("expected static tuple taking a single head word")
+	}
+	for i, components := range [][]ArgumentMarshaling{
+		nil,
+		{{Name: "", Type: "uint256"}},
+		{{Name: "_", Type: "uint256"}},
+		{{Name: "a_b", Type: "uint256"}, {Name: "aB", Type: "uint256"}},
+		{{Name: "a", Type: "uint257x"}},
+	} {
+		if _, err := NewType("tuple", components...); err == nil {
+			t.Errorf("test %d: expected tuple parse error", i)
+		}
+	}
+}
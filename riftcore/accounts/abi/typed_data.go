@@ -0,0 +1,428 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/common/math"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// TypedDataDomainType is the name of the struct type describing the signing
+// domain of structured data, as defined by EIP-712.
+const TypedDataDomainType = "EIP712Domain"
+
+// typedDataAtomicRegex matches the names of the atomic and dynamic types.
+var typedDataAtomicRegex = regexp.MustCompile(`^[a-z]+[0-9]*$`)
+
+// TypedDataField is a named member of a structured data type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataTypes maps the names of structured data types to their members.
+type TypedDataTypes map[string][]TypedDataField
+
+// TypedDataDomain is the signing domain of structured data, binding signatures
+// to a specific application, version, chain and contract. Unset fields are not
+// part of the domain.
+type TypedDataDomain struct {
+	Name              string          `json:"name,omitempty"`
+	Version           string          `json:"version,omitempty"`
+	ChainId           *big.Int        `json:"chainId,omitempty"`
+	VerifyingContract *common.Address `json:"verifyingContract,omitempty"`
+	Salt              *common.Hash    `json:"salt,omitempty"`
+}
+
+// fields returns the members of the domain struct type, consisting of the set
+// fields of the domain.
+func (domain *TypedDataDomain) fields() []TypedDataField {
+	var fields []TypedDataField
+	if domain.Name != "" {
+		fields = append(fields, TypedDataField{Name: "name", Type: "string"})
+	}
+	if domain.Version != "" {
+		fields = append(fields, TypedDataField{Name: "version", Type: "string"})
+	}
+	if domain.ChainId != nil {
+		fields = append(fields, TypedDataField{Name: "chainId", Type: "uint256"})
+	}
+	if domain.VerifyingContract != nil {
+		fields = append(fields, TypedDataField{Name: "verifyingContract", Type: "address"})
+	}
+	if domain.Salt != nil {
+		fields = append(fields, TypedDataField{Name: "salt", Type: "bytes32"})
+	}
+	return fields
+}
+
+// values returns the set fields of the domain as structured data values.
+func (domain *TypedDataDomain) values() map[string]interface{} {
+	values := make(map[string]interface{})
+	if domain.Name != "" {
+		values["name"] = domain.Name
+	}
+	if domain.Version != "" {
+		values["version"] = domain.Version
+	}
+	if domain.ChainId != nil {
+		values["chainId"] = domain.ChainId
+	}
+	if domain.VerifyingContract != nil {
+		values["verifyingContract"] = *domain.VerifyingContract
+	}
+	if domain.Salt != nil {
+		values["salt"] = *domain.Salt
+	}
+	return values
+}
+
+// TypedData is a structured data message along with the definitions of the types
+// it is composed of and the domain it is signed for, as defined by EIP-712.
+//
+// Message values may be given as decoded from JSON (strings, numbers, nested
+// maps and slices) or as native Go types (*big.Int, common.Address, []byte, ...).
+// Numbers may also be given as decimal or 0x prefixed hexadecimal strings.
+type TypedData struct {
+	Types       TypedDataTypes         `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// UnmarshalJSON parses structured data from its JSON representation, retaining
+// the full precision of the numbers within the message.
+func (typedData *TypedData) UnmarshalJSON(input []byte) error {
+	type typedDataJSON TypedData
+
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	return dec.Decode((*typedDataJSON)(typedData))
+}
+
+// SigHash returns the hash of the structured data to be signed:
+//
+//   keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (typedData *TypedData) SigHash() (common.Hash, error) {
+	domain, err := typedData.DomainSeparator()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	message, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domain[:], message[:]), nil
+}
+
+// DomainSeparator returns the hash of the signing domain. If the domain type is
+// not explicitly defined, it is derived from the set fields of the domain.
+func (typedData *TypedData) DomainSeparator() (common.Hash, error) {
+	types := typedData.Types
+	if _, ok := types[TypedDataDomainType]; !ok {
+		types = make(TypedDataTypes, len(typedData.Types)+1)
+		for name, fields := range typedData.Types {
+			types[name] = fields
+		}
+		types[TypedDataDomainType] = typedData.Domain.fields()
+	}
+	return (&TypedData{Types: types}).HashStruct(TypedDataDomainType, typedData.Domain.values())
+}
+
+// HashStruct returns the hash of a struct value of the given type:
+//
+//   keccak256(typeHash ‖ encodeData(data))
+func (typedData *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	encoded, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// TypeHash returns the hash of the encoded type signature.
+func (typedData *TypedData) TypeHash(primaryType string) (common.Hash, error) {
+	encoded, err := typedData.EncodeType(primaryType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte(encoded)), nil
+}
+
+// EncodeType returns the signature of a struct type, followed by the signatures
+// of all the struct types it references, sorted by name:
+//
+//   Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) (string, error) {
+	deps := make(map[string]bool)
+	if err := typedData.dependencies(primaryType, deps); err != nil {
+		return "", err
+	}
+	delete(deps, primaryType)
+
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	for _, name := range append([]string{primaryType}, names...) {
+		fields := make([]string, len(typedData.Types[name]))
+		for i, field := range typedData.Types[name] {
+			fields[i] = field.Type + " " + field.Name
+		}
+		fmt.Fprintf(&buffer, "%s(%s)", name, strings.Join(fields, ","))
+	}
+	return buffer.String(), nil
+}
+
+// dependencies collects the struct types referenced by the given one, including
+// itself, recursively.
+func (typedData *TypedData) dependencies(name string, deps map[string]bool) error {
+	if deps[name] {
+		return nil
+	}
+	fields, ok := typedData.Types[name]
+	if !ok {
+		return fmt.Errorf("abi: unknown typed data type %q", name)
+	}
+	deps[name] = true
+	for _, field := range fields {
+		if elem := typedDataBaseType(field.Type); typedData.isStruct(elem) {
+			if err := typedData.dependencies(elem, deps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EncodeData returns the encoding of a struct value of the given type: the type
+// hash followed by the 32 byte encodings of its members, in order of definition.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("abi: unknown typed data type %q", primaryType)
+	}
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("abi: %s value has %d fields, type defines %d", primaryType, len(data), len(fields))
+	}
+	typeHash, err := typedData.TypeHash(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	encoded := append([]byte{}, typeHash[:]...)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("abi: %s value missing field %q", primaryType, field.Name)
+		}
+		word, err := typedData.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("abi: %s.%s: %v", primaryType, field.Name, err)
+		}
+		encoded = append(encoded, word...)
+	}
+	return encoded, nil
+}
+
+// isStruct reports whether the type name refers to a defined struct type.
+func (typedData *TypedData) isStruct(name string) bool {
+	_, ok := typedData.Types[name]
+	return ok
+}
+
+// typedDataBaseType strips any array suffixes from a type name.
+func typedDataBaseType(name string) string {
+	if i := strings.Index(name, "["); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// encodeValue returns the 32 byte encoding of a single value: structs, arrays
+// and dynamic types are hashed, atomic types are padded to 32 bytes.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated element encodings
+	if strings.HasSuffix(typ, "]") {
+		i := strings.LastIndex(typ, "[")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid type %q", typ)
+		}
+		elems := reflect.ValueOf(value)
+		if elems.Kind() != reflect.Slice && elems.Kind() != reflect.Array {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		if size := typ[i+1 : len(typ)-1]; size != "" {
+			if n, err := strconv.Atoi(size); err != nil || n != elems.Len() {
+				return nil, fmt.Errorf("invalid array length for type %s: %d", typ, elems.Len())
+			}
+		}
+		var encoded []byte
+		for j := 0; j < elems.Len(); j++ {
+			word, err := typedData.encodeValue(typ[:i], elems.Index(j).Interface())
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, word...)
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	// Nested structs are encoded as their struct hash
+	if typedData.isStruct(typ) {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		hash, err := typedData.HashStruct(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		return hash[:], nil
+	}
+	// Atomic and dynamic types are interpreted via their ABI type
+	if !typedDataAtomicRegex.MatchString(typ) {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	t, err := NewType(typ)
+	if err != nil {
+		return nil, err
+	}
+	switch t.T {
+	case IntTy, UintTy:
+		num, err := typedDataNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		if !typedDataInRange(num, t) {
+			return nil, fmt.Errorf("value %v out of range for type %s", num, typ)
+		}
+		return U256(num), nil
+
+	case BoolTy:
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		if flag {
+			return U256(common.Big1), nil
+		}
+		return U256(common.Big0), nil
+
+	case AddressTy:
+		blob, err := typedDataBytes(value)
+		if err != nil || len(blob) != common.AddressLength {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		return common.LeftPadBytes(blob, 32), nil
+
+	case FixedBytesTy:
+		blob, err := typedDataBytes(value)
+		if err != nil || len(blob) > t.SliceSize {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		return common.RightPadBytes(blob, 32), nil
+
+	case BytesTy:
+		blob, err := typedDataBytes(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		return crypto.Keccak256(blob), nil
+
+	case StringTy:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for type %s: %v", typ, value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+	}
+	return nil, fmt.Errorf("unsupported typed data type %s", typ)
+}
+
+// typedDataNumber converts a numeric structured data value into a big integer.
+func typedDataNumber(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case *math.HexOrDecimal256:
+		return new(big.Int).Set((*big.Int)(v)), nil
+	case *hexutil.Big:
+		return new(big.Int).Set(v.ToInt()), nil
+	case string:
+		if num, ok := math.ParseBig256(v); ok {
+			return num, nil
+		}
+		if num, ok := new(big.Int).SetString(v, 10); ok {
+			return num, nil
+		}
+	case json.Number:
+		if num, ok := new(big.Int).SetString(string(v), 10); ok {
+			return num, nil
+		}
+	case float64:
+		if num, acc := big.NewFloat(v).Int(nil); acc == big.Exact {
+			return num, nil
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return big.NewInt(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return new(big.Int).SetUint64(rv.Uint()), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid numeric value %v", value)
+}
+
+// typedDataInRange reports whether a number fits into the given integer type.
+func typedDataInRange(num *big.Int, t Type) bool {
+	if t.T == UintTy {
+		return num.Sign() >= 0 && num.BitLen() <= t.Size
+	}
+	limit := new(big.Int).Lsh(common.Big1, uint(t.Size-1))
+	return num.Cmp(limit) < 0 && num.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+// typedDataBytes converts a binary structured data value, either a 0x prefixed
+// hex string or a byte slice or array, into a byte slice.
+func typedDataBytes(value interface{}) ([]byte, error) {
+	if str, ok := value.(string); ok {
+		return hexutil.Decode(str)
+	}
+	rv := reflect.ValueOf(value)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() == reflect.Uint8 {
+		blob := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(blob), rv)
+		return blob, nil
+	}
+	return nil, fmt.Errorf("invalid binary value %v", value)
+}
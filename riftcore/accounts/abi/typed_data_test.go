@@ -0,0 +1,202 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// mailTypedData is the example message of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{ "name": "name", "type": "string" },
+			{ "name": "version", "type": "string" },
+			{ "name": "chainId", "type": "uint256" },
+			{ "name": "verifyingContract", "type": "address" }
+		],
+		"Person": [
+			{ "name": "name", "type": "string" },
+			{ "name": "wallet", "type": "address" }
+		],
+		"Mail": [
+			{ "name": "from", "type": "Person" },
+			{ "name": "to", "type": "Person" },
+			{ "name": "contents", "type": "string" }
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": { "name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826" },
+		"to": { "name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB" },
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// Tests that the example message of the EIP-712 specification is encoded, hashed
+// and signed according to the reference implementation.
+func TestTypedDataMail(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typedData); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	if typ, err := typedData.EncodeType("Mail"); err != nil || typ != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("type encoding mismatch: have %q (err %v)", typ, err)
+	}
+	if hash, err := typedData.TypeHash("Mail"); err != nil || hash != common.HexToHash("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2") {
+		t.Errorf("type hash mismatch: have %x (err %v)", hash, err)
+	}
+	if hash, err := typedData.DomainSeparator(); err != nil || hash != common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f") {
+		t.Errorf("domain separator mismatch: have %x (err %v)", hash, err)
+	}
+	if hash, err := typedData.HashStruct("Mail", typedData.Message); err != nil || hash != common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e") {
+		t.Errorf("message hash mismatch: have %x (err %v)", hash, err)
+	}
+	sighash, err := typedData.SigHash()
+	if err != nil || sighash != common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2") {
+		t.Fatalf("signing hash mismatch: have %x (err %v)", sighash, err)
+	}
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	sig, err := crypto.Sign(sighash[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	if r := common.BytesToHash(sig[:32]); r != common.HexToHash("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d") {
+		t.Errorf("signature R mismatch: have %x", r)
+	}
+	if s := common.BytesToHash(sig[32:64]); s != common.HexToHash("0x07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562") {
+		t.Errorf("signature S mismatch: have %x", s)
+	}
+	if sig[64] != 1 {
+		t.Errorf("signature V mismatch: have %d, want %d", sig[64], 1)
+	}
+}
+
+// Tests that structured data built from native Go values hashes identically to
+// its JSON counterpart, with the domain type derived from the set domain fields.
+func TestTypedDataNative(t *testing.T) {
+	var parsed TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &parsed); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	contract := common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+	native := TypedData{
+		Types: TypedDataTypes{
+			"Person": parsed.Types["Person"],
+			"Mail":   parsed.Types["Mail"],
+		},
+		PrimaryType: "Mail",
+		Domain: TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           big.NewInt(1),
+			VerifyingContract: &contract,
+		},
+		Message: map[string]interface{}{
+			"from":     map[string]interface{}{"name": "Cow", "wallet": common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")},
+			"to":       map[string]interface{}{"name": "Bob", "wallet": common.HexToAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")},
+			"contents": "Hello, Bob!",
+		},
+	}
+	want, _ := parsed.SigHash()
+	if have, err := native.SigHash(); err != nil || have != want {
+		t.Errorf("signing hash mismatch: have %x, want %x (err %v)", have, want, err)
+	}
+}
+
+// Tests that numbers in JSON messages are parsed without losing precision.
+func TestTypedDataPrecision(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(`{"message": {"amount": 123456789012345678901234567890}}`), &typedData); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	num, err := typedDataNumber(typedData.Message["amount"])
+	if err != nil {
+		t.Fatalf("failed to parse number: %v", err)
+	}
+	if want, _ := new(big.Int).SetString("123456789012345678901234567890", 10); num.Cmp(want) != 0 {
+		t.Errorf("number mismatch: have %v, want %v", num, want)
+	}
+}
+
+// Tests that arrays and atomic values are encoded and range checked.
+func TestTypedDataValues(t *testing.T) {
+	typedData := TypedData{
+		Types: TypedDataTypes{
+			"Order": {
+				{Name: "amounts", Type: "uint8[2]"},
+				{Name: "delta", Type: "int8"},
+				{Name: "tag", Type: "bytes4"},
+				{Name: "data", Type: "bytes"},
+				{Name: "valid", Type: "bool"},
+			},
+		},
+	}
+	valid := map[string]interface{}{
+		"amounts": []interface{}{float64(1), "0xff"},
+		"delta":   big.NewInt(-128),
+		"tag":     "0x01020304",
+		"data":    []byte{0xde, 0xad},
+		"valid":   true,
+	}
+	encoded, err := typedData.EncodeData("Order", valid)
+	if err != nil {
+		t.Fatalf("failed to encode valid data: %v", err)
+	}
+	if len(encoded) != 6*32 {
+		t.Fatalf("encoding length mismatch: have %d, want %d", len(encoded), 6*32)
+	}
+	if have, want := encoded[32:2*32], crypto.Keccak256(common.LeftPadBytes([]byte{1}, 32), common.LeftPadBytes([]byte{0xff}, 32)); common.BytesToHash(have) != common.BytesToHash(want) {
+		t.Errorf("array encoding mismatch: have %x, want %x", have, want)
+	}
+	invalid := []struct {
+		field string
+		value interface{}
+	}{
+		{"amounts", []interface{}{1, 2, 3}},
+		{"amounts", []interface{}{1, 256}},
+		{"delta", 128},
+		{"tag", "0x0102030405"},
+		{"data", "not hex"},
+		{"valid", "true"},
+	}
+	for i, tt := range invalid {
+		data := make(map[string]interface{})
+		for k, v := range valid {
+			data[k] = v
+		}
+		data[tt.field] = tt.value
+		if _, err := typedData.EncodeData("Order", data); err == nil {
+			t.Errorf("test %d: invalid %s value %v accepted", i, tt.field, tt.value)
+		}
+	}
+	delete(valid, "valid")
+	if _, err := typedData.EncodeData("Order", valid); err == nil {
+		t.Errorf("missing field accepted")
+	}
+}
@@ -25,118 +25,73 @@ import (
 	"github.com/cryptorift/riftcore/common"
 )
 
-// toGoSliceType parses the input and casts it to the proper slice defined by the ABI
-// argument in T.
-func toGoSlice(i int, t Argument, output []byte) (interface{}, error) {
-	index := i * 32
-	// The slice must, at very least be large enough for the index+32 which is exactly the size required
-	// for the [offset in output, size of offset].
-	if index+32 > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go slice: insufficient size output %d require %d", len(output), index+32)
+// readOffset reads the head word at index in the output, pointing to where a
+// dynamic value is stored in the tail section.
+func readOffset(index int, output []byte) (int, error) {
+	offset := new(big.Int).SetBytes(output[index : index+32])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(output)) {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: offset %v would go over slice boundary (len=%d)", offset, len(output))
 	}
-	elem := t.Type.Elem
+	return int(offset.Uint64()), nil
+}
 
-	// first we need to create a slice of the type
-	var refSlice reflect.Value
-	switch elem.T {
-	case IntTy, UintTy, BoolTy:
-		// create a new reference slice matching the element type
-		switch t.Type.Kind {
-		case reflect.Bool:
-			refSlice = reflect.ValueOf([]bool(nil))
-		case reflect.Uint8:
-			refSlice = reflect.ValueOf([]uint8(nil))
-		case reflect.Uint16:
-			refSlice = reflect.ValueOf([]uint16(nil))
-		case reflect.Uint32:
-			refSlice = reflect.ValueOf([]uint32(nil))
-		case reflect.Uint64:
-			refSlice = reflect.ValueOf([]uint64(nil))
-		case reflect.Int8:
-			refSlice = reflect.ValueOf([]int8(nil))
-		case reflect.Int16:
-			refSlice = reflect.ValueOf([]int16(nil))
-		case reflect.Int32:
-			refSlice = reflect.ValueOf([]int32(nil))
-		case reflect.Int64:
-			refSlice = reflect.ValueOf([]int64(nil))
-		default:
-			refSlice = reflect.ValueOf([]*big.Int(nil))
-		}
-	case AddressTy: // address must be of slice Address
-		refSlice = reflect.ValueOf([]common.Address(nil))
-	case HashTy: // hash must be of slice hash
-		refSlice = reflect.ValueOf([]common.Hash(nil))
-	case FixedBytesTy:
-		refSlice = reflect.ValueOf([][]byte(nil))
-	default: // no other types are supported
-		return nil, fmt.Errorf("abi: unsupported slice type %v", elem.T)
+// readLength reads the length prefix of a dynamic value stored at index in the
+// output.
+func readLength(index int, output []byte) (int, error) {
+	length := new(big.Int).SetBytes(output[index : index+32])
+	if !length.IsUint64() || length.Uint64() > uint64(len(output)) {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: length %v exceeds output (len=%d)", length, len(output))
 	}
+	return int(length.Uint64()), nil
+}
 
-	var slice []byte
-	var size int
-	var offset int
-	if t.Type.IsSlice {
-		// get the offset which determines the start of this array ...
-		offset = int(binary.BigEndian.Uint64(output[index+24 : index+32]))
-		if offset+32 > len(output) {
-			return nil, fmt.Errorf("abi: cannot marshal in to go slice: offset %d would go over slice boundary (len=%d)", len(output), offset+32)
-		}
-
-		slice = output[offset:]
-		// ... starting with the size of the array in elements ...
-		size = int(binary.BigEndian.Uint64(slice[24:32]))
-		slice = slice[32:]
-		// ... and make sure that we've at the very least the amount of bytes
-		// available in the buffer.
-		if size*32 > len(slice) {
-			return nil, fmt.Errorf("abi: cannot marshal in to go slice: insufficient size output %d require %d", len(output), offset+32+size*32)
-		}
-
-		// reslice to match the required size
-		slice = slice[:size*32]
-	} else if t.Type.IsArray {
-		//get the number of elements in the array
-		size = t.Type.SliceSize
-
-		//check to make sure array size matches up
-		if index+32*size > len(output) {
-			return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), index+32*size)
+// toGoSlice unpacks the elements of the array or slice type t stored at index in
+// the output. Slices are prefixed with their length, arrays have a fixed one.
+func toGoSlice(index int, t Type, output []byte) (interface{}, error) {
+	size := t.SliceSize
+	if t.IsSlice {
+		var err error
+		if size, err = readLength(index, output); err != nil {
+			return nil, err
 		}
-		//slice is there for a fixed amount of times
-		slice = output[index : index+size*32]
+		index += 32
 	}
-
+	// Offsets of dynamic elements are relative to the start of the first element
+	elems := output[index:]
+	elemSize := t.Elem.headSize()
+	if size*elemSize > len(elems) {
+		return nil, fmt.Errorf("abi: cannot marshal in to go slice: insufficient size output %d require %d", len(output), index+size*elemSize)
+	}
+	refSlice := reflect.MakeSlice(t.goType(), size, size)
 	for i := 0; i < size; i++ {
-		var (
-			inter        interface{}             // interface type
-			returnOutput = slice[i*32 : i*32+32] // the return output
-			err          error
-		)
-		// set inter to the correct type (cast)
-		switch elem.T {
-		case IntTy, UintTy:
-			inter = readInteger(t.Type.Kind, returnOutput)
-		case BoolTy:
-			inter, err = readBool(returnOutput)
-			if err != nil {
-				return nil, err
-			}
-		case AddressTy:
-			inter = common.BytesToAddress(returnOutput)
-		case HashTy:
-			inter = common.BytesToHash(returnOutput)
-		case FixedBytesTy:
-			inter = returnOutput
+		inter, err := toGoType(i*elemSize, *t.Elem, elems)
+		if err != nil {
+			return nil, err
 		}
-		// append the item to our reflect slice
-		refSlice = reflect.Append(refSlice, reflect.ValueOf(inter))
+		refSlice.Index(i).Set(reflect.ValueOf(inter))
 	}
-
-	// return the interface
 	return refSlice.Interface(), nil
 }
 
+// toGoTuple unpacks the fields of the tuple type t stored at index in the output
+// in to a value of the tuple's Go struct type.
+func toGoTuple(index int, t Type, output []byte) (interface{}, error) {
+	// Offsets of dynamic fields are relative to the start of the tuple
+	fields := output[index:]
+	tuple := reflect.New(t.Type).Elem()
+
+	offset := 0
+	for i, elem := range t.TupleElems {
+		inter, err := toGoType(offset, *elem, fields)
+		if err != nil {
+			return nil, err
+		}
+		tuple.Field(i).Set(reflect.ValueOf(inter))
+		offset += elem.headSize()
+	}
+	return tuple.Interface(), nil
+}
+
 func readInteger(kind reflect.Kind, b []byte) interface{} {
 	switch kind {
 	case reflect.Uint8:
@@ -181,55 +136,54 @@ func readBool(word []byte) (bool, error) {
 
 }
 
-// toGoType parses the input and casts it to the proper type defined by the ABI
-// argument in T.
-func toGoType(i int, t Argument, output []byte) (interface{}, error) {
-	// we need to treat slices differently
-	if (t.Type.IsSlice || t.Type.IsArray) && t.Type.T != BytesTy && t.Type.T != StringTy && t.Type.T != FixedBytesTy && t.Type.T != FunctionTy {
-		return toGoSlice(i, t, output)
-	}
-
-	index := i * 32
+// toGoType parses the value of type t stored at index in the output and casts it
+// to the proper Go type.
+func toGoType(index int, t Type, output []byte) (interface{}, error) {
 	if index+32 > len(output) {
 		return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
 	}
-
-	// Parse the given index output and check whether we need to read
-	// a different offset and length based on the type (i.e. string, bytes)
-	var returnOutput []byte
-	switch t.Type.T {
-	case StringTy, BytesTy: // variable arrays are written at the end of the return bytes
-		// parse offset from which we should start reading
-		offset := int(binary.BigEndian.Uint64(output[index+24 : index+32]))
-		if offset+32 > len(output) {
-			return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), offset+32)
+	// Dynamic values are stored in the tail section, the head only points to them
+	if t.isDynamic() {
+		offset, err := readOffset(index, output)
+		if err != nil {
+			return nil, err
 		}
-		// parse the size up until we should be reading
-		size := int(binary.BigEndian.Uint64(output[offset+24 : offset+32]))
-		if offset+32+size > len(output) {
-			return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), offset+32+size)
+		index = offset
+	}
+	switch {
+	case t.isList():
+		return toGoSlice(index, t, output)
+	case t.T == TupleTy:
+		return toGoTuple(index, t, output)
+	}
+	// Strings and bytes are prefixed with their length in bytes
+	if t.T == StringTy || t.T == BytesTy {
+		size, err := readLength(index, output)
+		if err != nil {
+			return nil, err
 		}
-
-		// get the bytes for this return value
-		returnOutput = output[offset+32 : offset+32+size]
-	default:
-		returnOutput = output[index : index+32]
+		if index+32+size > len(output) {
+			return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32+size)
+		}
+		if t.T == StringTy {
+			return string(output[index+32 : index+32+size]), nil
+		}
+		return output[index+32 : index+32+size], nil
 	}
+	// All other types are stored in a single word
+	word := output[index : index+32]
 
-	// convert the bytes to whatever is specified by the ABI.
-	switch t.Type.T {
+	switch t.T {
 	case IntTy, UintTy:
-		return readInteger(t.Type.Kind, returnOutput), nil
+		return readInteger(t.Kind, word), nil
 	case BoolTy:
-		return readBool(returnOutput)
+		return readBool(word)
 	case AddressTy:
-		return common.BytesToAddress(returnOutput), nil
+		return common.BytesToAddress(word), nil
 	case HashTy:
-		return common.BytesToHash(returnOutput), nil
-	case BytesTy, FixedBytesTy, FunctionTy:
-		return returnOutput, nil
-	case StringTy:
-		return string(returnOutput), nil
+		return common.BytesToHash(word), nil
+	case FixedBytesTy, FunctionTy:
+		return word, nil
 	}
-	return nil, fmt.Errorf("abi: unknown type %v", t.Type.T)
+	return nil, fmt.Errorf("abi: unknown type %v", t.T)
 }
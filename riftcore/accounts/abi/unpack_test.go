@@ -679,3 +679,107 @@ func TestUnmarshal(t *testing.T) {
 		t.Fatal("expected error:", err)
 	}
 }
+
+func TestUnpackTuple(t *testing.T) {
+	const definition = `[{"name": "tuple", "constant": true,
+		"inputs": [
+			{"name": "ret", "type": "tuple", "components": [
+				{"name": "a", "type": "uint256"},
+				{"name": "b", "type": "string"},
+				{"name": "c", "type": "uint8[][]"},
+				{"name": "d", "type": "bytes32[2]"}
+			]}
+		],
+		"outputs": [
+			{"name": "ret", "type": "tuple", "components": [
+				{"name": "a", "type": "uint256"},
+				{"name": "b", "type": "string"},
+				{"name": "c", "type": "uint8[][]"},
+				{"name": "d", "type": "bytes32[2]"}
+			]}
+		]}]`
+
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tuple struct {
+		A *big.Int
+		B string
+		C [][]uint8
+		D [2][32]byte
+	}
+	in := tuple{big.NewInt(1), "hello", [][]uint8{{1, 2}, {}, {3}}, [2][32]byte{{1}, {2}}}
+
+	packed, err := abi.Pack("tuple", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out tuple
+	if err := abi.Unpack(&out, "tuple", packed[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("unpacked tuple mismatch:\nhave %+v\nwant %+v", out, in)
+	}
+	// Unpacking in to an interface yields the generic struct of the tuple
+	var generic interface{}
+	if err := abi.Unpack(&generic, "tuple", packed[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if b := reflect.ValueOf(generic).FieldByName("B"); !b.IsValid() || b.String() != "hello" {
+		t.Errorf("unpacked generic tuple mismatch: %+v", generic)
+	}
+}
+
+func TestUnpackNestedArrays(t *testing.T) {
+	const definition = `[{"name": "nested", "constant": true, "outputs": [
+		{"name": "fixed", "type": "uint8[2]"},
+		{"name": "list", "type": "tuple[]", "components": [
+			{"name": "id", "type": "uint8"},
+			{"name": "tags", "type": "string[]"}
+		]},
+		{"name": "count", "type": "uint8"}
+	]}]`
+
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // fixed[0]
+		"0000000000000000000000000000000000000000000000000000000000000002" + // fixed[1]
+		"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to list
+		"0000000000000000000000000000000000000000000000000000000000000003" + // count
+		"0000000000000000000000000000000000000000000000000000000000000001" + // len(list) = 1
+		"0000000000000000000000000000000000000000000000000000000000000020" + // offset 32 to list[0]
+		"0000000000000000000000000000000000000000000000000000000000000007" + // list[0].id
+		"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to list[0].tags
+		"0000000000000000000000000000000000000000000000000000000000000001" + // len(list[0].tags) = 1
+		"0000000000000000000000000000000000000000000000000000000000000020" + // offset 32 to list[0].tags[0]
+		"0000000000000000000000000000000000000000000000000000000000000003" + // len(list[0].tags[0]) = 3
+		"666f6f0000000000000000000000000000000000000000000000000000000000") // list[0].tags[0]
+
+	type item struct {
+		Id   uint8
+		Tags []string
+	}
+	var out struct {
+		Fixed [2]uint8
+		List  []item
+		Count uint8
+	}
+	if err := abi.Unpack(&out, "nested", output); err != nil {
+		t.Fatal(err)
+	}
+	if out.Fixed != [2]uint8{1, 2} || out.Count != 3 {
+		t.Errorf("static fields mismatch: have %v and %d", out.Fixed, out.Count)
+	}
+	if want := []item{{7, []string{"foo"}}}; !reflect.DeepEqual(out.List, want) {
+		t.Errorf("tuple list mismatch: have %+v, want %+v", out.List, want)
+	}
+	// Offsets pointing out of the output must be rejected
+	output[95] = 0xff
+	if err := abi.Unpack(&out, "nested", output); err == nil {
+		t.Error("expected error for out of bounds offset")
+	}
+}
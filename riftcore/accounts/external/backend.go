@@ -0,0 +1,259 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend that forwards all signing
+// requests to an external signer process over RPC, allowing the node to run
+// without holding any account keys itself.
+package external
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	cryptorift "github.com/cryptorift/riftcore"
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/rpc"
+	"github.com/cryptorift/riftcore/signer/core"
+)
+
+// ExternalBackend is an accounts.Backend exposing a single external signer as
+// its only wallet.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given endpoint (an
+// IPC path or an HTTP URL) and creates an account backend around it.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{
+		signers: []accounts.Wallet{signer},
+	}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. As the external signer is connected at
+// startup and never departs, no wallet events are ever fired.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is an accounts.Wallet forwarding account listing and signing
+// requests to an external signer.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	status   string
+
+	cacheMu sync.RWMutex
+	cache   []accounts.Account
+}
+
+// NewExternalSigner connects to the external signer at the given endpoint,
+// ensuring it is reachable and speaks a known API version.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := newExternalSigner(client, endpoint)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return signer, nil
+}
+
+// newExternalSigner creates an external signer around an already established
+// RPC connection, ensuring the remote end is reachable.
+func newExternalSigner(client *rpc.Client, endpoint string) (*ExternalSigner, error) {
+	var version string
+	if err := client.Call(&version, "account_version"); err != nil {
+		return nil, fmt.Errorf("external signer unreachable: %v", err)
+	}
+	return &ExternalSigner{
+		client:   client,
+		endpoint: endpoint,
+		status:   fmt.Sprintf("ok [version=%v]", version),
+	}, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{
+		Scheme: "extapi",
+		Path:   api.endpoint,
+	}
+}
+
+// Status implements accounts.Wallet, returning the connection status.
+func (api *ExternalSigner) Status() string {
+	return api.status
+}
+
+// Open implements accounts.Wallet. The external signer is always open.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return accounts.ErrNotSupported
+}
+
+// Close implements accounts.Wallet. The external signer cannot be closed.
+func (api *ExternalSigner) Close() error {
+	return accounts.ErrNotSupported
+}
+
+// Accounts implements accounts.Wallet, returning the accounts the user of the
+// external signer agreed to reveal. As listing requires approval, the result of
+// the first successful listing is cached.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	api.cacheMu.RLock()
+	cache := api.cache
+	api.cacheMu.RUnlock()
+
+	if cache != nil {
+		return cache
+	}
+	var addresses []common.Address
+	if err := api.client.Call(&addresses, "account_list"); err != nil {
+		log.Error("Failed to list accounts of external signer", "err", err)
+		return nil
+	}
+	accs := make([]accounts.Account, 0, len(addresses))
+	for _, addr := range addresses {
+		accs = append(accs, accounts.Account{
+			Address: addr,
+			URL:     api.URL(),
+		})
+	}
+	api.cacheMu.Lock()
+	api.cache = accs
+	api.cacheMu.Unlock()
+
+	return accs
+}
+
+// Contains implements accounts.Wallet, returning whether the account is among
+// the ones revealed by the external signer.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	for _, acc := range api.Accounts() {
+		if acc.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet. Account derivation is the responsibility
+// of the external signer.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet. Account derivation is the responsibility
+// of the external signer, so the request is silently ignored.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain cryptorift.ChainStateReader) {
+}
+
+// SignHash implements accounts.Wallet. The external signer refuses to sign raw
+// hashes as the user would not be able to tell what they are approving, use the
+// account_signData method of the signer instead.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTx implements accounts.Wallet, forwarding the transaction to the external
+// signer for approval and signing.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := core.SendTxArgs{
+		From:     account.Address,
+		To:       tx.To(),
+		Gas:      hexutil.Big(*tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+	}
+	var res riftapi.SignTransactionResult
+	if err := api.client.CallContext(context.Background(), &res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	// Ensure the signer signed for the expected account and chain
+	if chainID != nil && res.Tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("external signer chain id mismatch: have %v, want %v", res.Tx.ChainId(), chainID)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if res.Tx.Protected() {
+		signer = types.NewEIP155Signer(res.Tx.ChainId())
+	}
+	if from, err := types.Sender(signer, res.Tx); err != nil || from != account.Address {
+		return nil, fmt.Errorf("external signer sender mismatch: have %x, want %x (err %v)", from, account.Address, err)
+	}
+	// Ensure the signer didn't change the transaction itself
+	if err := checkSignedTx(tx, res.Tx); err != nil {
+		return nil, err
+	}
+	return res.Tx, nil
+}
+
+// checkSignedTx verifies that a transaction returned by the external signer is
+// the one requested to be signed, not a modified version of it.
+func checkSignedTx(want, have *types.Transaction) error {
+	switch {
+	case (want.To() == nil) != (have.To() == nil) || (want.To() != nil && *want.To() != *have.To()):
+		return fmt.Errorf("external signer recipient mismatch: have %v, want %v", have.To(), want.To())
+	case want.Value().Cmp(have.Value()) != 0:
+		return fmt.Errorf("external signer value mismatch: have %v, want %v", have.Value(), want.Value())
+	case want.Nonce() != have.Nonce():
+		return fmt.Errorf("external signer nonce mismatch: have %d, want %d", have.Nonce(), want.Nonce())
+	case want.Gas().Cmp(have.Gas()) != 0:
+		return fmt.Errorf("external signer gas mismatch: have %v, want %v", have.Gas(), want.Gas())
+	case want.GasPrice().Cmp(have.GasPrice()) != 0:
+		return fmt.Errorf("external signer gas price mismatch: have %v, want %v", have.GasPrice(), want.GasPrice())
+	case !bytes.Equal(want.Data(), have.Data()):
+		return fmt.Errorf("external signer data mismatch: have %x, want %x", have.Data(), want.Data())
+	}
+	return nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet. Passwords are managed by
+// the external signer and cannot be provided by the node.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet. Passwords are managed by the
+// external signer and cannot be provided by the node.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
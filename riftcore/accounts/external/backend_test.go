@@ -0,0 +1,130 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/keystore"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/rpc"
+	"github.com/cryptorift/riftcore/signer/core"
+)
+
+// approvingUI is a core.SignerUI approving all requests with a fixed password.
+type approvingUI struct {
+	password string
+}
+
+func (ui *approvingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true, Password: ui.password}, nil
+}
+
+func (ui *approvingUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return core.SignDataResponse{Approved: true, Password: ui.password}, nil
+}
+
+func (ui *approvingUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return core.ListResponse{Accounts: request.Accounts}, nil
+}
+
+func (ui *approvingUI) ShowError(message string)                      {}
+func (ui *approvingUI) ShowInfo(message string)                       {}
+func (ui *approvingUI) OnApprovedTx(tx riftapi.SignTransactionResult) {}
+func (ui *approvingUI) OnSignerStartup(info core.StartupInfo)         {}
+
+// tamperingUI is a core.SignerUI approving transactions after raising their value.
+type tamperingUI struct {
+	approvingUI
+}
+
+func (ui *tamperingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	res, err := ui.approvingUI.ApproveTx(request)
+	res.Transaction.Value = hexutil.Big(*new(big.Int).Add(request.Transaction.Value.ToInt(), big.NewInt(1)))
+	return res, err
+}
+
+// Tests that the external signer wallet lists the accounts of the remote signer
+// and returns transactions signed by the requested account.
+func TestExternalSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-signer-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary keystore: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("password")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", core.NewSignerAPI(accounts.NewManager(ks), 1, &approvingUI{"password"})); err != nil {
+		t.Fatalf("failed to register signer API: %v", err)
+	}
+	defer server.Stop()
+
+	signer, err := newExternalSigner(rpc.DialInProc(server), "inproc")
+	if err != nil {
+		t.Fatalf("failed to connect to signer: %v", err)
+	}
+	accs := signer.Accounts()
+	if len(accs) != 1 || accs[0].Address != account.Address {
+		t.Fatalf("account list mismatch: have %v, want [%x]", accs, account.Address)
+	}
+	if !signer.Contains(accounts.Account{Address: account.Address}) {
+		t.Fatalf("signer does not contain listed account")
+	}
+	// Sign a transaction remotely and ensure it's signed by the right account
+	to := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	tx := types.NewTransaction(3, to, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil)
+
+	signed, err := signer.SignTx(accs[0], tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), signed); err != nil || from != account.Address {
+		t.Fatalf("signer mismatch: have %x (err %v), want %x", from, err, account.Address)
+	}
+	if signed.Nonce() != tx.Nonce() || *signed.To() != to || signed.Value().Cmp(tx.Value()) != 0 {
+		t.Fatalf("signed transaction mismatch: have %v, want %v", signed, tx)
+	}
+	// Ensure signing with a mismatching chain id is caught
+	if _, err := signer.SignTx(accs[0], tx, big.NewInt(2)); err == nil {
+		t.Fatalf("chain id mismatch not detected")
+	}
+	// Ensure transactions modified by the signer are rejected
+	tampering := rpc.NewServer()
+	if err := tampering.RegisterName("account", core.NewSignerAPI(accounts.NewManager(ks), 1, &tamperingUI{approvingUI{"password"}})); err != nil {
+		t.Fatalf("failed to register signer API: %v", err)
+	}
+	defer tampering.Stop()
+
+	if signer, err = newExternalSigner(rpc.DialInProc(tampering), "inproc"); err != nil {
+		t.Fatalf("failed to connect to signer: %v", err)
+	}
+	if _, err := signer.SignTx(accs[0], tx, big.NewInt(1)); err == nil {
+		t.Fatalf("modified transaction not detected")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common/math"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// hardenedOffset is the first child index of hardened BIP-32 derivations.
+const hardenedOffset = 0x80000000
+
+// errInvalidHDKey is returned if a BIP-32 derivation step yields a key outside
+// of the valid range of the secp256k1 curve. The probability of this happening
+// is lower than 1 in 2^127.
+var errInvalidHDKey = errors.New("invalid hierarchical deterministic key")
+
+// hdKey is a BIP-32 extended private key on the secp256k1 curve.
+type hdKey struct {
+	key   []byte // 32 byte private key
+	chain []byte // 32 byte chain code
+}
+
+// newMasterKey generates the BIP-32 master extended key from a wallet seed.
+func newMasterKey(seed []byte) (*hdKey, error) {
+	return newHDKey([]byte("Bitcoin seed"), seed, nil)
+}
+
+// newHDKey computes the HMAC-SHA512 of data keyed with chain, and interprets
+// the output as an extended key, adding parent (if set) to the private part.
+func newHDKey(chain, data []byte, parent []byte) (*hdKey, error) {
+	mac := hmac.New(sha512.New, chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Cmp(n) >= 0 {
+		return nil, errInvalidHDKey
+	}
+	if parent != nil {
+		key.Add(key, new(big.Int).SetBytes(parent))
+		key.Mod(key, n)
+	}
+	if key.Sign() == 0 {
+		return nil, errInvalidHDKey
+	}
+	return &hdKey{key: math.PaddedBigBytes(key, 32), chain: sum[32:]}, nil
+}
+
+// child derives the extended private key of the child with the given index,
+// hardened if the index is at least 2^31.
+func (k *hdKey) child(index uint32) (*hdKey, error) {
+	var data []byte
+	if index >= hardenedOffset {
+		data = append([]byte{0x00}, k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		pub := crypto.FromECDSAPub(&priv.PublicKey)
+		data = append([]byte{0x02 | pub[64]&1}, pub[1:33]...)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	return newHDKey(k.chain, data, k.key)
+}
+
+// derive walks a derivation path from the current key, returning the extended
+// private key at its end.
+func (k *hdKey) derive(path accounts.DerivationPath) (*hdKey, error) {
+	var err error
+	for _, index := range path {
+		if k, err = k.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// privateKey converts the extended key into an ECDSA private key.
+func (k *hdKey) privateKey() (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(k.key)
+}
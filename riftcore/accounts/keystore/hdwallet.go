@@ -0,0 +1,438 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sync"
+	"time"
+
+	cryptorift "github.com/cryptorift/riftcore"
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/log"
+)
+
+const (
+	// hdWalletDir is the subdirectory of the keystore holding the encrypted seeds
+	// of hierarchical deterministic wallets.
+	hdWalletDir = "hd"
+
+	// hdWalletVersion is the version of the HD wallet seed file format.
+	hdWalletVersion = 1
+
+	// selfDeriveThrottling is the minimum time between two self-derivation runs.
+	selfDeriveThrottling = time.Second
+)
+
+// hdWalletJSON is the on-disk format of a hierarchical deterministic wallet: the
+// encrypted BIP-39 seed and the list of accounts pinned by the user.
+type hdWalletJSON struct {
+	Version  int             `json:"version"`
+	Id       string          `json:"id"`
+	Crypto   cryptoJSON      `json:"crypto"`
+	Accounts []hdAccountJSON `json:"accounts"`
+}
+
+// hdAccountJSON is a pinned account of a hierarchical deterministic wallet.
+type hdAccountJSON struct {
+	Address common.Address `json:"address"`
+	Path    string         `json:"path"`
+}
+
+// hdWallet implements the accounts.Wallet interface for hierarchical deterministic
+// wallets whose seed is stored encrypted in the keystore. Accounts are derived
+// from the seed on demand, pinned ones being listed even while locked.
+type hdWallet struct {
+	url  accounts.URL // Location of the seed file within the keystore
+	file hdWalletJSON // Contents of the seed file, updated on pinning
+
+	seed     []byte                                     // Decrypted seed, nil while the wallet is locked
+	accounts []accounts.Account                         // List of derived accounts tracked by the wallet
+	paths    map[common.Address]accounts.DerivationPath // Known derivation paths for signing operations
+
+	deriveNextPath accounts.DerivationPath     // Next derivation path for account auto-discovery
+	deriveChain    cryptorift.ChainStateReader // Blockchain state reader to discover used account with
+	deriving       bool                        // Whether a self-derivation run is in progress
+	deriveTime     time.Time                   // Time of the last self-derivation run
+
+	lock sync.RWMutex // Protects the wallet state against concurrent access
+}
+
+// loadHDWallet parses the seed file at path into a locked HD wallet.
+func loadHDWallet(path string) (*hdWallet, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &hdWallet{url: accounts.URL{Scheme: KeyStoreScheme, Path: path}}
+	if err := json.Unmarshal(blob, &w.file); err != nil {
+		return nil, err
+	}
+	if w.file.Version != hdWalletVersion {
+		return nil, fmt.Errorf("HD wallet version not supported: %v", w.file.Version)
+	}
+	if err := w.reset(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// reset drops all self-derived accounts, leaving only the pinned ones.
+func (w *hdWallet) reset() error {
+	w.accounts = make([]accounts.Account, 0, len(w.file.Accounts))
+	w.paths = make(map[common.Address]accounts.DerivationPath)
+
+	for _, pinned := range w.file.Accounts {
+		path, err := accounts.ParseDerivationPath(pinned.Path)
+		if err != nil {
+			return err
+		}
+		w.track(pinned.Address, path)
+	}
+	return nil
+}
+
+// track starts tracking a derived account if it's not yet known.
+func (w *hdWallet) track(address common.Address, path accounts.DerivationPath) accounts.Account {
+	account := accounts.Account{
+		Address: address,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+	if _, ok := w.paths[address]; !ok {
+		w.accounts = append(w.accounts, account)
+		w.paths[address] = path
+	}
+	return account
+}
+
+// store writes the seed file of the wallet to disk.
+func (w *hdWallet) store() error {
+	blob, err := json.Marshal(w.file)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(w.url.Path, blob)
+}
+
+// URL implements accounts.Wallet, returning the URL of the seed file.
+func (w *hdWallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the seed is decrypted.
+func (w *hdWallet) Status() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.seed != nil {
+		return "Unlocked"
+	}
+	return "Locked"
+}
+
+// Open implements accounts.Wallet, decrypting the seed of the wallet to allow
+// account derivation and signing. An empty passphrase is a noop, leaving the
+// wallet locked with only its pinned accounts accessible.
+func (w *hdWallet) Open(passphrase string) error {
+	if passphrase == "" {
+		return nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed != nil {
+		return accounts.ErrWalletAlreadyOpen
+	}
+	seed, err := decryptData(w.file.Crypto, passphrase)
+	if err != nil {
+		return err
+	}
+	w.seed = seed
+	return nil
+}
+
+// Close implements accounts.Wallet, dropping the decrypted seed from memory along
+// with any accounts that were self-derived but not pinned.
+func (w *hdWallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed != nil {
+		zeroBytes(w.seed)
+		w.seed = nil
+	}
+	return w.reset()
+}
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned to
+// the wallet or discovered by self-derivation. If self-derivation is enabled and
+// the wallet unlocked, a new account discovery round is started in the background.
+func (w *hdWallet) Accounts() []accounts.Account {
+	w.lock.Lock()
+	if w.seed != nil && w.deriveChain != nil && !w.deriving && time.Since(w.deriveTime) > selfDeriveThrottling {
+		w.deriving = true
+		go w.selfDerive()
+	}
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	w.lock.Unlock()
+
+	return cpy
+}
+
+// selfDerive derives accounts from the next self-derivation path onwards, tracking
+// them until the first one is found that was never used on the chain.
+func (w *hdWallet) selfDerive() {
+	w.lock.RLock()
+	var (
+		seed     = w.seed
+		chain    = w.deriveChain
+		nextPath = make(accounts.DerivationPath, len(w.deriveNextPath))
+
+		addrs []common.Address
+		paths []accounts.DerivationPath
+	)
+	copy(nextPath, w.deriveNextPath)
+	w.lock.RUnlock()
+
+	for seed != nil {
+		key, err := deriveKey(seed, nextPath)
+		if err != nil {
+			log.Warn("HD wallet account derivation failed", "err", err)
+			break
+		}
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		zeroKey(key)
+
+		// Check the account's status against the current chain state
+		balance, err := chain.BalanceAt(context.Background(), addr, nil)
+		if err != nil {
+			log.Warn("HD wallet balance retrieval failed", "err", err)
+			break
+		}
+		nonce, err := chain.NonceAt(context.Background(), addr, nil)
+		if err != nil {
+			log.Warn("HD wallet nonce retrieval failed", "err", err)
+			break
+		}
+		path := make(accounts.DerivationPath, len(nextPath))
+		copy(path, nextPath)
+		addrs, paths = append(addrs, addr), append(paths, path)
+
+		// If the account is empty, stop self-derivation, but track it nonetheless
+		if balance.Sign() == 0 && nonce == 0 {
+			break
+		}
+		log.Info("HD wallet discovered new account", "address", addr, "path", path, "balance", balance, "nonce", nonce)
+		nextPath[len(nextPath)-1]++
+	}
+	// Insert any accounts successfully derived, unless the wallet was closed
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed != nil {
+		for i, addr := range addrs {
+			w.track(addr, paths[i])
+		}
+		w.deriveNextPath = nextPath
+	}
+	w.deriving = false
+	w.deriveTime = time.Now()
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not pinned into this wallet instance.
+func (w *hdWallet) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists
+}
+
+// Derive implements accounts.Wallet, deriving a new account at the specific
+// derivation path. If pin is set to true, the account will be added to the list
+// of tracked accounts and persisted into the seed file.
+func (w *hdWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed == nil {
+		return accounts.Account{}, ErrLocked
+	}
+	key, err := deriveKey(w.seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	account := accounts.Account{
+		Address: addr,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+	if !pin {
+		return account, nil
+	}
+	for _, pinned := range w.file.Accounts {
+		if pinned.Address == addr {
+			return w.track(addr, path), nil
+		}
+	}
+	w.file.Accounts = append(w.file.Accounts, hdAccountJSON{Address: addr, Path: path.String()})
+	if err := w.store(); err != nil {
+		w.file.Accounts = w.file.Accounts[:len(w.file.Accounts)-1]
+		return accounts.Account{}, err
+	}
+	return w.track(addr, path), nil
+}
+
+// SelfDerive implements accounts.Wallet, setting a base account derivation path
+// from which the wallet attempts to discover non zero accounts and automatically
+// add them to the list of tracked accounts.
+//
+// Note, self derivation will increment the last component of the specified path
+// opposed to descending into a child path to allow discovering accounts starting
+// from non zero components.
+//
+// You can disable automatic account discovery by calling SelfDerive with a nil
+// chain state reader.
+func (w *hdWallet) SelfDerive(base accounts.DerivationPath, chain cryptorift.ChainStateReader) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.deriveNextPath = make(accounts.DerivationPath, len(base))
+	copy(w.deriveNextPath[:], base[:])
+
+	w.deriveChain = chain
+}
+
+// signingKey derives the private key of a tracked account from the given seed.
+func (w *hdWallet) signingKey(account accounts.Account, seed []byte) (*ecdsa.PrivateKey, error) {
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if seed == nil {
+		return nil, ErrLocked
+	}
+	return deriveKey(seed, path)
+}
+
+// SignHash implements accounts.Wallet, signing the given hash with the key of
+// the requested account, derived from the unlocked seed.
+func (w *hdWallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	w.lock.RLock()
+	key, err := w.signingKey(account, w.seed)
+	w.lock.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the given transaction with the key
+// of the requested account, derived from the unlocked seed.
+func (w *hdWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.lock.RLock()
+	key, err := w.signingKey(account, w.seed)
+	w.lock.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return signTx(tx, chainID, key)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, decrypting the seed with
+// the given passphrase just for the duration of signing the hash.
+func (w *hdWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.passphraseKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, decrypting the seed with the
+// given passphrase just for the duration of signing the transaction.
+func (w *hdWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.passphraseKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return signTx(tx, chainID, key)
+}
+
+// passphraseKey decrypts the seed with the given passphrase and derives the
+// private key of the requested account from it.
+func (w *hdWallet) passphraseKey(account accounts.Account, passphrase string) (*ecdsa.PrivateKey, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if _, ok := w.paths[account.Address]; !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	seed, err := decryptData(w.file.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(seed)
+	return w.signingKey(account, seed)
+}
+
+// deriveKey generates the private key at the given derivation path of a seed.
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	master, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.privateKey()
+}
+
+// signTx signs a transaction with EIP155 if a chain ID is given, or with the
+// homestead rules otherwise.
+func signTx(tx *types.Transaction, chainID *big.Int, key *ecdsa.PrivateKey) (*types.Transaction, error) {
+	if chainID != nil {
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, key)
+}
+
+// zeroBytes zeroes a byte slice in memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
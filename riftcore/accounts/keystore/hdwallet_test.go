@@ -0,0 +1,229 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/crypto"
+)
+
+// Tests that mnemonics are encoded and converted to seeds according to the test
+// vectors of the BIP-39 reference implementation.
+func TestMnemonicVectors(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"80808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+	for i, tt := range tests {
+		entropy, _ := hex.DecodeString(tt.entropy)
+		if mnemonic := entropyToMnemonic(entropy); mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		decoded, err := mnemonicToEntropy(tt.mnemonic)
+		if err != nil {
+			t.Errorf("test %d: failed to decode mnemonic: %v", i, err)
+		} else if !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x", i, decoded, entropy)
+		}
+		seed, err := mnemonicToSeed(tt.mnemonic, "TREZOR")
+		if err != nil {
+			t.Errorf("test %d: failed to generate seed: %v", i, err)
+		} else if hex.EncodeToString(seed) != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %x, want %s", i, seed, tt.seed)
+		}
+	}
+}
+
+// Tests that invalid mnemonics are rejected and fresh ones round trip.
+func TestMnemonicValidation(t *testing.T) {
+	invalid := []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon riftcore",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	}
+	for i, mnemonic := range invalid {
+		if _, err := mnemonicToSeed(mnemonic, ""); err == nil {
+			t.Errorf("test %d: invalid mnemonic accepted", i)
+		}
+	}
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := NewMnemonic(bits)
+		if err != nil {
+			t.Fatalf("failed to generate %d bit mnemonic: %v", bits, err)
+		}
+		if entropy, err := mnemonicToEntropy(mnemonic); err != nil || len(entropy) != bits/8 {
+			t.Errorf("%d bit mnemonic: failed to decode: %v", bits, err)
+		}
+	}
+	if _, err := NewMnemonic(100); err == nil {
+		t.Errorf("invalid entropy size accepted")
+	}
+}
+
+// Tests that keys are derived according to the test vectors of the BIP-32 spec.
+func TestHDKeyDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := newMasterKey(seed)
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path  accounts.DerivationPath
+		key   string
+		chain string
+	}{
+		{
+			accounts.DerivationPath{},
+			"e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			"873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+		},
+		{
+			accounts.DerivationPath{hardenedOffset},
+			"edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+			"47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141",
+		},
+		{
+			accounts.DerivationPath{hardenedOffset, 1},
+			"3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+			"2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19",
+		},
+	}
+	for i, tt := range tests {
+		key, err := master.derive(tt.path)
+		if err != nil {
+			t.Errorf("test %d: failed to derive key: %v", i, err)
+			continue
+		}
+		if hex.EncodeToString(key.key) != tt.key {
+			t.Errorf("test %d: key mismatch: have %x, want %s", i, key.key, tt.key)
+		}
+		if hex.EncodeToString(key.chain) != tt.chain {
+			t.Errorf("test %d: chain code mismatch: have %x, want %s", i, key.chain, tt.chain)
+		}
+	}
+}
+
+// Tests that an imported mnemonic derives the well known accounts, can be locked
+// and unlocked, signs transactions and persists pinned accounts.
+func TestHDWallet(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	wallet, err := ks.ImportMnemonic(mnemonic, "foo")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if _, err := ks.ImportMnemonic(mnemonic, "bar"); err == nil {
+		t.Fatalf("duplicate mnemonic import succeeded")
+	}
+	// The account at the base derivation path should be pinned and visible while locked
+	accs := wallet.Accounts()
+	if len(accs) != 1 || !strings.HasSuffix(accs[0].URL.Path, accounts.DefaultBaseDerivationPath.String()) {
+		t.Fatalf("pinned accounts mismatch: have %v", accs)
+	}
+	first := accs[0]
+	if wallets := ks.Wallets(); len(wallets) != 1 || wallets[0] != wallet {
+		t.Fatalf("keystore wallets mismatch: have %v", wallets)
+	}
+	path := append(accounts.DerivationPath{}, accounts.DefaultBaseDerivationPath...)
+	path[len(path)-1] = 1
+
+	if _, err := wallet.Derive(path, true); err != ErrLocked {
+		t.Fatalf("locked derivation error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+	if _, err := wallet.SignTx(first, tx, big.NewInt(1)); err != ErrLocked {
+		t.Fatalf("locked signing error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	// Unlock the wallet and pin a second account
+	if err := wallet.Open("bar"); err != ErrDecrypt {
+		t.Fatalf("invalid passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	if acc, err := wallet.Derive(accounts.DefaultBaseDerivationPath, false); err != nil || acc != first {
+		t.Fatalf("base account mismatch: have %v, want %v (err %v)", acc, first, err)
+	}
+	bip44, _ := accounts.ParseDerivationPath("m/44'/60'/0'/0/0")
+	if acc, err := wallet.Derive(bip44, false); err != nil || acc.Address != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Fatalf("BIP-44 account mismatch: have %x (err %v)", acc.Address, err)
+	}
+	second, err := wallet.Derive(path, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	signed, err := wallet.SignTx(second, tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), signed); err != nil || from != second.Address {
+		t.Fatalf("signer mismatch: have %x, want %x (err %v)", from, second.Address, err)
+	}
+	// Reload the keystore and ensure the pinned accounts survived
+	wallet.Close()
+
+	ks = NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	wallets := ks.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("reloaded wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	if accs := wallets[0].Accounts(); len(accs) != 2 || accs[0] != first || accs[1] != second {
+		t.Fatalf("reloaded accounts mismatch: have %v", accs)
+	}
+	hash := crypto.Keccak256([]byte("hello"))
+	sig, err := wallets[0].SignHashWithPassphrase(second, "foo", hash)
+	if err != nil {
+		t.Fatalf("failed to sign with passphrase: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != second.Address {
+		t.Fatalf("passphrase signature mismatch (err %v)", err)
+	}
+}
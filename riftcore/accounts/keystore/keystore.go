@@ -25,11 +25,13 @@ import (
 	crand "crypto/rand"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -38,6 +40,8 @@ import (
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/crypto"
 	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/pborman/uuid"
 )
 
 var (
@@ -63,6 +67,7 @@ type KeyStore struct {
 	unlocked map[common.Address]*unlocked // Currently unlocked account (decrypted private keys)
 
 	wallets     []accounts.Wallet       // Wallet wrappers around the individual key files
+	hdwallets   []*hdWallet             // Hierarchical deterministic wallets backed by encrypted seeds
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
@@ -113,10 +118,37 @@ func (ks *KeyStore) init(keydir string) {
 	for i := 0; i < len(accs); i++ {
 		ks.wallets[i] = &keystoreWallet{account: accs[i], keystore: ks}
 	}
+	// Load any hierarchical deterministic wallets from the seed directory
+	ks.hdwallets = loadHDWallets(ks.storage.JoinPath(hdWalletDir))
+}
+
+// loadHDWallets loads all the HD wallet seed files from the given directory.
+func loadHDWallets(dir string) []*hdWallet {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to read HD wallet directory", "dir", dir, "err", err)
+		}
+		return nil
+	}
+	var wallets []*hdWallet
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		wallet, err := loadHDWallet(path)
+		if err != nil {
+			log.Warn("Failed to load HD wallet", "path", path, "err", err)
+			continue
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets
 }
 
 // Wallets implements accounts.Backend, returning all single-key wallets from the
-// keystore directory.
+// keystore directory along with the hierarchical deterministic ones.
 func (ks *KeyStore) Wallets() []accounts.Wallet {
 	// Make sure the list of wallets is in sync with the account cache
 	ks.refreshWallets()
@@ -124,11 +156,21 @@ func (ks *KeyStore) Wallets() []accounts.Wallet {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
-	cpy := make([]accounts.Wallet, len(ks.wallets))
+	cpy := make([]accounts.Wallet, len(ks.wallets), len(ks.wallets)+len(ks.hdwallets))
 	copy(cpy, ks.wallets)
+	for _, wallet := range ks.hdwallets {
+		cpy = append(cpy, wallet)
+	}
+	sort.Sort(walletsByURL(cpy))
 	return cpy
 }
 
+type walletsByURL []accounts.Wallet
+
+func (s walletsByURL) Len() int           { return len(s) }
+func (s walletsByURL) Less(i, j int) bool { return s[i].URL().Cmp(s[j].URL()) < 0 }
+func (s walletsByURL) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 // refreshWallets retrieves the current account list and based on that does any
 // necessary wallet refreshes.
 func (ks *KeyStore) refreshWallets() {
@@ -423,13 +465,17 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
+	N, P := ks.scryptParams()
+	return EncryptKey(key, newPassphrase, N, P)
+}
+
+// scryptParams returns the scrypt parameters of the keystore, falling back to
+// the standard ones for plaintext keystores.
+func (ks *KeyStore) scryptParams() (int, int) {
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.scryptN, store.scryptP
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return StandardScryptN, StandardScryptP
 }
 
 // Import stores the given encrypted JSON key into the key directory.
@@ -484,6 +530,62 @@ func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (account
 	return a, nil
 }
 
+// ImportMnemonic converts the given BIP-39 mnemonic into a hierarchical
+// deterministic wallet, storing its seed encrypted with the passphrase into the
+// keystore. The account at the default base derivation path is pinned into the
+// wallet, further ones being derived on demand once the wallet is opened.
+func (ks *KeyStore) ImportMnemonic(mnemonic, passphrase string) (accounts.Wallet, error) {
+	seed, err := mnemonicToSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(seed)
+
+	key, err := deriveKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	ks.mu.Lock()
+	for _, wallet := range ks.hdwallets {
+		if wallet.Contains(accounts.Account{Address: addr}) {
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("HD wallet already exists")
+		}
+	}
+	ks.mu.Unlock()
+
+	// Encrypt the seed and store it into the keystore
+	N, P := ks.scryptParams()
+	cryptoStruct, err := encryptData(seed, []byte(passphrase), N, P)
+	if err != nil {
+		return nil, err
+	}
+	wallet := &hdWallet{
+		url: accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(ks.storage.JoinPath(hdWalletDir), keyFileName(addr))},
+		file: hdWalletJSON{
+			Version:  hdWalletVersion,
+			Id:       uuid.NewRandom().String(),
+			Crypto:   cryptoStruct,
+			Accounts: []hdAccountJSON{{Address: addr, Path: accounts.DefaultBaseDerivationPath.String()}},
+		},
+	}
+	if err := wallet.store(); err != nil {
+		return nil, err
+	}
+	if err := wallet.reset(); err != nil {
+		return nil, err
+	}
+	ks.mu.Lock()
+	ks.hdwallets = append(ks.hdwallets, wallet)
+	ks.mu.Unlock()
+
+	ks.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Arrive: true})
+	return wallet, nil
+}
+
 // zeroKey zeroes a private key in memory.
 func zeroKey(k *ecdsa.PrivateKey) {
 	b := k.D.Bits()
@@ -0,0 +1,117 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/cryptorift/riftcore/common/math"
+	"github.com/cryptorift/riftcore/crypto/randentropy"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	// ErrInvalidMnemonic is returned if a mnemonic phrase contains unknown words
+	// or its embedded checksum doesn't match the encoded entropy.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// mnemonicIndices maps the words of the BIP-39 word list to their positions.
+	mnemonicIndices = make(map[string]int, len(mnemonicWordlist))
+)
+
+func init() {
+	for i, word := range mnemonicWordlist {
+		mnemonicIndices[word] = i
+	}
+}
+
+// NewMnemonic generates a random BIP-39 mnemonic phrase encoding the requested
+// number of entropy bits. The entropy must be a multiple of 32 bits, between 128
+// and 256 bits inclusive (i.e. 12 to 24 words).
+func NewMnemonic(bits int) (string, error) {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("invalid mnemonic entropy size: %d bits", bits)
+	}
+	return entropyToMnemonic(randentropy.GetEntropyCSPRNG(bits / 8)), nil
+}
+
+// entropyToMnemonic encodes a blob of entropy into a BIP-39 mnemonic phrase,
+// appending the leading bits of its SHA256 hash as checksum.
+func entropyToMnemonic(entropy []byte) string {
+	hash := sha256.Sum256(entropy)
+
+	// Concatenate the entropy and checksum bits into a single big integer
+	checksum := len(entropy) * 8 / 32
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksum))
+	data.Or(data, big.NewInt(int64(hash[0]>>uint(8-checksum))))
+
+	// Split the concatenated bits into 11 bit word indices, last word first
+	words := make([]string, (len(entropy)*8+checksum)/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = mnemonicWordlist[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " ")
+}
+
+// mnemonicToEntropy decodes a BIP-39 mnemonic phrase into its entropy, verifying
+// the embedded checksum.
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("%v: invalid word count %d", ErrInvalidMnemonic, len(words))
+	}
+	// Concatenate the 11 bit word indices into a single big integer
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := mnemonicIndices[word]
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	// Split off the checksum and ensure it matches the entropy
+	checksum := len(words) * 11 / 33
+	sum := new(big.Int).And(data, big.NewInt(int64(1)<<uint(checksum)-1)).Int64()
+	data.Rsh(data, uint(checksum))
+
+	entropy := math.PaddedBigBytes(data, (len(words)*11-checksum)/8)
+
+	if hash := sha256.Sum256(entropy); int64(hash[0]>>uint(8-checksum)) != sum {
+		return nil, fmt.Errorf("%v: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
+
+// mnemonicToSeed validates a BIP-39 mnemonic phrase and converts it, along with
+// an optional extension password, into the 64 byte seed used to generate the
+// master key of a hierarchical deterministic wallet.
+func mnemonicToSeed(mnemonic, password string) ([]byte, error) {
+	if _, err := mnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+password), 2048, 64, sha512.New), nil
+}
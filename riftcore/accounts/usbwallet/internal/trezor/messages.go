@@ -0,0 +1,414 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package trezor
+
+// Initialize resets the device and requests its features.
+type Initialize struct{}
+
+func (m *Initialize) Marshal() []byte             { return nil }
+func (m *Initialize) Unmarshal(blob []byte) error { return decode(blob, func(int, uint64, []byte) {}) }
+
+// Ping tests the device connection, optionally requesting user authentication.
+type Ping struct {
+	Message              string // Message to send back in the Success reply
+	ButtonProtection     bool   // Ask for button press before replying
+	PinProtection        bool   // Ask for PIN entry if the device is locked
+	PassphraseProtection bool   // Ask for passphrase if not cached yet
+}
+
+func (m *Ping) Marshal() []byte {
+	var e encoder
+	e.string(1, m.Message)
+	e.bool(2, m.ButtonProtection)
+	e.bool(3, m.PinProtection)
+	e.bool(4, m.PassphraseProtection)
+	return e
+}
+
+func (m *Ping) Unmarshal(blob []byte) error {
+	*m = Ping{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.Message = string(data)
+		case 2:
+			m.ButtonProtection = num != 0
+		case 3:
+			m.PinProtection = num != 0
+		case 4:
+			m.PassphraseProtection = num != 0
+		}
+	})
+}
+
+// Success is the generic positive reply to a request.
+type Success struct {
+	Message string // Optional human readable message
+}
+
+func (m *Success) Marshal() []byte {
+	var e encoder
+	e.string(1, m.Message)
+	return e
+}
+
+func (m *Success) Unmarshal(blob []byte) error {
+	*m = Success{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Message = string(data)
+		}
+	})
+}
+
+// Failure is the generic negative reply to a request.
+type Failure struct {
+	Code    uint32 // Failure code from the FailureType enumeration
+	Message string // Human readable failure reason
+}
+
+func (m *Failure) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Code))
+	e.string(2, m.Message)
+	return e
+}
+
+func (m *Failure) Unmarshal(blob []byte) error {
+	*m = Failure{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.Code = uint32(num)
+		case 2:
+			m.Message = string(data)
+		}
+	})
+}
+
+// Features is the reply to an Initialize request, describing the device.
+type Features struct {
+	Vendor               string // Name of the manufacturer
+	MajorVersion         uint32 // Major version of the firmware
+	MinorVersion         uint32 // Minor version of the firmware
+	PatchVersion         uint32 // Patch version of the firmware
+	BootloaderMode       bool   // Whether the device is in bootloader mode
+	DeviceID             string // Unique identifier of the device
+	PinProtection        bool   // Whether the device is protected by a PIN
+	PassphraseProtection bool   // Whether the device is protected by a passphrase
+	Label                string // User assigned name of the device
+	Initialized          bool   // Whether the device has a seed loaded
+}
+
+func (m *Features) Marshal() []byte {
+	var e encoder
+	e.string(1, m.Vendor)
+	e.uint(2, uint64(m.MajorVersion))
+	e.uint(3, uint64(m.MinorVersion))
+	e.uint(4, uint64(m.PatchVersion))
+	e.bool(5, m.BootloaderMode)
+	e.string(6, m.DeviceID)
+	e.bool(7, m.PinProtection)
+	e.bool(8, m.PassphraseProtection)
+	e.string(10, m.Label)
+	e.bool(12, m.Initialized)
+	return e
+}
+
+func (m *Features) Unmarshal(blob []byte) error {
+	*m = Features{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.Vendor = string(data)
+		case 2:
+			m.MajorVersion = uint32(num)
+		case 3:
+			m.MinorVersion = uint32(num)
+		case 4:
+			m.PatchVersion = uint32(num)
+		case 5:
+			m.BootloaderMode = num != 0
+		case 6:
+			m.DeviceID = string(data)
+		case 7:
+			m.PinProtection = num != 0
+		case 8:
+			m.PassphraseProtection = num != 0
+		case 10:
+			m.Label = string(data)
+		case 12:
+			m.Initialized = num != 0
+		}
+	})
+}
+
+// PinMatrixRequest asks the host to enter the PIN using the matrix displayed on
+// the device screen.
+type PinMatrixRequest struct {
+	Type uint32 // Kind of PIN requested (current, new first, new second)
+}
+
+func (m *PinMatrixRequest) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Type))
+	return e
+}
+
+func (m *PinMatrixRequest) Unmarshal(blob []byte) error {
+	*m = PinMatrixRequest{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Type = uint32(num)
+		}
+	})
+}
+
+// PinMatrixAck sends the PIN, encoded as positions on the scrambled matrix
+// displayed on the device.
+type PinMatrixAck struct {
+	Pin string // Matrix positions of the PIN digits
+}
+
+func (m *PinMatrixAck) Marshal() []byte {
+	var e encoder
+	e.string(1, m.Pin)
+	return e
+}
+
+func (m *PinMatrixAck) Unmarshal(blob []byte) error {
+	*m = PinMatrixAck{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Pin = string(data)
+		}
+	})
+}
+
+// ButtonRequest signals that the device is waiting for a user confirmation.
+type ButtonRequest struct {
+	Code uint32 // Kind of confirmation requested
+}
+
+func (m *ButtonRequest) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Code))
+	return e
+}
+
+func (m *ButtonRequest) Unmarshal(blob []byte) error {
+	*m = ButtonRequest{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Code = uint32(num)
+		}
+	})
+}
+
+// ButtonAck acknowledges a button request, letting the device wait for the user.
+type ButtonAck struct{}
+
+func (m *ButtonAck) Marshal() []byte             { return nil }
+func (m *ButtonAck) Unmarshal(blob []byte) error { return decode(blob, func(int, uint64, []byte) {}) }
+
+// PassphraseRequest asks the host for the passphrase protecting the wallet.
+type PassphraseRequest struct{}
+
+func (m *PassphraseRequest) Marshal() []byte { return nil }
+func (m *PassphraseRequest) Unmarshal(blob []byte) error {
+	return decode(blob, func(int, uint64, []byte) {})
+}
+
+// PassphraseAck sends the passphrase protecting the wallet.
+type PassphraseAck struct {
+	Passphrase string // Passphrase to derive the wallet seed with
+}
+
+func (m *PassphraseAck) Marshal() []byte {
+	var e encoder
+	e.string(1, m.Passphrase)
+	return e
+}
+
+func (m *PassphraseAck) Unmarshal(blob []byte) error {
+	*m = PassphraseAck{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Passphrase = string(data)
+		}
+	})
+}
+
+// RiftGetAddress requests the CryptoRift address at a derivation path.
+type RiftGetAddress struct {
+	AddressN    []uint32 // BIP-32 derivation path of the account
+	ShowDisplay bool     // Whether to display the address on the device
+}
+
+func (m *RiftGetAddress) Marshal() []byte {
+	var e encoder
+	for _, index := range m.AddressN {
+		e.key(1, wireVarint)
+		e.varint(uint64(index))
+	}
+	e.bool(2, m.ShowDisplay)
+	return e
+}
+
+func (m *RiftGetAddress) Unmarshal(blob []byte) error {
+	*m = RiftGetAddress{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.AddressN = append(m.AddressN, uint32(num))
+		case 2:
+			m.ShowDisplay = num != 0
+		}
+	})
+}
+
+// RiftAddress is the reply to a RiftGetAddress request.
+type RiftAddress struct {
+	Address []byte // 20 byte CryptoRift address
+}
+
+func (m *RiftAddress) Marshal() []byte {
+	var e encoder
+	e.bytes(1, m.Address)
+	return e
+}
+
+func (m *RiftAddress) Unmarshal(blob []byte) error {
+	*m = RiftAddress{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.Address = append([]byte{}, data...)
+		}
+	})
+}
+
+// RiftSignTx requests the signing of a transaction. Numeric fields are big
+// endian encoded without leading zeroes. If the payload doesn't fit into the
+// initial chunk, the device requests the rest via RiftTxRequest messages.
+type RiftSignTx struct {
+	AddressN         []uint32 // BIP-32 derivation path of the signing account
+	Nonce            []byte   // Transaction nonce
+	GasPrice         []byte   // Price of a unit of gas
+	GasLimit         []byte   // Maximum gas allowance of the transaction
+	To               []byte   // Recipient address, empty for contract creation
+	Value            []byte   // Amount of wei to transfer
+	DataInitialChunk []byte   // First chunk of the transaction payload (max 1024 bytes)
+	DataLength       uint32   // Total length of the transaction payload
+	ChainID          uint32   // EIP-155 chain id, zero for Homestead signing
+}
+
+func (m *RiftSignTx) Marshal() []byte {
+	var e encoder
+	for _, index := range m.AddressN {
+		e.key(1, wireVarint)
+		e.varint(uint64(index))
+	}
+	e.bytes(2, m.Nonce)
+	e.bytes(3, m.GasPrice)
+	e.bytes(4, m.GasLimit)
+	e.bytes(5, m.To)
+	e.bytes(6, m.Value)
+	e.bytes(7, m.DataInitialChunk)
+	e.uint(8, uint64(m.DataLength))
+	e.uint(9, uint64(m.ChainID))
+	return e
+}
+
+func (m *RiftSignTx) Unmarshal(blob []byte) error {
+	*m = RiftSignTx{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.AddressN = append(m.AddressN, uint32(num))
+		case 2:
+			m.Nonce = append([]byte{}, data...)
+		case 3:
+			m.GasPrice = append([]byte{}, data...)
+		case 4:
+			m.GasLimit = append([]byte{}, data...)
+		case 5:
+			m.To = append([]byte{}, data...)
+		case 6:
+			m.Value = append([]byte{}, data...)
+		case 7:
+			m.DataInitialChunk = append([]byte{}, data...)
+		case 8:
+			m.DataLength = uint32(num)
+		case 9:
+			m.ChainID = uint32(num)
+		}
+	})
+}
+
+// RiftTxRequest is the reply to a signing request, either asking for the next
+// chunk of the transaction payload, or containing the final signature.
+type RiftTxRequest struct {
+	DataLength uint32 // Number of payload bytes requested, zero if done
+	SignatureV uint32 // Recovery id of the signature, with EIP-155 applied
+	SignatureR []byte // R component of the signature
+	SignatureS []byte // S component of the signature
+}
+
+func (m *RiftTxRequest) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.DataLength))
+	e.uint(2, uint64(m.SignatureV))
+	e.bytes(3, m.SignatureR)
+	e.bytes(4, m.SignatureS)
+	return e
+}
+
+func (m *RiftTxRequest) Unmarshal(blob []byte) error {
+	*m = RiftTxRequest{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		switch field {
+		case 1:
+			m.DataLength = uint32(num)
+		case 2:
+			m.SignatureV = uint32(num)
+		case 3:
+			m.SignatureR = append([]byte{}, data...)
+		case 4:
+			m.SignatureS = append([]byte{}, data...)
+		}
+	})
+}
+
+// RiftTxAck sends the next chunk of the transaction payload.
+type RiftTxAck struct {
+	DataChunk []byte // Next chunk of the transaction payload
+}
+
+func (m *RiftTxAck) Marshal() []byte {
+	var e encoder
+	e.bytes(1, m.DataChunk)
+	return e
+}
+
+func (m *RiftTxAck) Unmarshal(blob []byte) error {
+	*m = RiftTxAck{}
+	return decode(blob, func(field int, num uint64, data []byte) {
+		if field == 1 {
+			m.DataChunk = append([]byte{}, data...)
+		}
+	})
+}
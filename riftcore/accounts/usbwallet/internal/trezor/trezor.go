@@ -0,0 +1,260 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package trezor contains the wire protocol messages used to communicate with
+// Trezor hardware wallets, along with a minimal protocol buffer codec needed to
+// serialize them. Only the messages required for account derivation and
+// transaction signing are implemented.
+//
+// The message definitions can be found in the Trezor common GitHub repo:
+// https://github.com/trezor/trezor-common/blob/master/protob/messages.proto
+package trezor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Message is a protocol buffer message that can be exchanged with a Trezor.
+type Message interface {
+	// Marshal serializes the message into its protocol buffer wire encoding.
+	Marshal() []byte
+
+	// Unmarshal parses a protocol buffer wire encoded blob into the message,
+	// resetting any previously set fields.
+	Unmarshal(blob []byte) error
+}
+
+// Message type numbers of the protocol buffer messages, as defined by the
+// MessageType enumeration of the Trezor protocol.
+const (
+	TypeInitialize        uint16 = 0
+	TypePing              uint16 = 1
+	TypeSuccess           uint16 = 2
+	TypeFailure           uint16 = 3
+	TypeFeatures          uint16 = 17
+	TypePinMatrixRequest  uint16 = 18
+	TypePinMatrixAck      uint16 = 19
+	TypeButtonRequest     uint16 = 26
+	TypeButtonAck         uint16 = 27
+	TypePassphraseRequest uint16 = 41
+	TypePassphraseAck     uint16 = 42
+	TypeRiftGetAddress    uint16 = 56
+	TypeRiftAddress       uint16 = 57
+	TypeRiftSignTx        uint16 = 58
+	TypeRiftTxRequest     uint16 = 59
+	TypeRiftTxAck         uint16 = 60
+)
+
+// typeNames maps the message type numbers to friendly names for error reporting.
+var typeNames = map[uint16]string{
+	TypeInitialize:        "Initialize",
+	TypePing:              "Ping",
+	TypeSuccess:           "Success",
+	TypeFailure:           "Failure",
+	TypeFeatures:          "Features",
+	TypePinMatrixRequest:  "PinMatrixRequest",
+	TypePinMatrixAck:      "PinMatrixAck",
+	TypeButtonRequest:     "ButtonRequest",
+	TypeButtonAck:         "ButtonAck",
+	TypePassphraseRequest: "PassphraseRequest",
+	TypePassphraseAck:     "PassphraseAck",
+	TypeRiftGetAddress:    "RiftGetAddress",
+	TypeRiftAddress:       "RiftAddress",
+	TypeRiftSignTx:        "RiftSignTx",
+	TypeRiftTxRequest:     "RiftTxRequest",
+	TypeRiftTxAck:         "RiftTxAck",
+}
+
+// Type returns the protocol buffer type number of a specific message. If the
+// message is nil or unknown, this method panics!
+func Type(msg Message) uint16 {
+	switch msg.(type) {
+	case *Initialize:
+		return TypeInitialize
+	case *Ping:
+		return TypePing
+	case *Success:
+		return TypeSuccess
+	case *Failure:
+		return TypeFailure
+	case *Features:
+		return TypeFeatures
+	case *PinMatrixRequest:
+		return TypePinMatrixRequest
+	case *PinMatrixAck:
+		return TypePinMatrixAck
+	case *ButtonRequest:
+		return TypeButtonRequest
+	case *ButtonAck:
+		return TypeButtonAck
+	case *PassphraseRequest:
+		return TypePassphraseRequest
+	case *PassphraseAck:
+		return TypePassphraseAck
+	case *RiftGetAddress:
+		return TypeRiftGetAddress
+	case *RiftAddress:
+		return TypeRiftAddress
+	case *RiftSignTx:
+		return TypeRiftSignTx
+	case *RiftTxRequest:
+		return TypeRiftTxRequest
+	case *RiftTxAck:
+		return TypeRiftTxAck
+	}
+	panic(fmt.Sprintf("unknown trezor message %T", msg))
+}
+
+// Name returns the friendly message type name of a specific protocol buffer
+// type number.
+func Name(kind uint16) string {
+	if name, ok := typeNames[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", kind)
+}
+
+// Protocol buffer wire types used by the Trezor messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var (
+	// errTruncated is returned if a protocol buffer blob ends mid-field.
+	errTruncated = errors.New("truncated protobuf message")
+
+	// errOverflow is returned if a protocol buffer varint doesn't fit 64 bits.
+	errOverflow = errors.New("protobuf varint overflow")
+)
+
+// encoder accumulates the protocol buffer wire encoding of a message. Fields
+// with zero values are omitted, which the protocol interprets as their default.
+type encoder []byte
+
+// key appends the tag of a field with the given number and wire type.
+func (e *encoder) key(field int, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+// varint appends a raw base 128 varint.
+func (e *encoder) varint(v uint64) {
+	for v >= 0x80 {
+		*e = append(*e, byte(v)|0x80)
+		v >>= 7
+	}
+	*e = append(*e, byte(v))
+}
+
+// uint appends an unsigned integer field, omitting it if zero.
+func (e *encoder) uint(field int, v uint64) {
+	if v != 0 {
+		e.key(field, wireVarint)
+		e.varint(v)
+	}
+}
+
+// bool appends a boolean field, omitting it if false.
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+// bytes appends a length prefixed byte field, omitting it if empty.
+func (e *encoder) bytes(field int, v []byte) {
+	if len(v) != 0 {
+		e.key(field, wireBytes)
+		e.varint(uint64(len(v)))
+		*e = append(*e, v...)
+	}
+}
+
+// string appends a length prefixed string field, omitting it if empty.
+func (e *encoder) string(field int, v string) {
+	e.bytes(field, []byte(v))
+}
+
+// uvarint decodes a base 128 varint from the start of a blob, returning the
+// value and the number of bytes consumed.
+func uvarint(blob []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(blob); i++ {
+		if i == 10 {
+			return 0, 0, errOverflow
+		}
+		v |= uint64(blob[i]&0x7f) << (7 * uint(i))
+		if blob[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errTruncated
+}
+
+// decode iterates over the fields of a protocol buffer encoded blob, invoking
+// the callback with the numeric value of varint fields or the contents of
+// length prefixed fields. Fixed size fields are not used by any of the Trezor
+// messages and are skipped.
+func decode(blob []byte, field func(field int, num uint64, data []byte)) error {
+	for len(blob) > 0 {
+		key, n, err := uvarint(blob)
+		if err != nil {
+			return err
+		}
+		blob = blob[n:]
+
+		switch int(key & 0x07) {
+		case wireVarint:
+			num, n, err := uvarint(blob)
+			if err != nil {
+				return err
+			}
+			blob = blob[n:]
+			field(int(key>>3), num, nil)
+
+		case wireBytes:
+			size, n, err := uvarint(blob)
+			if err != nil {
+				return err
+			}
+			blob = blob[n:]
+			if uint64(len(blob)) < size {
+				return errTruncated
+			}
+			field(int(key>>3), 0, blob[:size])
+			blob = blob[size:]
+
+		case wireFixed64:
+			if len(blob) < 8 {
+				return errTruncated
+			}
+			blob = blob[8:]
+
+		case wireFixed32:
+			if len(blob) < 4 {
+				return errTruncated
+			}
+			blob = blob[4:]
+
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", key&0x07)
+		}
+	}
+	return nil
+}
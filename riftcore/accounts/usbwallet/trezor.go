@@ -0,0 +1,391 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the implementation for interacting with the Trezor hardware
+// wallets. The wire protocol spec can be found on the SatoshiLabs website:
+// https://doc.satoshilabs.com/trezor-tech/api-protobuf.html
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/usbwallet/internal/trezor"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/log"
+)
+
+// ErrTrezorPINNeeded is returned if opening the trezor requires a PIN code. In
+// this case, the calling application should display a pinpad and send back the
+// encoded passphrase.
+var ErrTrezorPINNeeded = errors.New("trezor: pin needed")
+
+// ErrTrezorPassphraseNeeded is returned if opening the trezor requires the
+// passphrase protecting the wallet. In this case, the calling application should
+// prompt for the passphrase and send it back as is.
+var ErrTrezorPassphraseNeeded = errors.New("trezor: passphrase needed")
+
+// errTrezorReplyInvalidHeader is the error message returned by a Trezor data exchange
+// if the device replies with a mismatching header. This usually means the device
+// is in browser mode.
+var errTrezorReplyInvalidHeader = errors.New("trezor: invalid reply header")
+
+// errTrezorReplyTooLarge is the error message returned by a Trezor data exchange
+// if the device announces a reply larger than any message it may legitimately
+// send, which would otherwise make the driver allocate arbitrary amounts of memory.
+var errTrezorReplyTooLarge = errors.New("trezor: reply too large")
+
+// trezorMaxReplySize is the maximum length of a reply message accepted from a
+// Trezor device, well above the largest message of the protocol.
+const trezorMaxReplySize = 64 * 1024
+
+// trezorDriver implements the communication with a Trezor hardware wallet.
+type trezorDriver struct {
+	device         io.ReadWriter // USB device connection to communicate through
+	version        [3]uint32     // Current version of the Trezor firmware
+	label          string        // Current textual label of the Trezor device
+	pinwait        bool          // Flags whether the device is waiting for PIN entry
+	passphrasewait bool          // Flags whether the device is waiting for passphrase entry
+	failure        error         // Any failure that would make the device unusable
+	log            log.Logger    // Contextual logger to tag the trezor with its id
+}
+
+// newTrezorDriver creates a new instance of a Trezor USB protocol driver.
+func newTrezorDriver(logger log.Logger) driver {
+	return &trezorDriver{
+		log: logger,
+	}
+}
+
+// Status implements usbwallet.driver, returning whether the Trezor is online or
+// waiting for the user to unlock it.
+func (w *trezorDriver) Status() (string, error) {
+	if w.failure != nil {
+		return fmt.Sprintf("Failed: %v", w.failure), w.failure
+	}
+	if w.pinwait {
+		return fmt.Sprintf("Trezor v%d.%d.%d '%s' waiting for PIN", w.version[0], w.version[1], w.version[2], w.label), w.failure
+	}
+	if w.passphrasewait {
+		return fmt.Sprintf("Trezor v%d.%d.%d '%s' waiting for passphrase", w.version[0], w.version[1], w.version[2], w.label), w.failure
+	}
+	return fmt.Sprintf("Trezor v%d.%d.%d '%s' online", w.version[0], w.version[1], w.version[2], w.label), w.failure
+}
+
+// Open implements usbwallet.driver, attempting to initialize the connection to
+// the Trezor hardware wallet. Initializing the Trezor is a multi or two phase
+// operation:
+//  * The first phase is to initialize the connection and read the wallet's
+//    features. This phase is invoked if the provided passphrase is empty. The
+//    device will display the pinpad as a result and will return an appropriate
+//    error to notify the user that a second open phase is needed.
+//  * The second phase is to unlock access to the Trezor, which is done by the
+//    user actually providing a passphrase mapping a keyboard keypad to the pin
+//    number of the user (shuffled according to the pinpad displayed).
+//  * If the wallet is protected by a passphrase, a third phase is needed where
+//    the user provides the passphrase itself, which may also be empty.
+func (w *trezorDriver) Open(device io.ReadWriter, passphrase string) error {
+	w.device, w.failure = device, nil
+
+	// If phase 1 is requested, init the connection and wait for user callback
+	if passphrase == "" && !w.passphrasewait {
+		// If we're already waiting for a PIN entry, insta-return
+		if w.pinwait {
+			return ErrTrezorPINNeeded
+		}
+		// Initialize a connection to the device
+		features := new(trezor.Features)
+		if _, err := w.trezorExchange(&trezor.Initialize{}, features); err != nil {
+			return err
+		}
+		w.version = [3]uint32{features.MajorVersion, features.MinorVersion, features.PatchVersion}
+		w.label = features.Label
+
+		// Do a manual ping, forcing the device to ask for its PIN and passphrase
+		res, err := w.trezorExchange(&trezor.Ping{PinProtection: true, PassphraseProtection: true}, new(trezor.PinMatrixRequest), new(trezor.PassphraseRequest), new(trezor.Success))
+		if err != nil {
+			return err
+		}
+		// Only return the PIN or passphrase request if the device wasn't unlocked until now
+		switch res {
+		case 0:
+			w.pinwait = true
+			return ErrTrezorPINNeeded
+		case 1:
+			w.passphrasewait = true
+			return ErrTrezorPassphraseNeeded
+		}
+		return nil
+	}
+	// Phase 2 requested with actual PIN entry
+	if w.pinwait {
+		w.pinwait = false
+
+		res, err := w.trezorExchange(&trezor.PinMatrixAck{Pin: passphrase}, new(trezor.Success), new(trezor.PassphraseRequest))
+		if err != nil {
+			w.failure = err
+			return err
+		}
+		if res == 1 {
+			w.passphrasewait = true
+			return ErrTrezorPassphraseNeeded
+		}
+		return nil
+	}
+	// Phase 3 requested with the wallet passphrase
+	if w.passphrasewait {
+		w.passphrasewait = false
+
+		if _, err := w.trezorExchange(&trezor.PassphraseAck{Passphrase: passphrase}, new(trezor.Success)); err != nil {
+			w.failure = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements usbwallet.driver, cleaning up and metadata maintained within
+// the Trezor driver.
+func (w *trezorDriver) Close() error {
+	w.device, w.version, w.label, w.pinwait, w.passphrasewait = nil, [3]uint32{}, "", false, false
+	return nil
+}
+
+// Heartbeat implements usbwallet.driver, performing a sanity check against the
+// Trezor to see if it's still online.
+func (w *trezorDriver) Heartbeat() error {
+	if _, err := w.trezorExchange(&trezor.Ping{}, new(trezor.Success)); err != nil {
+		w.failure = err
+		return err
+	}
+	return nil
+}
+
+// Derive implements usbwallet.driver, sending a derivation request to the Trezor
+// and returning the CryptoRift address located on that derivation path.
+func (w *trezorDriver) Derive(path accounts.DerivationPath) (common.Address, error) {
+	return w.trezorDerive(path)
+}
+
+// SignTx implements usbwallet.driver, sending the transaction to the Trezor and
+// waiting for the user to confirm or deny the transaction.
+func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
+	if w.device == nil {
+		return common.Address{}, nil, accounts.ErrWalletClosed
+	}
+	return w.trezorSign(path, tx, chainID)
+}
+
+// trezorDerive sends a derivation request to the Trezor device and returns the
+// CryptoRift address located on that path.
+func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
+	address := new(trezor.RiftAddress)
+	if _, err := w.trezorExchange(&trezor.RiftGetAddress{AddressN: derivationPath}, address); err != nil {
+		return common.Address{}, err
+	}
+	if len(address.Address) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("trezor: invalid address length %d", len(address.Address))
+	}
+	return common.BytesToAddress(address.Address), nil
+}
+
+// trezorSign sends the transaction to the Trezor wallet, and waits for the user
+// to confirm or deny the transaction. Payloads longer than 1024 bytes are sent
+// in chunks, as requested by the device.
+func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
+	// Create the transaction initiation message
+	data := tx.Data()
+
+	request := &trezor.RiftSignTx{
+		AddressN:   derivationPath,
+		Nonce:      new(big.Int).SetUint64(tx.Nonce()).Bytes(),
+		GasPrice:   tx.GasPrice().Bytes(),
+		GasLimit:   tx.Gas().Bytes(),
+		Value:      tx.Value().Bytes(),
+		DataLength: uint32(len(data)),
+	}
+	if to := tx.To(); to != nil {
+		request.To = (*to)[:] // Non contract deploy, set recipient explicitly
+	}
+	if len(data) > 1024 { // Send the data chunked if that was requested
+		request.DataInitialChunk, data = data[:1024], data[1024:]
+	} else {
+		request.DataInitialChunk, data = data, nil
+	}
+	if chainID != nil { // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported)
+		if !chainID.IsUint64() || chainID.Uint64() > uint64(^uint32(0)) {
+			return common.Address{}, nil, fmt.Errorf("trezor: chain id %v too large", chainID)
+		}
+		request.ChainID = uint32(chainID.Uint64())
+	}
+	// Send the initiation message and stream content until a signature is returned
+	response := new(trezor.RiftTxRequest)
+	if _, err := w.trezorExchange(request, response); err != nil {
+		return common.Address{}, nil, err
+	}
+	for response.DataLength != 0 {
+		if int(response.DataLength) > len(data) {
+			return common.Address{}, nil, fmt.Errorf("trezor: requested %d payload bytes, only %d left", response.DataLength, len(data))
+		}
+		chunk := data[:response.DataLength]
+		data = data[response.DataLength:]
+
+		if _, err := w.trezorExchange(&trezor.RiftTxAck{DataChunk: chunk}, response); err != nil {
+			return common.Address{}, nil, err
+		}
+	}
+	// Extract the CryptoRift signature and do a sanity validation
+	if len(response.SignatureR) == 0 || len(response.SignatureR) > 32 || len(response.SignatureS) == 0 || len(response.SignatureS) > 32 || response.SignatureV == 0 {
+		return common.Address{}, nil, errors.New("reply lacks signature")
+	}
+	signature := append(common.LeftPadBytes(response.SignatureR, 32), common.LeftPadBytes(response.SignatureS, 32)...)
+
+	// Create the correct signer and signature transform based on the chain ID
+	var signer types.Signer
+	if chainID == nil {
+		signer = new(types.HomesteadSigner)
+		signature = append(signature, byte(response.SignatureV-27))
+	} else {
+		signer = types.NewEIP155Signer(chainID)
+		signature = append(signature, byte(uint64(response.SignatureV)-(chainID.Uint64()*2+35)))
+	}
+	// Inject the final signature into the transaction and sanity check the sender
+	signed, err := tx.WithSignature(signer, signature)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return sender, signed, nil
+}
+
+// trezorExchange performs a data exchange with the Trezor wallet, sending it a
+// message and retrieving the response. If multiple responses are possible, the
+// method will also return the index of the destination object used.
+//
+// The transport header of the first HID report is defined as follows:
+//
+//  Description                       | Length
+//  ----------------------------------+----------
+//  Report ID magic number (0x3f)     | 1 byte
+//  Message magic number ("##")       | 2 bytes
+//  Message type (big endian)         | 2 bytes
+//  Message length (big endian)       | 4 bytes
+//  Protocol buffer encoded message   | arbitrary
+//
+// Subsequent reports carry the report ID followed by the remainder of the
+// message. All reports are padded with zeroes to 64 bytes.
+func (w *trezorDriver) trezorExchange(req trezor.Message, results ...trezor.Message) (int, error) {
+	// Construct the original message payload to chunk up
+	data := req.Marshal()
+
+	payload := make([]byte, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], trezor.Type(req))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	// Stream all the chunks to the device
+	chunk := make([]byte, 64)
+	chunk[0] = 0x3f // Report ID magic number
+
+	for len(payload) > 0 {
+		// Construct the new message to stream, padding with zeroes if needed
+		if len(payload) > 63 {
+			copy(chunk[1:], payload[:63])
+			payload = payload[63:]
+		} else {
+			copy(chunk[1:], payload)
+			copy(chunk[1+len(payload):], make([]byte, 63-len(payload)))
+			payload = nil
+		}
+		// Send over to the device
+		w.log.Trace("Data chunk sent to the Trezor", "chunk", hexutil.Bytes(chunk))
+		if _, err := w.device.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	// Stream the reply back from the wallet in 64 byte chunks
+	var (
+		kind  uint16
+		reply []byte
+	)
+	for {
+		// Read the next chunk from the Trezor wallet
+		if _, err := io.ReadFull(w.device, chunk); err != nil {
+			return 0, err
+		}
+		w.log.Trace("Data chunk received from the Trezor", "chunk", hexutil.Bytes(chunk))
+
+		// Make sure the transport header matches
+		if chunk[0] != 0x3f || (reply == nil && (chunk[1] != 0x23 || chunk[2] != 0x23)) {
+			return 0, errTrezorReplyInvalidHeader
+		}
+		// If it's the first chunk, retrieve the reply message type and total message length
+		var payload []byte
+
+		if reply == nil {
+			length := binary.BigEndian.Uint32(chunk[5:9])
+			if length > trezorMaxReplySize {
+				return 0, errTrezorReplyTooLarge
+			}
+			kind = binary.BigEndian.Uint16(chunk[3:5])
+			reply = make([]byte, 0, int(length))
+			payload = chunk[9:]
+		} else {
+			payload = chunk[1:]
+		}
+		// Append to the reply and stop when filled up
+		if left := cap(reply) - len(reply); left > len(payload) {
+			reply = append(reply, payload...)
+		} else {
+			reply = append(reply, payload[:left]...)
+			break
+		}
+	}
+	// Try to parse the reply into the requested reply message
+	if kind == trezor.TypeFailure {
+		// Trezor returned a failure, extract and return the message
+		failure := new(trezor.Failure)
+		if err := failure.Unmarshal(reply); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("trezor: " + failure.Message)
+	}
+	if kind == trezor.TypeButtonRequest {
+		// Trezor is waiting for user confirmation, ack and wait for the next message
+		return w.trezorExchange(&trezor.ButtonAck{}, results...)
+	}
+	for i, res := range results {
+		if trezor.Type(res) == kind {
+			return i, res.Unmarshal(reply)
+		}
+	}
+	expected := make([]string, len(results))
+	for i, res := range results {
+		expected[i] = trezor.Name(trezor.Type(res))
+	}
+	return 0, fmt.Errorf("trezor: expected reply types %s, got %s", expected, trezor.Name(kind))
+}
@@ -0,0 +1,326 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/usbwallet/internal/trezor"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/log"
+)
+
+// mockTrezor is an emulated Trezor device speaking the HID framed protobuf
+// protocol, protected by a PIN and optionally a passphrase.
+type mockTrezor struct {
+	pin        string // PIN protecting the device
+	passphrase string // Passphrase protecting the wallet, empty if disabled
+	unlocked   bool   // Whether the PIN was already entered
+	authorized bool   // Whether the passphrase was already entered
+
+	request []byte // Partially received request message
+	replies []byte // Queued HID reports of the replies
+
+	signing *trezor.RiftSignTx // Transaction currently being signed
+	payload []byte             // Transaction payload received so far
+	button  trezor.Message     // Reply deferred until a button confirmation
+}
+
+// Write implements io.Writer, accepting a single 64 byte HID report from the
+// driver and processing the request once fully received.
+func (t *mockTrezor) Write(report []byte) (int, error) {
+	if len(report) != 64 || report[0] != 0x3f {
+		return 0, fmt.Errorf("invalid report: %x", report)
+	}
+	if t.request == nil {
+		if report[1] != 0x23 || report[2] != 0x23 {
+			return 0, fmt.Errorf("invalid message header: %x", report[1:3])
+		}
+		t.request = make([]byte, 0, 6+binary.BigEndian.Uint32(report[5:9]))
+		t.request = append(t.request, report[3:9]...)
+		report = report[9:]
+	} else {
+		report = report[1:]
+	}
+	if left := cap(t.request) - len(t.request); left > len(report) {
+		t.request = append(t.request, report...)
+	} else {
+		t.request = append(t.request, report[:left]...)
+		kind, data := binary.BigEndian.Uint16(t.request), t.request[6:]
+		t.request = nil
+
+		if err := t.handle(kind, data); err != nil {
+			return 0, err
+		}
+	}
+	return 64, nil
+}
+
+// Read implements io.Reader, returning the next queued HID report.
+func (t *mockTrezor) Read(report []byte) (int, error) {
+	if len(t.replies) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(report, t.replies[:64])
+	t.replies = t.replies[64:]
+	return n, nil
+}
+
+// reply queues a reply message, split up into 64 byte HID reports.
+func (t *mockTrezor) reply(msg trezor.Message) {
+	data := msg.Marshal()
+
+	payload := make([]byte, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], trezor.Type(msg))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	for len(payload) > 0 {
+		report := make([]byte, 64)
+		report[0] = 0x3f
+		payload = payload[copy(report[1:], payload):]
+		t.replies = append(t.replies, report...)
+	}
+}
+
+// key returns the private key of the account at the given derivation path,
+// which depends on the passphrase as on a real device.
+func (t *mockTrezor) key(path accounts.DerivationPath) *ecdsa.PrivateKey {
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte(t.passphrase + path.String())))
+	return key
+}
+
+// handle processes a fully received request message.
+func (t *mockTrezor) handle(kind uint16, data []byte) error {
+	switch kind {
+	case trezor.TypeInitialize:
+		t.reply(&trezor.Features{Vendor: "bitcointrezor.com", MajorVersion: 1, MinorVersion: 5, PatchVersion: 2, Label: "mock", Initialized: true})
+
+	case trezor.TypePing:
+		req := new(trezor.Ping)
+		if err := req.Unmarshal(data); err != nil {
+			return err
+		}
+		switch {
+		case req.PinProtection && !t.unlocked:
+			t.reply(&trezor.PinMatrixRequest{Type: 1})
+		case req.PassphraseProtection && t.passphrase != "" && !t.authorized:
+			t.reply(new(trezor.PassphraseRequest))
+		default:
+			t.reply(&trezor.Success{Message: req.Message})
+		}
+
+	case trezor.TypePinMatrixAck:
+		req := new(trezor.PinMatrixAck)
+		if err := req.Unmarshal(data); err != nil {
+			return err
+		}
+		switch {
+		case req.Pin != t.pin:
+			t.reply(&trezor.Failure{Code: 7, Message: "PIN invalid"})
+		case t.passphrase != "":
+			t.unlocked = true
+			t.reply(new(trezor.PassphraseRequest))
+		default:
+			t.unlocked = true
+			t.reply(new(trezor.Success))
+		}
+
+	case trezor.TypePassphraseAck:
+		req := new(trezor.PassphraseAck)
+		if err := req.Unmarshal(data); err != nil {
+			return err
+		}
+		// A real device accepts any passphrase, opening a different wallet
+		t.passphrase, t.authorized = req.Passphrase, true
+		t.reply(new(trezor.Success))
+
+	case trezor.TypeRiftGetAddress:
+		req := new(trezor.RiftGetAddress)
+		if err := req.Unmarshal(data); err != nil {
+			return err
+		}
+		t.reply(&trezor.RiftAddress{Address: crypto.PubkeyToAddress(t.key(req.AddressN).PublicKey).Bytes()})
+
+	case trezor.TypeRiftSignTx:
+		t.signing = new(trezor.RiftSignTx)
+		if err := t.signing.Unmarshal(data); err != nil {
+			return err
+		}
+		t.payload = t.signing.DataInitialChunk
+
+		// Require the user to confirm the transaction before continuing
+		t.button = t.signNext()
+		t.reply(&trezor.ButtonRequest{Code: 1})
+
+	case trezor.TypeButtonAck:
+		t.reply(t.button)
+
+	case trezor.TypeRiftTxAck:
+		req := new(trezor.RiftTxAck)
+		if err := req.Unmarshal(data); err != nil {
+			return err
+		}
+		t.payload = append(t.payload, req.DataChunk...)
+		t.reply(t.signNext())
+
+	default:
+		t.reply(&trezor.Failure{Code: 1, Message: "Unexpected message"})
+	}
+	return nil
+}
+
+// signNext either requests the next chunk of the transaction payload, or if all
+// has been received, signs the transaction.
+func (t *mockTrezor) signNext() trezor.Message {
+	if left := int(t.signing.DataLength) - len(t.payload); left > 0 {
+		if left > 1024 {
+			left = 1024
+		}
+		return &trezor.RiftTxRequest{DataLength: uint32(left)}
+	}
+	req := t.signing
+
+	var tx *types.Transaction
+	if len(req.To) == 0 {
+		tx = types.NewContractCreation(new(big.Int).SetBytes(req.Nonce).Uint64(), new(big.Int).SetBytes(req.Value), new(big.Int).SetBytes(req.GasLimit), new(big.Int).SetBytes(req.GasPrice), t.payload)
+	} else {
+		tx = types.NewTransaction(new(big.Int).SetBytes(req.Nonce).Uint64(), common.BytesToAddress(req.To), new(big.Int).SetBytes(req.Value), new(big.Int).SetBytes(req.GasLimit), new(big.Int).SetBytes(req.GasPrice), t.payload)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if req.ChainID != 0 {
+		signer = types.NewEIP155Signer(big.NewInt(int64(req.ChainID)))
+	}
+	signed, err := types.SignTx(tx, signer, t.key(req.AddressN))
+	if err != nil {
+		return &trezor.Failure{Code: 99, Message: err.Error()}
+	}
+	v, r, s := signed.RawSignatureValues()
+	return &trezor.RiftTxRequest{SignatureV: uint32(v.Uint64()), SignatureR: r.Bytes(), SignatureS: s.Bytes()}
+}
+
+// Tests that opening a Trezor walks through the PIN and passphrase challenges,
+// and that invalid PINs are reported as failures.
+func TestTrezorOpen(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		entries    []string
+		errs       []error
+	}{
+		// PIN protected device, correct PIN
+		{"", []string{"", "1234"}, []error{ErrTrezorPINNeeded, nil}},
+		// PIN and passphrase protected device
+		{"secret", []string{"", "1234", "secret"}, []error{ErrTrezorPINNeeded, ErrTrezorPassphraseNeeded, nil}},
+		// Repeated first phase while waiting for the PIN
+		{"", []string{"", "", "1234"}, []error{ErrTrezorPINNeeded, ErrTrezorPINNeeded, nil}},
+		// Invalid PIN entry
+		{"", []string{"", "4321"}, []error{ErrTrezorPINNeeded, fmt.Errorf("trezor: PIN invalid")}},
+	}
+	for i, tt := range tests {
+		device := &mockTrezor{pin: "1234", passphrase: tt.passphrase}
+		driver := newTrezorDriver(log.New()).(*trezorDriver)
+
+		for j, entry := range tt.entries {
+			err := driver.Open(device, entry)
+			if fmt.Sprint(err) != fmt.Sprint(tt.errs[j]) {
+				t.Errorf("test %d, open %d: error mismatch: have %v, want %v", i, j, err, tt.errs[j])
+			}
+		}
+		status, failure := driver.Status()
+		if last := tt.errs[len(tt.errs)-1]; last == nil {
+			if failure != nil || status != "Trezor v1.5.2 'mock' online" {
+				t.Errorf("test %d: status mismatch: have %q (%v), want online", i, status, failure)
+			}
+		} else if failure == nil {
+			t.Errorf("test %d: failure not reported, status %q", i, status)
+		}
+	}
+}
+
+// Tests that accounts can be derived from an opened Trezor and that signed
+// transactions, including chunked payloads and Homestead ones, recover to the
+// derived account.
+func TestTrezorSignTx(t *testing.T) {
+	device := &mockTrezor{pin: "1234"}
+	driver := newTrezorDriver(log.New())
+
+	if err := driver.Open(device, ""); err != ErrTrezorPINNeeded {
+		t.Fatalf("first open error mismatch: have %v, want %v", err, ErrTrezorPINNeeded)
+	}
+	if err := driver.Open(device, "1234"); err != nil {
+		t.Fatalf("failed to unlock device: %v", err)
+	}
+	path := append(accounts.DerivationPath{}, accounts.DefaultBaseDerivationPath...)
+	address, err := driver.Derive(path)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(device.key(path).PublicKey); address != want {
+		t.Fatalf("derived address mismatch: have %x, want %x", address, want)
+	}
+	to := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	tests := []struct {
+		tx      *types.Transaction
+		chainID *big.Int
+	}{
+		{types.NewTransaction(0, to, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), big.NewInt(1)},
+		{types.NewTransaction(1, to, big.NewInt(0), big.NewInt(90000), big.NewInt(1), bytes.Repeat([]byte{0xaa}, 2500)), big.NewInt(1337)},
+		{types.NewContractCreation(2, big.NewInt(0), big.NewInt(90000), big.NewInt(1), []byte{0x60, 0x00}), big.NewInt(1)},
+		{types.NewTransaction(3, to, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), nil},
+	}
+	for i, tt := range tests {
+		sender, signed, err := driver.SignTx(path, tt.tx, tt.chainID)
+		if err != nil {
+			t.Errorf("test %d: failed to sign transaction: %v", i, err)
+			continue
+		}
+		if sender != address {
+			t.Errorf("test %d: sender mismatch: have %x, want %x", i, sender, address)
+		}
+		if signed.Hash() == tt.tx.Hash() || !bytes.Equal(signed.Data(), tt.tx.Data()) || signed.Nonce() != tt.tx.Nonce() {
+			t.Errorf("test %d: signed transaction mismatch", i)
+		}
+		if protected := tt.chainID != nil; signed.Protected() != protected {
+			t.Errorf("test %d: replay protection mismatch: have %v, want %v", i, signed.Protected(), protected)
+		}
+	}
+}
+
+// Tests that replies announcing an oversized message are rejected before any
+// memory is allocated for them.
+func TestTrezorReplyTooLarge(t *testing.T) {
+	report := make([]byte, 64)
+	report[0], report[1], report[2] = 0x3f, 0x23, 0x23
+	binary.BigEndian.PutUint32(report[5:9], 0xffffffff)
+
+	device := &mockTrezor{pin: "1234", replies: report}
+	driver := newTrezorDriver(log.New())
+
+	if err := driver.Open(device, ""); err != errTrezorReplyTooLarge {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTrezorReplyTooLarge)
+	}
+}
@@ -35,6 +35,7 @@ var (
 
 	solFlag  = flag.String("sol", "", "Path to the CryptoRift contract Solidity source to build and bind")
 	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
+	jsonFlag = flag.String("combined-json", "", "Path to the solc --combined-json output to bind (abi,bin and optionally metadata)")
 	excFlag  = flag.String("exc", "", "Comma separated types to exclude from binding")
 
 	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
@@ -46,11 +47,14 @@ func main() {
 	// Parse and ensure all needed inputs are specified
 	flag.Parse()
 
-	if *abiFlag == "" && *solFlag == "" {
-		fmt.Printf("No contract ABI (--abi) or Solidity source (--sol) specified\n")
+	if *abiFlag == "" && *solFlag == "" && *jsonFlag == "" {
+		fmt.Printf("No contract ABI (--abi), Solidity source (--sol) or combined compiler output (--combined-json) specified\n")
 		os.Exit(-1)
-	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && *solFlag != "" {
-		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) flag\n")
+	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && (*solFlag != "" || *jsonFlag != "") {
+		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) and combined compiler output (--combined-json) flags\n")
+		os.Exit(-1)
+	} else if *solFlag != "" && *jsonFlag != "" {
+		fmt.Printf("Solidity source (--sol) and combined compiler output (--combined-json) flags are mutually exclusive\n")
 		os.Exit(-1)
 	}
 	if *pkgFlag == "" {
@@ -69,34 +73,56 @@ func main() {
 		fmt.Printf("Unsupported destination language \"%s\" (--lang)\n", *langFlag)
 		os.Exit(-1)
 	}
-	// If the entire solidity code was specified, build and bind based on that
+	// If the entire solidity code or the compiler output was specified, bind based on that
 	var (
 		abis  []string
 		bins  []string
+		metas []string
 		types []string
+		libs  = make(map[string]string)
 	)
-	if *solFlag != "" {
+	if *solFlag != "" || *jsonFlag != "" {
 		// Generate the list of types to exclude from binding
 		exclude := make(map[string]bool)
 		for _, kind := range strings.Split(*excFlag, ",") {
 			exclude[strings.ToLower(kind)] = true
 		}
-		contracts, err := compiler.CompileSolidity(*solcFlag, *solFlag)
-		if err != nil {
-			fmt.Printf("Failed to build Solidity contract: %v\n", err)
-			os.Exit(-1)
+		var contracts map[string]*compiler.Contract
+		if *solFlag != "" {
+			var err error
+			if contracts, err = compiler.CompileSolidity(*solcFlag, *solFlag); err != nil {
+				fmt.Printf("Failed to build Solidity contract: %v\n", err)
+				os.Exit(-1)
+			}
+		} else {
+			output, err := ioutil.ReadFile(*jsonFlag)
+			if err != nil {
+				fmt.Printf("Failed to read combined compiler output: %v\n", err)
+				os.Exit(-1)
+			}
+			if contracts, err = compiler.ParseCombinedJSON(output, "", "", "", ""); err != nil {
+				fmt.Printf("Failed to parse combined compiler output: %v\n", err)
+				os.Exit(-1)
+			}
 		}
 		// Gather all non-excluded contract for binding
 		for name, contract := range contracts {
+			nameParts := strings.Split(name, ":")
+			kind := nameParts[len(nameParts)-1]
+
+			// Any contract may be a library the others need to be linked against, keep
+			// the fully qualified names to tell apart libraries of different sources
+			for _, placeholder := range compiler.LinkPlaceholders(name) {
+				libs[placeholder] = name
+			}
 			if exclude[strings.ToLower(name)] {
 				continue
 			}
 			abi, _ := json.Marshal(contract.Info.AbiDefinition) // Flatten the compiler parse
 			abis = append(abis, string(abi))
 			bins = append(bins, contract.Code)
-
-			nameParts := strings.Split(name, ":")
-			types = append(types, nameParts[len(nameParts)-1])
+			metas = append(metas, contract.Info.Metadata)
+			types = append(types, kind)
 		}
 	} else {
 		// Otherwise load up the ABI, optional bytecode and type name from the parameters
@@ -115,6 +141,7 @@ func main() {
 			}
 		}
 		bins = append(bins, string(bin))
+		metas = append(metas, "")
 
 		kind := *typFlag
 		if kind == "" {
@@ -123,7 +150,7 @@ func main() {
 		types = append(types, kind)
 	}
 	// Generate the contract binding
-	code, err := bind.Bind(types, abis, bins, *pkgFlag, lang)
+	code, err := bind.Bind(types, abis, bins, metas, *pkgFlag, lang, libs)
 	if err != nil {
 		fmt.Printf("Failed to generate ABI binding: %v\n", err)
 		os.Exit(-1)
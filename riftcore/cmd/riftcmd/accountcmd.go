@@ -71,7 +71,8 @@ passwordfile as argument containing the wallet password in plaintext.`,
 		Description: `
 
 Manage accounts, list all existing accounts, import a private key into a new
-account, create a new account or update an existing account.
+account, import a mnemonic into a new HD wallet, create a new account or update
+an existing account.
 
 It supports interactive mode, when you are prompted for password as well as
 non-interactive mode where passwords are supplied via a given password file.
@@ -185,6 +186,34 @@ Note:
 As you can directly copy your encrypted accounts to another cryptorift instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "import-mnemonic",
+				Usage:  "Import a BIP-39 mnemonic into a new HD wallet",
+				Action: utils.MigrateFlags(accountImportMnemonic),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				ArgsUsage: "[<mnemonicFile>]",
+				Description: `
+    riftcmd account import-mnemonic [<mnemonicFile>]
+
+Imports a BIP-39 mnemonic phrase and creates a new hierarchical deterministic
+wallet from it. The phrase is read from <mnemonicFile> if given, otherwise you
+are prompted for it. Prints the address of the first account.
+
+The seed of the wallet is saved in encrypted format under <DATADIR>/keystore/hd,
+you are prompted for a passphrase. Further accounts are derived on demand from
+the seed after opening the wallet with this passphrase, so the same accounts
+can be reproduced on any machine from the mnemonic alone.
+
+For non-interactive use the passphrase can be specified with the --password flag:
+
+    riftcmd account import-mnemonic [options] <mnemonicFile>
 `,
 			},
 		},
@@ -321,6 +350,35 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountImportMnemonic imports a BIP-39 mnemonic into a new HD wallet in the
+// keystore defined by the CLI flags.
+func accountImportMnemonic(ctx *cli.Context) error {
+	var mnemonic string
+	if file := ctx.Args().First(); len(file) > 0 {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read mnemonic file: %v", err)
+		}
+		mnemonic = string(blob)
+	} else {
+		var err error
+		if mnemonic, err = console.Stdin.PromptPassword("Mnemonic: "); err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	passphrase := getPassPhrase("Your new wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	wallet, err := ks.ImportMnemonic(mnemonic, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not import the mnemonic: %v", err)
+	}
+	fmt.Printf("Wallet:  %s\n", wallet.URL())
+	fmt.Printf("Address: {%x}\n", wallet.Accounts()[0].Address)
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
@@ -30,6 +30,7 @@ import (
 
 	"github.com/cryptorift/riftcore/cmd/utils"
 	"github.com/cryptorift/riftcore/contracts/release"
+	"github.com/cryptorift/riftcore/graphql"
 	"github.com/cryptorift/riftcore/rift"
 	"github.com/cryptorift/riftcore/node"
 	"github.com/cryptorift/riftcore/params"
@@ -80,6 +81,7 @@ type riftcmdConfig struct {
 	Shh      whisper.Config
 	Node     node.Config
 	Riftstats riftstatsConfig
+	GraphQL  graphql.Config
 }
 
 func loadConfig(file string, cfg *riftcmdConfig) error {
@@ -113,6 +115,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, riftcmdConfig) {
 		Rift:  rift.DefaultConfig,
 		Shh:  whisper.DefaultConfig,
 		Node: defaultNodeConfig(),
+		GraphQL: graphql.DefaultConfig,
 	}
 
 	// Load config file.
@@ -134,6 +137,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, riftcmdConfig) {
 	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
+	utils.SetGraphQLConfig(ctx, &cfg.GraphQL)
 
 	return stack, cfg
 }
@@ -171,6 +175,11 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterRiftStatsService(stack, cfg.Riftstats.URL)
 	}
 
+	// Add the GraphQL endpoint if requested.
+	if cfg.GraphQL.Endpoint() != "" {
+		utils.RegisterGraphQLService(stack, &cfg.GraphQL)
+	}
+
 	// Add the release oracle service so it boots along with node.
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		config := release.Config{
@@ -58,8 +58,10 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.ExternalSignerFlag,
 		utils.RifthashCacheDirFlag,
 		utils.RifthashCachesInMemoryFlag,
 		utils.RifthashCachesOnDiskFlag,
@@ -67,6 +69,8 @@ var (
 		utils.RifthashDatasetsInMemoryFlag,
 		utils.RifthashDatasetsOnDiskFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -77,6 +81,7 @@ var (
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.GCModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
@@ -117,11 +122,25 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCVirtualHostsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCMaxRequestSizeFlag,
+		utils.RPCMaxBatchSizeFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCMethodRateLimitsFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
+		utils.GraphQLVirtualHostsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -234,13 +253,13 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		}
 		stateReader := riftclient.NewClient(rpcClient)
 
-		// Open and self derive any wallets already attached
+		// Open and self derive any wallets already attached. Wallets requiring further
+		// authentication (e.g. a Trezor PIN) start self-deriving once opened manually.
 		for _, wallet := range stack.AccountManager().Wallets() {
 			if err := wallet.Open(""); err != nil {
 				log.Warn("Failed to open wallet", "url", wallet.URL(), "err", err)
-			} else {
-				wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
 			}
+			wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
 		}
 		// Listen for wallet event till termination
 		for event := range events {
@@ -249,8 +268,8 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 					log.Warn("New wallet appeared, failed to open", "url", event.Wallet.URL(), "err", err)
 				} else {
 					log.Info("New wallet appeared", "url", event.Wallet.URL(), "status", event.Wallet.Status())
-					event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
 				}
+				event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
 			} else {
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
 				event.Wallet.Close()
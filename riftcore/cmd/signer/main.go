@@ -0,0 +1,234 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of riftcore.
+//
+// riftcore is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// riftcore is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with riftcore. If not, see <http://www.gnu.org/licenses/>.
+
+// signer is a standalone account manager and signer, holding the keys outside
+// of the node and requiring every signing request to be approved.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/keystore"
+	"github.com/cryptorift/riftcore/accounts/usbwallet"
+	"github.com/cryptorift/riftcore/cmd/utils"
+	"github.com/cryptorift/riftcore/internal/debug"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/rpc"
+	"github.com/cryptorift/riftcore/signer/core"
+	"github.com/cryptorift/riftcore/signer/rules"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// Git SHA1 commit hash of the release (set via linker flags)
+	gitCommit = ""
+
+	// The app that holds all commands and flags.
+	app = utils.NewApp(gitCommit, "the CryptoRift external signer")
+)
+
+var (
+	keystoreFlag = cli.StringFlag{
+		Name:  "keystore",
+		Usage: "Directory for the keystore",
+		Value: filepath.Join(node.DefaultDataDir(), "keystore"),
+	}
+	chainIdFlag = cli.Int64Flag{
+		Name:  "chainid",
+		Usage: "Chain id to use for signing (1=mainnet, 3=ropsten, 4=rinkeby)",
+		Value: params.MainnetChainConfig.ChainId.Int64(),
+	}
+	rulesFlag = cli.StringFlag{
+		Name:  "rules",
+		Usage: "JavaScript ruleset file to automatically approve or reject requests",
+	}
+	rpcPortFlag = cli.IntFlag{
+		Name:  "rpcport",
+		Usage: "HTTP-RPC server listening port",
+		Value: 8550,
+	}
+	ipcPathFlag = cli.StringFlag{
+		Name:  "ipcpath",
+		Usage: "Filename for the IPC socket/pipe",
+		Value: filepath.Join(node.DefaultDataDir(), "signer.ipc"),
+	}
+)
+
+func init() {
+	app.Action = signer
+	app.Flags = []cli.Flag{
+		keystoreFlag,
+		chainIdFlag,
+		rulesFlag,
+		utils.LightKDFFlag,
+		utils.NoUSBFlag,
+		utils.UnlockedAccountFlag,
+		utils.PasswordFileFlag,
+		utils.RPCEnabledFlag,
+		utils.RPCListenAddrFlag,
+		rpcPortFlag,
+		utils.RPCCORSDomainFlag,
+		utils.RPCVirtualHostsFlag,
+		utils.IPCDisabledFlag,
+		ipcPathFlag,
+	}
+	app.Flags = append(app.Flags, debug.Flags...)
+
+	app.Before = func(ctx *cli.Context) error {
+		return debug.Setup(ctx)
+	}
+	app.After = func(ctx *cli.Context) error {
+		debug.Exit()
+		return nil
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// signer is the main entry point, creating the account backends and exposing
+// the signing API until interrupted.
+func signer(ctx *cli.Context) error {
+	// Assemble the approval UI, consulting the ruleset first if one was given
+	var ui core.SignerUI = core.NewCommandlineUI()
+	if path := ctx.GlobalString(rulesFlag.Name); path != "" {
+		ruleset, err := ioutil.ReadFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to read ruleset: %v", err)
+		}
+		if ui, err = rules.NewRuleEvaluator(ui, string(ruleset)); err != nil {
+			utils.Fatalf("Failed to load ruleset: %v", err)
+		}
+		log.Info("Loaded request approval ruleset", "path", path)
+	}
+	am := makeAccountManager(ctx)
+
+	// Expose the signing API over the requested endpoints
+	api := core.NewSignerAPI(am, ctx.GlobalInt64(chainIdFlag.Name), ui)
+
+	handler := rpc.NewServer()
+	if err := handler.RegisterName("account", api); err != nil {
+		utils.Fatalf("Failed to register signer API: %v", err)
+	}
+	info := map[string]interface{}{
+		"chainid": ctx.GlobalInt64(chainIdFlag.Name),
+	}
+	if ctx.GlobalBool(utils.RPCEnabledFlag.Name) {
+		endpoint := fmt.Sprintf("%s:%d", ctx.GlobalString(utils.RPCListenAddrFlag.Name), ctx.GlobalInt(rpcPortFlag.Name))
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			utils.Fatalf("Failed to start HTTP endpoint: %v", err)
+		}
+		defer listener.Close()
+
+		var cors []string
+		if domains := ctx.GlobalString(utils.RPCCORSDomainFlag.Name); domains != "" {
+			cors = strings.Split(domains, ",")
+		}
+		vhosts := strings.Split(ctx.GlobalString(utils.RPCVirtualHostsFlag.Name), ",")
+		go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
+
+		info["http"] = fmt.Sprintf("http://%s", endpoint)
+		log.Info("HTTP endpoint opened", "url", info["http"])
+	}
+	if !ctx.GlobalBool(utils.IPCDisabledFlag.Name) {
+		endpoint := ctx.GlobalString(ipcPathFlag.Name)
+		listener, err := rpc.CreateIPCListener(endpoint)
+		if err != nil {
+			utils.Fatalf("Failed to start IPC endpoint: %v", err)
+		}
+		defer listener.Close()
+		go handler.ServeListener(listener)
+
+		info["ipc"] = endpoint
+		log.Info("IPC endpoint opened", "url", endpoint)
+	}
+	ui.OnSignerStartup(core.StartupInfo{Info: info})
+
+	// Wait until the signer is interrupted
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+	<-sigc
+
+	log.Info("Got interrupt, shutting down...")
+	handler.Stop()
+	return nil
+}
+
+// makeAccountManager creates the account manager holding the keystore and any
+// USB hardware wallet backends, unlocking all requested keystore accounts.
+func makeAccountManager(ctx *cli.Context) *accounts.Manager {
+	scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+	if ctx.GlobalBool(utils.LightKDFFlag.Name) {
+		scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	}
+	keydir := ctx.GlobalString(keystoreFlag.Name)
+	if err := os.MkdirAll(keydir, 0700); err != nil {
+		utils.Fatalf("Failed to create keystore: %v", err)
+	}
+	ks := keystore.NewKeyStore(keydir, scryptN, scryptP)
+
+	backends := []accounts.Backend{ks}
+	if !ctx.GlobalBool(utils.NoUSBFlag.Name) {
+		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Ledger hub, disabling: %v", err))
+		} else {
+			backends = append(backends, ledgerhub)
+		}
+		if trezorhub, err := usbwallet.NewTrezorHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Trezor hub, disabling: %v", err))
+		} else {
+			backends = append(backends, trezorhub)
+		}
+	}
+	// Unlock any keystore accounts requested for rule based signing
+	passwords := utils.MakePasswordList(ctx)
+	for i, address := range strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		account, err := utils.MakeAddress(ks, address)
+		if err != nil {
+			utils.Fatalf("Could not list accounts: %v", err)
+		}
+		if len(passwords) == 0 {
+			utils.Fatalf("No password file specified to unlock %s", address)
+		}
+		password := passwords[len(passwords)-1]
+		if i < len(passwords) {
+			password = passwords[i]
+		}
+		if err := ks.Unlock(account, password); err != nil {
+			utils.Fatalf("Failed to unlock account %s: %v", address, err)
+		}
+		log.Info("Unlocked account", "address", account.Address.Hex())
+	}
+	return accounts.NewManager(backends...)
+}
@@ -25,8 +25,10 @@ import (
 	"strings"
 
 	"github.com/cryptorift/riftcore/cmd/utils"
+	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/swarm/api"
 	swarm "github.com/cryptorift/riftcore/swarm/api/client"
+	"github.com/cryptorift/riftcore/swarm/storage"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -121,6 +123,12 @@ func remove(ctx *cli.Context) {
 	}
 }
 
+// isEncrypted reports whether mhash references an encrypted manifest, which
+// has to stay encrypted when modified as it holds the keys of its entries
+func isEncrypted(mhash string) bool {
+	return storage.IsEncryptedKey(common.FromHex(mhash))
+}
+
 func addEntryToManifest(ctx *cli.Context, mhash, path, hash, ctype string) string {
 
 	var (
@@ -178,7 +186,7 @@ func addEntryToManifest(ctx *cli.Context, mhash, path, hash, ctype string) strin
 		mroot.Entries = append(mroot.Entries, newEntry)
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
@@ -255,7 +263,7 @@ func updateEntryInManifest(ctx *cli.Context, mhash, path, hash, ctype string) st
 		mroot = newMRoot
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
@@ -321,7 +329,7 @@ func removeEntryFromManifest(ctx *cli.Context, mhash, path string) string {
 		mroot = newMRoot
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -42,6 +43,7 @@ import (
 	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/riftstats"
 	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/graphql"
 	"github.com/cryptorift/riftcore/les"
 	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/metrics"
@@ -115,6 +117,10 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -123,6 +129,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managine USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer to forward signing requests to (IPC path or HTTP URL)",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -163,6 +173,11 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	GCModeFlag = cli.StringFlag{
+		Name:  "gcmode",
+		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Value: "full",
+	}
 
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
@@ -213,6 +228,16 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for local transaction to survive node restarts",
+		Value: rift.DefaultConfig.TxPool.Journal,
+	}
+	TxPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the local transaction journal",
+		Value: rift.DefaultConfig.TxPool.Rejournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -346,6 +371,11 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCVirtualHostsFlag = cli.StringFlag{
+		Name:  "rpcvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -378,6 +408,66 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpctlscert",
+		Usage: "TLS certificate file (PEM) to serve the HTTP-RPC and WS-RPC interfaces with",
+		Value: "",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpctlskey",
+		Usage: "TLS private key file (PEM) of the HTTP-RPC and WS-RPC certificate",
+		Value: "",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "File containing the hex encoded secret to authenticate HTTP-RPC and WS-RPC requests with (HS256 JWT)",
+		Value: "",
+	}
+	RPCMaxRequestSizeFlag = cli.Int64Flag{
+		Name:  "rpcmaxrequestsize",
+		Usage: "Maximum size of an HTTP-RPC request body in bytes (0 = 128KB)",
+	}
+	RPCMaxBatchSizeFlag = cli.IntFlag{
+		Name:  "rpcmaxbatch",
+		Usage: "Maximum number of requests in an HTTP-RPC or WS-RPC batch (0 = no limit)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Requests per second allowed per client IP on the HTTP-RPC and WS-RPC interfaces (0 = no limit)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Maximum burst of requests allowed per client IP (0 = rate limit)",
+	}
+	RPCMethodRateLimitsFlag = cli.StringFlag{
+		Name:  "rpcmethodlimits",
+		Usage: "Comma separated list of per method request rate limits per client IP (e.g. rift_call=5,rift_getLogs=0.5)",
+		Value: "",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphqladdr",
+		Usage: "GraphQL server listening interface",
+		Value: graphql.DefaultHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphqlport",
+		Usage: "GraphQL server listening port",
+		Value: graphql.DefaultPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphqlcorsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin GraphQL requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphqlvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept GraphQL requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(graphql.DefaultConfig.VirtualHosts, ","),
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -640,6 +730,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
+		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -663,6 +756,51 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCSecurity applies the TLS and authentication settings of the HTTP and
+// WebSocket RPC interfaces from the set command line flags.
+func setRPCSecurity(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.RPCTLSCert = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.RPCTLSKey = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+}
+
+// setRPCLimits applies the request size, batch size and rate limits of the HTTP
+// and WebSocket RPC interfaces from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCMaxRequestSizeFlag.Name) {
+		cfg.RPCMaxRequestSize = ctx.GlobalInt64(RPCMaxRequestSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMaxBatchSizeFlag.Name) {
+		cfg.RPCMaxBatchSize = ctx.GlobalInt(RPCMaxBatchSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodRateLimitsFlag.Name) {
+		cfg.RPCMethodRateLimits = make(map[string]float64)
+		for _, limit := range splitAndTrim(ctx.GlobalString(RPCMethodRateLimitsFlag.Name)) {
+			parts := strings.Split(limit, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid method rate limit %q, want <method>=<rate>", limit)
+			}
+			rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || rate < 0 {
+				Fatalf("Invalid rate in method rate limit %q", limit)
+			}
+			cfg.RPCMethodRateLimits[strings.TrimSpace(parts[0])] = rate
+		}
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -801,6 +939,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCSecurity(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -823,6 +963,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -838,6 +981,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -904,6 +1053,25 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// SetGraphQLConfig applies GraphQL related command line flags to the config.
+func SetGraphQLConfig(ctx *cli.Context, cfg *graphql.Config) {
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) && cfg.Host == "" {
+		cfg.Host = "127.0.0.1"
+		if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+			cfg.Host = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.Port = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.Cors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.VirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+}
+
 // SetRiftConfig applies rift-related command line flags to the config.
 func SetRiftConfig(ctx *cli.Context, stack *node.Node, cfg *rift.Config) {
 	// Avoid conflicting network flags
@@ -942,6 +1110,14 @@ func SetRiftConfig(ctx *cli.Context, stack *node.Node, cfg *rift.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	}
+	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
@@ -1034,6 +1210,25 @@ func RegisterRiftStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL endpoint of the chain data to the given
+// node, serving it from either the full or the light CryptoRift service.
+func RegisterGraphQLService(stack *node.Node, cfg *graphql.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Retrieve the backend of either the rift or the les service
+		var riftServ *rift.CryptoRift
+		if err := ctx.Service(&riftServ); err == nil {
+			return graphql.New(ctx, riftServ.ApiBackend, cfg)
+		}
+		var lesServ *les.LightCryptorift
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(ctx, lesServ.ApiBackend, cfg)
+		}
+		return nil, errors.New("no CryptoRift service to query")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1054,6 +1249,20 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) riftdb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	// Light clients only store headers, there are no chain segments to freeze
+	if ctx.GlobalBool(LightModeFlag.Name) || stack.ResolvePath(name) == "" {
+		return chainDb
+	}
+	freezer := ctx.GlobalString(AncientFlag.Name)
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(stack.ResolvePath(name), "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = stack.ResolvePath(freezer)
+	}
+	if chainDb, err = core.NewDatabaseWithFreezer(chainDb, freezer); err != nil {
+		Fatalf("Could not open ancient database: %v", err)
+	}
 	return chainDb
 }
 
@@ -1083,8 +1292,16 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("%v", err)
 	}
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	}
+	cache := &core.CacheConfig{
+		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
+		TrieNodeLimit: rift.DefaultConfig.TrieCache,
+		TrieTimeLimit: rift.DefaultConfig.TrieTimeout,
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
-	chain, err = core.NewBlockChain(chainDb, config, engine, new(event.TypeMux), vmcfg)
+	chain, err = core.NewBlockChain(chainDb, cache, config, engine, new(event.TypeMux), vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
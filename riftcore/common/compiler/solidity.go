@@ -19,6 +19,7 @@ package compiler
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,6 +28,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/cryptorift/riftcore/crypto"
 )
 
 var versionRegexp = regexp.MustCompile(`([0-9]+)\.([0-9]+)\.([0-9]+)`)
@@ -143,8 +146,19 @@ func (s *Solidity) run(cmd *exec.Cmd, source string) (map[string]*Contract, erro
 	if err := cmd.Run(); err != nil {
 		return nil, fmt.Errorf("solc: %v\n%s", err, stderr.Bytes())
 	}
+	return ParseCombinedJSON(stdout.Bytes(), source, s.Version, s.Version, strings.Join(s.makeArgs(), " "))
+}
+
+// ParseCombinedJSON takes the direct output of a solc --combined-json run and
+// parses it into a map of string contract name to Contract structs. The
+// provided source, language and compiler version, and compiler options are all
+// passed through into the Contract structs.
+//
+// The solc output is expected to contain ABI and bytecode data, the user and
+// developer docs and the metadata are optional.
+func ParseCombinedJSON(combinedJSON []byte, source string, languageVersion string, compilerVersion string, compilerOptions string) (map[string]*Contract, error) {
 	var output solcOutput
-	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+	if err := json.Unmarshal(combinedJSON, &output); err != nil {
 		return nil, err
 	}
 
@@ -157,21 +171,25 @@ func (s *Solidity) run(cmd *exec.Cmd, source string) (map[string]*Contract, erro
 			return nil, fmt.Errorf("solc: error reading abi definition (%v)", err)
 		}
 		var userdoc interface{}
-		if err := json.Unmarshal([]byte(info.Userdoc), &userdoc); err != nil {
-			return nil, fmt.Errorf("solc: error reading user doc: %v", err)
+		if info.Userdoc != "" {
+			if err := json.Unmarshal([]byte(info.Userdoc), &userdoc); err != nil {
+				return nil, fmt.Errorf("solc: error reading user doc: %v", err)
+			}
 		}
 		var devdoc interface{}
-		if err := json.Unmarshal([]byte(info.Devdoc), &devdoc); err != nil {
-			return nil, fmt.Errorf("solc: error reading dev doc: %v", err)
+		if info.Devdoc != "" {
+			if err := json.Unmarshal([]byte(info.Devdoc), &devdoc); err != nil {
+				return nil, fmt.Errorf("solc: error reading dev doc: %v", err)
+			}
 		}
 		contracts[name] = &Contract{
 			Code: "0x" + info.Bin,
 			Info: ContractInfo{
 				Source:          source,
 				Language:        "Solidity",
-				LanguageVersion: s.Version,
-				CompilerVersion: s.Version,
-				CompilerOptions: strings.Join(s.makeArgs(), " "),
+				LanguageVersion: languageVersion,
+				CompilerVersion: compilerVersion,
+				CompilerOptions: compilerOptions,
 				AbiDefinition:   abi,
 				UserDoc:         userdoc,
 				DeveloperDoc:    devdoc,
@@ -182,6 +200,22 @@ func (s *Solidity) run(cmd *exec.Cmd, source string) (map[string]*Contract, erro
 	return contracts, nil
 }
 
+// LinkPlaceholders returns the placeholders solc leaves in the bytecode of the
+// contracts calling into the library with the given fully qualified name (e.g.
+// "math.sol:Math"). Compilers before 0.5 use the padded name of the library,
+// later ones a hash of it, both forms are returned.
+func LinkPlaceholders(name string) []string {
+	legacy := "__" + name
+	if len(legacy) > 38 {
+		legacy = legacy[:38]
+	}
+	legacy += strings.Repeat("_", 40-len(legacy))
+
+	hashed := "__$" + hex.EncodeToString(crypto.Keccak256([]byte(name)))[:34] + "$__"
+
+	return []string{legacy, hashed}
+}
+
 func slurpFiles(files []string) (string, error) {
 	var concat bytes.Buffer
 	for _, file := range files {
@@ -18,6 +18,7 @@ package compiler
 
 import (
 	"os/exec"
+	"strings"
 	"testing"
 )
 
@@ -32,6 +33,23 @@ contract test {
 `
 )
 
+// testCombinedJSON is the output of solc --combined-json abi,bin,metadata for a
+// contract linked against a library, with the bytecode shortened.
+const testCombinedJSON = `{
+  "contracts": {
+    "linked.sol:Linked": {
+      "abi": "[{\"constant\":true,\"inputs\":[],\"name\":\"lib\",\"outputs\":[{\"name\":\"\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"}]",
+      "bin": "6060604052341561000f57600080fd5b73__linked.sol:Math_______________________6000f3",
+      "metadata": "{\"compiler\":{\"version\":\"0.4.18\"},\"language\":\"Solidity\",\"settings\":{\"compilationTarget\":{\"linked.sol\":\"Linked\"}},\"sources\":{\"linked.sol\":{\"keccak256\":\"0x5e1d7a4e2cd0ca9c8ab7bfd54b8e0c7e1e5e0d07ab5e3eb2bb1dd58b20e2b75c\"}},\"version\":1}"
+    },
+    "linked.sol:Math": {
+      "abi": "[]",
+      "bin": "60606040523415600e57600080fd5b"
+    }
+  },
+  "version": "0.4.18+commit.9cf6e910.Linux.g++"
+}`
+
 func skipWithoutSolc(t *testing.T) {
 	if _, err := exec.LookPath("solc"); err != nil {
 		t.Skip(err)
@@ -75,3 +93,40 @@ func TestCompileError(t *testing.T) {
 	}
 	t.Logf("error: %v", err)
 }
+
+func TestParseCombinedJSON(t *testing.T) {
+	contracts, err := ParseCombinedJSON([]byte(testCombinedJSON), "", "0.4.18", "0.4.18", "")
+	if err != nil {
+		t.Fatalf("failed to parse combined json: %v", err)
+	}
+	if len(contracts) != 2 {
+		t.Fatalf("contract count mismatch: have %d, want 2", len(contracts))
+	}
+	linked, ok := contracts["linked.sol:Linked"]
+	if !ok {
+		t.Fatalf("info for contract 'linked.sol:Linked' not present in result")
+	}
+	if !strings.HasPrefix(linked.Code, "0x6060") {
+		t.Errorf("code mismatch: have %s", linked.Code)
+	}
+	if abi, ok := linked.Info.AbiDefinition.([]interface{}); !ok || len(abi) != 1 {
+		t.Errorf("abi definition mismatch: have %v", linked.Info.AbiDefinition)
+	}
+	if !strings.Contains(linked.Info.Metadata, "compilationTarget") {
+		t.Errorf("metadata mismatch: have %s", linked.Info.Metadata)
+	}
+	if linked.Info.CompilerVersion != "0.4.18" {
+		t.Errorf("compiler version mismatch: have %s, want 0.4.18", linked.Info.CompilerVersion)
+	}
+	// The library placeholders in the bytecode must be discoverable by name
+	var found bool
+	for _, placeholder := range LinkPlaceholders("linked.sol:Math") {
+		if len(placeholder) != 40 {
+			t.Errorf("placeholder %s length mismatch: have %d, want 40", placeholder, len(placeholder))
+		}
+		found = found || strings.Contains(linked.Code, placeholder)
+	}
+	if !found {
+		t.Errorf("library placeholder not found in code %s", linked.Code)
+	}
+}
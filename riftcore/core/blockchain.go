@@ -43,6 +43,7 @@ import (
 	"github.com/cryptorift/riftcore/rlp"
 	"github.com/cryptorift/riftcore/trie"
 	"github.com/hashicorp/golang-lru"
+	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
 var (
@@ -57,11 +58,20 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
+	triesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
 
+// CacheConfig contains the configuration values for the trie caching/pruning
+// that's resident in a blockchain.
+type CacheConfig struct {
+	Disabled      bool          // Whether to disable trie write caching (archive node)
+	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
+	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+}
+
 // BlockChain represents the canonical chain given a database with a genesis
 // block. The Blockchain manages chain imports, reverts, chain reorganisations.
 //
@@ -77,10 +87,14 @@ const (
 // included in the canonical one where as GetBlockByNumber always represents the
 // canonical chain.
 type BlockChain struct {
-	config *params.ChainConfig // chain & network configuration
+	config      *params.ChainConfig // chain & network configuration
+	cacheConfig *CacheConfig        // Cache configuration for pruning
 
 	hc           *HeaderChain
 	chainDb      riftdb.Database
+	triegc       *prque.Prque  // Priority queue mapping block numbers to tries to gc
+	gcproc       time.Duration // Accumulates canonical block processing for trie dumping
+	lastWrite    uint64        // Number of the last block whose state was flushed to disk
 	eventMux     *event.TypeMux
 	genesisBlock *types.Block
 
@@ -114,8 +128,14 @@ type BlockChain struct {
 
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default CryptoRift Validator and
-// Processor.
-func NewBlockChain(chainDb riftdb.Database, config *params.ChainConfig, engine consensus.Engine, mux *event.TypeMux, vmConfig vm.Config) (*BlockChain, error) {
+// Processor. A nil cacheConfig enables state pruning with the default limits.
+func NewBlockChain(chainDb riftdb.Database, cacheConfig *CacheConfig, config *params.ChainConfig, engine consensus.Engine, mux *event.TypeMux, vmConfig vm.Config) (*BlockChain, error) {
+	if cacheConfig == nil {
+		cacheConfig = &CacheConfig{
+			TrieNodeLimit: 256,
+			TrieTimeLimit: 5 * time.Minute,
+		}
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -124,7 +144,9 @@ func NewBlockChain(chainDb riftdb.Database, config *params.ChainConfig, engine c
 
 	bc := &BlockChain{
 		config:       config,
+		cacheConfig:  cacheConfig,
 		chainDb:      chainDb,
+		triegc:       prque.New(),
 		stateCache:   state.NewDatabase(chainDb),
 		eventMux:     mux,
 		quit:         make(chan struct{}),
@@ -193,8 +215,10 @@ func (bc *BlockChain) loadLastState() error {
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
 		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			return err
+		}
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
@@ -228,6 +252,24 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// repair tries to repair the current blockchain by rolling back the current block
+// until one with associated state is found. This is needed to fix incomplete db
+// writes caused either by crashes/power outages, or simply non-committed tries.
+//
+// This method only rolls back the current block. The current header and current
+// fast block are left intact.
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		// Abort if we've rewound to a head block that does have associated state
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			return nil
+		}
+		// Otherwise rewind one block and recheck state availability there
+		(*head) = bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+	}
+}
+
 // SetHead rewinds the local chain to a new head. In the case of headers, everything
 // above the new head will be deleted and the new one set. In the case of blocks
 // though, the head may be further rewound if block bodies are missing (non-archive
@@ -382,6 +424,11 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache)
 }
 
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -598,6 +645,30 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+
+	// Ensure the state of a few recent blocks is stored to disk before exiting:
+	//   - HEAD:     so we don't need to reprocess any blocks in the general case
+	//   - HEAD-1:   so we don't do large reorgs if our HEAD becomes an uncle
+	//   - HEAD-127: so we have a hard limit on the number of blocks reexecuted
+	if !bc.cacheConfig.Disabled {
+		triedb := bc.stateCache.TrieDB()
+		for _, offset := range []uint64{0, 1, triesInMemory - 1} {
+			if number := bc.CurrentBlock().NumberU64(); number > offset {
+				recent := bc.GetBlockByNumber(number - offset)
+
+				log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
+				if err := triedb.Commit(recent.Root(), true); err != nil {
+					log.Error("Failed to commit recent state trie", "err", err)
+				}
+			}
+		}
+		for !bc.triegc.Empty() {
+			triedb.Dereference(bc.triegc.PopItem().(common.Hash))
+		}
+		if size := triedb.Size(); size != 0 {
+			log.Error("Dangling trie nodes after full cleanup")
+		}
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -753,12 +824,6 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 				log.Crit("Failed to write block receipts", "err", err)
 				return
 			}
-			if err := WriteMipmapBloom(bc.chainDb, block.NumberU64(), receipts); err != nil {
-				errs[index] = fmt.Errorf("failed to write log blooms: %v", err)
-				atomic.AddInt32(&failed, 1)
-				log.Crit("Failed to write log blooms", "err", err)
-				return
-			}
 			if err := WriteTxLookupEntries(bc.chainDb, block); err != nil {
 				errs[index] = fmt.Errorf("failed to write lookup metadata: %v", err)
 				atomic.AddInt32(&failed, 1)
@@ -859,6 +924,92 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 	return
 }
 
+// WriteBlockAndState writes the block, its receipts and all associated state to
+// the database. The state is committed into the in-memory trie database and only
+// flushed to disk if the node is an archive one, or if the pruning limits are hit.
+func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+	// Commit all cached state changes into the underlying memory database
+	triedb := bc.stateCache.TrieDB()
+
+	root, err := state.CommitTo(triedb, bc.config.IsEIP158(block.Number()))
+	if err != nil {
+		return NonStatTy, err
+	}
+	if bc.cacheConfig.Disabled {
+		// Archive node, write every state trie to disk
+		if err := triedb.Commit(root, false); err != nil {
+			return NonStatTy, err
+		}
+	} else if err := bc.gcState(block, root); err != nil {
+		return NonStatTy, err
+	}
+	if err := WriteBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64(), receipts); err != nil {
+		return NonStatTy, err
+	}
+	return bc.WriteBlock(block)
+}
+
+// gcState keeps the state trie of a freshly written block alive in memory, flushes
+// a past trie to disk if the memory or time allowances are exceeded and garbage
+// collects all the tries that fell out of the retention window.
+func (bc *BlockChain) gcState(block *types.Block, root common.Hash) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	triedb := bc.stateCache.TrieDB()
+
+	triedb.Reference(root, common.Hash{}) // metadata reference to keep trie alive
+	bc.triegc.Push(root, -float32(block.NumberU64()))
+
+	current := block.NumberU64()
+	if current <= triesInMemory {
+		return nil
+	}
+	// Find the next state trie we need to commit
+	header := bc.GetHeaderByNumber(current - triesInMemory)
+	if header == nil {
+		return nil
+	}
+	chosen := header.Number.Uint64()
+
+	// Only write to disk if we exceeded our memory allowance *and* also have at
+	// least a given number of tries gapped.
+	var (
+		size  = triedb.Size()
+		limit = common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
+	)
+	if size > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+		// If we're exceeding limits but haven't reached a large enough memory gap,
+		// warn the user that the system is becoming unstable.
+		if chosen < bc.lastWrite+triesInMemory {
+			switch {
+			case size >= 2*limit:
+				log.Warn("State memory usage too high, committing", "size", size, "limit", limit, "optimum", float64(chosen-bc.lastWrite)/triesInMemory)
+			case bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit:
+				log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-bc.lastWrite)/triesInMemory)
+			}
+		}
+		// If optimum or critical limits reached, write to disk
+		if chosen >= bc.lastWrite+triesInMemory || size >= 2*limit || bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
+			if err := triedb.Commit(header.Root, true); err != nil {
+				return err
+			}
+			bc.lastWrite = chosen
+			bc.gcproc = 0
+		}
+	}
+	// Garbage collect anything below our required write retention
+	for !bc.triegc.Empty() {
+		root, number := bc.triegc.Pop()
+		if uint64(-number) > chosen {
+			bc.triegc.Push(root, number)
+			break
+		}
+		triedb.Dereference(root.(common.Hash))
+	}
+	return nil
+}
+
 // InsertChain will attempt to insert the given chain in to the canonical chain or, otherwise, create a fork. If an error is returned
 // it will return the index number of the failing block as well an error describing what went wrong (for possible errors see core/errors.go).
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
@@ -970,23 +1121,15 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			bc.reportBlock(block, receipts, err)
 			return i, err
 		}
-		// Write state changes to database
-		if _, err = state.CommitTo(bc.chainDb, bc.config.IsEIP158(block.Number())); err != nil {
-			return i, err
-		}
+		proctime := time.Since(bstart)
 
-		// coalesce logs for later processing
-		coalescedLogs = append(coalescedLogs, logs...)
-
-		if err = WriteBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64(), receipts); err != nil {
-			return i, err
-		}
-
-		// write the block to the chain and get the status
-		status, err := bc.WriteBlock(block)
+		// Write the block to the chain and get the status.
+		status, err := bc.WriteBlockAndState(block, receipts, state)
 		if err != nil {
 			return i, err
 		}
+		// coalesce logs for later processing
+		coalescedLogs = append(coalescedLogs, logs...)
 
 		switch status {
 		case CanonStatTy:
@@ -996,14 +1139,12 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			blockInsertTimer.UpdateSince(bstart)
 			events = append(events, ChainEvent{block, block.Hash(), logs})
 
+			bc.gcproc += proctime
+
 			// Write the positional metadata for transaction and receipt lookups
 			if err := WriteTxLookupEntries(bc.chainDb, block); err != nil {
 				return i, err
 			}
-			// Write map map bloom filters
-			if err := WriteMipmapBloom(bc.chainDb, block.NumberU64(), receipts); err != nil {
-				return i, err
-			}
 			// Write hash preimages
 			if err := WritePreimages(bc.chainDb, block.NumberU64(), state.Preimages()); err != nil {
 				return i, err
@@ -1161,11 +1302,6 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		if err := WriteTxLookupEntries(bc.chainDb, block); err != nil {
 			return err
 		}
-		// Write map map bloom filters
-		receipts := GetBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64())
-		if err := WriteMipmapBloom(bc.chainDb, block.NumberU64(), receipts); err != nil {
-			return err
-		}
 		addedTxs = append(addedTxs, block.Transactions()...)
 	}
 
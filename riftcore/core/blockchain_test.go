@@ -46,7 +46,7 @@ func newTestBlockChain(fake bool) *BlockChain {
 	if !fake {
 		engine = rifthash.NewTester()
 	}
-	blockchain, err := NewBlockChain(db, gspec.Config, engine, new(event.TypeMux), vm.Config{})
+	blockchain, err := NewBlockChain(db, nil, gspec.Config, engine, new(event.TypeMux), vm.Config{})
 	if err != nil {
 		panic(err)
 	}
@@ -479,7 +479,7 @@ func testReorgBadHashes(t *testing.T, full bool) {
 	}
 
 	// Create a new BlockChain and check that it rolled back the state.
-	ncm, err := NewBlockChain(bc.chainDb, bc.config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	ncm, err := NewBlockChain(bc.chainDb, nil, bc.config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 	if err != nil {
 		t.Fatalf("failed to create new chain manager: %v", err)
 	}
@@ -588,7 +588,7 @@ func TestFastVsFullChains(t *testing.T) {
 	// Import the chain as an archive node for the comparison baseline
 	archiveDb, _ := riftdb.NewMemDatabase()
 	gspec.MustCommit(archiveDb)
-	archive, _ := NewBlockChain(archiveDb, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	archive, _ := NewBlockChain(archiveDb, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 
 	if n, err := archive.InsertChain(blocks); err != nil {
 		t.Fatalf("failed to process block %d: %v", n, err)
@@ -597,7 +597,7 @@ func TestFastVsFullChains(t *testing.T) {
 	// Fast import the chain as a non-archive node to test
 	fastDb, _ := riftdb.NewMemDatabase()
 	gspec.MustCommit(fastDb)
-	fast, _ := NewBlockChain(fastDb, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	fast, _ := NewBlockChain(fastDb, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 
 	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
@@ -674,7 +674,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	archiveDb, _ := riftdb.NewMemDatabase()
 	gspec.MustCommit(archiveDb)
 
-	archive, _ := NewBlockChain(archiveDb, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	archive, _ := NewBlockChain(archiveDb, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 	if n, err := archive.InsertChain(blocks); err != nil {
 		t.Fatalf("failed to process block %d: %v", n, err)
 	}
@@ -685,7 +685,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	// Import the chain as a non-archive node and ensure all pointers are updated
 	fastDb, _ := riftdb.NewMemDatabase()
 	gspec.MustCommit(fastDb)
-	fast, _ := NewBlockChain(fastDb, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	fast, _ := NewBlockChain(fastDb, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 
 	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
@@ -705,7 +705,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	lightDb, _ := riftdb.NewMemDatabase()
 	gspec.MustCommit(lightDb)
 
-	light, _ := NewBlockChain(lightDb, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	light, _ := NewBlockChain(lightDb, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 	if n, err := light.InsertHeaderChain(headers, 1); err != nil {
 		t.Fatalf("failed to insert header %d: %v", n, err)
 	}
@@ -773,7 +773,7 @@ func TestChainTxReorgs(t *testing.T) {
 	})
 	// Import the chain. This runs all block validation rules.
 	evmux := &event.TypeMux{}
-	blockchain, _ := NewBlockChain(db, gspec.Config, rifthash.NewFaker(), evmux, vm.Config{})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), evmux, vm.Config{})
 	if i, err := blockchain.InsertChain(chain); err != nil {
 		t.Fatalf("failed to insert original chain[%d]: %v", i, err)
 	}
@@ -844,7 +844,7 @@ func TestLogReorgs(t *testing.T) {
 	)
 
 	var evmux event.TypeMux
-	blockchain, _ := NewBlockChain(db, gspec.Config, rifthash.NewFaker(), &evmux, vm.Config{})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), &evmux, vm.Config{})
 
 	subs := evmux.Subscribe(RemovedLogsEvent{})
 	chain, _ := GenerateChain(params.TestChainConfig, genesis, db, 2, func(i int, gen *BlockGen) {
@@ -885,7 +885,7 @@ func TestReorgSideEvent(t *testing.T) {
 	)
 
 	evmux := &event.TypeMux{}
-	blockchain, _ := NewBlockChain(db, gspec.Config, rifthash.NewFaker(), evmux, vm.Config{})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), evmux, vm.Config{})
 
 	chain, _ := GenerateChain(gspec.Config, genesis, db, 3, func(i int, gen *BlockGen) {})
 	if _, err := blockchain.InsertChain(chain); err != nil {
@@ -1000,7 +1000,7 @@ func TestEIP155Transition(t *testing.T) {
 		mux     event.TypeMux
 	)
 
-	blockchain, _ := NewBlockChain(db, gspec.Config, rifthash.NewFaker(), &mux, vm.Config{})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), &mux, vm.Config{})
 	blocks, _ := GenerateChain(gspec.Config, genesis, db, 4, func(i int, block *BlockGen) {
 		var (
 			tx      *types.Transaction
@@ -1106,7 +1106,7 @@ func TestEIP161AccountRemoval(t *testing.T) {
 		}
 		genesis       = gspec.MustCommit(db)
 		mux           event.TypeMux
-		blockchain, _ = NewBlockChain(db, gspec.Config, rifthash.NewFaker(), &mux, vm.Config{})
+		blockchain, _ = NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), &mux, vm.Config{})
 	)
 	blocks, _ := GenerateChain(gspec.Config, genesis, db, 3, func(i int, block *BlockGen) {
 		var (
@@ -1151,3 +1151,55 @@ func TestEIP161AccountRemoval(t *testing.T) {
 		t.Error("account should not exist")
 	}
 }
+
+// Tests that importing a long chain in pruning mode garbage collects the state
+// of old blocks, while an archive node keeps every state trie on disk.
+func TestTrieGC(t *testing.T) {
+	testTrieGC(t, false)
+	testTrieGC(t, true)
+}
+
+func testTrieGC(t *testing.T, archive bool) {
+	// Generate a canonical chain long enough to push states out of the retention window
+	var (
+		gendb, _ = riftdb.NewMemDatabase()
+		gspec    = &Genesis{Config: params.TestChainConfig}
+		genesis  = gspec.MustCommit(gendb)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, gendb, 2*triesInMemory, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{1})
+	})
+	// Import the chain into a fresh database and ensure old states are pruned
+	db, _ := riftdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	var cacheConfig *CacheConfig
+	if archive {
+		cacheConfig = &CacheConfig{Disabled: true}
+	}
+	chain, err := NewBlockChain(db, cacheConfig, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	for i, block := range blocks {
+		_, err := state.New(block.Root(), chain.StateCache())
+		switch {
+		case archive || i >= len(blocks)-triesInMemory:
+			if err != nil {
+				t.Errorf("archive %v: block #%d: state missing: %v", archive, block.Number(), err)
+			}
+		default:
+			if err == nil {
+				t.Errorf("archive %v: block #%d: state not garbage collected", archive, block.Number())
+			}
+		}
+	}
+	// Stop the chain and ensure the head state is persisted to disk
+	chain.Stop()
+	if _, err := state.New(chain.CurrentBlock().Root(), state.NewDatabase(db)); err != nil {
+		t.Errorf("archive %v: head state not persisted on stop: %v", archive, err)
+	}
+}
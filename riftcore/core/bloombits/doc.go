@@ -0,0 +1,24 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bloombits implements bloom filtering on batches of data.
+//
+// Instead of checking the header bloom of every single block, the blooms of an
+// entire section of blocks are rotated into per-bit vectors: the i-th vector of
+// a section contains the i-th bloom bit of every block in that section. Looking
+// up a key then only requires loading and AND-ing the three bit vectors that the
+// key's bloom bits map to, yielding a bitset of candidate blocks.
+package bloombits
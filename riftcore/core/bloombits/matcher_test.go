@@ -0,0 +1,136 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package bloombits
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+)
+
+const testSectionSize = 64
+
+// makeTestBlooms creates the header blooms of a number of sections, inserting
+// the given keys into the blooms of the blocks selected by the callback.
+func makeTestBlooms(sections int, keys [][]byte, include func(block uint64, key int) bool) []types.Bloom {
+	blooms := make([]types.Bloom, sections*testSectionSize)
+	for i := range blooms {
+		bin := new(big.Int)
+		for j, key := range keys {
+			if include(uint64(i), j) {
+				bin.Or(bin, types.LogsBloom([]*types.Log{{Address: common.BytesToAddress(key)}}))
+			}
+		}
+		blooms[i] = types.BytesToBloom(bin.Bytes())
+	}
+	return blooms
+}
+
+// makeTestBitsets rotates a list of header blooms into per-section bit vectors.
+func makeTestBitsets(t *testing.T, blooms []types.Bloom) [][types.BloomBitLength][]byte {
+	bitsets := make([][types.BloomBitLength][]byte, len(blooms)/testSectionSize)
+	for section := range bitsets {
+		gen, err := NewGenerator(testSectionSize)
+		if err != nil {
+			t.Fatalf("failed to create generator: %v", err)
+		}
+		for i := 0; i < testSectionSize; i++ {
+			gen.AddBloom(uint(i), blooms[section*testSectionSize+i])
+		}
+		for bit := 0; bit < types.BloomBitLength; bit++ {
+			bitsets[section][bit], _ = gen.Bitset(uint(bit))
+		}
+	}
+	return bitsets
+}
+
+// Tests that the matcher pipeline returns exactly the blocks whose bloom filters
+// match the requested address set AND-ed with the topic set.
+func TestMatcher(t *testing.T) {
+	var (
+		addr1 = common.BytesToAddress([]byte("address 1")).Bytes()
+		addr2 = common.BytesToAddress([]byte("address 2")).Bytes()
+		topic = common.BytesToAddress([]byte("topic")).Bytes()
+	)
+	blooms := makeTestBlooms(4, [][]byte{addr1, addr2, topic}, func(block uint64, key int) bool {
+		switch key {
+		case 0:
+			return block%5 == 0
+		case 1:
+			return block%7 == 0
+		default:
+			return block%3 == 0
+		}
+	})
+	bitsets := makeTestBitsets(t, blooms)
+
+	matcher := NewMatcher(testSectionSize, [][][]byte{{addr1, addr2}, {topic}, nil})
+
+	// Run the matcher over a range crossing section boundaries
+	var (
+		begin, end = uint64(10), uint64(4*testSectionSize - 20)
+		results    = make(chan uint64, 16)
+		requests   = make(chan chan *Retrieval)
+	)
+	session, err := matcher.Start(context.Background(), begin, end, results)
+	if err != nil {
+		t.Fatalf("failed to start matcher session: %v", err)
+	}
+	defer session.Close()
+
+	go session.Multiplex(4, time.Millisecond, requests)
+	go func() {
+		for {
+			select {
+			case request := <-requests:
+				task := <-request
+				task.Bitsets = make([][]byte, len(task.Sections))
+				for i, section := range task.Sections {
+					task.Bitsets[i] = bitsets[section][task.Bit]
+				}
+				request <- task
+			case <-session.quit:
+				return
+			}
+		}
+	}()
+	var have []uint64
+	for number := range results {
+		have = append(have, number)
+	}
+	var want []uint64
+	for number := begin; number <= end; number++ {
+		bloom := blooms[number]
+		if (types.BloomLookup(bloom, common.BytesToAddress(addr1)) || types.BloomLookup(bloom, common.BytesToAddress(addr2))) && types.BloomLookup(bloom, common.BytesToAddress(topic)) {
+			want = append(want, number)
+		}
+	}
+	if len(want) == 0 {
+		t.Fatalf("test setup produced no matches")
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("match mismatch:\nhave %v\nwant %v", have, want)
+	}
+	if err := session.Error(); err != nil {
+		t.Errorf("session failed: %v", err)
+	}
+}
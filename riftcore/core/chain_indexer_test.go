@@ -0,0 +1,130 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+// testChainIndexBackend implements ChainIndexerBackend, verifying that sections
+// are processed sequentially and with continuous headers.
+type testChainIndexBackend struct {
+	size      uint64      // Section size to validate processed headers against
+	section   uint64      // Section currently being processed
+	processed chan uint64 // Notification channel for committed sections
+}
+
+func (b *testChainIndexBackend) Reset(section uint64, prevHead common.Hash) error {
+	b.section = section
+	return nil
+}
+
+func (b *testChainIndexBackend) Process(header *types.Header) error {
+	if number := header.Number.Uint64(); number/b.size != b.section {
+		return fmt.Errorf("header #%d outside of section %d", number, b.section)
+	}
+	return nil
+}
+
+func (b *testChainIndexBackend) Commit() error {
+	b.processed <- b.section
+	return nil
+}
+
+// writeTestHeaders writes a canonical chain of headers into the database,
+// returning them for later reference.
+func writeTestHeaders(t *testing.T, db riftdb.Database, count int) []*types.Header {
+	var (
+		headers []*types.Header
+		parent  common.Hash
+	)
+	for i := 0; i < count; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Extra: []byte("indexer test")}
+		if err := WriteHeader(db, header); err != nil {
+			t.Fatalf("failed to write header %d: %v", i, err)
+		}
+		if err := WriteCanonicalHash(db, header.Hash(), header.Number.Uint64()); err != nil {
+			t.Fatalf("failed to write canonical hash %d: %v", i, err)
+		}
+		headers, parent = append(headers, header), header.Hash()
+	}
+	return headers
+}
+
+// Tests that the chain indexer processes confirmed sections in order, reverts
+// them on reorgs and cascades the results to child indexers.
+func TestChainIndexer(t *testing.T) {
+	db, _ := riftdb.NewMemDatabase()
+	headers := writeTestHeaders(t, db, 64)
+
+	var (
+		parent = &testChainIndexBackend{size: 8, processed: make(chan uint64, 16)}
+		child  = &testChainIndexBackend{size: 16, processed: make(chan uint64, 16)}
+	)
+	indexer := NewChainIndexer(db, riftdb.NewTable(db, "p-"), parent, 8, 4, 0, "parent")
+	defer indexer.Close()
+
+	indexer.AddChildIndexer(NewChainIndexer(db, riftdb.NewTable(db, "c-"), child, 16, 0, 0, "child"))
+
+	// expect waits for the given sections to be processed by a backend
+	expect := func(backend *testChainIndexBackend, sections ...uint64) {
+		for _, want := range sections {
+			select {
+			case have := <-backend.processed:
+				if have != want {
+					t.Fatalf("processed section mismatch: have %d, want %d", have, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("section %d processing timeout", want)
+			}
+		}
+	}
+	// wait waits for the parent indexer to mark the given number of sections valid
+	wait := func(sections uint64) {
+		for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+			if have, _, _ := indexer.Sections(); have == sections {
+				return
+			}
+		}
+		t.Fatalf("indexed section count timeout: want %d", sections)
+	}
+	// Notify the indexer of a new head, only fully confirmed sections are processed
+	indexer.newHead(39, false)
+	expect(parent, 0, 1, 2, 3)
+	expect(child, 0, 1)
+	wait(4)
+
+	if sections, head, hash := indexer.Sections(); sections != 4 || head != 31 || hash != headers[31].Hash() {
+		t.Fatalf("indexed sections mismatch: have %d/#%d [%x], want %d/#%d [%x]", sections, head, hash[:4], 4, 31, headers[31].Hash().Bytes()[:4])
+	}
+	// Roll back into the second section and ensure it gets invalidated
+	indexer.newHead(12, true)
+	if sections, _, _ := indexer.Sections(); sections != 1 {
+		t.Fatalf("reverted sections mismatch: have %d, want %d", sections, 1)
+	}
+	// Advance the head again and ensure sections are reprocessed
+	indexer.newHead(63, false)
+	expect(parent, 1, 2, 3, 4, 5, 6)
+	expect(child, 0, 1, 2)
+}
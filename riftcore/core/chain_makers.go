@@ -236,7 +236,7 @@ func newCanonical(n int, full bool) (riftdb.Database, *BlockChain, error) {
 	db, _ := riftdb.NewMemDatabase()
 	genesis := gspec.MustCommit(db)
 
-	blockchain, _ := NewBlockChain(db, params.AllProtocolChanges, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	blockchain, _ := NewBlockChain(db, nil, params.AllProtocolChanges, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
 	// Create and inject the requested chain
 	if n == 0 {
 		return db, blockchain, nil
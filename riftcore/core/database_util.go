@@ -23,7 +23,6 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"sync"
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/core/types"
@@ -48,8 +47,11 @@ var (
 	lookupPrefix        = []byte("l")   // lookupPrefix + hash -> transaction/receipt lookup metadata
 	preimagePrefix      = "secure-key-" // preimagePrefix + hash -> preimage
 
-	mipmapPre    = []byte("mipmap-log-bloom-")
-	MIPMapLevels = []uint64{1000000, 500000, 100000, 50000, 1000}
+	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+
+	// BloomBitsIndexPrefix is the data table prefix of the bloom bits chain indexer
+	// to track its own progress.
+	BloomBitsIndexPrefix = []byte("iB")
 
 	configPrefix = []byte("cryptorift-config-") // config prefix for the db
 
@@ -59,15 +61,13 @@ var (
 
 	ErrChainConfigNotFound = errors.New("ChainConfig not found") // general config not found error
 
-	mipmapBloomMu sync.Mutex // protect against race condition when updating mipmap blooms
-
 	preimageCounter    = metrics.NewCounter("db/preimage/total")
 	preimageHitCounter = metrics.NewCounter("db/preimage/hits")
 )
 
-// txLookupEntry is a positional metadata to help looking up the data content of
+// TxLookupEntry is a positional metadata to help looking up the data content of
 // a transaction or receipt given only its hash.
-type txLookupEntry struct {
+type TxLookupEntry struct {
 	BlockHash  common.Hash
 	BlockIndex uint64
 	Index      uint64
@@ -84,11 +84,30 @@ func encodeBlockNumber(number uint64) []byte {
 func GetCanonicalHash(db riftdb.Database, number uint64) common.Hash {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
 	if len(data) == 0 {
-		return common.Hash{}
+		if frdb, ok := db.(AncientReader); ok {
+			data, _ = frdb.Ancient(freezerHashTable, number)
+		}
+		if len(data) == 0 {
+			return common.Hash{}
+		}
 	}
 	return common.BytesToHash(data)
 }
 
+// getAncient retrieves a blob of the given kind from the ancient store, if the
+// database has one and the frozen canonical block at number matches hash.
+func getAncient(db riftdb.Database, kind string, hash common.Hash, number uint64) []byte {
+	frdb, ok := db.(AncientReader)
+	if !ok || !frdb.HasAncient(kind, number) {
+		return nil
+	}
+	if frozen, _ := frdb.Ancient(freezerHashTable, number); common.BytesToHash(frozen) != hash {
+		return nil
+	}
+	data, _ := frdb.Ancient(kind, number)
+	return data
+}
+
 // missingNumber is returned by GetBlockNumber if no header with the
 // given block hash has been stored in the database
 const missingNumber = uint64(0xffffffffffffffff)
@@ -141,6 +160,9 @@ func GetHeadFastBlockHash(db riftdb.Database) common.Hash {
 // if the header's not found.
 func GetHeaderRLP(db riftdb.Database, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		data = getAncient(db, freezerHeaderTable, hash, number)
+	}
 	return data
 }
 
@@ -162,6 +184,9 @@ func GetHeader(db riftdb.Database, hash common.Hash, number uint64) *types.Heade
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db riftdb.Database, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		data = getAncient(db, freezerBodiesTable, hash, number)
+	}
 	return data
 }
 
@@ -183,7 +208,7 @@ func GetBody(db riftdb.Database, hash common.Hash, number uint64) *types.Body {
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db riftdb.Database, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	data := getTdRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -195,6 +220,15 @@ func GetTd(db riftdb.Database, hash common.Hash, number uint64) *big.Int {
 	return td
 }
 
+// getTdRLP retrieves a block's total difficulty in its raw RLP database encoding.
+func getTdRLP(db riftdb.Database, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	if len(data) == 0 {
+		data = getAncient(db, freezerDifficultyTable, hash, number)
+	}
+	return data
+}
+
 // GetBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -218,7 +252,7 @@ func GetBlock(db riftdb.Database, hash common.Hash, number uint64) *types.Block
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db riftdb.Database, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data := getBlockReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -234,6 +268,16 @@ func GetBlockReceipts(db riftdb.Database, hash common.Hash, number uint64) types
 	return receipts
 }
 
+// getBlockReceiptsRLP retrieves the receipts of a block in their raw RLP storage
+// encoding.
+func getBlockReceiptsRLP(db riftdb.Database, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		data = getAncient(db, freezerReceiptTable, hash, number)
+	}
+	return data
+}
+
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func GetTxLookupEntry(db riftdb.Database, hash common.Hash) (common.Hash, uint64, uint64) {
@@ -243,7 +287,7 @@ func GetTxLookupEntry(db riftdb.Database, hash common.Hash) (common.Hash, uint64
 		return common.Hash{}, 0, 0
 	}
 	// Parse and return the contents of the lookup entry
-	var entry txLookupEntry
+	var entry TxLookupEntry
 	if err := rlp.DecodeBytes(data, &entry); err != nil {
 		log.Error("Invalid lookup entry RLP", "hash", hash, "err", err)
 		return common.Hash{}, 0, 0
@@ -279,7 +323,7 @@ func GetTransaction(db riftdb.Database, hash common.Hash) (*types.Transaction, c
 	if len(data) == 0 {
 		return nil, common.Hash{}, 0, 0
 	}
-	var entry txLookupEntry
+	var entry TxLookupEntry
 	if err := rlp.DecodeBytes(data, &entry); err != nil {
 		return nil, common.Hash{}, 0, 0
 	}
@@ -438,7 +482,7 @@ func WriteTxLookupEntries(db riftdb.Database, block *types.Block) error {
 
 	// Iterate over each transaction and encode its metadata
 	for i, tx := range block.Transactions() {
-		entry := txLookupEntry{
+		entry := TxLookupEntry{
 			BlockHash:  block.Hash(),
 			BlockIndex: block.NumberU64(),
 			Index:      uint64(i),
@@ -466,6 +510,12 @@ func DeleteCanonicalHash(db riftdb.Database, number uint64) {
 // DeleteHeader removes all block header data associated with a hash.
 func DeleteHeader(db riftdb.Database, hash common.Hash, number uint64) {
 	db.Delete(append(blockHashPrefix, hash.Bytes()...))
+	deleteHeaderWithoutNumber(db, hash, number)
+}
+
+// deleteHeaderWithoutNumber removes the block header but keeps the hash to
+// number mapping around.
+func deleteHeaderWithoutNumber(db riftdb.Database, hash common.Hash, number uint64) {
 	db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
@@ -497,48 +547,32 @@ func DeleteTxLookupEntry(db riftdb.Database, hash common.Hash) {
 	db.Delete(append(lookupPrefix, hash.Bytes()...))
 }
 
-// returns a formatted MIP mapped key by adding prefix, canonical number and level
-//
-// ex. fn(98, 1000) = (prefix || 1000 || 0)
-func mipmapKey(num, level uint64) []byte {
-	lkey := make([]byte, 8)
-	binary.BigEndian.PutUint64(lkey, level)
-	key := new(big.Int).SetUint64(num / level * level)
+// bloomBitsKey returns the database key of the compressed bit vector belonging
+// to the given bloom bit of a section, identified by the hash of its last header.
+func bloomBitsKey(bit uint, section uint64, head common.Hash) []byte {
+	key := append(append([]byte{}, bloomBitsPrefix...), make([]byte, 10)...)
+
+	binary.BigEndian.PutUint16(key[1:], uint16(bit))
+	binary.BigEndian.PutUint64(key[3:], section)
 
-	return append(mipmapPre, append(lkey, key.Bytes()...)...)
+	return append(key, head.Bytes()...)
 }
 
-// WriteMipmapBloom writes each address included in the receipts' logs to the
-// MIP bloom bin.
-func WriteMipmapBloom(db riftdb.Database, number uint64, receipts types.Receipts) error {
-	mipmapBloomMu.Lock()
-	defer mipmapBloomMu.Unlock()
+// GetBloomBits retrieves the compressed bloom bit vector belonging to the given
+// section and bit index from the database.
+func GetBloomBits(db riftdb.Database, bit uint, section uint64, head common.Hash) ([]byte, error) {
+	return db.Get(bloomBitsKey(bit, section, head))
+}
 
-	batch := db.NewBatch()
-	for _, level := range MIPMapLevels {
-		key := mipmapKey(number, level)
-		bloomDat, _ := db.Get(key)
-		bloom := types.BytesToBloom(bloomDat)
-		for _, receipt := range receipts {
-			for _, log := range receipt.Logs {
-				bloom.Add(log.Address.Big())
-			}
-		}
-		batch.Put(key, bloom.Bytes())
-	}
-	if err := batch.Write(); err != nil {
-		return fmt.Errorf("mipmap write fail for: %d: %v", number, err)
+// WriteBloomBits writes the compressed bloom bit vector belonging to the given
+// section and bit index into the database.
+func WriteBloomBits(db riftdb.Database, bit uint, section uint64, head common.Hash, bits []byte) error {
+	if err := db.Put(bloomBitsKey(bit, section, head), bits); err != nil {
+		return fmt.Errorf("failed to store bloom bits for section %d, bit %d: %v", section, bit, err)
 	}
 	return nil
 }
 
-// GetMipmapBloom returns a bloom filter using the number and level as input
-// parameters. For available levels see MIPMapLevels.
-func GetMipmapBloom(db riftdb.Database, number, level uint64) types.Bloom {
-	bloomDat, _ := db.Get(mipmapKey(number, level))
-	return types.BytesToBloom(bloomDat)
-}
-
 // PreimageTable returns a Database instance with the key prefix for preimage entries.
 func PreimageTable(db riftdb.Database) riftdb.Database {
 	return riftdb.NewTable(db, preimagePrefix)
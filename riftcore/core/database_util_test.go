@@ -18,17 +18,13 @@ package core
 
 import (
 	"bytes"
-	"io/ioutil"
 	"math/big"
-	"os"
 	"testing"
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/core/types"
-	"github.com/cryptorift/riftcore/crypto"
 	"github.com/cryptorift/riftcore/crypto/sha3"
 	"github.com/cryptorift/riftcore/riftdb"
-	"github.com/cryptorift/riftcore/params"
 	"github.com/cryptorift/riftcore/rlp"
 )
 
@@ -340,7 +336,7 @@ func TestBlockReceiptStorage(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 
 	receipt1 := &types.Receipt{
-		PostState:         []byte{0x01},
+		Status:            types.ReceiptStatusFailed,
 		CumulativeGasUsed: big.NewInt(1),
 		Logs: []*types.Log{
 			{Address: common.BytesToAddress([]byte{0x11})},
@@ -351,7 +347,7 @@ func TestBlockReceiptStorage(t *testing.T) {
 		GasUsed:         big.NewInt(111111),
 	}
 	receipt2 := &types.Receipt{
-		PostState:         []byte{0x02},
+		PostState:         common.Hash{2}.Bytes(),
 		CumulativeGasUsed: big.NewInt(2),
 		Logs: []*types.Log{
 			{Address: common.BytesToAddress([]byte{0x22})},
@@ -391,106 +387,32 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
-func TestMipmapBloom(t *testing.T) {
+// Tests that bloom bit vectors are stored and retrieved per section and head.
+func TestBloomBitsStorage(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 
-	receipt1 := new(types.Receipt)
-	receipt1.Logs = []*types.Log{
-		{Address: common.BytesToAddress([]byte("test"))},
-		{Address: common.BytesToAddress([]byte("address"))},
+	var (
+		head  = common.Hash{0x01}
+		other = common.Hash{0x02}
+		bits  = []byte("compressed bloom bits")
+	)
+	if blob, _ := GetBloomBits(db, 42, 1, head); len(blob) != 0 {
+		t.Fatalf("non existent bloom bits returned: %x", blob)
 	}
-	receipt2 := new(types.Receipt)
-	receipt2.Logs = []*types.Log{
-		{Address: common.BytesToAddress([]byte("test"))},
-		{Address: common.BytesToAddress([]byte("address1"))},
+	if err := WriteBloomBits(db, 42, 1, head, bits); err != nil {
+		t.Fatalf("failed to write bloom bits: %v", err)
 	}
-
-	WriteMipmapBloom(db, 1, types.Receipts{receipt1})
-	WriteMipmapBloom(db, 2, types.Receipts{receipt2})
-
-	for _, level := range MIPMapLevels {
-		bloom := GetMipmapBloom(db, 2, level)
-		if !bloom.Test(new(big.Int).SetBytes([]byte("address1"))) {
-			t.Error("expected test to be included on level:", level)
-		}
+	if blob, err := GetBloomBits(db, 42, 1, head); err != nil || !bytes.Equal(blob, bits) {
+		t.Fatalf("bloom bits mismatch: have %x, want %x, err %v", blob, bits, err)
 	}
-
-	// reset
-	db, _ = riftdb.NewMemDatabase()
-	receipt := new(types.Receipt)
-	receipt.Logs = []*types.Log{
-		{Address: common.BytesToAddress([]byte("test"))},
+	// Make sure different bits, sections and section heads are not mixed up
+	if blob, _ := GetBloomBits(db, 43, 1, head); len(blob) != 0 {
+		t.Errorf("bloom bits returned for different bit: %x", blob)
 	}
-	WriteMipmapBloom(db, 999, types.Receipts{receipt1})
-
-	receipt = new(types.Receipt)
-	receipt.Logs = []*types.Log{
-		{Address: common.BytesToAddress([]byte("test 1"))},
+	if blob, _ := GetBloomBits(db, 42, 2, head); len(blob) != 0 {
+		t.Errorf("bloom bits returned for different section: %x", blob)
 	}
-	WriteMipmapBloom(db, 1000, types.Receipts{receipt})
-
-	bloom := GetMipmapBloom(db, 1000, 1000)
-	if bloom.TestBytes([]byte("test")) {
-		t.Error("test should not have been included")
-	}
-}
-
-func TestMipmapChain(t *testing.T) {
-	dir, err := ioutil.TempDir("", "mipmap")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(dir)
-
-	var (
-		db, _   = riftdb.NewLDBDatabase(dir, 0, 0)
-		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
-		addr    = crypto.PubkeyToAddress(key1.PublicKey)
-		addr2   = common.BytesToAddress([]byte("jeff"))
-
-		hash1 = common.BytesToHash([]byte("topic1"))
-	)
-	defer db.Close()
-
-	gspec := &Genesis{
-		Config: params.TestChainConfig,
-		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(1000000)}},
-	}
-	genesis := gspec.MustCommit(db)
-	chain, receipts := GenerateChain(params.TestChainConfig, genesis, db, 1010, func(i int, gen *BlockGen) {
-		var receipts types.Receipts
-		switch i {
-		case 1:
-			receipt := types.NewReceipt(nil, new(big.Int))
-			receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{hash1}}}
-			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
-		case 1000:
-			receipt := types.NewReceipt(nil, new(big.Int))
-			receipt.Logs = []*types.Log{{Address: addr2}}
-			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
-
-		}
-
-		// store the receipts
-		WriteMipmapBloom(db, uint64(i+1), receipts)
-	})
-	for i, block := range chain {
-		WriteBlock(db, block)
-		if err := WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
-			t.Fatalf("failed to insert block number: %v", err)
-		}
-		if err := WriteHeadBlockHash(db, block.Hash()); err != nil {
-			t.Fatalf("failed to insert block number: %v", err)
-		}
-		if err := WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i]); err != nil {
-			t.Fatal("error writing block receipts:", err)
-		}
-	}
-
-	bloom := GetMipmapBloom(db, 0, 1000)
-	if bloom.TestBytes(addr2[:]) {
-		t.Error("address was included in bloom and should not have")
+	if blob, _ := GetBloomBits(db, 42, 1, other); len(blob) != 0 {
+		t.Errorf("bloom bits returned for different section head: %x", blob)
 	}
 }
@@ -24,6 +24,23 @@ import (
 // TxPreEvent is posted when a transaction enters the transaction pool.
 type TxPreEvent struct{ Tx *types.Transaction }
 
+// TxChange is the kind of transition a transaction underwent in the pool.
+type TxChange string
+
+const (
+	TxPromoted TxChange = "promoted" // Transaction moved into the executable set
+	TxReplaced TxChange = "replaced" // Transaction superseded by a higher priced one
+	TxDropped  TxChange = "dropped"  // Transaction removed from the pool
+)
+
+// TxPoolEvent is sent when a transaction is promoted, replaced or dropped in
+// the transaction pool. Reason is set for dropped transactions only.
+type TxPoolEvent struct {
+	Tx     *types.Transaction
+	Change TxChange
+	Reason error
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
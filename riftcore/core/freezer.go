@@ -0,0 +1,433 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+const (
+	// freezerHeaderTable indicates the name of the freezer header table.
+	freezerHeaderTable = "headers"
+
+	// freezerHashTable indicates the name of the freezer canonical hash table.
+	freezerHashTable = "hashes"
+
+	// freezerBodiesTable indicates the name of the freezer block body table.
+	freezerBodiesTable = "bodies"
+
+	// freezerReceiptTable indicates the name of the freezer receipts table.
+	freezerReceiptTable = "receipts"
+
+	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
+	freezerDifficultyTable = "diffs"
+)
+
+// freezerNoSnappy configures whether compression is disabled for the ancient
+// tables. Hashes and difficulties don't compress well.
+var freezerNoSnappy = map[string]bool{
+	freezerHeaderTable:     false,
+	freezerHashTable:       true,
+	freezerBodiesTable:     false,
+	freezerReceiptTable:    false,
+	freezerDifficultyTable: true,
+}
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+// errUnknownTable is returned if the user attempts to read from a table that is
+// not tracked by the freezer.
+var errUnknownTable = errors.New("unknown table")
+
+// AncientReader is implemented by chain databases that keep immutable chain
+// segments in an append-only ancient store next to the key-value database.
+type AncientReader interface {
+	// HasAncient returns an indicator whether the specified data exists in the
+	// ancient store.
+	HasAncient(kind string, number uint64) bool
+
+	// Ancient retrieves an ancient binary blob from the append-only immutable files.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// Ancients returns the number of items frozen in the ancient store.
+	Ancients() uint64
+}
+
+// freezer is an append-only database to store immutable chain data into flat
+// files:
+//
+// - The append only nature ensures that disk writes are minimized.
+// - The in-order data ensures that disk reads are always optimized.
+type freezer struct {
+	frozen uint64 // Number of blocks already frozen (atomically accessed)
+
+	tables map[string]*freezerTable // Data tables for storing everything
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newFreezer creates a chain freezer that moves ancient chain data into
+// append-only flat file containers.
+func newFreezer(datadir string) (*freezer, error) {
+	freezer := &freezer{
+		tables: make(map[string]*freezerTable),
+		quit:   make(chan struct{}),
+	}
+	for name, disableSnappy := range freezerNoSnappy {
+		table, err := newTable(datadir, name, !disableSnappy)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		for _, table := range freezer.tables {
+			table.Close()
+		}
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir, "frozen", freezer.frozen)
+	return freezer, nil
+}
+
+// repair truncates all data tables to the same length, in case a crash left
+// them partially appended.
+func (f *freezer) repair() error {
+	min := uint64(1<<64 - 1)
+	for _, table := range f.tables {
+		if items := table.Items(); items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// Close terminates the chain freezer, closing all the data files.
+func (f *freezer) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the freezer.
+func (f *freezer) HasAncient(kind string, number uint64) bool {
+	if table := f.tables[kind]; table != nil {
+		return number < table.Items()
+	}
+	return false
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the length of the frozen items.
+func (f *freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// append-only immutable table files.
+//
+// Notably, this function is lock free but kind of thread-safe. All out-of-order
+// injection will be rejected. But if two injections with same number happen at
+// the same time, we can get into the trouble.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	// Ensure the binary blobs we are appending is continuous with freezer.
+	if atomic.LoadUint64(&f.frozen) != number {
+		return errOutOfOrder
+	}
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't out of sync.
+	defer func() {
+		if err != nil {
+			if err := f.truncate(number); err != nil {
+				log.Error("Failed to rollback ancient data", "number", number, "err", err)
+			}
+		}
+	}()
+	blobs := map[string][]byte{
+		freezerHashTable:       hash,
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}
+	for kind, blob := range blobs {
+		if err := f.tables[kind].Append(number, blob); err != nil {
+			log.Error("Failed to append ancient data", "table", kind, "number", number, "err", err)
+			return err
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1) // Only modify atomically
+	return nil
+}
+
+// truncate discards any recent data above the provided threshold number.
+func (f *freezer) truncate(items uint64) error {
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all data tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the fast database into the freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *freezer) freeze(db riftdb.Database) {
+	defer f.wg.Done()
+
+	backoff := false
+	for {
+		select {
+		case <-f.quit:
+			log.Info("Freezer shutting down")
+			return
+		default:
+		}
+		if backoff {
+			select {
+			case <-time.NewTimer(freezerRecheckInterval).C:
+				backoff = false
+			case <-f.quit:
+				return
+			}
+		}
+		// Retrieve the freezing threshold from the current head block
+		hash := GetHeadBlockHash(db)
+		if hash == (common.Hash{}) {
+			log.Debug("Current full block hash unavailable") // new chain, empty database
+			backoff = true
+			continue
+		}
+		number := GetBlockNumber(db, hash)
+		switch {
+		case number == missingNumber:
+			log.Error("Current full block number unavailable", "hash", hash)
+			backoff = true
+			continue
+
+		case number < params.ImmutabilityThreshold:
+			log.Debug("Current full block not old enough", "number", number, "hash", hash, "delay", params.ImmutabilityThreshold)
+			backoff = true
+			continue
+
+		case number-params.ImmutabilityThreshold <= f.Ancients():
+			log.Debug("Ancient blocks frozen already", "number", number, "hash", hash, "frozen", f.Ancients())
+			backoff = true
+			continue
+		}
+		// Seems we have data ready to be frozen, process in usable batches
+		limit := number - params.ImmutabilityThreshold
+		if limit-f.Ancients() > freezerBatchLimit {
+			limit = f.Ancients() + freezerBatchLimit
+		}
+		if err := f.freezeRange(db, limit); err != nil {
+			backoff = true
+		}
+	}
+}
+
+// freezeRange moves all the canonical blocks below limit from the key-value
+// database into the freezer, deleting them from the fast store afterwards.
+func (f *freezer) freezeRange(db riftdb.Database, limit uint64) error {
+	var (
+		start    = time.Now()
+		first    = f.Ancients()
+		ancients = make([]common.Hash, 0, limit-first)
+	)
+	for f.Ancients() < limit {
+		// Retrieves all the components of the canonical block
+		number := f.Ancients()
+		hash := GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			log.Error("Canonical hash missing, can't freeze", "number", number)
+			break
+		}
+		header := GetHeaderRLP(db, hash, number)
+		if len(header) == 0 {
+			log.Error("Block header missing, can't freeze", "number", number, "hash", hash)
+			break
+		}
+		body := GetBodyRLP(db, hash, number)
+		if len(body) == 0 {
+			log.Error("Block body missing, can't freeze", "number", number, "hash", hash)
+			break
+		}
+		receipts := getBlockReceiptsRLP(db, hash, number)
+		if len(receipts) == 0 {
+			log.Error("Block receipts missing, can't freeze", "number", number, "hash", hash)
+			break
+		}
+		td := getTdRLP(db, hash, number)
+		if len(td) == 0 {
+			log.Error("Total difficulty missing, can't freeze", "number", number, "hash", hash)
+			break
+		}
+		log.Trace("Deep froze ancient block", "number", number, "hash", hash)
+
+		// Inject all the components into the relevant data tables
+		if err := f.AppendAncient(number, hash[:], header, body, receipts, td); err != nil {
+			break
+		}
+		ancients = append(ancients, hash)
+	}
+	// Batch of blocks have been frozen, flush them before wiping from leveldb
+	if err := f.Sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	// Wipe out all data from the active database, always keeping the genesis
+	// block around for quick chain initialization. The hash to number mappings
+	// are retained so frozen blocks can still be looked up by hash.
+	for i, hash := range ancients {
+		if number := first + uint64(i); number != 0 {
+			DeleteBlockReceipts(db, hash, number)
+			deleteHeaderWithoutNumber(db, hash, number)
+			DeleteBody(db, hash, number)
+			DeleteTd(db, hash, number)
+			DeleteCanonicalHash(db, number)
+		}
+	}
+	// Log something friendly for the user
+	context := []interface{}{
+		"blocks", f.Ancients() - first, "elapsed", common.PrettyDuration(time.Since(start)), "number", f.Ancients() - 1,
+	}
+	if n := len(ancients); n > 0 {
+		context = append(context, []interface{}{"hash", ancients[n-1]}...)
+	}
+	log.Info("Deep froze chain segment", context...)
+
+	if f.Ancients() < limit {
+		return errors.New("chain segment freezing interrupted")
+	}
+	return nil
+}
+
+// freezerdb is a database wrapper that enables freezer data retrievals.
+type freezerdb struct {
+	riftdb.Database
+	*freezer
+}
+
+// Close implements riftdb.Database, closing both the fast key-value store as
+// well as the slow ancient tables.
+func (frdb *freezerdb) Close() {
+	if err := frdb.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	frdb.Database.Close()
+}
+
+// KeyValueStore returns the fast key-value database underlying a chain database,
+// stripping any ancient store layered on top of it.
+func KeyValueStore(db riftdb.Database) riftdb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments into cold
+// storage at the given directory.
+func NewDatabaseWithFreezer(db riftdb.Database, freezer string) (riftdb.Database, error) {
+	frdb, err := newFreezer(freezer)
+	if err != nil {
+		return nil, err
+	}
+	// If the freezer already contains something, ensure that the genesis blocks
+	// match, otherwise we might mix up freezers across chains and destroy both
+	// the freezer and the key-value store.
+	if frgenesis, err := frdb.Ancient(freezerHashTable, 0); err == nil {
+		if kvgenesis := GetCanonicalHash(db, 0); kvgenesis != (common.Hash{}) && kvgenesis != common.BytesToHash(frgenesis) {
+			frdb.Close()
+			return nil, fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
+		}
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &freezerdb{
+		Database: db,
+		freezer:  frdb,
+	}, nil
+}
@@ -0,0 +1,388 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cryptorift/riftcore/log"
+	"github.com/golang/snappy"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOfOrder is returned if an item is appended to the freezer table with
+	// a number not following the current head.
+	errOutOfOrder = errors.New("out of order insertion")
+)
+
+// indexEntrySize is the size of a single freezer table index entry: a 2 byte
+// data file number followed by a 4 byte end offset within that file.
+const indexEntrySize = 6
+
+// maxDataFileSize is the maximum size of a single freezer table data file after
+// which a new file is started.
+const maxDataFileSize = 2 * 1000 * 1000 * 1000
+
+// indexEntry points to the end of an item within one of the data files.
+type indexEntry struct {
+	filenum uint16 // Data file number containing the item
+	offset  uint32 // Offset right after the end of the item within the file
+}
+
+// unmarshalBinary deserializes binary b into the index entry.
+func (i *indexEntry) unmarshalBinary(b []byte) {
+	i.filenum = binary.BigEndian.Uint16(b[:2])
+	i.offset = binary.BigEndian.Uint32(b[2:6])
+}
+
+// marshallBinary serializes the index entry into binary.
+func (i *indexEntry) marshallBinary() []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint16(b[:2], i.filenum)
+	binary.BigEndian.PutUint32(b[2:6], i.offset)
+	return b
+}
+
+// freezerTable is an append-only flat file store for a single kind of immutable
+// chain data. Items are stored back to back in a sequence of data files, with a
+// separate index file tracking the end offset of each item.
+//
+// The index file starts with a zero entry, so the item n spans from the end
+// offset of entry n to the end offset of entry n+1 (or from the beginning of
+// the data file if the two entries point to different files).
+type freezerTable struct {
+	path     string // Directory containing the table files
+	name     string // Name of the table, used as the file name prefix
+	compress bool   // Whether items are snappy compressed
+
+	items    uint64   // Number of items stored in the table
+	index    *os.File // File descriptor of the index file
+	head     *os.File // File descriptor of the data file currently being appended to
+	headId   uint16   // Number of the head data file
+	headSize uint32   // Number of bytes already written into the head data file
+	files    map[uint16]*os.File
+
+	lock sync.RWMutex // Mutex protecting the files and the item counters
+}
+
+// newTable opens a freezer table at the given path, creating it if it does not
+// exist yet and repairing any inconsistency left over from a previous crash.
+func newTable(path string, name string, compress bool) (*freezerTable, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	idxName := fmt.Sprintf("%s.ridx", name)
+	if compress {
+		idxName = fmt.Sprintf("%s.cidx", name)
+	}
+	index, err := os.OpenFile(filepath.Join(path, idxName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	tab := &freezerTable{
+		path:     path,
+		name:     name,
+		compress: compress,
+		index:    index,
+		files:    make(map[uint16]*os.File),
+	}
+	if err := tab.repair(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	return tab, nil
+}
+
+// dataPath returns the path of the data file with the given number.
+func (t *freezerTable) dataPath(num uint16) string {
+	if t.compress {
+		return filepath.Join(t.path, fmt.Sprintf("%s.%04d.cdat", t.name, num))
+	}
+	return filepath.Join(t.path, fmt.Sprintf("%s.%04d.rdat", t.name, num))
+}
+
+// openFile opens (or creates) a data file and caches the file descriptor.
+func (t *freezerTable) openFile(num uint16) (*os.File, error) {
+	if f, ok := t.files[num]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(t.dataPath(num), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t.files[num] = f
+	return f, nil
+}
+
+// repair cross checks the index and the head data file, truncating both to the
+// last item that was fully written to disk.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	// Ensure the index contains the zero entry and only full entries
+	size := stat.Size()
+	if size == 0 {
+		if _, err := t.index.Write(new(indexEntry).marshallBinary()); err != nil {
+			return err
+		}
+		size = indexEntrySize
+	}
+	if overflow := size % indexEntrySize; overflow != 0 {
+		size -= overflow
+		if err := t.index.Truncate(size); err != nil {
+			return err
+		}
+	}
+	// Drop any index entries pointing past the end of the data files
+	var last indexEntry
+	for {
+		buf := make([]byte, indexEntrySize)
+		if _, err := t.index.ReadAt(buf, size-indexEntrySize); err != nil {
+			return err
+		}
+		last.unmarshalBinary(buf)
+
+		head, err := t.openFile(last.filenum)
+		if err != nil {
+			return err
+		}
+		stat, err := head.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size() >= int64(last.offset) {
+			// Data file is long enough, drop any partially written item
+			if stat.Size() > int64(last.offset) {
+				log.Warn("Truncating dangling freezer data", "table", t.name, "indexed", last.offset, "stored", stat.Size())
+				if err := head.Truncate(int64(last.offset)); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		log.Warn("Truncating dangling freezer index", "table", t.name, "indexed", last.offset, "stored", stat.Size())
+		size -= indexEntrySize
+		if err := t.index.Truncate(size); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	t.items = uint64(size/indexEntrySize) - 1
+	t.head, t.headId, t.headSize = t.files[last.filenum], last.filenum, last.offset
+
+	// Open all the data files preceding the head for item retrievals
+	for num := uint16(0); num < t.headId; num++ {
+		if _, err := t.openFile(num); err != nil {
+			return err
+		}
+	}
+	// Remove any data files beyond the head, left over from an interrupted truncation
+	for num := t.headId + 1; ; num++ {
+		if _, err := os.Stat(t.dataPath(num)); err != nil {
+			break
+		}
+		if f, ok := t.files[num]; ok {
+			f.Close()
+			delete(t.files, num)
+		}
+		if err := os.Remove(t.dataPath(num)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.items
+}
+
+// Append injects a binary blob at the end of the freezer table. The item number
+// must be the next one following the current head of the table.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if t.items != item {
+		return errOutOfOrder
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	// Start a new data file if the current head would overflow
+	if uint64(t.headSize)+uint64(len(blob)) > maxDataFileSize {
+		head, err := t.openFile(t.headId + 1)
+		if err != nil {
+			return err
+		}
+		t.head, t.headId, t.headSize = head, t.headId+1, 0
+	}
+	if _, err := t.head.WriteAt(blob, int64(t.headSize)); err != nil {
+		return err
+	}
+	t.headSize += uint32(len(blob))
+
+	entry := indexEntry{filenum: t.headId, offset: t.headSize}
+	if _, err := t.index.WriteAt(entry.marshallBinary(), int64((t.items+1)*indexEntrySize)); err != nil {
+		return err
+	}
+	t.items++
+	return nil
+}
+
+// Retrieve looks up the data offset of an item and retrieves it from the data
+// files, decompressing it if needed.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil {
+		return nil, errClosed
+	}
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	// Read the start and end index entries of the item
+	buf := make([]byte, 2*indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item*indexEntrySize)); err != nil {
+		return nil, err
+	}
+	var start, end indexEntry
+	start.unmarshalBinary(buf[:indexEntrySize])
+	end.unmarshalBinary(buf[indexEntrySize:])
+
+	if start.filenum != end.filenum {
+		start.offset = 0
+	}
+	data, ok := t.files[end.filenum]
+	if !ok {
+		return nil, fmt.Errorf("missing data file %d", end.filenum)
+	}
+	blob := make([]byte, end.offset-start.offset)
+	if _, err := data.ReadAt(blob, int64(start.offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// truncate discards any recent data above the provided threshold number.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if t.items <= items {
+		return nil
+	}
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(items*indexEntrySize)); err != nil {
+		return err
+	}
+	var last indexEntry
+	last.unmarshalBinary(buf)
+
+	if err := t.index.Truncate(int64((items + 1) * indexEntrySize)); err != nil {
+		return err
+	}
+	// Drop all the data files past the new head
+	for num := last.filenum + 1; num <= t.headId; num++ {
+		if f, ok := t.files[num]; ok {
+			f.Close()
+			delete(t.files, num)
+		}
+		if err := os.Remove(t.dataPath(num)); err != nil {
+			return err
+		}
+	}
+	head, err := t.openFile(last.filenum)
+	if err != nil {
+		return err
+	}
+	if err := head.Truncate(int64(last.offset)); err != nil {
+		return err
+	}
+	t.items = items
+	t.head, t.headId, t.headSize = head, last.filenum, last.offset
+	return nil
+}
+
+// Sync pushes any pending data from memory out to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.head.Sync()
+}
+
+// Close closes all opened files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.index, t.head, t.files = nil, nil, nil
+
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
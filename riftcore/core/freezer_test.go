@@ -0,0 +1,214 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+// Tests that items appended to a freezer table can be retrieved, both with and
+// without compression, and that they survive reopening the table.
+func TestFreezerTableAppendRetrieve(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "freezer")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		table, err := newTable(dir, "test", compress)
+		if err != nil {
+			t.Fatalf("compress %v: failed to create table: %v", compress, err)
+		}
+		for i := uint64(0); i < 100; i++ {
+			if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, int(i))); err != nil {
+				t.Fatalf("compress %v: failed to append item %d: %v", compress, i, err)
+			}
+		}
+		if err := table.Append(200, []byte{0x01}); err != errOutOfOrder {
+			t.Errorf("compress %v: out of order append error mismatch: have %v, want %v", compress, err, errOutOfOrder)
+		}
+		table.Close()
+
+		if table, err = newTable(dir, "test", compress); err != nil {
+			t.Fatalf("compress %v: failed to reopen table: %v", compress, err)
+		}
+		if items := table.Items(); items != 100 {
+			t.Fatalf("compress %v: item count mismatch: have %d, want %d", compress, items, 100)
+		}
+		for i := uint64(0); i < 100; i++ {
+			blob, err := table.Retrieve(i)
+			if err != nil {
+				t.Fatalf("compress %v: failed to retrieve item %d: %v", compress, i, err)
+			}
+			if want := bytes.Repeat([]byte{byte(i)}, int(i)); !bytes.Equal(blob, want) {
+				t.Errorf("compress %v: item %d mismatch: have %x, want %x", compress, i, blob, want)
+			}
+		}
+		if _, err := table.Retrieve(100); err != errOutOfBounds {
+			t.Errorf("compress %v: out of bounds retrieval error mismatch: have %v, want %v", compress, err, errOutOfBounds)
+		}
+		table.Close()
+	}
+}
+
+// Tests that a freezer table with partially written data (e.g. after a crash)
+// is repaired to the last fully written item on reopening.
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newTable(dir, "test", false)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := table.Append(i, []byte(fmt.Sprintf("item %d", i))); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	table.Close()
+
+	// Chop the tail off the data file, corrupting the last item
+	path := filepath.Join(dir, "test.0000.rdat")
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat data file: %v", err)
+	}
+	if err := os.Truncate(path, stat.Size()-2); err != nil {
+		t.Fatalf("failed to truncate data file: %v", err)
+	}
+	if table, err = newTable(dir, "test", false); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 9 {
+		t.Fatalf("item count mismatch after repair: have %d, want %d", items, 9)
+	}
+	if blob, err := table.Retrieve(8); err != nil || string(blob) != "item 8" {
+		t.Errorf("last item mismatch: have %q, want %q, err %v", blob, "item 8", err)
+	}
+	// Ensure the table can be appended to after the repair
+	if err := table.Append(9, []byte("item 9")); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	if blob, err := table.Retrieve(9); err != nil || string(blob) != "item 9" {
+		t.Errorf("reappended item mismatch: have %q, want %q, err %v", blob, "item 9", err)
+	}
+}
+
+// Tests that chain data moved into the freezer is transparently accessible via
+// the standard database accessors and is removed from the key-value store.
+func TestFreezerChainMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, _ := riftdb.NewMemDatabase()
+
+	// Create a short canonical chain with receipts and total difficulties
+	var (
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < 10; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Extra: []byte("test block")}
+		block := types.NewBlockWithHeader(header)
+		receipts := types.Receipts{&types.Receipt{CumulativeGasUsed: big.NewInt(int64(i)), Logs: []*types.Log{}}}
+
+		if err := WriteBlock(kvdb, block); err != nil {
+			t.Fatalf("failed to write block %d: %v", i, err)
+		}
+		if err := WriteCanonicalHash(kvdb, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("failed to write canonical hash %d: %v", i, err)
+		}
+		if err := WriteTd(kvdb, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("failed to write td %d: %v", i, err)
+		}
+		if err := WriteBlockReceipts(kvdb, block.Hash(), block.NumberU64(), receipts); err != nil {
+			t.Fatalf("failed to write receipts %d: %v", i, err)
+		}
+		blocks, parent = append(blocks, block), block.Hash()
+	}
+	// Freeze the first half of the chain
+	frdb, err := newFreezer(dir)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer frdb.Close()
+
+	if err := frdb.freezeRange(kvdb, 5); err != nil {
+		t.Fatalf("failed to freeze chain segment: %v", err)
+	}
+	if frozen := frdb.Ancients(); frozen != 5 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", frozen, 5)
+	}
+	db := &freezerdb{Database: kvdb, freezer: frdb}
+
+	for i, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+
+		// Frozen blocks (apart from the genesis) must be gone from the key-value store
+		if i > 0 && i < 5 {
+			if GetHeader(kvdb, hash, number) != nil {
+				t.Errorf("block %d: header not removed from key-value store", i)
+			}
+			if GetBody(kvdb, hash, number) != nil {
+				t.Errorf("block %d: body not removed from key-value store", i)
+			}
+		}
+		// All blocks must be accessible through the combined database
+		if have := GetCanonicalHash(db, number); have != hash {
+			t.Errorf("block %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+		if have := GetBlockNumber(db, hash); have != number {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, have, number)
+		}
+		if header := GetHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Errorf("block %d: header mismatch: have %v", i, header)
+		}
+		if body := GetBody(db, hash, number); body == nil {
+			t.Errorf("block %d: body missing", i)
+		}
+		if td := GetTd(db, hash, number); td == nil || td.Int64() != int64(i+1) {
+			t.Errorf("block %d: td mismatch: have %v, want %d", i, td, i+1)
+		}
+		if receipts := GetBlockReceipts(db, hash, number); len(receipts) != 1 || receipts[0].CumulativeGasUsed.Int64() != int64(i) {
+			t.Errorf("block %d: receipts mismatch: have %v", i, receipts)
+		}
+	}
+	// Frozen data must not be served for non-canonical hashes
+	if header := GetHeader(db, common.Hash{0x01}, 1); header != nil {
+		t.Errorf("non-canonical header served from freezer: %v", header)
+	}
+}
@@ -50,6 +50,8 @@ type Database interface {
 	ContractCodeSize(addrHash, codeHash common.Hash) (int, error)
 	// CopyTrie returns an independent copy of the given trie.
 	CopyTrie(Trie) Trie
+	// TrieDB retrieves the low level trie database used for data storage.
+	TrieDB() *trie.NodeDatabase
 }
 
 // Trie is a CryptoRift Merkle Trie.
@@ -58,20 +60,22 @@ type Trie interface {
 	TryUpdate(key, value []byte) error
 	TryDelete(key []byte) error
 	CommitTo(trie.DatabaseWriter) (common.Hash, error)
+	CommitToWithCallback(trie.DatabaseWriter, trie.LeafCallback) (common.Hash, error)
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
 }
 
 // NewDatabase creates a backing store for state. The returned database is safe for
-// concurrent use and retains cached trie nodes in memory.
+// concurrent use and retains cached trie nodes in memory. Tries committed into
+// its trie database are held in memory until explicitly flushed to disk.
 func NewDatabase(db riftdb.Database) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
-	return &cachingDB{db: db, codeSizeCache: csc}
+	return &cachingDB{db: trie.NewNodeDatabase(db), codeSizeCache: csc}
 }
 
 type cachingDB struct {
-	db            riftdb.Database
+	db            *trie.NodeDatabase
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
@@ -120,6 +124,11 @@ func (db *cachingDB) CopyTrie(t Trie) Trie {
 	}
 }
 
+// TrieDB retrieves any intermediate trie-node caching layer.
+func (db *cachingDB) TrieDB() *trie.NodeDatabase {
+	return db.db
+}
+
 func (db *cachingDB) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
 	code, err := db.db.Get(codeHash[:])
 	if err == nil {
@@ -146,7 +155,11 @@ type cachedTrie struct {
 }
 
 func (m cachedTrie) CommitTo(dbw trie.DatabaseWriter) (common.Hash, error) {
-	root, err := m.SecureTrie.CommitTo(dbw)
+	return m.CommitToWithCallback(dbw, nil)
+}
+
+func (m cachedTrie) CommitToWithCallback(dbw trie.DatabaseWriter, onleaf trie.LeafCallback) (common.Hash, error) {
+	root, err := m.SecureTrie.CommitToWithCallback(dbw, onleaf)
 	if err == nil {
 		m.db.pushTrie(m.SecureTrie)
 	}
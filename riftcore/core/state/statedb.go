@@ -457,7 +457,7 @@ func (self *StateDB) Copy() *StateDB {
 	// Copy all the basic fields, initialize the memory ones
 	state := &StateDB{
 		db:                     self.db,
-		trie:                   self.trie,
+		trie:                   self.db.CopyTrie(self.trie),
 		stateObjects:           make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty:      make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		stateObjectsDestructed: make(map[common.Address]struct{}, len(self.stateObjectsDestructed)),
@@ -615,8 +615,20 @@ func (s *StateDB) CommitTo(dbw trie.DatabaseWriter, deleteEmptyObjects bool) (ro
 		}
 		delete(s.stateObjectsDirty, addr)
 	}
-	// Write trie changes.
-	root, err = s.trie.CommitTo(dbw)
+	// Write trie changes, tracking the account->storage references if the
+	// state is committed into a reference counted trie database.
+	var onleaf trie.LeafCallback
+	if triedb, ok := dbw.(*trie.NodeDatabase); ok {
+		onleaf = func(leaf []byte, parent common.Hash) error {
+			var account Account
+			if err := rlp.DecodeBytes(leaf, &account); err != nil {
+				return nil
+			}
+			triedb.Reference(account.Root, parent)
+			return nil
+		}
+	}
+	root, err = s.trie.CommitToWithCallback(dbw, onleaf)
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
 	return root, err
 }
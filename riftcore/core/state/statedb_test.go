@@ -117,6 +117,38 @@ func TestIntermediateLeaks(t *testing.T) {
 	}
 }
 
+// Tests that a copy of the state is unaffected by any changes to the original,
+// even after those are hashed into the account trie.
+func TestCopy(t *testing.T) {
+	// Create a state with a few accounts and reopen it from the database
+	db, _ := riftdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	orig, _ := New(common.Hash{}, sdb)
+
+	for i := byte(0); i < 255; i++ {
+		orig.AddBalance(common.BytesToAddress([]byte{i}), big.NewInt(int64(i)))
+	}
+	root, _ := orig.CommitTo(db, false)
+	orig, _ = New(root, sdb)
+
+	// Copy the state and modify the original one
+	copy := orig.Copy()
+	for i := byte(0); i < 255; i++ {
+		orig.AddBalance(common.BytesToAddress([]byte{i}), big.NewInt(1))
+	}
+	orig.IntermediateRoot(false)
+
+	// Ensure the copy still holds the original values
+	if have := copy.IntermediateRoot(false); have != root {
+		t.Errorf("copied root mismatch: have %x, want %x", have, root)
+	}
+	for i := byte(0); i < 255; i++ {
+		if balance := copy.GetBalance(common.BytesToAddress([]byte{i})); balance.Cmp(big.NewInt(int64(i))) != 0 {
+			t.Errorf("account %d: copied balance mismatch: have %v, want %v", i, balance, i)
+		}
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
@@ -72,6 +72,28 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 )
 
+var (
+	// ErrQueueOverflow is the drop reason of transactions that did not fit into
+	// the per account or global non-executable queue allowances.
+	ErrQueueOverflow = errors.New("queue overflow")
+
+	// ErrPendingOverflow is the drop reason of transactions that were shifted
+	// out of the executable set to equalize the per account pending allowances.
+	ErrPendingOverflow = errors.New("pending overflow")
+
+	// ErrTxExpired is the drop reason of queued transactions that have not been
+	// executable for longer than the configured lifetime.
+	ErrTxExpired = errors.New("lifetime expired")
+
+	// ErrTxEvicted is the drop reason of transactions explicitly evicted from
+	// the pool by the node operator.
+	ErrTxEvicted = errors.New("evicted")
+
+	// ErrTxRemoved is the drop reason of transactions removed from the pool by
+	// an internal subsystem (e.g. a resend or a failed mining attempt).
+	ErrTxRemoved = errors.New("removed")
+)
+
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
@@ -95,11 +117,24 @@ var (
 	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
 )
 
+// TxStatus is the current status of a transaction as seen by the pool.
+type TxStatus uint
+
+const (
+	TxStatusUnknown TxStatus = iota
+	TxStatusQueued
+	TxStatusPending
+	TxStatusIncluded
+	TxStatusError
+)
+
 type stateFn func() (*state.StateDB, error)
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals bool // Whether local transaction handling should be disabled
+	NoLocals  bool          // Whether local transaction handling should be disabled
+	Journal   string        // Journal of local transactions to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -115,6 +150,9 @@ type TxPoolConfig struct {
 // DefaultTxPoolConfig contains the default configurations for the transaction
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
+	Journal:   "transactions.rlp",
+	Rejournal: time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -130,6 +168,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 // unreasonable or unworkable.
 func (config *TxPoolConfig) sanitize() TxPoolConfig {
 	conf := *config
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -157,6 +199,11 @@ type TxPool struct {
 	gasPrice     *big.Int
 	eventMux     *event.TypeMux
 	events       *event.TypeMuxSubscription
+	txFeed       event.Feed              // Feed notifying of promoted, replaced and dropped transactions
+	scope        event.SubscriptionScope // Subscription scope tracking current live listeners
+	notifyMu     sync.Mutex              // Lock protecting the queue of undelivered events
+	notifyQueue  []TxPoolEvent           // Events awaiting delivery to the subscribers, in order
+	notifyWake   chan struct{}           // Channel signalling the delivery of queued events
 	locals       *accountSet
 	signer       types.Signer
 	mu           sync.RWMutex
@@ -167,6 +214,8 @@ type TxPool struct {
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
 
+	journal *txJournal // Journal of local transaction to back up to disk
+
 	wg   sync.WaitGroup // for shutdown sync
 	quit chan struct{}
 
@@ -194,16 +243,30 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *e
 		gasPrice:     new(big.Int).SetUint64(config.PriceLimit),
 		pendingState: nil,
 		events:       eventMux.Subscribe(ChainHeadEvent{}, RemovedTransactionEvent{}),
+		notifyWake:   make(chan struct{}, 1),
 		quit:         make(chan struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
 	pool.resetState()
 
+	// If local transactions and journaling is enabled, load from disk
+	if !config.NoLocals && config.Journal != "" {
+		pool.journal = newTxJournal(config.Journal)
+
+		if err := pool.journal.load(pool.AddLocal); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		if err := pool.journal.rotate(pool.local()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+	}
 	// Start the various events loops and return
-	pool.wg.Add(2)
+	pool.wg.Add(4)
 	go pool.eventLoop()
 	go pool.expirationLoop()
+	go pool.journalLoop()
+	go pool.notifyLoop()
 
 	return pool
 }
@@ -283,13 +346,27 @@ func (pool *TxPool) resetState() {
 
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
+	// Unsubscribe all subscriptions registered from txpool
+	pool.scope.Close()
+
 	pool.events.Unsubscribe()
 	close(pool.quit)
 	pool.wg.Wait()
 
+	if pool.journal != nil {
+		pool.journal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
+// SubscribeTxPoolEvent registers a subscription of TxPoolEvent and starts
+// sending the events to the given channel in the order the changes occurred.
+// Events are delivered outside of the pool lock, a slow subscriber only delays
+// the events of all subscribers, not the pool itself.
+func (pool *TxPool) SubscribeTxPoolEvent(ch chan<- TxPoolEvent) event.Subscription {
+	return pool.scope.Track(pool.txFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -306,7 +383,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
-		pool.removeTx(tx.Hash())
+		pool.removeTx(tx.Hash(), ErrUnderpriced)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
@@ -359,6 +436,23 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool belonging to a
+// single account, returning its pending as well as queued transactions, sorted
+// by nonce.
+func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var pending, queued types.Transactions
+	if list := pool.pending[addr]; list != nil {
+		pending = list.Flatten()
+	}
+	if list := pool.queue[addr]; list != nil {
+		queued = list.Flatten()
+	}
+	return pending, queued
+}
+
 // Pending retrieves all currently processable transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -373,6 +467,24 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// local retrieves all currently known local transactions, groupped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) local() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr := range pool.locals.accounts {
+		if pending := pool.pending[addr]; pending != nil {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+		if queued := pool.queue[addr]; queued != nil {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -453,7 +565,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash())
+			pool.removeTx(tx.Hash(), ErrUnderpriced)
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
@@ -470,9 +582,12 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			delete(pool.all, old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.notify(old, TxReplaced, nil)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
+		pool.journalTx(from, tx)
+		pool.notify(tx, TxPromoted, nil)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 		return old != nil, nil
@@ -485,10 +600,24 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if local {
 		pool.locals.add(from)
 	}
+	pool.journalTx(from, tx)
+
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replace, nil
 }
 
+// journalTx adds the specified transaction to the local disk journal if it is
+// deemed to have been sent from a local account.
+func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
+	// Only journal if it's enabled and the transaction is local
+	if pool.journal == nil || !pool.locals.contains(from) {
+		return
+	}
+	if err := pool.journal.insert(tx); err != nil {
+		log.Warn("Failed to journal local transaction", "err", err)
+	}
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -509,6 +638,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.notify(old, TxReplaced, nil)
 	}
 	pool.all[hash] = tx
 	pool.priced.Put(tx)
@@ -532,6 +662,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.notify(tx, TxDropped, ErrReplaceUnderpriced)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -540,6 +671,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.notify(old, TxReplaced, nil)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -550,6 +682,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 	go pool.eventMux.Post(TxPreEvent{tx})
+	pool.notify(tx, TxPromoted, nil)
 }
 
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
@@ -632,6 +765,26 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
 	return nil
 }
 
+// Status returns the status (unknown/pending/queued) of a batch of transactions
+// identified by their hashes.
+func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	status := make([]TxStatus, len(hashes))
+	for i, hash := range hashes {
+		if tx := pool.all[hash]; tx != nil {
+			from, _ := types.Sender(pool.signer, tx) // already validated
+			if pool.pending[from] != nil && pool.pending[from].txs.items[tx.Nonce()] != nil {
+				status[i] = TxStatusPending
+			} else {
+				status[i] = TxStatusQueued
+			}
+		}
+	}
+	return status
+}
+
 // Get returns a transaction if it is contained in the pool
 // and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
@@ -646,7 +799,7 @@ func (pool *TxPool) Remove(hash common.Hash) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	pool.removeTx(hash)
+	pool.removeTx(hash, ErrTxRemoved)
 }
 
 // RemoveBatch removes all given transactions from the pool.
@@ -655,13 +808,14 @@ func (pool *TxPool) RemoveBatch(txs types.Transactions) {
 	defer pool.mu.Unlock()
 
 	for _, tx := range txs {
-		pool.removeTx(tx.Hash())
+		pool.removeTx(tx.Hash(), ErrTxRemoved)
 	}
 }
 
 // removeTx removes a single transaction from the queue, moving all subsequent
-// transactions back to the future queue.
-func (pool *TxPool) removeTx(hash common.Hash) {
+// transactions back to the future queue. The reason is reported to any pool
+// event subscribers.
+func (pool *TxPool) removeTx(hash common.Hash, reason error) {
 	// Fetch the transaction we wish to delete
 	tx, ok := pool.all[hash]
 	if !ok {
@@ -672,19 +826,19 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	// Remove it from the list of known transactions
 	delete(pool.all, hash)
 	pool.priced.Removed()
+	pool.notify(tx, TxDropped, reason)
 
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
 		if removed, invalids := pending.Remove(tx); removed {
-			// If no more transactions are left, remove the list
+			// If no more pending transactions are left, remove the list
 			if pending.Empty() {
 				delete(pool.pending, addr)
 				delete(pool.beats, addr)
-			} else {
-				// Otherwise postpone any invalidated transactions
-				for _, tx := range invalids {
-					pool.enqueueTx(tx.Hash(), tx)
-				}
+			}
+			// Postpone any invalidated transactions
+			for _, tx := range invalids {
+				pool.enqueueTx(tx.Hash(), tx)
 			}
 			// Update the account nonce if needed
 			if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -702,6 +856,109 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	}
 }
 
+// Evict removes a single transaction from the pool on behalf of the node
+// operator, moving all subsequent pending transactions of the same account back
+// to the future queue. It returns whether the transaction was found.
+func (pool *TxPool) Evict(hash common.Hash) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	tx := pool.all[hash]
+	if tx == nil {
+		return false
+	}
+	pool.removeTx(hash, ErrTxEvicted)
+
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	pool.rejournal(from)
+
+	return true
+}
+
+// EvictAccount removes all the pending and queued transactions originating from
+// an account on behalf of the node operator, returning the number of evicted
+// transactions.
+func (pool *TxPool) EvictAccount(addr common.Address) int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var txs types.Transactions
+	if list := pool.queue[addr]; list != nil {
+		txs = append(txs, list.Flatten()...)
+	}
+	if list := pool.pending[addr]; list != nil {
+		txs = append(txs, list.Flatten()...)
+	}
+	// Remove the highest nonces first to avoid needlessly demoting the rest
+	for i := len(txs) - 1; i >= 0; i-- {
+		pool.removeTx(txs[i].Hash(), ErrTxEvicted)
+	}
+	if len(txs) > 0 {
+		pool.rejournal(addr)
+	}
+	return len(txs)
+}
+
+// rejournal regenerates the local transaction journal if the given account is a
+// local one, ensuring evicted transactions are not resurrected on restart.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) rejournal(addr common.Address) {
+	if pool.journal == nil || !pool.locals.contains(addr) {
+		return
+	}
+	if err := pool.journal.rotate(pool.local()); err != nil {
+		log.Warn("Failed to rotate local tx journal", "err", err)
+	}
+}
+
+// notify queues a transaction pool change event for delivery to any subscribers.
+// Events are queued in the order of the changes, which the pool lock guarantees.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) notify(tx *types.Transaction, change TxChange, reason error) {
+	pool.notifyMu.Lock()
+	pool.notifyQueue = append(pool.notifyQueue, TxPoolEvent{Tx: tx, Change: change, Reason: reason})
+	pool.notifyMu.Unlock()
+
+	select {
+	case pool.notifyWake <- struct{}{}:
+	default:
+	}
+}
+
+// notifyLoop delivers the queued transaction pool change events to the
+// subscribers in order, without holding up the pool while doing so.
+func (pool *TxPool) notifyLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case <-pool.notifyWake:
+			pool.notifyMu.Lock()
+			events := pool.notifyQueue
+			pool.notifyQueue = nil
+			pool.notifyMu.Unlock()
+
+			for _, event := range events {
+				pool.txFeed.Send(event)
+			}
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
+// unpayableReason returns the reason why a transaction was filtered out of an
+// account's list as unpayable: either exceeding the block gas limit or the
+// balance of its sender.
+func unpayableReason(tx *types.Transaction, gaslimit *big.Int) error {
+	if tx.Gas().Cmp(gaslimit) > 0 {
+		return ErrGasLimit
+	}
+	return ErrInsufficientFunds
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -727,6 +984,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			log.Trace("Removed old queued transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notify(tx, TxDropped, ErrNonceTooLow)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(state.GetBalance(addr), gaslimit)
@@ -736,6 +994,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
+			pool.notify(tx, TxDropped, unpayableReason(tx, gaslimit))
 		}
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
@@ -750,6 +1009,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 				delete(pool.all, hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.notify(tx, TxDropped, ErrQueueOverflow)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -794,6 +1054,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 							hash := tx.Hash()
 							delete(pool.all, hash)
 							pool.priced.Removed()
+							pool.notify(tx, TxDropped, ErrPendingOverflow)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -816,6 +1077,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 						hash := tx.Hash()
 						delete(pool.all, hash)
 						pool.priced.Removed()
+						pool.notify(tx, TxDropped, ErrPendingOverflow)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -854,7 +1116,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
-					pool.removeTx(tx.Hash())
+					pool.removeTx(tx.Hash(), ErrQueueOverflow)
 				}
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
@@ -863,7 +1125,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
-				pool.removeTx(txs[i].Hash())
+				pool.removeTx(txs[i].Hash(), ErrQueueOverflow)
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
@@ -887,6 +1149,7 @@ func (pool *TxPool) demoteUnexecutables(state *state.StateDB) {
 			log.Trace("Removed old pending transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notify(tx, TxDropped, ErrNonceTooLow)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(state.GetBalance(addr), gaslimit)
@@ -896,6 +1159,7 @@ func (pool *TxPool) demoteUnexecutables(state *state.StateDB) {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.notify(tx, TxDropped, unpayableReason(tx, gaslimit))
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -931,7 +1195,7 @@ func (pool *TxPool) expirationLoop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
-						pool.removeTx(tx.Hash())
+						pool.removeTx(tx.Hash(), ErrTxExpired)
 					}
 				}
 			}
@@ -943,6 +1207,31 @@ func (pool *TxPool) expirationLoop() {
 	}
 }
 
+// journalLoop is a loop that periodically regenerates the local transaction
+// journal, dropping any transactions that were since included or discarded.
+func (pool *TxPool) journalLoop() {
+	defer pool.wg.Done()
+
+	journal := time.NewTicker(pool.config.Rejournal)
+	defer journal.Stop()
+
+	for {
+		select {
+		case <-journal.C:
+			if pool.journal != nil {
+				pool.mu.Lock()
+				if err := pool.journal.rotate(pool.local()); err != nil {
+					log.Warn("Failed to rotate local tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
+
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
 type addressByHeartbeat struct {
 	address   common.Address
@@ -19,8 +19,10 @@ package core
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
 	"testing"
 	"time"
 
@@ -33,6 +35,15 @@ import (
 	"github.com/cryptorift/riftcore/params"
 )
 
+// testTxPoolConfig is a transaction pool configuration without stateful disk
+// sideeffects used during testing.
+var testTxPoolConfig TxPoolConfig
+
+func init() {
+	testTxPoolConfig = DefaultTxPoolConfig
+	testTxPoolConfig.Journal = ""
+}
+
 func transaction(nonce uint64, gaslimit *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
 	return pricedTransaction(nonce, gaslimit, big.NewInt(1), key)
 }
@@ -47,7 +58,7 @@ func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
 	key, _ := crypto.GenerateKey()
-	pool := NewTxPool(DefaultTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
 	pool.resetState()
 
 	return pool, key
@@ -125,7 +136,7 @@ func TestStateChangeDuringPoolReset(t *testing.T) {
 
 	gasLimitFunc := func() *big.Int { return big.NewInt(1000000000) }
 
-	pool := NewTxPool(DefaultTxPoolConfig, params.TestChainConfig, mux, stateFunc, gasLimitFunc)
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, mux, stateFunc, gasLimitFunc)
 	defer pool.Stop()
 	pool.resetState()
 
@@ -657,7 +668,7 @@ func testTransactionQueueGlobalLimiting(t *testing.T, nolocals bool) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.NoLocals = nolocals
 	config.GlobalQueue = config.AccountQueue*3 - 1 // reduce the queue limits to shorten test time (-1 to make it non divisible)
 
@@ -748,7 +759,7 @@ func testTransactionQueueTimeLimiting(t *testing.T, nolocals bool) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.Lifetime = 250 * time.Millisecond
 	config.NoLocals = nolocals
 
@@ -888,7 +899,7 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.GlobalSlots = config.AccountSlots * 10
 
 	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
@@ -935,7 +946,7 @@ func TestTransactionCapClearsFromAll(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.AccountSlots = 2
 	config.AccountQueue = 2
 	config.GlobalSlots = 8
@@ -970,7 +981,7 @@ func TestTransactionPendingMinimumAllowance(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.GlobalSlots = 0
 
 	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
@@ -1019,7 +1030,7 @@ func TestTransactionPoolRepricing(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	pool := NewTxPool(DefaultTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
 	defer pool.Stop()
 	pool.resetState()
 
@@ -1104,7 +1115,7 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	config := DefaultTxPoolConfig
+	config := testTxPoolConfig
 	config.GlobalSlots = 2
 	config.GlobalQueue = 2
 
@@ -1192,7 +1203,7 @@ func TestTransactionReplacement(t *testing.T) {
 	db, _ := riftdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
-	pool := NewTxPool(DefaultTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
 	defer pool.Stop()
 	pool.resetState()
 
@@ -1350,3 +1361,302 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that local transactions are journaled to disk, but remote transactions
+// get discarded between restarts.
+func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
+func TestTransactionJournalingNoLocals(t *testing.T) { testTransactionJournaling(t, true) }
+
+func testTransactionJournaling(t *testing.T, nolocals bool) {
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	db, _ := riftdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := testTxPoolConfig
+	config.NoLocals = nolocals
+	config.Journal = journal
+	config.Rejournal = time.Second
+
+	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	pool.resetState()
+
+	// Create two test accounts to ensure remotes expire but locals do not
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	statedb.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	statedb.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add three local and a remote transactions and ensure they are queued up
+	if err := pool.AddLocal(pricedTransaction(0, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(1, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(2, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, big.NewInt(100000), big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pending, queued := pool.stats()
+	if pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Terminate the old pool, bump the local nonce, create a new pool and ensure relevant transaction survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
+
+	pool = NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+
+	pending, queued = pool.stats()
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if nolocals {
+		if pending != 0 {
+			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
+		}
+	} else {
+		if pending != 2 {
+			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Bump the nonce temporarily and ensure the newly invalidated transaction is removed
+	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 2)
+	pool.mu.Lock()
+	pool.resetState()
+	pool.mu.Unlock()
+	time.Sleep(2 * config.Rejournal)
+	pool.Stop()
+
+	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
+	pool = NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+
+	pending, queued = pool.stats()
+	if pending != 0 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
+	}
+	if nolocals {
+		if queued != 0 {
+			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+		}
+	} else {
+		if queued != 1 {
+			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+}
+
+// checkTxPoolEvents verifies that the expected transaction pool events were
+// reported, in order, and that no others are pending.
+func checkTxPoolEvents(t *testing.T, events <-chan TxPoolEvent, want []TxPoolEvent) {
+	for i, exp := range want {
+		select {
+		case event := <-events:
+			if event.Tx.Hash() != exp.Tx.Hash() || event.Change != exp.Change || event.Reason != exp.Reason {
+				t.Errorf("event %d: mismatch: have %x %s/%v, want %x %s/%v", i, event.Tx.Hash().Bytes()[:4], event.Change, event.Reason, exp.Tx.Hash().Bytes()[:4], exp.Change, exp.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d: timeout waiting for %d events", i, len(want))
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event: %x %s/%v", event.Tx.Hash().Bytes()[:4], event.Change, event.Reason)
+	default:
+	}
+}
+
+// Tests that transactions can be inspected and evicted on a per account basis,
+// and that all pool changes are reported to subscribers along with the reason.
+func TestTransactionEviction(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan TxPoolEvent, 16)
+	sub := pool.SubscribeTxPoolEvent(events)
+	defer sub.Unsubscribe()
+
+	account, _ := deriveSender(transaction(0, big.NewInt(0), key))
+	state, _ := pool.currentState()
+	state.AddBalance(account, big.NewInt(1000000))
+
+	// Add two executable and one gapped transaction, ensure only the first are promoted
+	txs := types.Transactions{
+		transaction(0, big.NewInt(100000), key),
+		transaction(1, big.NewInt(100000), key),
+		transaction(3, big.NewInt(100000), key),
+	}
+	for i, tx := range txs {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	checkTxPoolEvents(t, events, []TxPoolEvent{
+		{Tx: txs[0], Change: TxPromoted},
+		{Tx: txs[1], Change: TxPromoted},
+	})
+
+	if pending, queued := pool.ContentFrom(account); len(pending) != 2 || len(queued) != 1 {
+		t.Fatalf("account content mismatch: have %d/%d, want %d/%d", len(pending), len(queued), 2, 1)
+	}
+	if pending, queued := pool.ContentFrom(common.Address{}); len(pending) != 0 || len(queued) != 0 {
+		t.Fatalf("unknown account content mismatch: have %d/%d, want %d/%d", len(pending), len(queued), 0, 0)
+	}
+	// Evict the first transaction, ensuring the subsequent one is demoted
+	if !pool.Evict(txs[0].Hash()) {
+		t.Fatalf("failed to evict pending transaction")
+	}
+	if pool.Evict(txs[0].Hash()) {
+		t.Fatalf("evicted already removed transaction")
+	}
+	checkTxPoolEvents(t, events, []TxPoolEvent{
+		{Tx: txs[0], Change: TxDropped, Reason: ErrTxEvicted},
+	})
+
+	if pending, queued := pool.ContentFrom(account); len(pending) != 0 || len(queued) != 2 {
+		t.Fatalf("account content mismatch: have %d/%d, want %d/%d", len(pending), len(queued), 0, 2)
+	}
+	// Evict the entire account and ensure nothing remains
+	if evicted := pool.EvictAccount(account); evicted != 2 {
+		t.Fatalf("evicted transaction count mismatch: have %d, want %d", evicted, 2)
+	}
+	checkTxPoolEvents(t, events, []TxPoolEvent{
+		{Tx: txs[2], Change: TxDropped, Reason: ErrTxEvicted},
+		{Tx: txs[1], Change: TxDropped, Reason: ErrTxEvicted},
+	})
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool content mismatch: have %d/%d, want %d/%d", pending, queued, 0, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Re-add a transaction and replace it, then ensure it's dropped as stale after a nonce bump
+	if err := pool.AddRemote(txs[0]); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	replacement := pricedTransaction(0, big.NewInt(100000), big.NewInt(2), key)
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	checkTxPoolEvents(t, events, []TxPoolEvent{
+		{Tx: txs[0], Change: TxPromoted},
+		{Tx: txs[0], Change: TxReplaced},
+		{Tx: replacement, Change: TxPromoted},
+	})
+
+	state.SetNonce(account, 1)
+	pool.mu.Lock()
+	pool.resetState()
+	pool.mu.Unlock()
+
+	checkTxPoolEvents(t, events, []TxPoolEvent{
+		{Tx: replacement, Change: TxDropped, Reason: ErrNonceTooLow},
+	})
+}
+
+// Tests that a subscriber not reading its events doesn't hold up the pool, and
+// that the events are still delivered in order once it catches up.
+func TestTransactionEventsStalledSubscriber(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan TxPoolEvent)
+	sub := pool.SubscribeTxPoolEvent(events)
+	defer sub.Unsubscribe()
+
+	account, _ := deriveSender(transaction(0, big.NewInt(0), key))
+	state, _ := pool.currentState()
+	state.AddBalance(account, big.NewInt(1000000))
+
+	// Add a batch of transactions without consuming any events
+	txs := make(types.Transactions, 8)
+	for i := range txs {
+		txs[i] = transaction(uint64(i), big.NewInt(100000), key)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, tx := range txs {
+			if err := pool.AddRemote(tx); err != nil {
+				t.Errorf("tx %d: failed to add transaction: %v", i, err)
+			}
+		}
+		pool.mu.Lock()
+		pool.resetState()
+		pool.mu.Unlock()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("pool blocked by stalled subscriber")
+	}
+	if pending, _ := pool.Stats(); pending != len(txs) {
+		t.Fatalf("pending transactions mismatch: have %d, want %d", pending, len(txs))
+	}
+	want := make([]TxPoolEvent, len(txs))
+	for i, tx := range txs {
+		want[i] = TxPoolEvent{Tx: tx, Change: TxPromoted}
+	}
+	checkTxPoolEvents(t, events, want)
+}
+
+// Tests that the pool reports the pending, queued or unknown status of a batch
+// of transactions.
+func TestTransactionStatusCheck(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account, _ := deriveSender(transaction(0, big.NewInt(0), key))
+	state, _ := pool.currentState()
+	state.AddBalance(account, big.NewInt(1000000))
+
+	// Add an executable and a gapped transaction, keeping a third one out
+	txs := types.Transactions{
+		transaction(0, big.NewInt(100000), key),
+		transaction(2, big.NewInt(100000), key),
+		transaction(3, big.NewInt(100000), key),
+	}
+	if err := pool.AddRemotes(txs[:2]); err != nil {
+		t.Fatalf("failed to add transactions: %v", err)
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	want := []TxStatus{TxStatusPending, TxStatusQueued, TxStatusUnknown}
+	status := pool.Status(hashes)
+	for i := range want {
+		if status[i] != want[i] {
+			t.Errorf("transaction %d: status mismatch: have %v, want %v", i, status[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/rlp"
+)
+
+// Tests that receipts round trip their post state or execution status through
+// both the consensus and the storage encodings.
+func TestReceiptStatusEncoding(t *testing.T) {
+	tests := []*Receipt{
+		NewReceipt(common.Hash{1}.Bytes(), false, big.NewInt(1)),
+		NewReceipt(nil, false, big.NewInt(2)),
+		NewReceipt(nil, true, big.NewInt(3)),
+	}
+	for i, want := range tests {
+		enc, err := rlp.EncodeToBytes(want)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode receipt: %v", i, err)
+		}
+		have := new(Receipt)
+		if err := rlp.DecodeBytes(enc, have); err != nil {
+			t.Fatalf("test %d: failed to decode receipt: %v", i, err)
+		}
+		if !bytes.Equal(have.PostState, want.PostState) {
+			t.Errorf("test %d: post state mismatch: have %x, want %x", i, have.PostState, want.PostState)
+		}
+		if len(want.PostState) == 0 && have.Status != want.Status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, have.Status, want.Status)
+		}
+		enc, err = rlp.EncodeToBytes((*ReceiptForStorage)(want))
+		if err != nil {
+			t.Fatalf("test %d: failed to encode stored receipt: %v", i, err)
+		}
+		stored := new(ReceiptForStorage)
+		if err := rlp.DecodeBytes(enc, stored); err != nil {
+			t.Fatalf("test %d: failed to decode stored receipt: %v", i, err)
+		}
+		if !bytes.Equal(stored.PostState, want.PostState) || (len(want.PostState) == 0 && stored.Status != want.Status) {
+			t.Errorf("test %d: stored receipt mismatch: have %v, want %v", i, (*Receipt)(stored), want)
+		}
+	}
+}
+
+// Tests that receipts with a malformed status field are rejected.
+func TestReceiptInvalidStatus(t *testing.T) {
+	enc, _ := rlp.EncodeToBytes(&receiptRLP{[]byte{0x02}, big.NewInt(1), Bloom{}, nil})
+	if err := rlp.DecodeBytes(enc, new(Receipt)); err == nil {
+		t.Fatalf("invalid receipt status accepted")
+	}
+}
@@ -1 +1,179 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
 package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+)
+
+// precompiledTest defines the input/output pairs for precompiled contract tests.
+type precompiledTest struct {
+	input, expected string
+	gas             uint64
+	name            string
+	fail            bool
+}
+
+// modexpTests are the test and benchmark data for the modexp precompiled contract.
+var modexpTests = []precompiledTest{
+	{
+		input:    "0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000200" + "3fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2efffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:      13056,
+		name:     "eip_example1",
+	}, {
+		input:    "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000020fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2efffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000",
+		gas:      13056,
+		name:     "eip_example2",
+	}, {
+		input:    "",
+		expected: "",
+		gas:      0,
+		name:     "empty",
+	},
+}
+
+// bn256AddTests are the test and benchmark data for the bn256 addition precompiled
+// contract.
+var bn256AddTests = []precompiledTest{
+	{
+		input:    "17072b2ed3bb8d759a5325f477629386cb6fc6ecb801bd76983a6b86abffe078168ada6cd130dd52017bb54bfa19377aadfe3bf05d18f41b77809f7f60d4af9e05e86f8cc8a7a4f10f56093465679f17f8b8c3fdb41469e408b529e030f52f3f2857bd14bbc09767bed8e913d3ccb42b2bc8738f715417dd6f020725d22bcd90",
+		expected: "29e3af2e9b9fc756f0aad5f65c3e7fa3261511aaccdf6db64bcccd46be00aada1b2d12d6440e9a25be30cef27d46de19bc37a81eee974111ca578bd44e0340a0",
+		name:     "7g_plus_13g",
+	}, {
+		input:    "17072b2ed3bb8d759a5325f477629386cb6fc6ecb801bd76983a6b86abffe078168ada6cd130dd52017bb54bfa19377aadfe3bf05d18f41b77809f7f60d4af9e",
+		expected: "17072b2ed3bb8d759a5325f477629386cb6fc6ecb801bd76983a6b86abffe078168ada6cd130dd52017bb54bfa19377aadfe3bf05d18f41b77809f7f60d4af9e",
+		name:     "point_plus_infinity",
+	}, {
+		input:    "",
+		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		name:     "infinity_plus_infinity",
+	}, {
+		input: "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000003",
+		name:  "not_on_curve",
+		fail:  true,
+	},
+}
+
+// bn256ScalarMulTests are the test and benchmark data for the bn256 scalar
+// multipication precompiled contract.
+var bn256ScalarMulTests = []precompiledTest{
+	{
+		input:    "17072b2ed3bb8d759a5325f477629386cb6fc6ecb801bd76983a6b86abffe078168ada6cd130dd52017bb54bfa19377aadfe3bf05d18f41b77809f7f60d4af9e0000000000000000000000000000000000000000000000000000000000003039",
+		expected: "214723468fa2e326ba8896103d155b73b135c645326aae8b34e6aa72342623f124f9e2a03ea9444ffc2c8aaff882dbb6c13858215b8027681e7babae39f06f5e",
+		name:     "7g_times_12345",
+	}, {
+		input:    "0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000230644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000000",
+		expected: "000000000000000000000000000000000000000000000000000000000000000130644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45",
+		name:     "g_times_order_minus_one",
+	}, {
+		input: "30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4700000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001",
+		name:  "coordinate_out_of_field",
+		fail:  true,
+	},
+}
+
+// bn256PairingTests are the test and benchmark data for the bn256 pairing check
+// precompiled contract.
+var bn256PairingTests = []precompiledTest{
+	{
+		input:    "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c21800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa000000000000000000000000000000000000000000000000000000000000000130644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c21800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:      260000,
+		name:     "g1_neg_g1",
+	}, {
+		input:    "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c21800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c21800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000",
+		gas:      260000,
+		name:     "g1_g1",
+	}, {
+		input:    "",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:      100000,
+		name:     "empty_data",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000001",
+		name:  "bad_size",
+		fail:  true,
+	},
+}
+
+func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
+	p := PrecompiledContractsMetropolis[common.HexToAddress(addr)]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), p.RequiredGas(in))
+
+	if test.gas != 0 && contract.Gas != test.gas {
+		t.Errorf("%s: gas mismatch: have %d, want %d", test.name, contract.Gas, test.gas)
+	}
+	res, err := RunPrecompiledContract(p, in, contract)
+	switch {
+	case test.fail && err == nil:
+		t.Errorf("%s: expected failure, got %x", test.name, res)
+	case !test.fail && err != nil:
+		t.Errorf("%s: unexpected error: %v", test.name, err)
+	case !test.fail && !bytes.Equal(res, common.Hex2Bytes(test.expected)):
+		t.Errorf("%s: result mismatch: have %x, want %s", test.name, res, test.expected)
+	}
+}
+
+// Tests the sample inputs from the modexp EIP.
+func TestPrecompiledModExp(t *testing.T) {
+	for _, test := range modexpTests {
+		testPrecompiled("05", test, t)
+	}
+}
+
+// Tests the elliptic curve point addition precompile.
+func TestPrecompiledBn256Add(t *testing.T) {
+	for _, test := range bn256AddTests {
+		testPrecompiled("06", test, t)
+	}
+}
+
+// Tests the elliptic curve scalar multiplication precompile.
+func TestPrecompiledBn256ScalarMul(t *testing.T) {
+	for _, test := range bn256ScalarMulTests {
+		testPrecompiled("07", test, t)
+	}
+}
+
+// Tests the elliptic curve pairing check precompile.
+func TestPrecompiledBn256Pairing(t *testing.T) {
+	for _, test := range bn256PairingTests {
+		testPrecompiled("08", test, t)
+	}
+}
+
+// Tests that the new precompiles are only available after the Metropolis fork.
+func TestPrecompiledForks(t *testing.T) {
+	for i := byte(5); i <= 8; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if PrecompiledContractsHomestead[addr] != nil {
+			t.Errorf("precompile %x available before Metropolis", addr)
+		}
+		if PrecompiledContractsMetropolis[addr] == nil {
+			t.Errorf("precompile %x missing in Metropolis", addr)
+		}
+	}
+}
@@ -24,4 +24,8 @@ var (
 	ErrDepth               = errors.New("max call depth exceeded")
 	ErrTraceLimitReached   = errors.New("the number of logs reached the specified limit")
 	ErrInsufficientBalance = errors.New("insufficient balance for transfer")
+
+	ErrExecutionReverted     = errors.New("execution reverted")
+	ErrWriteProtection       = errors.New("write protection")
+	ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
 )
@@ -69,7 +69,7 @@ func precompiledBenchmark(addr, input, expected string, gas uint64, bench *testi
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), gas)
 
-	p := PrecompiledContracts[common.HexToAddress(addr)]
+	p := PrecompiledContractsMetropolis[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(input)
 	var (
 		res []byte
@@ -89,6 +89,18 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration) error
 }
 
+// FrameTracer is an optional extension of Tracer, notified whenever the EVM
+// enters or leaves a call frame (including the outermost one of a message).
+// It allows building call level traces without interpreting individual opcodes.
+//
+// CaptureEnter is invoked before any state modification of the frame (value
+// transfer, account or nonce changes) takes place.
+type FrameTracer interface {
+	Tracer
+	CaptureEnter(env *EVM, typ OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error
+	CaptureExit(env *EVM, output []byte, gasUsed uint64, err error) error
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
@@ -53,13 +53,14 @@ type Config struct {
 func setDefaults(cfg *Config) {
 	if cfg.ChainConfig == nil {
 		cfg.ChainConfig = &params.ChainConfig{
-			ChainId:        big.NewInt(1),
-			HomesteadBlock: new(big.Int),
-			DAOForkBlock:   new(big.Int),
-			DAOForkSupport: false,
-			EIP150Block:    new(big.Int),
-			EIP155Block:    new(big.Int),
-			EIP158Block:    new(big.Int),
+			ChainId:         big.NewInt(1),
+			HomesteadBlock:  new(big.Int),
+			DAOForkBlock:    new(big.Int),
+			DAOForkSupport:  false,
+			EIP150Block:     new(big.Int),
+			EIP155Block:     new(big.Int),
+			EIP158Block:     new(big.Int),
+			MetropolisBlock: new(big.Int),
 		}
 	}
 
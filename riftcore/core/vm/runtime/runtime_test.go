@@ -117,6 +117,118 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestRevert(t *testing.T) {
+	db, _ := riftdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	address := common.HexToAddress("0x0a")
+	state.SetCode(address, []byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.SSTORE),
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.REVERT),
+	})
+
+	ret, leftOverGas, err := Call(address, nil, &Config{State: state, GasLimit: 100000})
+	if err != vm.ErrExecutionReverted {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	if num := new(big.Int).SetBytes(ret); num.Cmp(big.NewInt(10)) != 0 {
+		t.Error("Expected 10, got", num)
+	}
+	if leftOverGas == 0 {
+		t.Error("expected remaining gas to be refunded on revert")
+	}
+	if val := state.GetState(address, common.Hash{}); val != (common.Hash{}) {
+		t.Errorf("storage not reverted: have %x", val)
+	}
+}
+
+func TestReturnData(t *testing.T) {
+	db, _ := riftdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	callee := common.HexToAddress("0x0b")
+	state.SetCode(callee, []byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.REVERT),
+	})
+	// Call the callee, then return its revert data through RETURNDATACOPY.
+	caller := common.HexToAddress("0x0a")
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, // out size
+		byte(vm.PUSH1), 0, // out offset
+		byte(vm.PUSH1), 0, // in size
+		byte(vm.PUSH1), 0, // in offset
+		byte(vm.PUSH1), 0x0b, // address
+		byte(vm.GAS),
+		byte(vm.STATICCALL),
+		byte(vm.POP),
+		byte(vm.RETURNDATASIZE),
+		byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURNDATACOPY),
+		byte(vm.RETURNDATASIZE),
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	})
+
+	ret, _, err := Call(caller, nil, &Config{State: state, GasLimit: 100000})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if num := new(big.Int).SetBytes(ret); num.Cmp(big.NewInt(10)) != 0 {
+		t.Error("Expected 10, got", num)
+	}
+}
+
+func TestStaticCallWriteProtection(t *testing.T) {
+	db, _ := riftdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	callee := common.HexToAddress("0x0b")
+	state.SetCode(callee, []byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.SSTORE),
+	})
+	// Static call the storage modifying callee and return the call's result.
+	caller := common.HexToAddress("0x0a")
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0b,
+		byte(vm.GAS),
+		byte(vm.STATICCALL),
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	})
+
+	ret, _, err := Call(caller, nil, &Config{State: state, GasLimit: 100000})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if new(big.Int).SetBytes(ret).Sign() != 0 {
+		t.Error("expected static call to fail")
+	}
+	if val := state.GetState(callee, common.Hash{}); val != (common.Hash{}) {
+		t.Errorf("storage modified in static call: have %x", val)
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -0,0 +1,59 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import "fmt"
+
+const (
+	DefaultHost = "localhost" // Default host interface for the GraphQL server
+	DefaultPort = 8547        // Default TCP port for the GraphQL server
+)
+
+// Config contains the settings of the GraphQL endpoint.
+type Config struct {
+	// Host is the host interface on which to start the GraphQL server. If this
+	// field is empty, no GraphQL endpoint will be started.
+	Host string `toml:",omitempty"`
+
+	// Port is the TCP port number on which to start the GraphQL server.
+	Port int `toml:",omitempty"`
+
+	// Cors is the Cross-Origin Resource Sharing header to send to requesting
+	// clients. Please be aware that CORS is a browser enforced security, it's
+	// fully useless for custom HTTP clients.
+	Cors []string `toml:",omitempty"`
+
+	// VirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests. This is by default {'localhost'}. Accepts '*' wildcard.
+	VirtualHosts []string `toml:",omitempty"`
+}
+
+// DefaultConfig contains the default settings of the GraphQL endpoint, disabled
+// until a host interface is set.
+var DefaultConfig = Config{
+	Port:         DefaultPort,
+	VirtualHosts: []string{"localhost"},
+}
+
+// Endpoint resolves the GraphQL endpoint based on the configured host interface
+// and port parameters, returning empty if the endpoint is disabled.
+func (c *Config) Endpoint() string {
+	if c.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
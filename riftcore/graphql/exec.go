@@ -0,0 +1,765 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// resolver computes the value of a field from its parent object and arguments.
+type resolver func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// scalarCoercer converts an input value (JSON decoded or literal) into the Go
+// representation of a scalar type.
+type scalarCoercer func(value interface{}) (interface{}, error)
+
+// fieldDef is the definition of a field of an object type.
+type fieldDef struct {
+	typ     *typeRef
+	args    map[string]*typeRef
+	resolve resolver
+}
+
+// objectDef is the definition of an object type.
+type objectDef struct {
+	name   string
+	fields map[string]*fieldDef
+}
+
+// inputDef is the definition of an input object type.
+type inputDef struct {
+	name   string
+	fields map[string]*typeRef
+}
+
+// schema is a set of type definitions along with the resolvers of their fields,
+// rooted at a query type.
+type schema struct {
+	query   string
+	objects map[string]*objectDef
+	inputs  map[string]*inputDef
+	scalars map[string]scalarCoercer
+}
+
+// newSchema creates an empty schema with the built-in scalar types.
+func newSchema(query string) *schema {
+	return &schema{
+		query:   query,
+		objects: make(map[string]*objectDef),
+		inputs:  make(map[string]*inputDef),
+		scalars: map[string]scalarCoercer{
+			"Int":     coerceInt,
+			"Float":   coerceFloat,
+			"String":  coerceString,
+			"Boolean": coerceBoolean,
+			"ID":      coerceString,
+		},
+	}
+}
+
+// object defines a new object type in the schema.
+func (s *schema) object(name string) *objectDef {
+	obj := &objectDef{name: name, fields: make(map[string]*fieldDef)}
+	s.objects[name] = obj
+	return obj
+}
+
+// input defines a new input object type with the given fields and types.
+func (s *schema) input(name string, fields map[string]string) {
+	def := &inputDef{name: name, fields: make(map[string]*typeRef)}
+	for field, typ := range fields {
+		def.fields[field] = mustParseType(typ)
+	}
+	s.inputs[name] = def
+}
+
+// field defines a field of the object type, with its type and argument types in
+// GraphQL notation.
+func (o *objectDef) field(name string, typ string, args map[string]string, resolve resolver) {
+	def := &fieldDef{typ: mustParseType(typ), args: make(map[string]*typeRef), resolve: resolve}
+	for arg, typ := range args {
+		def.args[arg] = mustParseType(typ)
+	}
+	o.fields[name] = def
+}
+
+// mustParseType parses a type reference in GraphQL notation, panicking on error.
+func mustParseType(typ string) *typeRef {
+	p := &parser{lex: &lexer{input: typ}}
+	if err := p.advance(); err != nil {
+		panic(err)
+	}
+	ref, err := p.parseType()
+	if err != nil || p.tok.kind != tokenEOF {
+		panic(fmt.Sprintf("invalid type %q: %v", typ, err))
+	}
+	return ref
+}
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the result of a GraphQL request.
+type Response struct {
+	Data   interface{}      `json:"data,omitempty"`
+	Errors []*ResponseError `json:"errors,omitempty"`
+}
+
+// ResponseError is an error encountered while processing a GraphQL request.
+type ResponseError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Error implements error, returning the error message.
+func (e *ResponseError) Error() string { return e.Message }
+
+// orderedObject is a response object retaining the order of the selected fields.
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields in selection order.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// execution is the state of a single request being executed.
+type execution struct {
+	schema    *schema
+	doc       *document
+	vars      map[string]interface{}
+	errors    []*ResponseError
+	validated map[string]selectionCost // Costs of the fragments already validated
+}
+
+// selectionCost is the size of a selection set with all fragments expanded,
+// bounding the work a query may cause.
+type selectionCost struct {
+	fields int // Number of fields selected, including nested and aliased ones
+	depth  int // Maximum nesting depth of the selected fields
+}
+
+// add accumulates the cost of a nested selection set, saturating the field count
+// above the allowed maximum to avoid overflows.
+func (c *selectionCost) add(nested selectionCost) {
+	if c.fields += nested.fields; c.fields > maxQueryFields {
+		c.fields = maxQueryFields + 1
+	}
+	if nested.depth > c.depth {
+		c.depth = nested.depth
+	}
+}
+
+const (
+	maxQueryDepth  = 16   // Maximum nesting depth of the fields of a query
+	maxQueryFields = 1024 // Maximum number of fields a query may select
+)
+
+// exec parses, validates and executes a request against the schema.
+func (s *schema) exec(ctx context.Context, req *Request) *Response {
+	doc, err := parseDocument(req.Query)
+	if err != nil {
+		return &Response{Errors: []*ResponseError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*ResponseError{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*ResponseError{{Message: fmt.Sprintf("%s operations not supported", op.kind)}}}
+	}
+	e := &execution{schema: s, doc: doc, validated: make(map[string]selectionCost)}
+	if err := e.validate(ctx, op, s.objects[s.query]); err != nil {
+		return &Response{Errors: []*ResponseError{{Message: err.Error()}}}
+	}
+	if e.vars, err = s.coerceVariables(op, req.Variables); err != nil {
+		return &Response{Errors: []*ResponseError{{Message: err.Error()}}}
+	}
+	data, ok := e.executeSelections(ctx, s.objects[s.query], nil, op.selections, nil)
+	if err := ctx.Err(); err != nil {
+		return &Response{Errors: []*ResponseError{{Message: err.Error()}}}
+	}
+	if ok {
+		return &Response{Data: data, Errors: e.errors}
+	}
+	return &Response{Errors: e.errors}
+}
+
+// operation looks up the operation to execute, which must be named if the
+// document contains more than one.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operation name required for documents with multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// validate checks the selections of an operation against the schema, ensuring
+// that all fields, arguments and fragments exist and are used correctly, and
+// that the query stays within the allowed depth and number of fields.
+func (e *execution) validate(ctx context.Context, op *operation, root *objectDef) error {
+	declared := make(map[string]bool)
+	for _, def := range op.vars {
+		if declared[def.name] {
+			return fmt.Errorf("duplicate variable $%s", def.name)
+		}
+		if !e.schema.isInput(def.typ) {
+			return fmt.Errorf("variable $%s has non-input type %s", def.name, def.typ)
+		}
+		declared[def.name] = true
+	}
+	cost, err := e.validateSelections(ctx, root, op.selections, declared, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+	if cost.depth > maxQueryDepth {
+		return fmt.Errorf("query too deep (%d>%d)", cost.depth, maxQueryDepth)
+	}
+	if cost.fields > maxQueryFields {
+		return fmt.Errorf("query selects too many fields (>%d)", maxQueryFields)
+	}
+	return nil
+}
+
+// validateSelections recursively validates a selection set on an object type,
+// returning its cost. Fragments are only validated once, on their first spread.
+func (e *execution) validateSelections(ctx context.Context, obj *objectDef, selections []selection, vars map[string]bool, visiting map[string]bool) (selectionCost, error) {
+	var cost selectionCost
+	for _, sel := range selections {
+		if err := ctx.Err(); err != nil {
+			return cost, err
+		}
+		switch sel := sel.(type) {
+		case *field:
+			if err := validateDirectives(sel.directives, vars); err != nil {
+				return cost, err
+			}
+			if sel.name == "__typename" {
+				if len(sel.args) > 0 || sel.selections != nil {
+					return cost, fmt.Errorf("invalid selection of __typename on %s", obj.name)
+				}
+				cost.add(selectionCost{fields: 1, depth: 1})
+				continue
+			}
+			def, ok := obj.fields[sel.name]
+			if !ok {
+				return cost, fmt.Errorf("unknown field %q on type %s", sel.name, obj.name)
+			}
+			given := make(map[string]bool)
+			for _, arg := range sel.args {
+				if _, ok := def.args[arg.name]; !ok {
+					return cost, fmt.Errorf("unknown argument %q on field %s.%s", arg.name, obj.name, sel.name)
+				}
+				if given[arg.name] {
+					return cost, fmt.Errorf("duplicate argument %q on field %s.%s", arg.name, obj.name, sel.name)
+				}
+				if err := validateValue(arg.value, vars); err != nil {
+					return cost, err
+				}
+				given[arg.name] = true
+			}
+			for _, name := range sortedKeys(def.args) {
+				if def.args[name].nonNull && !given[name] {
+					return cost, fmt.Errorf("missing required argument %q on field %s.%s", name, obj.name, sel.name)
+				}
+			}
+			named := def.typ
+			for named.elem != nil {
+				named = named.elem
+			}
+			if child, ok := e.schema.objects[named.name]; ok {
+				if sel.selections == nil {
+					return cost, fmt.Errorf("field %s.%s of type %s must have a selection set", obj.name, sel.name, def.typ)
+				}
+				nested, err := e.validateSelections(ctx, child, sel.selections, vars, visiting)
+				if err != nil {
+					return cost, err
+				}
+				nested.depth++
+				cost.add(nested)
+				cost.add(selectionCost{fields: 1})
+			} else if sel.selections != nil {
+				return cost, fmt.Errorf("field %s.%s of type %s can't have a selection set", obj.name, sel.name, def.typ)
+			} else {
+				cost.add(selectionCost{fields: 1, depth: 1})
+			}
+
+		case *fragmentSpread:
+			if err := validateDirectives(sel.directives, vars); err != nil {
+				return cost, err
+			}
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				return cost, fmt.Errorf("unknown fragment %q", sel.name)
+			}
+			if nested, ok := e.validated[frag.name]; ok {
+				cost.add(nested)
+				continue
+			}
+			if visiting[frag.name] {
+				return cost, fmt.Errorf("fragment %q references itself", frag.name)
+			}
+			target, ok := e.schema.objects[frag.on]
+			if !ok {
+				return cost, fmt.Errorf("unknown type %q in fragment %q", frag.on, frag.name)
+			}
+			visiting[frag.name] = true
+			nested, err := e.validateSelections(ctx, target, frag.selections, vars, visiting)
+			if err != nil {
+				return cost, err
+			}
+			delete(visiting, frag.name)
+
+			e.validated[frag.name] = nested
+			cost.add(nested)
+
+		case *inlineFragment:
+			if err := validateDirectives(sel.directives, vars); err != nil {
+				return cost, err
+			}
+			target := obj
+			if sel.on != "" {
+				var ok bool
+				if target, ok = e.schema.objects[sel.on]; !ok {
+					return cost, fmt.Errorf("unknown type %q in inline fragment", sel.on)
+				}
+			}
+			nested, err := e.validateSelections(ctx, target, sel.selections, vars, visiting)
+			if err != nil {
+				return cost, err
+			}
+			cost.add(nested)
+		}
+	}
+	return cost, nil
+}
+
+// validateDirectives ensures only the built-in @skip and @include directives are
+// used, with their required condition.
+func validateDirectives(directives []*directive, vars map[string]bool) error {
+	for _, dir := range directives {
+		if dir.name != "skip" && dir.name != "include" {
+			return fmt.Errorf("unknown directive @%s", dir.name)
+		}
+		if len(dir.args) != 1 || dir.args[0].name != "if" {
+			return fmt.Errorf("directive @%s requires a single \"if\" argument", dir.name)
+		}
+		if err := validateValue(dir.args[0].value, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValue ensures all variables referenced by a value are declared.
+func validateValue(value interface{}, vars map[string]bool) error {
+	switch value := value.(type) {
+	case variableRef:
+		if !vars[string(value)] {
+			return fmt.Errorf("undeclared variable $%s", value)
+		}
+	case []interface{}:
+		for _, elem := range value {
+			if err := validateValue(elem, vars); err != nil {
+				return err
+			}
+		}
+	case objectValue:
+		for _, field := range value {
+			if err := validateValue(field.value, vars); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isInput reports whether a type reference denotes a scalar or input type.
+func (s *schema) isInput(typ *typeRef) bool {
+	for typ.elem != nil {
+		typ = typ.elem
+	}
+	_, scalar := s.scalars[typ.name]
+	_, input := s.inputs[typ.name]
+	return scalar || input
+}
+
+// coerceVariables checks the request variables against the types they were
+// declared with, applying defaults.
+func (s *schema) coerceVariables(op *operation, values map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	for _, def := range op.vars {
+		value, ok := values[def.name]
+		if !ok {
+			if def.def == nil {
+				if def.typ.nonNull {
+					return nil, fmt.Errorf("missing value for variable $%s", def.name)
+				}
+				continue
+			}
+			value = literalValue(def.def, nil)
+		}
+		if _, err := s.coerce(def.typ, value); err != nil {
+			return nil, fmt.Errorf("invalid value for variable $%s: %v", def.name, err)
+		}
+		vars[def.name] = value // coerced again where used, along with literals
+	}
+	return vars, nil
+}
+
+// literalValue converts a value literal into its JSON decoded equivalent,
+// substituting the referenced variables.
+func literalValue(value interface{}, vars map[string]interface{}) interface{} {
+	switch value := value.(type) {
+	case variableRef:
+		return vars[string(value)]
+	case intLiteral:
+		return json.Number(value)
+	case floatLiteral:
+		return json.Number(value)
+	case enumLiteral:
+		return string(value)
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, elem := range value {
+			list[i] = literalValue(elem, vars)
+		}
+		return list
+	case objectValue:
+		object := make(map[string]interface{})
+		for _, field := range value {
+			object[field.name] = literalValue(field.value, vars)
+		}
+		return object
+	}
+	return value
+}
+
+// coerce converts a JSON decoded input value to the Go representation of the
+// given input type. Lists accept single values as a list of one element.
+func (s *schema) coerce(typ *typeRef, value interface{}) (interface{}, error) {
+	if value == nil {
+		if typ.nonNull {
+			return nil, fmt.Errorf("null value for non-null type %s", typ)
+		}
+		return nil, nil
+	}
+	if typ.elem != nil {
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		coerced := make([]interface{}, len(list))
+		for i, elem := range list {
+			var err error
+			if coerced[i], err = s.coerce(typ.elem, elem); err != nil {
+				return nil, err
+			}
+		}
+		return coerced, nil
+	}
+	if coerce, ok := s.scalars[typ.name]; ok {
+		return coerce(value)
+	}
+	input, ok := s.inputs[typ.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown input type %s", typ.name)
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %s value %v", typ.name, value)
+	}
+	coerced := make(map[string]interface{})
+	for name := range object {
+		if _, ok := input.fields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q of %s", name, typ.name)
+		}
+	}
+	for _, name := range sortedKeys(input.fields) {
+		value, err := s.coerce(input.fields[name], object[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %q of %s: %v", name, typ.name, err)
+		}
+		if value != nil {
+			coerced[name] = value
+		}
+	}
+	return coerced, nil
+}
+
+// collectFields gathers the fields of a selection set to execute, grouped by
+// response key in selection order, resolving fragments and directives.
+func (e *execution) collectFields(obj *objectDef, selections []selection, keys []string, groups map[string][]*field, visited map[string]bool) []string {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+			if _, ok := groups[sel.alias]; !ok {
+				keys = append(keys, sel.alias)
+			}
+			groups[sel.alias] = append(groups[sel.alias], sel)
+
+		case *fragmentSpread:
+			if !e.included(sel.directives) || visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+			if frag := e.doc.fragments[sel.name]; frag.on == obj.name {
+				keys = e.collectFields(obj, frag.selections, keys, groups, visited)
+			}
+
+		case *inlineFragment:
+			if !e.included(sel.directives) || (sel.on != "" && sel.on != obj.name) {
+				continue
+			}
+			keys = e.collectFields(obj, sel.selections, keys, groups, visited)
+		}
+	}
+	return keys
+}
+
+// included evaluates the @skip and @include directives of a selection.
+func (e *execution) included(directives []*directive) bool {
+	for _, dir := range directives {
+		cond, _ := literalValue(dir.args[0].value, e.vars).(bool)
+		if (dir.name == "skip" && cond) || (dir.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// executeSelections resolves the selected fields of an object. If a non-null
+// field resolves to null, the whole object is nulled, reported by false. The
+// execution is aborted the same way if the context is cancelled.
+func (e *execution) executeSelections(ctx context.Context, obj *objectDef, source interface{}, selections []selection, path []interface{}) (*orderedObject, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	groups := make(map[string][]*field)
+	keys := e.collectFields(obj, selections, nil, groups, make(map[string]bool))
+
+	result := new(orderedObject)
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		fields := groups[key]
+		if fields[0].name == "__typename" {
+			result.keys, result.values = append(result.keys, key), append(result.values, obj.name)
+			continue
+		}
+		def := obj.fields[fields[0].name]
+		value, ok := e.executeField(ctx, def, source, fields, append(path[:len(path):len(path)], key))
+		if !ok {
+			return nil, false
+		}
+		result.keys, result.values = append(result.keys, key), append(result.values, value)
+	}
+	return result, true
+}
+
+// executeField resolves a single field and completes its value. Resolver errors
+// are recorded and turn the field null.
+func (e *execution) executeField(ctx context.Context, def *fieldDef, source interface{}, fields []*field, path []interface{}) (interface{}, bool) {
+	args, err := e.arguments(def, fields[0])
+	if err == nil {
+		var value interface{}
+		if value, err = def.resolve(ctx, source, args); err == nil {
+			return e.complete(ctx, def.typ, fields, value, path)
+		}
+	}
+	e.errors = append(e.errors, &ResponseError{Message: err.Error(), Path: path})
+	return nil, !def.typ.nonNull
+}
+
+// arguments coerces the arguments of a field to their declared types.
+func (e *execution) arguments(def *fieldDef, f *field) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	for _, arg := range f.args {
+		value, err := e.schema.coerce(def.args[arg.name], literalValue(arg.value, e.vars))
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q: %v", arg.name, err)
+		}
+		if value != nil {
+			args[arg.name] = value
+		}
+	}
+	for _, name := range sortedKeys(def.args) {
+		if _, ok := args[name]; !ok && def.args[name].nonNull {
+			return nil, fmt.Errorf("missing value for argument %q", name)
+		}
+	}
+	return args, nil
+}
+
+// complete converts a resolved value into its response representation based on
+// the field type, executing sub-selections of objects and lists. The returned
+// flag is false if a null value violated a non-null type.
+func (e *execution) complete(ctx context.Context, typ *typeRef, fields []*field, value interface{}, path []interface{}) (interface{}, bool) {
+	if isNil(value) {
+		if typ.nonNull {
+			e.errors = append(e.errors, &ResponseError{Message: fmt.Sprintf("null value for non-null type %s", typ), Path: path})
+			return nil, false
+		}
+		return nil, true
+	}
+	if typ.elem != nil {
+		list := reflect.ValueOf(value)
+		if list.Kind() != reflect.Slice {
+			e.errors = append(e.errors, &ResponseError{Message: fmt.Sprintf("non-list value for type %s", typ), Path: path})
+			return nil, !typ.nonNull
+		}
+		results := make([]interface{}, list.Len())
+		for i := range results {
+			elem, ok := e.complete(ctx, typ.elem, fields, list.Index(i).Interface(), append(path[:len(path):len(path)], i))
+			if !ok {
+				return nil, !typ.nonNull
+			}
+			results[i] = elem
+		}
+		return results, true
+	}
+	obj, ok := e.schema.objects[typ.name]
+	if !ok {
+		return value, true // scalars are serialized as is
+	}
+	var selections []selection
+	for _, f := range fields {
+		selections = append(selections, f.selections...)
+	}
+	result, ok := e.executeSelections(ctx, obj, value, selections, path)
+	if !ok {
+		return nil, !typ.nonNull
+	}
+	return result, true
+}
+
+// isNil reports whether a resolved value is nil, including typed nil pointers
+// and maps. Nil slices are empty lists or byte strings, not null.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// coerceInt converts an input value to a 32 bit signed integer.
+func coerceInt(value interface{}) (interface{}, error) {
+	n, err := coerceInt64(value)
+	if err != nil {
+		return nil, err
+	}
+	if n < -1<<31 || n >= 1<<31 {
+		return nil, fmt.Errorf("integer %d out of range", n)
+	}
+	return int(n), nil
+}
+
+// coerceInt64 converts an integral input value to a 64 bit signed integer.
+func coerceInt64(value interface{}) (int64, error) {
+	switch value := value.(type) {
+	case json.Number:
+		return strconv.ParseInt(string(value), 10, 64)
+	case float64:
+		if value != float64(int64(value)) {
+			return 0, fmt.Errorf("non-integral number %v", value)
+		}
+		return int64(value), nil
+	}
+	return 0, fmt.Errorf("invalid integer %v", value)
+}
+
+// coerceFloat converts an input value to a floating point number.
+func coerceFloat(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case json.Number:
+		return value.Float64()
+	case float64:
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid float %v", value)
+}
+
+// coerceString accepts string input values.
+func coerceString(value interface{}) (interface{}, error) {
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	return nil, fmt.Errorf("invalid string %v", value)
+}
+
+// coerceBoolean accepts boolean input values.
+func coerceBoolean(value interface{}) (interface{}, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("invalid boolean %v", value)
+}
+
+// sortedKeys returns the names of a set of typed arguments or fields in sorted
+// order.
+func sortedKeys(set map[string]*typeRef) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,290 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPerson is the source value of the objects in the test schema.
+type testPerson struct {
+	name    string
+	age     int
+	friends []*testPerson
+}
+
+// newTestSchema creates a small schema of people and their friends to exercise
+// the query engine with.
+func newTestSchema() *schema {
+	alice := &testPerson{name: "alice", age: 30}
+	bob := &testPerson{name: "bob", age: 25}
+	carol := &testPerson{name: "carol", age: 35}
+	alice.friends = []*testPerson{bob, carol}
+	bob.friends = []*testPerson{alice}
+	people := []*testPerson{alice, bob, carol}
+
+	s := newSchema("Query")
+	s.input("Filter", map[string]string{"minAge": "Int!", "names": "[String!]"})
+
+	person := s.object("Person")
+	person.field("name", "String!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*testPerson).name, nil
+	})
+	person.field("age", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*testPerson).age, nil
+	})
+	person.field("friends", "[Person!]!", map[string]string{"first": "Int"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		friends := src.(*testPerson).friends
+		if first, ok := args["first"].(int); ok && first < len(friends) {
+			friends = friends[:first]
+		}
+		return friends, nil
+	})
+	person.field("secret", "String!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("access denied")
+	})
+	person.field("nickname", "String", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	query := s.object("Query")
+	query.field("person", "Person", map[string]string{"name": "String!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		for _, p := range people {
+			if p.name == args["name"] {
+				return p, nil
+			}
+		}
+		return nil, nil
+	})
+	query.field("people", "[Person!]!", map[string]string{"filter": "Filter"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		filter, ok := args["filter"].(map[string]interface{})
+		if !ok {
+			return people, nil
+		}
+		var names map[string]bool
+		if list, ok := filter["names"].([]interface{}); ok {
+			names = make(map[string]bool)
+			for _, name := range list {
+				names[name.(string)] = true
+			}
+		}
+		var result []*testPerson
+		for _, p := range people {
+			if p.age >= filter["minAge"].(int) && (names == nil || names[p.name]) {
+				result = append(result, p)
+			}
+		}
+		return result, nil
+	})
+	return s
+}
+
+var execTests = []struct {
+	query     string
+	operation string
+	variables string
+	want      string
+}{
+	// Plain field selection, aliases and argument passing
+	{
+		query: `{ person(name: "alice") { name years: age } }`,
+		want:  `{"data":{"person":{"name":"alice","years":30}}}`,
+	},
+	{
+		query: `query { person(name: "dave") { name } }`,
+		want:  `{"data":{"person":null}}`,
+	},
+	{
+		query: `{ person(name: "alice") { friends(first: 1) { name friends { name } } } }`,
+		want:  `{"data":{"person":{"friends":[{"name":"bob","friends":[{"name":"alice"}]}]}}}`,
+	},
+	{
+		query: `{ people { name, __typename } }`,
+		want:  `{"data":{"people":[{"name":"alice","__typename":"Person"},{"name":"bob","__typename":"Person"},{"name":"carol","__typename":"Person"}]}}`,
+	},
+	// Input objects, list coercion and variables
+	{
+		query: `{ people(filter: {minAge: 30, names: "carol"}) { name } }`,
+		want:  `{"data":{"people":[{"name":"carol"}]}}`,
+	},
+	{
+		query:     `query Old($age: Int!, $names: [String!]) { people(filter: {minAge: $age, names: $names}) { name } }`,
+		variables: `{"age": 26, "names": ["alice", "bob"]}`,
+		want:      `{"data":{"people":[{"name":"alice"}]}}`,
+	},
+	{
+		query:     `query ($who: String = "bob") { person(name: $who) { age } }`,
+		variables: `{}`,
+		want:      `{"data":{"person":{"age":25}}}`,
+	},
+	// Fragments and directives
+	{
+		query: `{ person(name: "bob") { ...info friends { ... on Person { age } } } } fragment info on Person { name age }`,
+		want:  `{"data":{"person":{"name":"bob","age":25,"friends":[{"age":30}]}}}`,
+	},
+	{
+		query:     `query ($full: Boolean!) { person(name: "bob") { name age @include(if: $full) nickname @skip(if: true) } }`,
+		variables: `{"full": false}`,
+		want:      `{"data":{"person":{"name":"bob"}}}`,
+	},
+	// Operation selection
+	{
+		query:     `query A { person(name: "alice") { age } } query B { person(name: "bob") { age } }`,
+		operation: "B",
+		want:      `{"data":{"person":{"age":25}}}`,
+	},
+	{
+		query: `query A { person(name: "alice") { age } } query B { person(name: "bob") { age } }`,
+		want:  `{"errors":[{"message":"operation name required for documents with multiple operations"}]}`,
+	},
+	{
+		query: `mutation { person(name: "alice") { age } }`,
+		want:  `{"errors":[{"message":"mutation operations not supported"}]}`,
+	},
+	// Resolver errors and null propagation
+	{
+		query: `{ person(name: "alice") { name nickname } }`,
+		want:  `{"data":{"person":{"name":"alice","nickname":null}}}`,
+	},
+	{
+		query: `{ person(name: "alice") { name secret } }`,
+		want:  `{"data":{"person":null},"errors":[{"message":"access denied","path":["person","secret"]}]}`,
+	},
+	{
+		query: `{ people { secret } }`,
+		want:  `{"errors":[{"message":"access denied","path":["people",0,"secret"]}]}`,
+	},
+	// Validation failures
+	{
+		query: `{ person(name: "alice") { height } }`,
+		want:  `{"errors":[{"message":"unknown field \"height\" on type Person"}]}`,
+	},
+	{
+		query: `{ person { name } }`,
+		want:  `{"errors":[{"message":"missing required argument \"name\" on field Query.person"}]}`,
+	},
+	{
+		query: `{ person(name: "alice") }`,
+		want:  `{"errors":[{"message":"field Query.person of type Person must have a selection set"}]}`,
+	},
+	{
+		query: `{ person(name: $who) { name } }`,
+		want:  `{"errors":[{"message":"undeclared variable $who"}]}`,
+	},
+	{
+		query: `{ person(name: "alice") { ...loop } } fragment loop on Person { friends { ...loop } }`,
+		want:  `{"errors":[{"message":"fragment \"loop\" references itself"}]}`,
+	},
+	{
+		query:     `query ($age: Int!) { people(filter: {minAge: $age}) { name } }`,
+		variables: `{"age": "old"}`,
+		want:      `{"errors":[{"message":"invalid value for variable $age: invalid integer old"}]}`,
+	},
+	{
+		query: `{ person(name: "alice") { name `,
+		want:  `{"errors":[{"message":"unexpected end of document"}]}`,
+	},
+}
+
+func TestExec(t *testing.T) {
+	s := newTestSchema()
+	for i, tt := range execTests {
+		req := &Request{Query: tt.query, OperationName: tt.operation}
+		if tt.variables != "" {
+			if err := decodeJSON([]byte(tt.variables), &req.Variables); err != nil {
+				t.Fatalf("test %d: failed to decode variables: %v", i, err)
+			}
+		}
+		res, err := json.Marshal(s.exec(context.Background(), req))
+		if err != nil {
+			t.Errorf("test %d: failed to encode response: %v", i, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("test %d: response mismatch:\nquery: %s\nhave:  %s\nwant:  %s", i, tt.query, res, tt.want)
+		}
+	}
+}
+
+var parseErrorTests = []string{
+	`{ a(b: ) }`,
+	`{ a(b: 1, b: 2) }`,
+	`query ($a Int) { a }`,
+	`{ a } fragment on on A { b }`,
+	`{ a(b: "unterminated) }`,
+	`{ a(b: 1.) }`,
+	`{ a(b: """block""") }`,
+	`{ a } { b }`,
+	`fragment f on A { b }`,
+	`query { a } query { b }`,
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, query := range parseErrorTests {
+		doc, err := parseDocument(query)
+		if err == nil {
+			_, err = doc.operation("")
+		}
+		if err == nil {
+			t.Errorf("test %d: expected error for query %q", i, query)
+		}
+	}
+}
+
+// Tests that queries expanding into an excessive amount of work are rejected
+// quickly during validation.
+func TestExecLimits(t *testing.T) {
+	s := newTestSchema()
+
+	// Each fragment spreads the next one twice, expanding exponentially
+	query := `{ person(name: "alice") { ...f0 } }`
+	for i := 0; i < 64; i++ {
+		query += fmt.Sprintf(` fragment f%d on Person { ...f%d ...f%d }`, i, i+1, i+1)
+	}
+	query += ` fragment f64 on Person { name }`
+
+	start := time.Now()
+	res, _ := json.Marshal(s.exec(context.Background(), &Request{Query: query}))
+	if want := fmt.Sprintf(`{"errors":[{"message":"query selects too many fields (\u003e%d)"}]}`, maxQueryFields); string(res) != want {
+		t.Errorf("fragment bomb: response mismatch: have %s, want %s", res, want)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fragment bomb: validation took too long: %v", elapsed)
+	}
+	// Deeply nested selections are rejected even if small
+	query = `{ person(name: "alice") { name } }`
+	for i := 0; i < maxQueryDepth; i++ {
+		query = strings.Replace(query, "{ name }", "{ friends { name } }", 1)
+	}
+	res, _ = json.Marshal(s.exec(context.Background(), &Request{Query: query}))
+	if want := fmt.Sprintf(`{"errors":[{"message":"query too deep (%d\u003e%d)"}]}`, maxQueryDepth+2, maxQueryDepth); string(res) != want {
+		t.Errorf("deep query: response mismatch: have %s, want %s", res, want)
+	}
+	// Cancelled requests are not executed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, _ = json.Marshal(s.exec(ctx, &Request{Query: `{ people { name } }`}))
+	if want := `{"errors":[{"message":"context canceled"}]}`; string(res) != want {
+		t.Errorf("cancelled query: response mismatch: have %s, want %s", res, want)
+	}
+}
@@ -0,0 +1,1284 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the chain data of a full or
+// light node, allowing related blocks, transactions, receipts, logs and account
+// states to be retrieved in a single request.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/state"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/rift/filters"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// maxBlockRange is the maximum number of blocks a single range query may return.
+const maxBlockRange = 1024
+
+var (
+	errBlockNotFound = errors.New("block not found")
+	errBlockConflict = errors.New("only one of block number and hash may be specified")
+)
+
+// Account is a CryptoRift account at a particular block.
+type Account struct {
+	backend     riftapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber // Number of the block, used if the hash is not known
+	blockHash   common.Hash     // Hash of the block, zero if looked up by number
+}
+
+// state retrieves the state the account is resolved against.
+func (a *Account) state(ctx context.Context) (*state.StateDB, error) {
+	var (
+		state *state.StateDB
+		err   error
+	)
+	if a.blockHash != (common.Hash{}) {
+		state, _, err = a.backend.StateAndHeaderByHash(ctx, a.blockHash)
+	} else {
+		state, _, err = a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errBlockNotFound
+	}
+	return state, nil
+}
+
+func (a *Account) Address() common.Address {
+	return a.address
+}
+
+func (a *Account) Balance(ctx context.Context) (*hexutil.Big, error) {
+	state, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(state.GetBalance(a.address)), state.Error()
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (uint64, error) {
+	state, err := a.state(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return state.GetNonce(a.address), state.Error()
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	state, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), state.Error()
+}
+
+func (a *Account) Storage(ctx context.Context, slot common.Hash) (common.Hash, error) {
+	state, err := a.state(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, slot), state.Error()
+}
+
+// Log is a contract event emitted by a transaction.
+type Log struct {
+	backend     riftapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+// newLog wraps a log retrieved from the chain, linking it to its transaction.
+func newLog(backend riftapi.Backend, log *types.Log) *Log {
+	block := &Block{backend: backend, hash: log.BlockHash, number: rpc.BlockNumber(log.BlockNumber)}
+	return &Log{
+		backend:     backend,
+		transaction: &Transaction{backend: backend, hash: log.TxHash, block: block, index: uint64(log.TxIndex)},
+		log:         log,
+	}
+}
+
+func (l *Log) Index() int {
+	return int(l.log.Index)
+}
+
+func (l *Log) Account(blockNumber rpc.BlockNumber) *Account {
+	return &Account{backend: l.backend, address: l.log.Address, blockNumber: blockNumber}
+}
+
+func (l *Log) Topics() []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data() hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+func (l *Log) Transaction() *Transaction {
+	return l.transaction
+}
+
+// Transaction is a CryptoRift transaction, either included in a block or pending
+// in the transaction pool. Fields are lazily resolved from the hash if needed.
+type Transaction struct {
+	backend riftapi.Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block // Block containing the transaction, nil if pending
+	index   uint64 // Index of the transaction within the block
+}
+
+// resolve retrieves the transaction from its block, the chain database or the
+// transaction pool, returning nil if it's unknown.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	if t.tx != nil {
+		return t.tx, nil
+	}
+	if t.block != nil {
+		block, err := t.block.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if txs := block.Transactions(); t.index < uint64(len(txs)) {
+			t.tx = txs[t.index]
+		}
+		return t.tx, nil
+	}
+	tx, blockHash, blockNumber, index := core.GetTransaction(t.backend.ChainDb(), t.hash)
+	if tx != nil {
+		t.tx, t.index = tx, index
+		t.block = &Block{backend: t.backend, hash: blockHash, number: rpc.BlockNumber(blockNumber)}
+		return tx, nil
+	}
+	t.tx = t.backend.GetPoolTransaction(t.hash)
+	return t.tx, nil
+}
+
+// receipt retrieves the receipt of the transaction, returning nil if it's not
+// yet included in a block.
+func (t *Transaction) receipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil || t.index >= uint64(len(receipts)) {
+		return nil, err
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Hash() common.Hash {
+	return t.hash
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return tx.Nonce(), nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (interface{}, error) {
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err
+	}
+	return int(t.index), nil
+}
+
+func (t *Transaction) From(ctx context.Context, blockNumber rpc.BlockNumber) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: from, blockNumber: blockNumber}, nil
+}
+
+func (t *Transaction) To(ctx context.Context, blockNumber rpc.BlockNumber) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || tx.To() == nil {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: *tx.To(), blockNumber: blockNumber}, nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(tx.Value()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(tx.GasPrice()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return tx.Gas().Uint64(), nil
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (interface{}, error) {
+	receipt, err := t.receipt(ctx)
+	if err != nil || receipt == nil || len(receipt.PostState) > 0 {
+		return nil, err // pre-Byzantium receipts carry a state root instead
+	}
+	return uint64(receipt.Status), nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (interface{}, error) {
+	receipt, err := t.receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return receipt.GasUsed.Uint64(), nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (interface{}, error) {
+	receipt, err := t.receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return receipt.CumulativeGasUsed.Uint64(), nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, blockNumber rpc.BlockNumber) (*Account, error) {
+	receipt, err := t.receipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: receipt.ContractAddress, blockNumber: blockNumber}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (interface{}, error) {
+	receipt, err := t.receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	logs := make([]*Log, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = &Log{backend: t.backend, transaction: t, log: log}
+	}
+	return logs, nil
+}
+
+// Block is a CryptoRift block, identified either by hash or by number. Fields are
+// lazily resolved, retrieving only the header if the body is not needed.
+type Block struct {
+	backend  riftapi.Backend
+	number   rpc.BlockNumber // Number of the block, used if the hash is not known
+	hash     common.Hash     // Hash of the block, zero if looked up by number
+	header   *types.Header
+	block    *types.Block
+	receipts types.Receipts
+}
+
+// resolve retrieves the full block, failing if it's unknown.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var err error
+	if b.hash != (common.Hash{}) {
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	} else {
+		b.block, err = b.backend.BlockByNumber(ctx, b.number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.block == nil {
+		return nil, errBlockNotFound
+	}
+	b.header = b.block.Header()
+	return b.block, nil
+}
+
+// resolveHeader retrieves the header of the block, failing if it's unknown.
+func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
+	if b.header != nil {
+		return b.header, nil
+	}
+	if b.hash != (common.Hash{}) {
+		if _, err := b.resolve(ctx); err != nil {
+			return nil, err
+		}
+		return b.header, nil
+	}
+	header, err := b.backend.HeaderByNumber(ctx, b.number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errBlockNotFound
+	}
+	b.header = header
+	return header, nil
+}
+
+// resolveReceipts retrieves the receipts of the transactions in the block.
+func (b *Block) resolveReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts != nil {
+		return b.receipts, nil
+	}
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if b.receipts, err = b.backend.GetReceipts(ctx, hash); err != nil {
+		return nil, err
+	}
+	return b.receipts, nil
+}
+
+// pending reports whether the block is the pending one, which has no state root
+// of its own to resolve the state with.
+func (b *Block) pending() bool {
+	return b.hash == (common.Hash{}) && b.number == rpc.PendingBlockNumber
+}
+
+// state retrieves the state of the block by its hash, ensuring it is the state
+// rooted in the block itself, not the one of a canonical block of the same number.
+func (b *Block) state(ctx context.Context) (*state.StateDB, *types.Header, error) {
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, header, err := b.backend.StateAndHeaderByHash(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return nil, nil, errBlockNotFound
+	}
+	return state, header, nil
+}
+
+func (b *Block) Number(ctx context.Context) (uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	if b.hash == (common.Hash{}) {
+		header, err := b.resolveHeader(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		b.hash = header.Hash()
+	}
+	return b.hash, nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil || header.Number.Sign() == 0 {
+		return nil, err
+	}
+	number := rpc.BlockNumber(header.Number.Int64() - 1)
+	return &Block{backend: b.backend, hash: header.ParentHash, number: number}, nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(header.Nonce[:]), nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.TxHash, nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Root, nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.ReceiptHash, nil
+}
+
+func (b *Block) OmmerHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.UncleHash, nil
+}
+
+func (b *Block) Miner(ctx context.Context, blockNumber rpc.BlockNumber) (*Account, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{backend: b.backend, address: header.Coinbase, blockNumber: blockNumber}, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(header.Extra), nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return header.GasLimit.Uint64(), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return header.GasUsed.Uint64(), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (*hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(header.Time), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(header.Bloom.Bytes()), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.MixDigest, nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (*hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(header.Difficulty), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (*hexutil.Big, error) {
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	td := b.backend.GetTd(hash)
+	if td == nil {
+		return nil, fmt.Errorf("total difficulty of block %x not found", hash)
+	}
+	return (*hexutil.Big)(td), nil
+}
+
+func (b *Block) OmmerCount(ctx context.Context) (int, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(block.Uncles()), nil
+}
+
+func (b *Block) Ommers(ctx context.Context) ([]*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ommers := make([]*Block, len(block.Uncles()))
+	for i, uncle := range block.Uncles() {
+		ommers[i] = &Block{backend: b.backend, hash: uncle.Hash(), number: rpc.BlockNumber(uncle.Number.Int64()), header: uncle}
+	}
+	return ommers, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (int, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(block.Transactions()), nil
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*Transaction, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		txs[i] = &Transaction{backend: b.backend, hash: tx.Hash(), tx: tx, block: b, index: uint64(i)}
+	}
+	return txs, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, index int) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if index < 0 || index >= len(txs) {
+		return nil, nil
+	}
+	return &Transaction{backend: b.backend, hash: txs[index].Hash(), tx: txs[index], block: b, index: uint64(index)}, nil
+}
+
+func (b *Block) Logs(ctx context.Context, addresses []common.Address, topics [][]common.Hash) ([]*Log, error) {
+	txs, err := b.Transactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*Log{}
+	for i, receipt := range receipts {
+		if i >= len(txs) {
+			break
+		}
+		for _, log := range receipt.Logs {
+			if matchLog(log, addresses, topics) {
+				logs = append(logs, &Log{backend: b.backend, transaction: txs[i], log: log})
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (b *Block) Account(ctx context.Context, address common.Address) (*Account, error) {
+	if b.pending() {
+		return &Account{backend: b.backend, address: address, blockNumber: rpc.PendingBlockNumber}, nil
+	}
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{backend: b.backend, address: address, blockHash: hash}, nil
+}
+
+func (b *Block) Call(ctx context.Context, args riftapi.CallArgs) (*CallResult, error) {
+	if b.pending() {
+		return call(ctx, b.backend, args, rpc.PendingBlockNumber)
+	}
+	state, header, err := b.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newCallResult(riftapi.DoCallAtState(ctx, b.backend, args, state, header, vm.Config{}))
+}
+
+func (b *Block) EstimateGas(ctx context.Context, args riftapi.CallArgs) (uint64, error) {
+	if b.pending() {
+		gas, err := riftapi.DoEstimateGas(ctx, b.backend, args, rpc.PendingBlockNumber)
+		if err != nil {
+			return 0, err
+		}
+		return gas.Uint64(), nil
+	}
+	state, header, err := b.state(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return riftapi.DoEstimateGasAtState(ctx, b.backend, args, state, header).Uint64(), nil
+}
+
+// CallResult is the outcome of executing a call message.
+type CallResult struct {
+	data    hexutil.Bytes
+	gasUsed uint64
+	status  uint64
+}
+
+func (c *CallResult) Data() hexutil.Bytes { return c.data }
+func (c *CallResult) GasUsed() uint64     { return c.gasUsed }
+func (c *CallResult) Status() uint64      { return c.status }
+
+// call executes a call message on the state of the given block.
+func call(ctx context.Context, backend riftapi.Backend, args riftapi.CallArgs, number rpc.BlockNumber) (*CallResult, error) {
+	return newCallResult(riftapi.DoCall(ctx, backend, args, number, vm.Config{}))
+}
+
+// newCallResult wraps the outcome of executing a call message.
+func newCallResult(result []byte, gas *big.Int, failed bool, err error) (*CallResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	status := uint64(1)
+	if failed {
+		status = 0
+	}
+	return &CallResult{data: hexutil.Bytes(result), gasUsed: gas.Uint64(), status: status}, nil
+}
+
+// Pending is the pending state of the node, on top of the latest block.
+type Pending struct {
+	backend riftapi.Backend
+}
+
+func (p *Pending) TransactionCount() (int, error) {
+	txs, err := p.backend.GetPoolTransactions()
+	return len(txs), err
+}
+
+func (p *Pending) Transactions() ([]*Transaction, error) {
+	txs, err := p.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Transaction, len(txs))
+	for i, tx := range txs {
+		results[i] = &Transaction{backend: p.backend, hash: tx.Hash(), tx: tx}
+	}
+	return results, nil
+}
+
+func (p *Pending) Account(address common.Address) *Account {
+	return &Account{backend: p.backend, address: address, blockNumber: rpc.PendingBlockNumber}
+}
+
+func (p *Pending) Call(ctx context.Context, args riftapi.CallArgs) (*CallResult, error) {
+	return call(ctx, p.backend, args, rpc.PendingBlockNumber)
+}
+
+func (p *Pending) EstimateGas(ctx context.Context, args riftapi.CallArgs) (uint64, error) {
+	gas, err := riftapi.DoEstimateGas(ctx, p.backend, args, rpc.PendingBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return gas.Uint64(), nil
+}
+
+// Query is the root of all GraphQL queries.
+type Query struct {
+	backend riftapi.Backend
+}
+
+func (q *Query) Block(ctx context.Context, number *uint64, hash *common.Hash) (*Block, error) {
+	if number != nil && hash != nil {
+		return nil, errBlockConflict
+	}
+	block := &Block{backend: q.backend, number: rpc.LatestBlockNumber}
+	switch {
+	case number != nil:
+		block.number = rpc.BlockNumber(*number)
+	case hash != nil:
+		block.hash = *hash
+	}
+	// Ensure the block exists, returning null if it doesn't
+	if _, err := block.resolveHeader(ctx); err != nil {
+		if err == errBlockNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+func (q *Query) Blocks(ctx context.Context, from uint64, to *uint64) ([]*Block, error) {
+	if to == nil {
+		header, err := q.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		head := header.Number.Uint64()
+		to = &head
+	}
+	if *to >= from && *to-from >= maxBlockRange {
+		return nil, fmt.Errorf("block range too large (%d>%d)", *to-from+1, maxBlockRange)
+	}
+	blocks := []*Block{}
+	for number := from; number <= *to; number++ {
+		block := &Block{backend: q.backend, number: rpc.BlockNumber(number)}
+		if _, err := block.resolveHeader(ctx); err != nil {
+			if err == errBlockNotFound {
+				break
+			}
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (q *Query) Pending() *Pending {
+	return &Pending{backend: q.backend}
+}
+
+func (q *Query) Transaction(ctx context.Context, hash common.Hash) (*Transaction, error) {
+	tx := &Transaction{backend: q.backend, hash: hash}
+	if resolved, err := tx.resolve(ctx); err != nil || resolved == nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (q *Query) Logs(ctx context.Context, from, to *uint64, addresses []common.Address, topics [][]common.Hash) ([]*Log, error) {
+	filter := filters.New(q.backend)
+	if from != nil {
+		filter.SetBeginBlock(int64(*from))
+	} else {
+		filter.SetBeginBlock(int64(rpc.LatestBlockNumber))
+	}
+	if to != nil {
+		filter.SetEndBlock(int64(*to))
+	} else {
+		filter.SetEndBlock(int64(rpc.LatestBlockNumber))
+	}
+	filter.SetAddresses(addresses)
+	filter.SetTopics(topics)
+
+	found, err := filter.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]*Log, len(found))
+	for i, log := range found {
+		logs[i] = newLog(q.backend, log)
+	}
+	return logs, nil
+}
+
+func (q *Query) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := q.backend.SuggestPrice(ctx)
+	return (*hexutil.Big)(price), err
+}
+
+func (q *Query) ProtocolVersion() int {
+	return q.backend.ProtocolVersion()
+}
+
+// matchLog checks whether a log was emitted by one of the addresses (if any) and
+// matches the topic filters, an empty set of topics at a position matching any.
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, addr := range addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, options := range topics {
+		match := len(options) == 0
+		for _, topic := range options {
+			if log.Topics[i] == topic {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// newSchemaFor creates the GraphQL schema of the chain data, resolving against
+// the given backend.
+func newSchemaFor(backend riftapi.Backend) *schema {
+	s := newSchema("Query")
+
+	s.scalars["Long"] = coerceLong
+	s.scalars["BigInt"] = coerceBigInt
+	s.scalars["Address"] = coerceAddress
+	s.scalars["Bytes32"] = coerceBytes32
+	s.scalars["Bytes"] = coerceBytes
+
+	s.input("CallData", map[string]string{
+		"from":     "Address",
+		"to":       "Address",
+		"gas":      "Long",
+		"gasPrice": "BigInt",
+		"value":    "BigInt",
+		"data":     "Bytes",
+	})
+	s.input("BlockFilterCriteria", map[string]string{
+		"addresses": "[Address!]",
+		"topics":    "[[Bytes32!]!]",
+	})
+	s.input("FilterCriteria", map[string]string{
+		"fromBlock": "Long",
+		"toBlock":   "Long",
+		"addresses": "[Address!]",
+		"topics":    "[[Bytes32!]!]",
+	})
+
+	blockArg := map[string]string{"block": "Long"}
+	callArg := map[string]string{"data": "CallData!"}
+
+	account := s.object("Account")
+	account.field("address", "Address!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Account).Address(), nil
+	})
+	account.field("balance", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Account).Balance(ctx)
+	})
+	account.field("transactionCount", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Account).TransactionCount(ctx)
+	})
+	account.field("code", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Account).Code(ctx)
+	})
+	account.field("storage", "Bytes32!", map[string]string{"slot": "Bytes32!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Account).Storage(ctx, args["slot"].(common.Hash))
+	})
+
+	log := s.object("Log")
+	log.field("index", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Log).Index(), nil
+	})
+	log.field("account", "Account!", blockArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Log).Account(blockNumberArg(args)), nil
+	})
+	log.field("topics", "[Bytes32!]!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Log).Topics(), nil
+	})
+	log.field("data", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Log).Data(), nil
+	})
+	log.field("transaction", "Transaction!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Log).Transaction(), nil
+	})
+
+	tx := s.object("Transaction")
+	tx.field("hash", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Hash(), nil
+	})
+	tx.field("nonce", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Nonce(ctx)
+	})
+	tx.field("index", "Int", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Index(ctx)
+	})
+	tx.field("from", "Account!", blockArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).From(ctx, blockNumberArg(args))
+	})
+	tx.field("to", "Account", blockArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).To(ctx, blockNumberArg(args))
+	})
+	tx.field("value", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Value(ctx)
+	})
+	tx.field("gasPrice", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).GasPrice(ctx)
+	})
+	tx.field("gas", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Gas(ctx)
+	})
+	tx.field("inputData", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).InputData(ctx)
+	})
+	tx.field("block", "Block", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Block(ctx)
+	})
+	tx.field("status", "Long", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Status(ctx)
+	})
+	tx.field("gasUsed", "Long", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).GasUsed(ctx)
+	})
+	tx.field("cumulativeGasUsed", "Long", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).CumulativeGasUsed(ctx)
+	})
+	tx.field("createdContract", "Account", blockArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).CreatedContract(ctx, blockNumberArg(args))
+	})
+	tx.field("logs", "[Log!]", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Transaction).Logs(ctx)
+	})
+
+	block := s.object("Block")
+	block.field("number", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Number(ctx)
+	})
+	block.field("hash", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Hash(ctx)
+	})
+	block.field("parent", "Block", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Parent(ctx)
+	})
+	block.field("nonce", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Nonce(ctx)
+	})
+	block.field("transactionsRoot", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).TransactionsRoot(ctx)
+	})
+	block.field("stateRoot", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).StateRoot(ctx)
+	})
+	block.field("receiptsRoot", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).ReceiptsRoot(ctx)
+	})
+	block.field("ommerHash", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).OmmerHash(ctx)
+	})
+	block.field("miner", "Account!", blockArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Miner(ctx, blockNumberArg(args))
+	})
+	block.field("extraData", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).ExtraData(ctx)
+	})
+	block.field("gasLimit", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).GasLimit(ctx)
+	})
+	block.field("gasUsed", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).GasUsed(ctx)
+	})
+	block.field("timestamp", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Timestamp(ctx)
+	})
+	block.field("logsBloom", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).LogsBloom(ctx)
+	})
+	block.field("mixHash", "Bytes32!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).MixHash(ctx)
+	})
+	block.field("difficulty", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Difficulty(ctx)
+	})
+	block.field("totalDifficulty", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).TotalDifficulty(ctx)
+	})
+	block.field("ommerCount", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).OmmerCount(ctx)
+	})
+	block.field("ommers", "[Block!]!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Ommers(ctx)
+	})
+	block.field("transactionCount", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).TransactionCount(ctx)
+	})
+	block.field("transactions", "[Transaction!]!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Transactions(ctx)
+	})
+	block.field("transactionAt", "Transaction", map[string]string{"index": "Int!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).TransactionAt(ctx, args["index"].(int))
+	})
+	block.field("logs", "[Log!]!", map[string]string{"filter": "BlockFilterCriteria!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		addresses, topics := filterArgs(args["filter"].(map[string]interface{}))
+		return src.(*Block).Logs(ctx, addresses, topics)
+	})
+	block.field("account", "Account!", map[string]string{"address": "Address!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Account(ctx, args["address"].(common.Address))
+	})
+	block.field("call", "CallResult", callArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).Call(ctx, callArgs(args["data"].(map[string]interface{})))
+	})
+	block.field("estimateGas", "Long!", callArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Block).EstimateGas(ctx, callArgs(args["data"].(map[string]interface{})))
+	})
+
+	result := s.object("CallResult")
+	result.field("data", "Bytes!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*CallResult).Data(), nil
+	})
+	result.field("gasUsed", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*CallResult).GasUsed(), nil
+	})
+	result.field("status", "Long!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*CallResult).Status(), nil
+	})
+
+	pending := s.object("Pending")
+	pending.field("transactionCount", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Pending).TransactionCount()
+	})
+	pending.field("transactions", "[Transaction!]!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Pending).Transactions()
+	})
+	pending.field("account", "Account!", map[string]string{"address": "Address!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Pending).Account(args["address"].(common.Address)), nil
+	})
+	pending.field("call", "CallResult", callArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Pending).Call(ctx, callArgs(args["data"].(map[string]interface{})))
+	})
+	pending.field("estimateGas", "Long!", callArg, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return src.(*Pending).EstimateGas(ctx, callArgs(args["data"].(map[string]interface{})))
+	})
+
+	root := &Query{backend: backend}
+	query := s.object("Query")
+	query.field("block", "Block", map[string]string{"number": "Long", "hash": "Bytes32"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		var (
+			number *uint64
+			hash   *common.Hash
+		)
+		if n, ok := args["number"].(uint64); ok {
+			number = &n
+		}
+		if h, ok := args["hash"].(common.Hash); ok {
+			hash = &h
+		}
+		return root.Block(ctx, number, hash)
+	})
+	query.field("blocks", "[Block!]!", map[string]string{"from": "Long!", "to": "Long"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		var to *uint64
+		if n, ok := args["to"].(uint64); ok {
+			to = &n
+		}
+		return root.Blocks(ctx, args["from"].(uint64), to)
+	})
+	query.field("pending", "Pending!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return root.Pending(), nil
+	})
+	query.field("transaction", "Transaction", map[string]string{"hash": "Bytes32!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return root.Transaction(ctx, args["hash"].(common.Hash))
+	})
+	query.field("logs", "[Log!]!", map[string]string{"filter": "FilterCriteria!"}, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		filter := args["filter"].(map[string]interface{})
+		var from, to *uint64
+		if n, ok := filter["fromBlock"].(uint64); ok {
+			from = &n
+		}
+		if n, ok := filter["toBlock"].(uint64); ok {
+			to = &n
+		}
+		addresses, topics := filterArgs(filter)
+		return root.Logs(ctx, from, to, addresses, topics)
+	})
+	query.field("gasPrice", "BigInt!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return root.GasPrice(ctx)
+	})
+	query.field("protocolVersion", "Int!", nil, func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+		return root.ProtocolVersion(), nil
+	})
+	return s
+}
+
+// blockNumberArg returns the block an account should be resolved at, defaulting
+// to the latest one.
+func blockNumberArg(args map[string]interface{}) rpc.BlockNumber {
+	if number, ok := args["block"].(uint64); ok {
+		return rpc.BlockNumber(number)
+	}
+	return rpc.LatestBlockNumber
+}
+
+// callArgs converts a coerced CallData input object into call arguments.
+func callArgs(data map[string]interface{}) riftapi.CallArgs {
+	var args riftapi.CallArgs
+	if from, ok := data["from"].(common.Address); ok {
+		args.From = from
+	}
+	if to, ok := data["to"].(common.Address); ok {
+		args.To = &to
+	}
+	if gas, ok := data["gas"].(uint64); ok {
+		args.Gas = hexutil.Big(*new(big.Int).SetUint64(gas))
+	}
+	if price, ok := data["gasPrice"].(*big.Int); ok {
+		args.GasPrice = hexutil.Big(*price)
+	}
+	if value, ok := data["value"].(*big.Int); ok {
+		args.Value = hexutil.Big(*value)
+	}
+	if input, ok := data["data"].([]byte); ok {
+		args.Data = hexutil.Bytes(input)
+	}
+	return args
+}
+
+// filterArgs converts the addresses and topics of a coerced filter criteria input
+// object.
+func filterArgs(filter map[string]interface{}) ([]common.Address, [][]common.Hash) {
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if list, ok := filter["addresses"].([]interface{}); ok {
+		for _, addr := range list {
+			addresses = append(addresses, addr.(common.Address))
+		}
+	}
+	if list, ok := filter["topics"].([]interface{}); ok {
+		for _, options := range list {
+			var hashes []common.Hash
+			for _, topic := range options.([]interface{}) {
+				hashes = append(hashes, topic.(common.Hash))
+			}
+			topics = append(topics, hashes)
+		}
+	}
+	return addresses, topics
+}
+
+// coerceLong converts a number or a decimal or hex string into a non-negative
+// 64 bit integer.
+func coerceLong(value interface{}) (interface{}, error) {
+	if str, ok := value.(string); ok {
+		var (
+			n   uint64
+			err error
+		)
+		if strings.HasPrefix(str, "0x") {
+			n, err = hexutil.DecodeUint64(str)
+		} else {
+			n, err = strconv.ParseUint(str, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Long %q: %v", str, err)
+		}
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("Long %d out of range", n)
+		}
+		return n, nil
+	}
+	n, err := coerceInt64(value)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("Long %d out of range", n)
+	}
+	return uint64(n), nil
+}
+
+// coerceBigInt converts a number or a decimal or hex string into a big integer.
+func coerceBigInt(value interface{}) (interface{}, error) {
+	var str string
+	switch value := value.(type) {
+	case string:
+		str = value
+	case fmt.Stringer:
+		str = value.String() // json.Number
+	case float64:
+		str = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return nil, fmt.Errorf("invalid BigInt %v", value)
+	}
+	if strings.HasPrefix(str, "0x") {
+		return hexutil.DecodeBig(str)
+	}
+	n, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid BigInt %q", str)
+	}
+	return n, nil
+}
+
+// coerceAddress converts a hex string into an address.
+func coerceAddress(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok || !strings.HasPrefix(str, "0x") || !common.IsHexAddress(str) {
+		return nil, fmt.Errorf("invalid Address %v", value)
+	}
+	return common.HexToAddress(str), nil
+}
+
+// coerceBytes32 converts a hex string into a 32 byte hash.
+func coerceBytes32(value interface{}) (interface{}, error) {
+	blob, err := coerceBytes(value)
+	if err != nil || len(blob.([]byte)) != common.HashLength {
+		return nil, fmt.Errorf("invalid Bytes32 %v", value)
+	}
+	return common.BytesToHash(blob.([]byte)), nil
+}
+
+// coerceBytes converts a hex string into a byte slice.
+func coerceBytes(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid Bytes %v", value)
+	}
+	blob, err := hexutil.Decode(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Bytes %q: %v", str, err)
+	}
+	return blob, nil
+}
@@ -0,0 +1,392 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/consensus/rifthash"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
+	"github.com/cryptorift/riftcore/core/state"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/riftdb"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testRecipent = common.HexToAddress("0x0000000000000000000000000000000000000bad")
+	testMiner    = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	testContract = common.HexToAddress("0x000000000000000000000000000000000000c0de")
+	testTopic    = common.HexToHash("0x2a")
+)
+
+// testBackend is a riftapi.Backend serving the chain data of an in-memory chain.
+// Methods not needed by the GraphQL resolvers panic.
+type testBackend struct {
+	riftapi.Backend
+
+	db    riftdb.Database
+	chain *core.BlockChain
+	pool  types.Transactions
+}
+
+// newTestBackend creates a chain of three blocks: the first with a plain value
+// transfer, the second with a contract call emitting a log and the third empty.
+func newTestBackend(t *testing.T) (*testBackend, []*types.Block) {
+	db, _ := riftdb.NewMemDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddr: {Balance: big.NewInt(1000000000000000000)},
+			// PUSH1 0x2a PUSH1 0 PUSH1 0 LOG1 STOP
+			testContract: {Balance: new(big.Int), Code: common.FromHex("602a60006000a100")},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 3, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 0:
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), testRecipent, big.NewInt(10000), big.NewInt(21000), nil, nil), signer, testKey)
+			gen.AddTx(tx)
+		case 1:
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), testContract, new(big.Int), big.NewInt(100000), nil, nil), signer, testKey)
+			gen.AddTx(tx)
+		case 2:
+			gen.SetCoinbase(testMiner)
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, rifthash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	pending, _ := types.SignTx(types.NewTransaction(3, testRecipent, big.NewInt(1), big.NewInt(21000), nil, nil), signer, testKey)
+
+	return &testBackend{db: db, chain: chain, pool: types.Transactions{pending}}, blocks
+}
+
+func (b *testBackend) ProtocolVersion() int                             { return 63 }
+func (b *testBackend) ChainDb() riftdb.Database                         { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig                 { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block                       { return b.chain.CurrentBlock() }
+func (b *testBackend) GetTd(hash common.Hash) *big.Int                  { return b.chain.GetTdByHash(hash) }
+func (b *testBackend) GetPoolTransactions() (types.Transactions, error) { return b.pool, nil }
+func (b *testBackend) BloomStatus() (uint64, uint64)                    { return params.BloomBitsBlocks, 0 }
+
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {}
+
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(params.Shannon), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, number)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Reflect the transaction pool in the pending state
+	if number == rpc.PendingBlockNumber {
+		for _, tx := range b.pool {
+			statedb.SubBalance(testAddr, tx.Value())
+			statedb.AddBalance(*tx.To(), tx.Value())
+		}
+	}
+	return statedb, header, nil
+}
+
+func (b *testBackend) StateAndHeaderByHash(ctx context.Context, hash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(b.db, hash, core.GetBlockNumber(b.db, hash)), nil
+}
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	for _, tx := range b.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), vmCfg), func() error { return nil }, nil
+}
+
+// post executes a GraphQL query through the HTTP handler, returning the response.
+func post(t *testing.T, handler http.Handler, query string, vars map[string]interface{}) string {
+	body, err := json.Marshal(&Request{Query: query, Variables: vars})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("request failed with status %d: %s", rec.Code, rec.Body)
+	}
+	return strings.TrimSpace(rec.Body.String())
+}
+
+// Tests that blocks, their transactions, receipts and the related accounts can
+// be retrieved in a single query.
+func TestBlockQuery(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	handler := NewHandler(backend)
+
+	tx := blocks[0].Transactions()[0]
+	have := post(t, handler, `query ($n: Long!) {
+		block(number: $n) {
+			number
+			hash
+			parent { number }
+			transactionCount
+			transactions {
+				hash
+				index
+				value
+				from { address }
+				to { address balance }
+				cumulativeGasUsed
+				block { number }
+			}
+		}
+	}`, map[string]interface{}{"n": 1})
+
+	want := fmt.Sprintf(`{"data":{"block":{"number":1,"hash":%q,"parent":{"number":0},"transactionCount":1,"transactions":[{"hash":%q,"index":0,"value":"0x2710","from":{"address":%q},"to":{"address":%q,"balance":"0x2710"},"cumulativeGasUsed":21000,"block":{"number":1}}]}}}`,
+		blocks[0].Hash().Hex(), tx.Hash().Hex(), testAddr.Hex(), testRecipent.Hex())
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	// Blocks may also be looked up by hash, defaulting to the chain head
+	have = post(t, handler, fmt.Sprintf(`{ byHash: block(hash: %q) { number } head: block { number miner { address } } }`, blocks[1].Hash().Hex()), nil)
+	want = fmt.Sprintf(`{"data":{"byHash":{"number":2},"head":{"number":3,"miner":{"address":%q}}}}`, testMiner.Hex())
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	// Ranges stop at the chain head and unknown blocks resolve to null
+	have = post(t, handler, `{ blocks(from: 2, to: 10) { number } block(number: 10) { number } }`, nil)
+	want = `{"data":{"blocks":[{"number":2},{"number":3}],"block":null}}`
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	have = post(t, handler, fmt.Sprintf(`{ block(number: 1, hash: %q) { number } }`, blocks[0].Hash().Hex()), nil)
+	want = `{"data":{"block":null},"errors":[{"message":"only one of block number and hash may be specified","path":["block"]}]}`
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	// Ranges above the limit are rejected instead of iterated
+	have = post(t, handler, fmt.Sprintf(`{ blocks(from: 1, to: %d) { number } }`, maxBlockRange+1), nil)
+	want = fmt.Sprintf(`{"errors":[{"message":"block range too large (%d\u003e%d)","path":["blocks"]}]}`, maxBlockRange+1, maxBlockRange)
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+// Tests that the state of a block looked up by hash is the one rooted in the
+// block itself, even if it's not part of the canonical chain.
+func TestSideBlockState(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	handler := NewHandler(backend)
+
+	// Import a shorter fork, transferring a different amount in its first block
+	signer := types.NewEIP155Signer(backend.chain.Config().ChainId)
+	fork, _ := core.GenerateChain(backend.chain.Config(), backend.chain.Genesis(), backend.db, 1, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), testRecipent, big.NewInt(5000), big.NewInt(21000), nil, nil), signer, testKey)
+		gen.AddTx(tx)
+	})
+	if _, err := backend.chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	if head := backend.chain.CurrentBlock(); head.Hash() != blocks[2].Hash() {
+		t.Fatalf("fork became canonical: head #%d [%x]", head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+	have := post(t, handler, fmt.Sprintf(`{
+		side: block(hash: %q) {
+			account(address: %q) { balance }
+			estimateGas(data: {from: %q, to: %q, value: "0x1"})
+		}
+		canonical: block(number: 1) { account(address: %q) { balance } }
+	}`, fork[0].Hash().Hex(), testRecipent.Hex(), testAddr.Hex(), testRecipent.Hex(), testRecipent.Hex()), nil)
+
+	want := `{"data":{"side":{"account":{"balance":"0x1388"},"estimateGas":21000},"canonical":{"account":{"balance":"0x2710"}}}}`
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+// Tests that logs can be filtered both within a block and across the chain, and
+// linked back to their transactions.
+func TestLogQuery(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	handler := NewHandler(backend)
+
+	tx := blocks[1].Transactions()[0]
+	have := post(t, handler, fmt.Sprintf(`{
+		block(number: 2) {
+			matching: logs(filter: {topics: [[%q]]}) { index topics account { address } transaction { hash } }
+			other: logs(filter: {addresses: [%q]}) { index }
+		}
+		logs(filter: {fromBlock: 0, addresses: %q}) { data transaction { block { number } status gasUsed } }
+	}`, testTopic.Hex(), testRecipent.Hex(), testContract.Hex()), nil)
+
+	want := fmt.Sprintf(`{"data":{"block":{"matching":[{"index":0,"topics":[%q],"account":{"address":%q},"transaction":{"hash":%q}}],"other":[]},"logs":[{"data":"0x","transaction":{"block":{"number":2},"status":null,"gasUsed":21759}}]}}`,
+		testTopic.Hex(), testContract.Hex(), tx.Hash().Hex())
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	// Transactions looked up by hash should link to their block and logs
+	have = post(t, handler, fmt.Sprintf(`{ transaction(hash: %q) { index block { number } logs { topics } } }`, tx.Hash().Hex()), nil)
+	want = fmt.Sprintf(`{"data":{"transaction":{"index":0,"block":{"number":2},"logs":[{"topics":[%q]}]}}}`, testTopic.Hex())
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+// Tests that the pending state, calls and gas estimation are served.
+func TestPendingAndCallQuery(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	handler := NewHandler(backend)
+
+	pending := backend.pool[0]
+	have := post(t, handler, fmt.Sprintf(`{
+		pending {
+			transactionCount
+			transactions { hash index block { number } }
+			account(address: %q) { balance }
+		}
+		transaction(hash: %q) { nonce status }
+	}`, testRecipent.Hex(), pending.Hash().Hex()), nil)
+
+	want := fmt.Sprintf(`{"data":{"pending":{"transactionCount":1,"transactions":[{"hash":%q,"index":null,"block":null}],"account":{"balance":"0x2711"}},"transaction":{"nonce":3,"status":null}}}`, pending.Hash().Hex())
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+	have = post(t, handler, `query ($call: CallData!) {
+		block(number: 2) {
+			call(data: $call) { data status }
+			estimateGas(data: {from: "`+testAddr.Hex()+`", to: "`+testRecipent.Hex()+`", value: "0x1"})
+		}
+		pending { call(data: $call) { status } }
+	}`, map[string]interface{}{"call": map[string]interface{}{"from": testAddr.Hex(), "to": testContract.Hex(), "gas": "0x30000"}})
+
+	want = `{"data":{"block":{"call":{"data":"0x","status":1},"estimateGas":21000},"pending":{"call":{"status":1}}}}`
+	if have != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+// Tests that queries are accepted over GET requests and malformed requests are
+// rejected.
+func TestHandler(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	handler := NewHandler(backend)
+
+	query := url.Values{"query": {"query ($n: Long) { block(number: $n) { number } gasPrice protocolVersion }"}, "variables": {`{"n": "0x2"}`}}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+query.Encode(), nil))
+
+	if want := `{"data":{"block":{"number":2},"gasPrice":"0x3b9aca00","protocolVersion":63}}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("response mismatch:\nhave: %s\nwant: %s", rec.Body, want)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed request status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("invalid method status mismatch: have %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	// Tokens restricted to a set of modules must grant the graphql one
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	authed := rpc.NewJWTHandler(secret, handler)
+	for _, tt := range []struct {
+		modules []string
+		code    int
+	}{
+		{nil, http.StatusOK},
+		{[]string{"graphql"}, http.StatusOK},
+		{[]string{"rift", "net"}, http.StatusForbidden},
+	} {
+		token, _ := rpc.NewJWT(secret, tt.modules, time.Minute)
+		req := httptest.NewRequest("GET", "/?"+query.Encode(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec = httptest.NewRecorder()
+		authed.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("modules %v: status mismatch: have %d, want %d", tt.modules, rec.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,721 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the type of a lexical token of a GraphQL document.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a single lexical token of a GraphQL document.
+type token struct {
+	kind  tokenKind
+	value string // Raw text of the token, unescaped for strings
+	pos   int    // Byte offset of the token in the document
+}
+
+// lexer splits a GraphQL document into tokens.
+type lexer struct {
+	input string
+	pos   int
+}
+
+// next skips any ignored characters and returns the next token of the input.
+func (l *lexer) next() (token, error) {
+	// Skip whitespace, commas and comments
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' && l.input[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if strings.HasPrefix(l.input[l.pos:], "\ufeff") {
+			l.pos += len("\ufeff")
+			continue
+		}
+		break
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	c := l.input[l.pos]
+	switch {
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), pos: start}, nil
+
+	case strings.HasPrefix(l.input[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", pos: start}, nil
+
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.input) && (l.input[l.pos] == '_' || isLetter(l.input[l.pos]) || isDigit(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.input[start:l.pos], pos: start}, nil
+
+	case c == '-' || isDigit(c):
+		return l.number()
+
+	case c == '"':
+		return l.string()
+	}
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", c, start)
+}
+
+// number lexes an integer or floating point literal.
+func (l *lexer) number() (token, error) {
+	start, kind := l.pos, tokenInt
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.digits() {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	if l.pos < len(l.input) && l.input[l.pos] == '.' {
+		l.pos, kind = l.pos+1, tokenFloat
+		if !l.digits() {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.input) && (l.input[l.pos] == 'e' || l.input[l.pos] == 'E') {
+		l.pos, kind = l.pos+1, tokenFloat
+		if l.pos < len(l.input) && (l.input[l.pos] == '+' || l.input[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.digits() {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	return token{kind: kind, value: l.input[start:l.pos], pos: start}, nil
+}
+
+// digits consumes a run of decimal digits, reporting whether there was any.
+func (l *lexer) digits() bool {
+	start := l.pos
+	for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+// string lexes and unescapes a quoted string literal. Block strings are not
+// supported.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.input[l.pos:], `"""`) {
+		return token{}, fmt.Errorf("block strings not supported (offset %d)", start)
+	}
+	l.pos++
+
+	var value []byte
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: string(value), pos: start}, nil
+
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+
+		case c == '\\':
+			if l.pos+1 >= len(l.input) {
+				return token{}, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			switch esc := l.input[l.pos+1]; esc {
+			case '"', '\\', '/':
+				value = append(value, esc)
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'u':
+				if l.pos+6 > len(l.input) {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos)
+				}
+				code, err := strconv.ParseUint(l.input[l.pos+2:l.pos+6], 16, 16)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos)
+				}
+				var buf [utf8.UTFMax]byte
+				value = append(value, buf[:utf8.EncodeRune(buf[:], rune(code))]...)
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("invalid escape sequence at offset %d", l.pos)
+			}
+			l.pos += 2
+
+		default:
+			value = append(value, c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// typeRef is a reference to a named or list type, e.g. [Transaction!]!
+type typeRef struct {
+	name    string   // Name of the referenced type, empty for lists
+	elem    *typeRef // Element type of lists
+	nonNull bool     // Whether null values are forbidden
+}
+
+// String implements fmt.Stringer, formatting the type reference in GraphQL
+// notation.
+func (t *typeRef) String() string {
+	name := t.name
+	if t.elem != nil {
+		name = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		name += "!"
+	}
+	return name
+}
+
+// document is a parsed GraphQL request document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription of a document.
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []*variableDef
+	directives []*directive
+	selections []selection
+}
+
+// variableDef is the declaration of an operation variable.
+type variableDef struct {
+	name string
+	typ  *typeRef
+	def  interface{} // Default value if any
+}
+
+// selection is a field, a fragment spread or an inline fragment.
+type selection interface{}
+
+// field is a selected field of an object.
+type field struct {
+	alias      string // Response key of the field
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+}
+
+// fragmentSpread is a reference to a named fragment.
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+// inlineFragment is an anonymous fragment nested into a selection set.
+type inlineFragment struct {
+	on         string // Type condition, empty if none
+	directives []*directive
+	selections []selection
+}
+
+// fragment is a named fragment definition.
+type fragment struct {
+	name       string
+	on         string
+	directives []*directive
+	selections []selection
+}
+
+// argument is a named value passed to a field, directive or input object.
+type argument struct {
+	name  string
+	value interface{}
+}
+
+// directive is an annotation of a selection, e.g. @skip(if: true).
+type directive struct {
+	name string
+	args []*argument
+}
+
+// Value literals in addition to strings, booleans, nil, lists and objects.
+type (
+	variableRef  string      // Reference to an operation variable
+	intLiteral   string      // Integer literal, parsed based on the expected type
+	floatLiteral string      // Floating point literal
+	enumLiteral  string      // Enum value
+	objectValue  []*argument // Input object literal
+)
+
+// parser is a recursive descent parser of GraphQL request documents.
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parseDocument parses a GraphQL request document.
+func parseDocument(query string) (*document, error) {
+	p := &parser{lex: &lexer{input: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case p.peek(tokenName, "fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, fmt.Errorf("duplicate fragment %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operation in document")
+	}
+	return doc, nil
+}
+
+// parseOperation parses an operation definition with an explicit type.
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunct, "(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(tokenPunct, ")") {
+			def, err := p.parseVariableDef()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, def)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if op.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// parseVariableDef parses a variable declaration, e.g. $hash: Bytes32! = "0x..."
+func (p *parser) parseVariableDef() (*variableDef, error) {
+	if err := p.expect(tokenPunct, "$"); err != nil {
+		return nil, err
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenPunct, ":"); err != nil {
+		return nil, err
+	}
+	def := &variableDef{name: name}
+	if def.typ, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunct, "=") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if def.def, err = p.parseValue(true); err != nil {
+			return nil, err
+		}
+	}
+	return def, nil
+}
+
+// parseType parses a type reference.
+func (p *parser) parseType() (*typeRef, error) {
+	typ := new(typeRef)
+	if p.peek(tokenPunct, "[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return nil, err
+		}
+		typ.elem = elem
+	} else {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		typ.name = name
+	}
+	if p.peek(tokenPunct, "!") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		typ.nonNull = true
+	}
+	return typ, nil
+}
+
+// parseFragment parses a named fragment definition.
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("invalid fragment name %q", name)
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return nil, err
+	}
+	frag := &fragment{name: name}
+	if frag.on, err = p.parseName(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+// parseSelectionSet parses a brace enclosed list of selections.
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.peek(tokenPunct, "}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set at offset %d", p.tok.pos)
+	}
+	return selections, p.advance()
+}
+
+// parseSelection parses a field, fragment spread or inline fragment.
+func (p *parser) parseSelection() (selection, error) {
+	if p.peek(tokenPunct, "...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			spread := &fragmentSpread{name: p.tok.value}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			var err error
+			if spread.directives, err = p.parseDirectives(); err != nil {
+				return nil, err
+			}
+			return spread, nil
+		}
+		frag := new(inlineFragment)
+		if p.peek(tokenName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			on, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			frag.on = on
+		}
+		var err error
+		if frag.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		if frag.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+		return frag, nil
+	}
+	// Not a fragment, parse a field with an optional alias
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{alias: name, name: name}
+	if p.peek(tokenPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if f.name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	if f.args, err = p.parseArguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunct, "{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseDirectives parses an optional list of directives.
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &directive{name: name, args: args})
+	}
+	return directives, nil
+}
+
+// parseArguments parses an optional parenthesized argument list.
+func (p *parser) parseArguments(constant bool) ([]*argument, error) {
+	if !p.peek(tokenPunct, "(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var (
+		args []*argument
+		seen = make(map[string]bool)
+	)
+	for !p.peek(tokenPunct, ")") {
+		pos := p.tok.pos
+		arg, err := p.parseArgument(constant)
+		if err != nil {
+			return nil, err
+		}
+		if seen[arg.name] {
+			return nil, fmt.Errorf("duplicate argument %q at offset %d", arg.name, pos)
+		}
+		seen[arg.name] = true
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty argument list at offset %d", p.tok.pos)
+	}
+	return args, p.advance()
+}
+
+// parseArgument parses a single name: value pair.
+func (p *parser) parseArgument(constant bool) (*argument, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenPunct, ":"); err != nil {
+		return nil, err
+	}
+	value, err := p.parseValue(constant)
+	if err != nil {
+		return nil, err
+	}
+	return &argument{name: name, value: value}, nil
+}
+
+// parseValue parses a value literal. Constant values may not reference variables.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokenPunct && tok.value == "$" && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return variableRef(name), nil
+
+	case tok.kind == tokenPunct && tok.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.peek(tokenPunct, "]") {
+			value, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, p.advance()
+
+	case tok.kind == tokenPunct && tok.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := objectValue{}
+		for !p.peek(tokenPunct, "}") {
+			arg, err := p.parseArgument(constant)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, arg)
+		}
+		return object, p.advance()
+
+	case tok.kind == tokenInt:
+		return intLiteral(tok.value), p.advance()
+
+	case tok.kind == tokenFloat:
+		return floatLiteral(tok.value), p.advance()
+
+	case tok.kind == tokenString:
+		return tok.value, p.advance()
+
+	case tok.kind == tokenName:
+		var value interface{}
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = enumLiteral(tok.value)
+		}
+		return value, p.advance()
+	}
+	return nil, p.unexpected()
+}
+
+// parseName consumes a name token, returning its value.
+func (p *parser) parseName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// peek reports whether the current token matches the given kind and value.
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// expect consumes a token of the given kind and value, failing on anything else.
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.peek(kind, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// advance moves the parser to the next token.
+func (p *parser) advance() (err error) {
+	p.tok, err = p.lex.next()
+	return err
+}
+
+// unexpected creates an error for the current token.
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.tok.value, p.tok.pos)
+}
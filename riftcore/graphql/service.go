@@ -0,0 +1,151 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/p2p"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// maxRequestSize is the maximum size of a GraphQL request body.
+const maxRequestSize = 128 * 1024
+
+// module is the API module name authentication tokens must grant, if restricted
+// to a set of modules, to access the GraphQL endpoint.
+const module = "graphql"
+
+// Service exposes the GraphQL interface of the chain data over HTTP.
+type Service struct {
+	ctx      *node.ServiceContext // The node context providing the HTTP endpoint security
+	endpoint string               // The host:port endpoint for this service
+	cors     []string             // Allowed CORS domains
+	vhosts   []string             // Recognised virtual hosts
+	handler  http.Handler         // The GraphQL request handler
+	listener net.Listener         // The listening socket of the HTTP server
+	url      string               // The URL the endpoint is served on
+}
+
+// New creates a GraphQL service resolving queries against the given backend of a
+// full or light node. The endpoint is served with the TLS, authentication and
+// request limit settings of the node's HTTP RPC endpoint.
+func New(ctx *node.ServiceContext, backend riftapi.Backend, config *Config) (*Service, error) {
+	endpoint := config.Endpoint()
+	if endpoint == "" {
+		return nil, errors.New("GraphQL endpoint not configured")
+	}
+	return &Service{
+		ctx:      ctx,
+		endpoint: endpoint,
+		cors:     config.Cors,
+		vhosts:   config.VirtualHosts,
+		handler:  NewHandler(backend),
+	}, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the GraphQL service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// GraphQL service (nil as it is served over its own HTTP endpoint).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting the GraphQL HTTP server.
+func (s *Service) Start(server *p2p.Server) error {
+	listener, url, err := s.ctx.StartHTTP(s.endpoint, s.cors, s.vhosts, s.handler)
+	if err != nil {
+		return err
+	}
+	s.listener, s.url = listener, url
+
+	log.Info(fmt.Sprintf("GraphQL endpoint opened: %s", s.url))
+	return nil
+}
+
+// Stop implements node.Service, terminating the GraphQL HTTP server.
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+
+		log.Info(fmt.Sprintf("GraphQL endpoint closed: %s", s.url))
+	}
+	return nil
+}
+
+// handler is an http.Handler executing GraphQL requests against a schema.
+type handler struct {
+	schema *schema
+}
+
+// NewHandler creates an HTTP handler serving GraphQL queries against the given
+// backend. Queries are accepted either as the query and variables parameters of
+// a GET request, or as a JSON encoded POST request.
+func NewHandler(backend riftapi.Backend) http.Handler {
+	return &handler{schema: newSchemaFor(backend)}
+}
+
+// ServeHTTP implements http.Handler, decoding and executing a GraphQL request.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !rpc.ModuleAllowed(r.Context(), module) {
+		http.Error(w, "access to the graphql module not granted", http.StatusForbidden)
+		return
+	}
+	req := new(Request)
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := decodeJSON([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("invalid variables: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		dec.UseNumber()
+		if err := dec.Decode(req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	res := h.schema.exec(r.Context(), req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// decodeJSON decodes a JSON blob, retaining numbers in their textual form.
+func decodeJSON(blob []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
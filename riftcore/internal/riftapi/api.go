@@ -25,12 +25,14 @@ import (
 	"time"
 
 	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/abi"
 	"github.com/cryptorift/riftcore/accounts/keystore"
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/hexutil"
 	"github.com/cryptorift/riftcore/common/math"
 	"github.com/cryptorift/riftcore/consensus/rifthash"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
 	"github.com/cryptorift/riftcore/crypto"
@@ -131,6 +133,29 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	return content
 }
 
+// ContentFrom returns the transactions contained within the transaction pool
+// originating from a single account.
+func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
+	content := make(map[string]map[string]*RPCTransaction, 2)
+	pending, queue := s.b.TxPoolContentFrom(addr)
+
+	// Build the pending transactions
+	dump := make(map[string]*RPCTransaction, len(pending))
+	for _, tx := range pending {
+		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+	}
+	content["pending"] = dump
+
+	// Build the queued transactions
+	dump = make(map[string]*RPCTransaction, len(queue))
+	for _, tx := range queue {
+		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+	}
+	content["queued"] = dump
+
+	return content
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -175,6 +200,103 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCTxPoolEvent is the notification sent to transaction pool event subscribers
+// whenever a transaction is promoted, replaced or dropped.
+type RPCTxPoolEvent struct {
+	Tx     *RPCTransaction `json:"transaction"`
+	Change core.TxChange   `json:"change"`
+	Reason string          `json:"reason,omitempty"`
+}
+
+// txPoolEventsBuffer is the number of transaction pool events that may await
+// delivery to an RPC subscriber before it is considered lagging and dropped.
+const txPoolEventsBuffer = 128
+
+// Events creates a subscription that is triggered each time a transaction is
+// promoted, replaced or dropped in the transaction pool, reporting the reason
+// why dropped transactions were discarded. Subscribers that can't keep up with
+// the events are dropped instead of holding up the pool.
+func (s *PublicTxPoolAPI) Events(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.TxPoolEvent, txPoolEventsBuffer)
+		sub := s.b.SubscribeTxPoolEvent(events)
+		defer sub.Unsubscribe()
+
+		// Write the notifications from a separate goroutine, a stalled client
+		// may block it indefinitely
+		queue := make(chan *RPCTxPoolEvent, txPoolEventsBuffer)
+		defer close(queue)
+
+		go func() {
+			for notification := range queue {
+				notifier.Notify(rpcSub.ID, notification)
+			}
+		}()
+		for {
+			select {
+			case event := <-events:
+				notification := &RPCTxPoolEvent{
+					Tx:     newRPCPendingTransaction(event.Tx),
+					Change: event.Change,
+				}
+				if event.Reason != nil {
+					notification.Reason = event.Reason.Error()
+				}
+				select {
+				case queue <- notification:
+				default:
+					log.Warn("Dropping lagging transaction pool subscriber", "id", rpcSub.ID)
+					return
+				}
+
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// PrivateTxPoolAPI offers administrative access to the transaction pool, allowing
+// the node operator to forcefully remove transactions from it.
+type PrivateTxPoolAPI struct {
+	b Backend
+}
+
+// NewPrivateTxPoolAPI creates a new tx pool service for node operators.
+func NewPrivateTxPoolAPI(b Backend) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{b}
+}
+
+// Evict removes a single transaction (identified by its hash) or all transactions
+// of an account (identified by its address) from the pool, returning the number
+// of evicted transactions.
+func (s *PrivateTxPoolAPI) Evict(target hexutil.Bytes) (hexutil.Uint, error) {
+	switch len(target) {
+	case common.HashLength:
+		if s.b.TxPoolEvict(common.BytesToHash(target)) {
+			return 1, nil
+		}
+		return 0, nil
+
+	case common.AddressLength:
+		return hexutil.Uint(s.b.TxPoolEvictAccount(common.BytesToAddress(target))), nil
+
+	default:
+		return 0, fmt.Errorf("invalid eviction target length %d: need transaction hash or account address", len(target))
+	}
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -247,6 +369,22 @@ func (s *PrivateAccountAPI) ListWallets() []rawWallet {
 	return wallets
 }
 
+// OpenWallet initiates a hardware wallet opening procedure, establishing a USB
+// connection and attempting to authenticate via the provided passphrase. Note,
+// the method may return an extra challenge requiring a second open (e.g. the
+// Trezor PIN matrix challenge).
+func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return err
+	}
+	pass := ""
+	if passphrase != nil {
+		pass = *passphrase
+	}
+	return wallet.Open(pass)
+}
+
 // DeriveAccount requests a HD wallet to derive a new account, optionally pinning
 // it for later reuse.
 func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (accounts.Account, error) {
@@ -386,6 +524,34 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// SignTypedData calculates an EIP-712 ECDSA signature for:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message))
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, typedData abi.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	hash, err := typedData.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble sign the data with the wallet
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with rift_sign and personal_sign. As such it recovers
 // the address of:
@@ -578,17 +744,25 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// DoCall executes the given call message on the state of the given block using
+// the backend, returning the output, the gas used and whether execution failed.
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
+	state, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, common.Big0, err
+		return nil, common.Big0, false, err
 	}
+	return DoCallAtState(ctx, b, args, state, header, vmCfg)
+}
+
+// DoCallAtState executes the given call message on top of the given state and
+// block header, modifying the state in the process.
+func DoCallAtState(ctx context.Context, b Backend, args CallArgs, state *state.StateDB, header *types.Header, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -619,9 +793,9 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	defer func() { cancel() }()
 
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, common.Big0, err
+		return nil, common.Big0, false, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -635,50 +809,80 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxBig256)
-	res, gas, err := core.ApplyMessage(evm, msg, gp)
+	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, common.Big0, err
+		return nil, common.Big0, false, err
 	}
-	return res, gas, err
+	return res, gas, failed, err
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, err := s.doCall(ctx, args, blockNr, vm.Config{DisableGasMetering: true})
+	result, _, _, err := DoCall(ctx, s.b, args, blockNr, vm.Config{DisableGasMetering: true})
 	return (hexutil.Bytes)(result), err
 }
 
-// EstimateGas returns an estimate of the amount of gas needed to execute the given transaction.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
-	// Binary search the gas requirement, as it may be higher than the amount used
-	var lo, hi uint64
-	if (*big.Int)(&args.Gas).Sign() != 0 {
-		hi = (*big.Int)(&args.Gas).Uint64()
-	} else {
-		// Retrieve the current pending block to act as the gas ceiling
-		block, err := s.b.BlockByNumber(ctx, rpc.PendingBlockNumber)
+// DoEstimateGas binary searches the gas requirement of the given call message on
+// the state of the given block using the backend.
+func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber) (*big.Int, error) {
+	var hi uint64
+	if (*big.Int)(&args.Gas).Sign() == 0 {
+		// Retrieve the block to act as the gas ceiling
+		block, err := b.BlockByNumber(ctx, blockNr)
 		if err != nil {
 			return nil, err
 		}
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", blockNr)
+		}
 		hi = block.GasLimit().Uint64()
 	}
+	return searchGas(args, hi, func(args CallArgs) (bool, error) {
+		_, _, failed, err := DoCall(ctx, b, args, blockNr, vm.Config{})
+		return failed, err
+	}), nil
+}
+
+// DoEstimateGasAtState binary searches the gas requirement of the given call
+// message on top of the given state and block header. The state is not modified.
+func DoEstimateGasAtState(ctx context.Context, b Backend, args CallArgs, state *state.StateDB, header *types.Header) *big.Int {
+	return searchGas(args, header.GasLimit.Uint64(), func(args CallArgs) (bool, error) {
+		_, _, failed, err := DoCallAtState(ctx, b, args, state.Copy(), header, vm.Config{})
+		return failed, err
+	})
+}
+
+// searchGas binary searches the gas requirement of a call message, as it may be
+// higher than the amount used. The gas of the message, if set, takes precedence
+// over the given ceiling.
+func searchGas(args CallArgs, hi uint64, call func(args CallArgs) (failed bool, err error)) *big.Int {
+	var lo uint64
+	if (*big.Int)(&args.Gas).Sign() != 0 {
+		hi = (*big.Int)(&args.Gas).Uint64()
+	}
 	for lo+1 < hi {
 		// Take a guess at the gas, and check transaction validity
 		mid := (hi + lo) / 2
 		(*big.Int)(&args.Gas).SetUint64(mid)
 
-		_, gas, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{})
+		failed, err := call(args)
 
-		// If the transaction became invalid or used all the gas (failed), raise the gas limit
-		if err != nil || gas.Cmp((*big.Int)(&args.Gas)) == 0 {
+		// If the transaction became invalid or execution failed, raise the gas limit
+		if err != nil || failed {
 			lo = mid
 			continue
 		}
 		// Otherwise assume the transaction succeeded, lower the gas limit
 		hi = mid
 	}
-	return (*hexutil.Big)(new(big.Int).SetUint64(hi)), nil
+	return new(big.Int).SetUint64(hi)
+}
+
+// EstimateGas returns an estimate of the amount of gas needed to execute the given transaction.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
+	gas, err := DoEstimateGas(ctx, s.b, args, rpc.PendingBlockNumber)
+	return (*hexutil.Big)(gas), err
 }
 
 // ExecutionResult groups all structured logs emitted by the EVM
@@ -686,6 +890,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*
 // gas used and the return value
 type ExecutionResult struct {
 	Gas         *big.Int       `json:"gas"`
+	Failed      bool           `json:"failed"`
 	ReturnValue string         `json:"returnValue"`
 	StructLogs  []StructLogRes `json:"structLogs"`
 }
@@ -990,7 +1195,6 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
-		"root":              hexutil.Bytes(receipt.PostState),
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
 		"transactionHash":   hash,
@@ -1003,6 +1207,13 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
 	}
+
+	// Assign receipt status or post state.
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
 	if receipt.Logs == nil {
 		fields["logs"] = [][]*types.Log{}
 	}
@@ -1178,6 +1389,33 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an EIP-712 ECDSA signature for:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message))
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, typedData abi.TypedData) (hexutil.Bytes, error) {
+	hash, err := typedData.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the requested hash with the wallet
+	signature, err := wallet.SignHash(account, hash[:])
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -24,6 +24,7 @@ import (
 	"github.com/cryptorift/riftcore/accounts"
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
@@ -49,6 +50,7 @@ type Backend interface {
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
@@ -62,6 +64,14 @@ type Backend interface {
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions)
+	TxPoolEvict(txHash common.Hash) bool
+	TxPoolEvictAccount(addr common.Address) int
+	SubscribeTxPoolEvent(ch chan<- core.TxPoolEvent) event.Subscription
+
+	// Filter API
+	BloomStatus() (uint64, uint64)
+	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -90,6 +100,10 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTxPoolAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(apiBackend),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -0,0 +1,235 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package riftapi
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/vm"
+)
+
+// errNoCallFrame is returned by the call tracer if the traced execution never
+// entered a call frame (e.g. it failed before reaching the EVM).
+var errNoCallFrame = errors.New("no call frame captured")
+
+// nativeTracers is the collection of built-in Go tracers selectable by name.
+var nativeTracers = map[string]func() vm.Tracer{
+	"callTracer":     func() vm.Tracer { return NewCallTracer() },
+	"prestateTracer": func() vm.Tracer { return NewPrestateTracer() },
+}
+
+// NewNativeTracer creates one of the built-in Go tracers by name, returning
+// false if no native tracer exists with the given name.
+func NewNativeTracer(name string) (vm.Tracer, bool) {
+	constructor, ok := nativeTracers[name]
+	if !ok {
+		return nil, false
+	}
+	return constructor(), true
+}
+
+// CallFrame is a single call or contract creation made during the execution of
+// a message, along with all the nested calls it made.
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+}
+
+// CallTracer is a native tracer that records the tree of call frames entered
+// during the execution of a message. Gas values are reported as seen by the EVM,
+// i.e. the outermost frame excludes the intrinsic gas of the transaction.
+type CallTracer struct {
+	root  *CallFrame   // Outermost call frame of the execution
+	stack []*CallFrame // Call frames currently being executed
+}
+
+// NewCallTracer creates a new call frame tracer.
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+// CaptureState implements vm.Tracer, ignoring individual execution steps.
+func (t *CallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer. The outermost frame is already closed via
+// CaptureExit, so there's nothing left to do.
+func (t *CallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration) error {
+	return nil
+}
+
+// CaptureEnter implements vm.FrameTracer, opening a new call frame.
+func (t *CallTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	frame := &CallFrame{
+		Type:  typ.String(),
+		From:  from,
+		To:    to,
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	if value != nil {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	if len(t.stack) == 0 {
+		t.root = frame
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, frame)
+	}
+	t.stack = append(t.stack, frame)
+	return nil
+}
+
+// CaptureExit implements vm.FrameTracer, closing the current call frame.
+func (t *CallTracer) CaptureExit(env *vm.EVM, output []byte, gasUsed uint64, err error) error {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	frame.GasUsed = hexutil.Uint64(gasUsed)
+	frame.Output = common.CopyBytes(output)
+	if err != nil {
+		frame.Error = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the outermost call frame of the traced execution.
+func (t *CallTracer) GetResult() (interface{}, error) {
+	if t.root == nil {
+		return nil, errNoCallFrame
+	}
+	return t.root, nil
+}
+
+// PrestateAccount is the state of an account prior to executing a message.
+// Only the storage slots accessed during execution are included.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// PrestateTracer is a native tracer that records the state of all the accounts
+// and storage slots touched by a message, as they were before its execution.
+type PrestateTracer struct {
+	prestate map[common.Address]*PrestateAccount
+	entered  bool // Whether the outermost call frame was already seen
+}
+
+// NewPrestateTracer creates a new pre-state tracer.
+func NewPrestateTracer() *PrestateTracer {
+	return &PrestateTracer{
+		prestate: make(map[common.Address]*PrestateAccount),
+	}
+}
+
+// lookupAccount records the current state of an account if it wasn't touched
+// before, returning the recorded state.
+func (t *PrestateTracer) lookupAccount(db vm.StateDB, addr common.Address) *PrestateAccount {
+	if account, ok := t.prestate[addr]; ok {
+		return account
+	}
+	account := &PrestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(db.GetBalance(addr))),
+		Nonce:   db.GetNonce(addr),
+		Code:    common.CopyBytes(db.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+	t.prestate[addr] = account
+	return account
+}
+
+// lookupStorage records the current value of a storage slot if it wasn't
+// touched before.
+func (t *PrestateTracer) lookupStorage(db vm.StateDB, addr common.Address, key common.Hash) {
+	account := t.lookupAccount(db, addr)
+	if _, ok := account.Storage[key]; !ok {
+		account.Storage[key] = db.GetState(addr, key)
+	}
+}
+
+// CaptureState implements vm.Tracer, recording any account or storage slot
+// accessed by the upcoming operation.
+func (t *PrestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil || len(stack.Data()) == 0 {
+		return nil
+	}
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(env.StateDB, contract.Address(), common.BigToHash(stack.Back(0)))
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.SELFDESTRUCT:
+		t.lookupAccount(env.StateDB, common.BigToAddress(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *PrestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration) error {
+	return nil
+}
+
+// CaptureEnter implements vm.FrameTracer, recording both parties of the call.
+// For the outermost frame, the gas purchase and nonce bump of the sender done
+// before entering the EVM are undone, along with recording the coinbase.
+func (t *PrestateTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if !t.entered {
+		t.entered = true
+
+		sender := t.lookupAccount(env.StateDB, from)
+
+		create := typ == vm.CREATE
+		intrinsic := core.IntrinsicGas(input, create, env.ChainConfig().IsHomestead(env.BlockNumber))
+		cost := new(big.Int).Add(intrinsic, new(big.Int).SetUint64(gas))
+		cost.Mul(cost, env.GasPrice)
+		sender.Balance = (*hexutil.Big)(cost.Add(cost, sender.Balance.ToInt()))
+		if !create && sender.Nonce > 0 {
+			sender.Nonce--
+		}
+		t.lookupAccount(env.StateDB, env.Coinbase)
+	}
+	t.lookupAccount(env.StateDB, from)
+	t.lookupAccount(env.StateDB, to)
+	return nil
+}
+
+// CaptureExit implements vm.FrameTracer.
+func (t *PrestateTracer) CaptureExit(env *vm.EVM, output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// GetResult returns the pre-state of all the accounts touched by the message.
+func (t *PrestateTracer) GetResult() (interface{}, error) {
+	return t.prestate, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package riftapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/state"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+var (
+	nativeCaller = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	nativeOuter  = common.HexToAddress("0x1000000000000000000000000000000000000002")
+	nativeInner  = common.HexToAddress("0x1000000000000000000000000000000000000003")
+)
+
+// runNativeTrace executes a call into a contract which in turn calls a second
+// contract reading one of its storage slots, tracing it with the given tracer.
+func runNativeTrace(t *testing.T, tracer vm.Tracer) {
+	db, _ := riftdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// Outer contract: CALL(0xffff, inner, 0, 0, 0, 0, 0), STOP
+	outer := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	outer = append(outer, nativeInner.Bytes()...)
+	outer = append(outer, byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.STOP))
+
+	// Inner contract: SLOAD(1), STOP
+	inner := []byte{byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.STOP)}
+
+	statedb.SetBalance(nativeCaller, big.NewInt(1000000))
+	statedb.SetCode(nativeOuter, outer)
+	statedb.SetCode(nativeInner, inner)
+	statedb.SetState(nativeInner, common.BigToHash(big.NewInt(1)), common.HexToHash("0x2a"))
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(0),
+		GasPrice:    big.NewInt(1),
+	}
+	env := vm.NewEVM(context, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := env.Call(vm.AccountRef(nativeCaller), nativeOuter, []byte{0x01}, 100000, big.NewInt(10)); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+}
+
+// Tests that the native call tracer reconstructs the nested call frames.
+func TestNativeCallTracer(t *testing.T) {
+	tracer, ok := NewNativeTracer("callTracer")
+	if !ok {
+		t.Fatalf("callTracer not found")
+	}
+	runNativeTrace(t, tracer)
+
+	result, err := tracer.(*CallTracer).GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	root := result.(*CallFrame)
+	if root.Type != "CALL" || root.From != nativeCaller || root.To != nativeOuter {
+		t.Errorf("outer frame mismatch: have %s %x -> %x", root.Type, root.From, root.To)
+	}
+	if root.Value.ToInt().Int64() != 10 || uint64(root.Gas) != 100000 || len(root.Input) != 1 {
+		t.Errorf("outer frame params mismatch: value %v, gas %d, input %x", root.Value, root.Gas, root.Input)
+	}
+	if root.GasUsed == 0 || root.Error != "" {
+		t.Errorf("outer frame result mismatch: gas used %d, error %q", root.GasUsed, root.Error)
+	}
+	if len(root.Calls) != 1 {
+		t.Fatalf("nested call count mismatch: have %d, want 1", len(root.Calls))
+	}
+	call := root.Calls[0]
+	if call.Type != "CALL" || call.From != nativeOuter || call.To != nativeInner {
+		t.Errorf("inner frame mismatch: have %s %x -> %x", call.Type, call.From, call.To)
+	}
+	if call.GasUsed == 0 || call.GasUsed >= root.GasUsed || len(call.Calls) != 0 {
+		t.Errorf("inner frame result mismatch: gas used %d (outer %d), calls %d", call.GasUsed, root.GasUsed, len(call.Calls))
+	}
+}
+
+// Tests that the native prestate tracer records all touched accounts and slots.
+func TestNativePrestateTracer(t *testing.T) {
+	tracer, ok := NewNativeTracer("prestateTracer")
+	if !ok {
+		t.Fatalf("prestateTracer not found")
+	}
+	runNativeTrace(t, tracer)
+
+	result, err := tracer.(*PrestateTracer).GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	prestate := result.(map[common.Address]*PrestateAccount)
+	for _, addr := range []common.Address{nativeCaller, nativeOuter, nativeInner} {
+		if _, ok := prestate[addr]; !ok {
+			t.Errorf("account %x missing from prestate", addr)
+		}
+	}
+	if balance := prestate[nativeOuter].Balance.ToInt(); balance.Sign() != 0 {
+		t.Errorf("outer balance mismatch: have %v, want 0", balance)
+	}
+	if len(prestate[nativeInner].Code) == 0 {
+		t.Errorf("inner code missing from prestate")
+	}
+	slot := common.BigToHash(big.NewInt(1))
+	if have := prestate[nativeInner].Storage[slot]; have != common.HexToHash("0x2a") {
+		t.Errorf("inner storage mismatch: have %x, want %x", have, common.HexToHash("0x2a"))
+	}
+}
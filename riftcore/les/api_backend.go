@@ -24,6 +24,7 @@ import (
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/math"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
@@ -78,6 +79,14 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return light.NewState(ctx, header, b.rift.odr), header, nil
 }
 
+func (b *LesApiBackend) StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.rift.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	return light.NewState(ctx, header, b.rift.odr), header, nil
+}
+
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.rift.blockchain.GetBlockByHash(ctx, blockHash)
 }
@@ -124,6 +133,32 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.rift.txPool.Content()
 }
 
+func (b *LesApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return b.rift.txPool.ContentFrom(addr)
+}
+
+func (b *LesApiBackend) TxPoolEvict(txHash common.Hash) bool {
+	if b.rift.txPool.GetTransaction(txHash) == nil {
+		return false
+	}
+	b.rift.txPool.RemoveTx(txHash)
+	return true
+}
+
+func (b *LesApiBackend) TxPoolEvictAccount(addr common.Address) int {
+	pending, _ := b.rift.txPool.ContentFrom(addr)
+	b.rift.txPool.RemoveTransactions(pending)
+	return len(pending)
+}
+
+func (b *LesApiBackend) SubscribeTxPoolEvent(ch chan<- core.TxPoolEvent) event.Subscription {
+	// The light pool doesn't track transaction promotions, nothing to report
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) Downloader() *downloader.Downloader {
 	return b.rift.Downloader()
 }
@@ -147,3 +182,18 @@ func (b *LesApiBackend) EventMux() *event.TypeMux {
 func (b *LesApiBackend) AccountManager() *accounts.Manager {
 	return b.rift.accountManager
 }
+
+// BloomStatus implements filters.Backend, reporting the bloom bits sections
+// indexed locally by the light client.
+func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
+	sections, _, _ := b.rift.bloomIndexer.Sections()
+	return light.BloomTrieFrequency, sections
+}
+
+// ServiceFilter implements filters.Backend, multiplexing the bloom bit
+// retrievals of the given session onto the light client's bloom handlers.
+func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.rift.bloomRequests)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/cryptorift/riftcore/common/hexutil"
 	"github.com/cryptorift/riftcore/consensus"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/rift"
 	"github.com/cryptorift/riftcore/rift/downloader"
@@ -61,6 +62,9 @@ type LightCryptorift struct {
 	// DB interfaces
 	chainDb riftdb.Database // Block chain database
 
+	bloomRequests                              chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer, chtIndexer, bloomTrieIndexer *core.ChainIndexer
+
 	ApiBackend *LesApiBackend
 
 	eventMux       *event.TypeMux
@@ -89,24 +93,31 @@ func New(ctx *node.ServiceContext, config *rift.Config) (*LightCryptorift, error
 	quitSync := make(chan struct{})
 
 	rift := &LightCryptorift{
-		chainConfig:    chainConfig,
-		chainDb:        chainDb,
-		eventMux:       ctx.EventMux,
-		peers:          peers,
-		reqDist:        newRequestDistributor(peers, quitSync),
-		accountManager: ctx.AccountManager,
-		engine:         rift.CreateConsensusEngine(ctx, config, chainConfig, chainDb),
-		shutdownChan:   make(chan bool),
-		networkId:      config.NetworkId,
+		chainConfig:      chainConfig,
+		chainDb:          chainDb,
+		eventMux:         ctx.EventMux,
+		peers:            peers,
+		reqDist:          newRequestDistributor(peers, quitSync),
+		accountManager:   ctx.AccountManager,
+		engine:           rift.CreateConsensusEngine(ctx, config, chainConfig, chainDb),
+		shutdownChan:     make(chan bool),
+		networkId:        config.NetworkId,
+		bloomRequests:    make(chan chan *bloombits.Retrieval),
+		bloomIndexer:     rift.NewBloomIndexer(chainDb, light.BloomTrieFrequency),
+		chtIndexer:       light.NewChtIndexer(chainDb, true),
+		bloomTrieIndexer: light.NewBloomTrieIndexer(chainDb, true),
 	}
 
 	rift.relay = NewLesTxRelay(peers, rift.reqDist)
 	rift.serverPool = newServerPool(chainDb, quitSync, &rift.wg)
 	rift.retriever = newRetrieveManager(peers, rift.reqDist, rift.serverPool)
-	rift.odr = NewLesOdr(chainDb, rift.retriever)
+	rift.odr = NewLesOdr(chainDb, rift.chtIndexer, rift.bloomTrieIndexer, rift.bloomIndexer, rift.retriever)
 	if rift.blockchain, err = light.NewLightChain(rift.odr, rift.chainConfig, rift.engine, rift.eventMux); err != nil {
 		return nil, err
 	}
+	rift.bloomIndexer.AddChildIndexer(rift.bloomTrieIndexer)
+	rift.chtIndexer.Start(rift.blockchain.CurrentHeader(), rift.eventMux)
+	rift.bloomIndexer.Start(rift.blockchain.CurrentHeader(), rift.eventMux)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -203,6 +214,7 @@ func (s *LightCryptorift) Protocols() []p2p.Protocol {
 func (s *LightCryptorift) Start(srvr *p2p.Server) error {
 	log.Warn("Light client mode is an experimental feature")
 	s.netRPCService = riftapi.NewPublicNetAPI(srvr, s.networkId)
+	s.startBloomHandlers()
 	s.serverPool.start(srvr, lesTopic(s.blockchain.Genesis().Hash()))
 	s.protocolManager.Start()
 	return nil
@@ -212,6 +224,8 @@ func (s *LightCryptorift) Start(srvr *p2p.Server) error {
 // CryptoRift protocol.
 func (s *LightCryptorift) Stop() error {
 	s.odr.Stop()
+	s.bloomIndexer.Close()
+	s.chtIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	s.txPool.Stop()
@@ -32,6 +32,7 @@ import (
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
 	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/rift"
 	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/event"
 	"github.com/cryptorift/riftcore/les/flowcontrol"
@@ -149,7 +150,7 @@ func newTestProtocolManager(lightSync bool, blocks int, generator func(int, *cor
 	if lightSync {
 		chain, _ = light.NewLightChain(odr, gspec.Config, engine, evmux)
 	} else {
-		blockchain, _ := core.NewBlockChain(db, gspec.Config, engine, evmux, vm.Config{})
+		blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, engine, evmux, vm.Config{})
 		gchain, _ := core.GenerateChain(gspec.Config, genesis, db, blocks, generator)
 		if _, err := blockchain.InsertChain(gchain); err != nil {
 			panic(err)
@@ -172,6 +173,15 @@ func newTestProtocolManager(lightSync bool, blocks int, generator func(int, *cor
 
 		srv.fcManager = flowcontrol.NewClientManager(50, 10, 1000000000)
 		srv.fcCostStats = newCostStats(nil)
+
+		// Generate the helper tries of the full chain in the background
+		srv.chtIndexer = light.NewChtIndexer(db, false)
+		srv.chtIndexer.Start(chain.CurrentHeader(), evmux)
+
+		srv.bloomTrieIndexer = light.NewBloomTrieIndexer(db, false)
+		bloomIndexer := rift.NewBloomIndexer(db, params.BloomBitsBlocks)
+		bloomIndexer.AddChildIndexer(srv.bloomTrieIndexer)
+		bloomIndexer.Start(chain.CurrentHeader(), evmux)
 	}
 	pm.Start()
 	return pm, nil
@@ -19,6 +19,7 @@ package les
 import (
 	"context"
 
+	"github.com/cryptorift/riftcore/core"
 	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/light"
 	"github.com/cryptorift/riftcore/log"
@@ -26,16 +27,20 @@ import (
 
 // LesOdr implements light.OdrBackend
 type LesOdr struct {
-	db        riftdb.Database
-	stop      chan struct{}
-	retriever *retrieveManager
+	db                                         riftdb.Database
+	chtIndexer, bloomTrieIndexer, bloomIndexer *core.ChainIndexer
+	retriever                                  *retrieveManager
+	stop                                       chan struct{}
 }
 
-func NewLesOdr(db riftdb.Database, retriever *retrieveManager) *LesOdr {
+func NewLesOdr(db riftdb.Database, chtIndexer, bloomTrieIndexer, bloomIndexer *core.ChainIndexer, retriever *retrieveManager) *LesOdr {
 	return &LesOdr{
-		db:        db,
-		retriever: retriever,
-		stop:      make(chan struct{}),
+		db:               db,
+		chtIndexer:       chtIndexer,
+		bloomTrieIndexer: bloomTrieIndexer,
+		bloomIndexer:     bloomIndexer,
+		retriever:        retriever,
+		stop:             make(chan struct{}),
 	}
 }
 
@@ -43,16 +48,35 @@ func (odr *LesOdr) Stop() {
 	close(odr.stop)
 }
 
+// Database returns the backing database
 func (odr *LesOdr) Database() riftdb.Database {
 	return odr.db
 }
 
+// ChtIndexer returns the CHT chain indexer
+func (odr *LesOdr) ChtIndexer() *core.ChainIndexer {
+	return odr.chtIndexer
+}
+
+// BloomTrieIndexer returns the bloom trie chain indexer
+func (odr *LesOdr) BloomTrieIndexer() *core.ChainIndexer {
+	return odr.bloomTrieIndexer
+}
+
+// BloomIndexer returns the bloombits chain indexer
+func (odr *LesOdr) BloomIndexer() *core.ChainIndexer {
+	return odr.bloomIndexer
+}
+
 const (
 	MsgBlockBodies = iota
 	MsgCode
 	MsgReceipts
-	MsgProofs
+	MsgProofsV1
+	MsgProofsV2
 	MsgHeaderProofs
+	MsgHelperTrieProofs
+	MsgTxStatus
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -171,6 +171,17 @@ func (rm *retrieveManager) deliver(peer distPeer, msg *Msg) error {
 	return errResp(ErrUnexpectedResponse, "reqID = %v", msg.ReqID)
 }
 
+// requested reports whether the request with given reqID is currently waiting
+// for a reply. Replies to other requests (e.g. transaction relay) can be told
+// apart this way.
+func (rm *retrieveManager) requested(id uint64) bool {
+	rm.lock.RLock()
+	defer rm.lock.RUnlock()
+
+	_, ok := rm.sentReqs[id]
+	return ok
+}
+
 // reqStateFn represents a state of the retrieve loop state machine
 type reqStateFn func() reqStateFn
 
@@ -21,7 +21,6 @@ import (
 	"encoding/binary"
 	"math"
 	"sync"
-	"time"
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/core"
@@ -34,17 +33,18 @@ import (
 	"github.com/cryptorift/riftcore/p2p"
 	"github.com/cryptorift/riftcore/p2p/discv5"
 	"github.com/cryptorift/riftcore/rlp"
-	"github.com/cryptorift/riftcore/trie"
 )
 
 type LesServer struct {
-	protocolManager *ProtocolManager
-	fcManager       *flowcontrol.ClientManager // nil if our node is client only
-	fcCostStats     *requestCostStats
-	defParams       *flowcontrol.ServerParams
-	lesTopic        discv5.Topic
-	quitSync        chan struct{}
-	stopped         bool
+	protocolManager  *ProtocolManager
+	fcManager        *flowcontrol.ClientManager // nil if our node is client only
+	fcCostStats      *requestCostStats
+	defParams        *flowcontrol.ServerParams
+	lesTopic         discv5.Topic
+	chtIndexer       *core.ChainIndexer
+	bloomTrieIndexer *core.ChainIndexer
+	quitSync         chan struct{}
+	stopped          bool
 }
 
 func NewLesServer(rift *rift.CryptoRift, config *rift.Config) (*LesServer, error) {
@@ -56,10 +56,13 @@ func NewLesServer(rift *rift.CryptoRift, config *rift.Config) (*LesServer, error
 	pm.blockLoop()
 
 	srv := &LesServer{
-		protocolManager: pm,
-		quitSync:        quitSync,
-		lesTopic:        lesTopic(rift.BlockChain().Genesis().Hash()),
+		protocolManager:  pm,
+		quitSync:         quitSync,
+		lesTopic:         lesTopic(rift.BlockChain().Genesis().Hash()),
+		chtIndexer:       light.NewChtIndexer(rift.ChainDb(), false),
+		bloomTrieIndexer: light.NewBloomTrieIndexer(rift.ChainDb(), false),
 	}
+	srv.chtIndexer.Start(rift.BlockChain().CurrentHeader(), rift.EventMux())
 	pm.server = srv
 
 	srv.defParams = &flowcontrol.ServerParams{
@@ -87,8 +90,16 @@ func (s *LesServer) Start(srvr *p2p.Server) {
 	}()
 }
 
+// SetBloomBitsIndexer attaches the bloom trie indexer to the full node's bloom
+// bits indexer, so that it is fed with the sections the latter produces.
+func (s *LesServer) SetBloomBitsIndexer(bloomIndexer *core.ChainIndexer) {
+	bloomIndexer.AddChildIndexer(s.bloomTrieIndexer)
+}
+
 // Stop stops the LES service
 func (s *LesServer) Stop() {
+	s.chtIndexer.Close()
+	// bloom trie indexer is closed by parent bloombits indexer
 	s.fcCostStats.store()
 	s.fcManager.Stop()
 	go func() {
@@ -283,10 +294,7 @@ func (s *requestCostStats) update(msgCode, reqCnt, cost uint64) {
 func (pm *ProtocolManager) blockLoop() {
 	pm.wg.Add(1)
 	sub := pm.eventMux.Subscribe(core.ChainHeadEvent{})
-	newCht := make(chan struct{}, 10)
-	newCht <- struct{}{}
 	go func() {
-		var mu sync.Mutex
 		var lastHead *types.Header
 		lastBroadcastTd := common.Big0
 		for {
@@ -318,17 +326,6 @@ func (pm *ProtocolManager) blockLoop() {
 						}
 					}
 				}
-				newCht <- struct{}{}
-			case <-newCht:
-				go func() {
-					mu.Lock()
-					more := makeCht(pm.chainDb)
-					mu.Unlock()
-					if more {
-						time.Sleep(time.Millisecond * 10)
-						newCht <- struct{}{}
-					}
-				}()
 			case <-pm.quitSync:
 				sub.Unsubscribe()
 				pm.wg.Done()
@@ -337,86 +334,3 @@ func (pm *ProtocolManager) blockLoop() {
 		}
 	}()
 }
-
-var (
-	lastChtKey = []byte("LastChtNumber") // chtNum (uint64 big endian)
-	chtPrefix  = []byte("cht")           // chtPrefix + chtNum (uint64 big endian) -> trie root hash
-)
-
-func getChtRoot(db riftdb.Database, num uint64) common.Hash {
-	var encNumber [8]byte
-	binary.BigEndian.PutUint64(encNumber[:], num)
-	data, _ := db.Get(append(chtPrefix, encNumber[:]...))
-	return common.BytesToHash(data)
-}
-
-func storeChtRoot(db riftdb.Database, num uint64, root common.Hash) {
-	var encNumber [8]byte
-	binary.BigEndian.PutUint64(encNumber[:], num)
-	db.Put(append(chtPrefix, encNumber[:]...), root[:])
-}
-
-func makeCht(db riftdb.Database) bool {
-	headHash := core.GetHeadBlockHash(db)
-	headNum := core.GetBlockNumber(db, headHash)
-
-	var newChtNum uint64
-	if headNum > light.ChtConfirmations {
-		newChtNum = (headNum - light.ChtConfirmations) / light.ChtFrequency
-	}
-
-	var lastChtNum uint64
-	data, _ := db.Get(lastChtKey)
-	if len(data) == 8 {
-		lastChtNum = binary.BigEndian.Uint64(data[:])
-	}
-	if newChtNum <= lastChtNum {
-		return false
-	}
-
-	var t *trie.Trie
-	if lastChtNum > 0 {
-		var err error
-		t, err = trie.New(getChtRoot(db, lastChtNum), db)
-		if err != nil {
-			lastChtNum = 0
-		}
-	}
-	if lastChtNum == 0 {
-		t, _ = trie.New(common.Hash{}, db)
-	}
-
-	for num := lastChtNum * light.ChtFrequency; num < (lastChtNum+1)*light.ChtFrequency; num++ {
-		hash := core.GetCanonicalHash(db, num)
-		if hash == (common.Hash{}) {
-			panic("Canonical hash not found")
-		}
-		td := core.GetTd(db, hash, num)
-		if td == nil {
-			panic("TD not found")
-		}
-		var encNumber [8]byte
-		binary.BigEndian.PutUint64(encNumber[:], num)
-		var node light.ChtNode
-		node.Hash = hash
-		node.Td = td
-		data, _ := rlp.EncodeToBytes(node)
-		t.Update(encNumber[:], data)
-	}
-
-	root, err := t.Commit()
-	if err != nil {
-		lastChtNum = 0
-	} else {
-		lastChtNum++
-
-		log.Trace("Generated CHT", "number", lastChtNum, "root", root.Hex())
-
-		storeChtRoot(db, lastChtNum, root)
-		var data [8]byte
-		binary.BigEndian.PutUint64(data[:], lastChtNum)
-		db.Put(lastChtKey, data[:])
-	}
-
-	return newChtNum > lastChtNum
-}
@@ -37,6 +37,9 @@ var NoOdr = context.Background()
 // OdrBackend is an interface to a backend service that handles ODR retrievals type
 type OdrBackend interface {
 	Database() riftdb.Database
+	ChtIndexer() *core.ChainIndexer
+	BloomTrieIndexer() *core.ChainIndexer
+	BloomIndexer() *core.ChainIndexer
 	Retrieve(ctx context.Context, req OdrRequest) error
 }
 
@@ -138,14 +141,14 @@ func (req *ReceiptsRequest) StoreResult(db riftdb.Database) {
 	core.WriteBlockReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
-// TrieRequest is the ODR request type for state/storage trie entries
+// ChtRequest is the ODR request type for retrieving headers by Canonical Hash Trie
 type ChtRequest struct {
 	OdrRequest
 	ChtNum, BlockNum uint64
 	ChtRoot          common.Hash
 	Header           *types.Header
 	Td               *big.Int
-	Proof            []rlp.RawValue
+	Proof            NodeList
 }
 
 // StoreResult stores the retrieved data in local database
@@ -155,5 +158,45 @@ func (req *ChtRequest) StoreResult(db riftdb.Database) {
 	hash, num := req.Header.Hash(), req.Header.Number.Uint64()
 	core.WriteTd(db, hash, num, req.Td)
 	core.WriteCanonicalHash(db, hash, num)
-	//storeProof(db, req.Proof)
 }
+
+// BloomRequest is the ODR request type for retrieving bloom bit vectors through a BloomTrie
+type BloomRequest struct {
+	OdrRequest
+	BloomTrieNum   uint64
+	BitIdx         uint
+	SectionIdxList []uint64
+	BloomTrieRoot  common.Hash
+	BloomBits      [][]byte
+	Proofs         NodeList
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *BloomRequest) StoreResult(db riftdb.Database) {
+	for i, sectionIdx := range req.SectionIdxList {
+		sectionHead := core.GetCanonicalHash(db, (sectionIdx+1)*BloomTrieFrequency-1)
+		// if we don't have the canonical hash stored for this section head number, we'll still store it with
+		// a zero sectionHead. GetBloomBits will look there too if we still don't have the canonical hash. In
+		// the unlikely case we've retrieved the section head hash since then, we'll just retrieve the bit
+		// vector again from the network.
+		core.WriteBloomBits(db, req.BitIdx, sectionIdx, sectionHead, req.BloomBits[i])
+	}
+}
+
+// TxStatus describes the status of a transaction as reported by a server. The
+// lookup entry is only present for transactions already included in a block.
+type TxStatus struct {
+	Status core.TxStatus
+	Lookup *core.TxLookupEntry `rlp:"nil"`
+	Error  string
+}
+
+// TxStatusRequest is the ODR request type for retrieving transaction status
+type TxStatusRequest struct {
+	OdrRequest
+	Hashes []common.Hash
+	Status []TxStatus
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *TxStatusRequest) StoreResult(db riftdb.Database) {}
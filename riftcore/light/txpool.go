@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -482,6 +483,23 @@ func (self *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool belonging to a
+// single account, returning its pending transactions sorted by nonce. There are
+// no queued transactions in a light pool.
+func (self *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	var pending types.Transactions
+	for _, tx := range self.pending {
+		if account, _ := types.Sender(self.signer, tx); account == addr {
+			pending = append(pending, tx)
+		}
+	}
+	sort.Sort(types.TxByNonce(pending))
+	return pending, nil
+}
+
 // RemoveTransactions removes all given transactions from the pool.
 func (self *TxPool) RemoveTransactions(txs types.Transactions) {
 	self.mu.Lock()
@@ -17,14 +17,17 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/cryptorift/riftcore/common/hexutil"
 	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/p2p"
 	"github.com/cryptorift/riftcore/p2p/discover"
+	"github.com/cryptorift/riftcore/rpc"
 	"github.com/rcrowley/go-metrics"
 )
 
@@ -73,6 +76,64 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// peerEventsBuffer is the number of peer events that may await delivery to an
+// RPC subscriber before it is considered lagging and dropped.
+const peerEventsBuffer = 128
+
+// PeerEvents creates an RPC subscription which receives peer events from the
+// node's p2p.Server: peers being added or dropped (along with the reason) and
+// messages being exchanged with them. Subscribers that can't keep up with the
+// events are dropped instead of holding up the peers.
+func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	// Create the subscription
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan *p2p.PeerEvent, peerEventsBuffer)
+		sub := server.SubscribeEvents(events)
+		defer sub.Unsubscribe()
+
+		// Write the notifications from a separate goroutine, a stalled client
+		// may block it indefinitely
+		queue := make(chan *p2p.PeerEvent, peerEventsBuffer)
+		defer close(queue)
+
+		go func() {
+			for event := range queue {
+				notifier.Notify(rpcSub.ID, event)
+			}
+		}()
+		for {
+			select {
+			case event := <-events:
+				select {
+				case queue <- event:
+				default:
+					log.Warn("Dropping lagging peer event subscriber", "id", rpcSub.ID)
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // StartRPC starts the HTTP RPC API server.
 func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string) (bool, error) {
 	api.node.lock.Lock()
@@ -109,7 +170,7 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins); err != nil {
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, api.node.config.HTTPVirtualHosts); err != nil {
 		return false, err
 	}
 	return true, nil
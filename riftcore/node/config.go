@@ -18,14 +18,19 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/external"
 	"github.com/cryptorift/riftcore/accounts/keystore"
 	"github.com/cryptorift/riftcore/accounts/usbwallet"
 	"github.com/cryptorift/riftcore/common"
@@ -33,6 +38,7 @@ import (
 	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/p2p"
 	"github.com/cryptorift/riftcore/p2p/discover"
+	"github.com/cryptorift/riftcore/rpc"
 )
 
 var (
@@ -85,6 +91,11 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the endpoint (IPC path or HTTP URL) of an external signer
+	// to forward signing requests to. Accounts revealed by the signer take
+	// precedence over any in the local keystore.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -105,6 +116,13 @@ type Config struct {
 	// useless for custom HTTP clients.
 	HTTPCors []string `toml:",omitempty"`
 
+	// HTTPVirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests. This is by default {'localhost'}. Using this prevents attacks like
+	// DNS rebinding, which bypasses SOP by simply masquerading as being within the
+	// same origin. Requests addressed to an IP address are always accepted, whereas
+	// '*' accepts any Host header.
+	HTTPVirtualHosts []string `toml:",omitempty"`
+
 	// HTTPModules is a list of API modules to expose via the HTTP RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
@@ -128,6 +146,36 @@ type Config struct {
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
 	WSModules []string `toml:",omitempty"`
+
+	// RPCTLSCert and RPCTLSKey are the paths to a PEM encoded certificate and its
+	// private key. If both are set, the HTTP and websocket RPC interfaces are served
+	// over TLS.
+	RPCTLSCert string `toml:",omitempty"`
+	RPCTLSKey  string `toml:",omitempty"`
+
+	// RPCJWTSecret is the path to a file containing the hex encoded secret used to
+	// authenticate HTTP and websocket RPC requests. If set, every request needs to
+	// carry an HS256 signed JSON web token as bearer authorization. Tokens may limit
+	// the API modules accessible through them via a "modules" claim.
+	RPCJWTSecret string `toml:",omitempty"`
+
+	// RPCMaxRequestSize is the maximum size of an HTTP RPC request body in bytes. If
+	// zero, a default of 128KB is used.
+	RPCMaxRequestSize int64 `toml:",omitempty"`
+
+	// RPCMaxBatchSize is the maximum number of requests accepted in a single batch
+	// over the HTTP and websocket RPC interfaces. Zero means no limit.
+	RPCMaxBatchSize int `toml:",omitempty"`
+
+	// RPCRateLimit is the number of requests per second a single client IP may issue
+	// to the HTTP and websocket RPC interfaces, with bursts up to RPCRateBurst. Zero
+	// means no limit.
+	RPCRateLimit float64 `toml:",omitempty"`
+	RPCRateBurst int     `toml:",omitempty"`
+
+	// RPCMethodRateLimits are additional per method (e.g. rift_getLogs) limits on
+	// the requests per second a single client IP may issue.
+	RPCMethodRateLimits map[string]float64 `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -205,6 +253,83 @@ func DefaultWSEndpoint() string {
 	return config.WSEndpoint()
 }
 
+// rpcTLSConfig loads the TLS certificate of the HTTP and websocket RPC interfaces,
+// returning nil if TLS is not configured.
+func (c *Config) rpcTLSConfig() (*tls.Config, error) {
+	if c.RPCTLSCert == "" && c.RPCTLSKey == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.RPCTLSCert, c.RPCTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load RPC TLS certificate: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// rpcJWTSecret loads the secret authenticating HTTP and websocket RPC requests,
+// returning nil if authentication is not configured.
+func (c *Config) rpcJWTSecret() ([]byte, error) {
+	if c.RPCJWTSecret == "" {
+		return nil, nil
+	}
+	blob, err := ioutil.ReadFile(c.RPCJWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPC JWT secret: %v", err)
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC JWT secret: %v", err)
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("RPC JWT secret too short: have %d bytes, want at least 32", len(secret))
+	}
+	return secret, nil
+}
+
+// rpcLimits returns the resource limits of the clients of the HTTP and websocket
+// RPC interfaces.
+func (c *Config) rpcLimits() rpc.Limits {
+	return rpc.Limits{
+		MaxRequestSize: c.RPCMaxRequestSize,
+		MaxBatchSize:   c.RPCMaxBatchSize,
+		RequestRate:    c.RPCRateLimit,
+		RequestBurst:   c.RPCRateBurst,
+		MethodRates:    c.RPCMethodRateLimits,
+	}
+}
+
+// secureRPC applies the configured TLS and JSON web token authentication settings
+// to an HTTP or websocket RPC server and its listener. On failure the listener is
+// closed.
+func (c *Config) secureRPC(listener net.Listener, server *http.Server) (net.Listener, error) {
+	secret, err := c.rpcJWTSecret()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if secret != nil {
+		server.Handler = rpc.NewJWTHandler(secret, server.Handler)
+	}
+	config, err := c.rpcTLSConfig()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+	return listener, nil
+}
+
+// rpcScheme returns the URL scheme of the HTTP or websocket RPC endpoints, adding
+// the secure suffix if TLS is configured.
+func (c *Config) rpcScheme(scheme string) string {
+	if c.RPCTLSCert != "" {
+		return scheme + "s"
+	}
+	return scheme
+}
+
 // NodeName returns the devp2p node identifier.
 func (c *Config) NodeName() string {
 	name := c.name()
@@ -398,6 +523,19 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		} else {
 			backends = append(backends, ledgerhub)
 		}
+		if trezorhub, err := usbwallet.NewTrezorHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Trezor hub, disabling: %v", err))
+		} else {
+			backends = append(backends, trezorhub)
+		}
+	}
+	if conf.ExternalSigner != "" {
+		log.Info("Using external signer", "endpoint", conf.ExternalSigner)
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		backends = append(backends, extapi)
 	}
 	return accounts.NewManager(backends...), ephemeral, nil
 }
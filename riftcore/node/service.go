@@ -17,6 +17,9 @@
 package node
 
 import (
+	"fmt"
+	"net"
+	"net/http"
 	"reflect"
 
 	"github.com/cryptorift/riftcore/accounts"
@@ -57,6 +60,24 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.resolvePath(path)
 }
 
+// StartHTTP opens an HTTP endpoint serving the given handler on behalf of a
+// service, protected by the same TLS, JSON web token authentication and request
+// limits as the HTTP RPC endpoint of the node. It returns the listener, which
+// the service must close when stopped, along with the URL of the endpoint.
+func (ctx *ServiceContext) StartHTTP(endpoint string, cors []string, vhosts []string, handler http.Handler) (net.Listener, string, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	server := rpc.NewHTTPServer(cors, vhosts, rpc.NewLimitHandler(ctx.config.rpcLimits(), handler))
+	if listener, err = ctx.config.secureRPC(listener, server); err != nil {
+		return nil, "", err
+	}
+	go server.Serve(listener)
+
+	return listener, fmt.Sprintf("%s://%s", ctx.config.rpcScheme("http"), listener.Addr()), nil
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
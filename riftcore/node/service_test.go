@@ -19,9 +19,13 @@ package node
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/rpc"
 )
 
 // Tests that databases are correctly created persistent or ephemeral based on
@@ -61,6 +65,50 @@ func TestContextDatabases(t *testing.T) {
 	}
 }
 
+// Tests that HTTP endpoints opened on behalf of services are protected by the
+// authentication and request limits of the HTTP RPC endpoint.
+func TestContextHTTP(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create secret file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(secret)
+	file.Close()
+
+	ctx := &ServiceContext{config: &Config{RPCJWTSecret: file.Name(), RPCRateLimit: 0.001, RPCRateBurst: 1}}
+	listener, url, err := ctx.StartHTTP("127.0.0.1:0", nil, []string{"*"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if err != nil {
+		t.Fatalf("failed to start HTTP endpoint: %v", err)
+	}
+	defer listener.Close()
+
+	get := func(token string) int {
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get(""); code != http.StatusUnauthorized {
+		t.Errorf("missing token: status mismatch: have %d, want %d", code, http.StatusUnauthorized)
+	}
+	key, _ := ctx.config.rpcJWTSecret()
+	token, _ := rpc.NewJWT(key, nil, time.Minute)
+	if code := get(token); code != http.StatusOK {
+		t.Errorf("authenticated request: status mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	if code := get(token); code != http.StatusTooManyRequests {
+		t.Errorf("over rate request: status mismatch: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
 // Tests that already constructed services can be retrieves by later ones.
 func TestContextServices(t *testing.T) {
 	stack, err := New(testNodeConfig())
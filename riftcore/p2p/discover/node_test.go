@@ -17,6 +17,7 @@
 package discover
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -192,6 +193,28 @@ func TestHexID(t *testing.T) {
 	}
 }
 
+func TestNodeID_textEncoding(t *testing.T) {
+	ref := MustHexID("000000000000000000000000000000000000000000000000000000000000000000000000000000806ad9b61fa5ae014307ebdc964253adcd9f2c0a392aa11abc")
+	enc, err := json.Marshal(map[string]NodeID{"id": ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"` + ref.String() + `"}`
+	if string(enc) != want {
+		t.Errorf("wrong encoding\ngot  %s\nwant %s", enc, want)
+	}
+	var dec map[string]NodeID
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec["id"] != ref {
+		t.Errorf("wrong decoded id\ngot  %v\nwant %v", dec["id"], ref)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"0x1234"}`), &dec); err == nil {
+		t.Errorf("expected error for short id")
+	}
+}
+
 func TestNodeID_recover(t *testing.T) {
 	prv := newkey()
 	hash := make([]byte, 32)
@@ -22,9 +22,11 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cryptorift/riftcore/common/mclock"
+	"github.com/cryptorift/riftcore/event"
 	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/p2p/discover"
 	"github.com/cryptorift/riftcore/rlp"
@@ -60,6 +62,41 @@ type protoHandshake struct {
 	Rest []rlp.RawValue `rlp:"tail"`
 }
 
+// PeerEventType is the type of peer events emitted by a p2p.Server.
+type PeerEventType string
+
+const (
+	// PeerEventTypeAdd is the type of event emitted when a peer is added
+	// to a p2p.Server.
+	PeerEventTypeAdd PeerEventType = "add"
+
+	// PeerEventTypeDrop is the type of event emitted when a peer is
+	// dropped from a p2p.Server.
+	PeerEventTypeDrop PeerEventType = "drop"
+
+	// PeerEventTypeMsgSend is the type of event emitted when a
+	// message is successfully sent to a peer.
+	PeerEventTypeMsgSend PeerEventType = "msgsend"
+
+	// PeerEventTypeMsgRecv is the type of event emitted when a
+	// message is received from a peer.
+	PeerEventTypeMsgRecv PeerEventType = "msgrecv"
+)
+
+// PeerEvent is an event emitted when peers are either added or dropped from
+// a p2p.Server or when a message is sent or received on a peer connection.
+type PeerEvent struct {
+	Type       PeerEventType   `json:"type"`
+	Peer       discover.NodeID `json:"peer"`
+	RemoteAddr string          `json:"remoteAddr,omitempty"` // Remote endpoint of added and dropped peers
+	Error      string          `json:"error,omitempty"`      // Reason a peer was dropped for
+	Requested  bool            `json:"requested,omitempty"`  // Whether the drop was requested by the remote peer
+	Duration   float64         `json:"duration,omitempty"`   // Seconds a dropped peer was connected for
+	Protocol   string          `json:"protocol,omitempty"`
+	MsgCode    *uint64         `json:"msgCode,omitempty"`
+	MsgSize    *uint32         `json:"msgSize,omitempty"`
+}
+
 // Peer represents a connected remote node.
 type Peer struct {
 	rw      *conn
@@ -71,6 +108,9 @@ type Peer struct {
 	protoErr chan error
 	closed   chan struct{}
 	disc     chan DiscReason
+
+	// events receives message send / receive events if set
+	events *event.Feed
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -108,6 +148,11 @@ func (p *Peer) LocalAddr() net.Addr {
 	return p.rw.fd.LocalAddr()
 }
 
+// Duration returns the time elapsed since the connection was established.
+func (p *Peer) Duration() time.Duration {
+	return time.Duration(mclock.Now() - p.created)
+}
+
 // Disconnect terminates the peer connection with the given reason.
 // It returns immediately and does not wait until the connection is closed.
 func (p *Peer) Disconnect(reason DiscReason) {
@@ -279,7 +324,7 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw, traffic: new(trafficCounter)}
 				offset += proto.Length
 
 				continue outer
@@ -296,6 +341,8 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
+		proto.events = p.events
+		proto.peer = p.ID()
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			err := proto.Run(p, proto)
@@ -324,18 +371,22 @@ func (p *Peer) getProto(code uint64) (*protoRW, error) {
 
 type protoRW struct {
 	Protocol
-	in     chan Msg        // receices read messages
-	closed <-chan struct{} // receives when peer is shutting down
-	wstart <-chan struct{} // receives when write may start
-	werr   chan<- error    // for write results
-	offset uint64
-	w      MsgWriter
+	in      chan Msg        // receices read messages
+	closed  <-chan struct{} // receives when peer is shutting down
+	wstart  <-chan struct{} // receives when write may start
+	werr    chan<- error    // for write results
+	offset  uint64
+	w       MsgWriter
+	traffic *trafficCounter // messages and bytes exchanged over the protocol
+	events  *event.Feed     // receives message events if set
+	peer    discover.NodeID // peer to report message events for
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code := msg.Code
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
@@ -348,6 +399,11 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	case <-rw.closed:
 		err = fmt.Errorf("shutting down")
 	}
+	if err == nil {
+		atomic.AddUint64(&rw.traffic.msgOut, 1)
+		atomic.AddUint64(&rw.traffic.bytesOut, uint64(msg.Size))
+		rw.sendEvent(PeerEventTypeMsgSend, code, msg.Size)
+	}
 	return err
 }
 
@@ -355,12 +411,55 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		atomic.AddUint64(&rw.traffic.msgIn, 1)
+		atomic.AddUint64(&rw.traffic.bytesIn, uint64(msg.Size))
+		rw.sendEvent(PeerEventTypeMsgRecv, msg.Code, msg.Size)
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
 	}
 }
 
+// sendEvent reports a message sent or received over the protocol to the event
+// feed of the peer, if any.
+func (rw *protoRW) sendEvent(typ PeerEventType, code uint64, size uint32) {
+	if rw.events == nil {
+		return
+	}
+	rw.events.Send(&PeerEvent{
+		Type:     typ,
+		Peer:     rw.peer,
+		Protocol: rw.Name,
+		MsgCode:  &code,
+		MsgSize:  &size,
+	})
+}
+
+// trafficCounter tracks the messages and payload bytes exchanged over a single
+// protocol. It's allocated on its own to keep the counters 64 bit aligned.
+type trafficCounter struct {
+	msgIn, msgOut     uint64
+	bytesIn, bytesOut uint64
+}
+
+// PeerTraffic is the number of messages and payload bytes exchanged with a peer.
+type PeerTraffic struct {
+	MessagesIn  uint64 `json:"messagesIn"`
+	MessagesOut uint64 `json:"messagesOut"`
+	BytesIn     uint64 `json:"bytesIn"`
+	BytesOut    uint64 `json:"bytesOut"`
+}
+
+// snapshot retrieves the current values of the traffic counters.
+func (c *trafficCounter) snapshot() PeerTraffic {
+	return PeerTraffic{
+		MessagesIn:  atomic.LoadUint64(&c.msgIn),
+		MessagesOut: atomic.LoadUint64(&c.msgOut),
+		BytesIn:     atomic.LoadUint64(&c.bytesIn),
+		BytesOut:    atomic.LoadUint64(&c.bytesOut),
+	}
+}
+
 // PeerInfo represents a short summary of the information known about a connected
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
@@ -372,6 +471,11 @@ type PeerInfo struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
 	} `json:"network"`
+	Session struct {
+		Duration  float64                `json:"duration"`  // Seconds elapsed since the connection was established
+		Traffic   PeerTraffic            `json:"traffic"`   // Total sub-protocol traffic exchanged with the peer
+		Protocols map[string]PeerTraffic `json:"protocols"` // Traffic exchanged per sub-protocol
+	} `json:"session"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
 
@@ -392,6 +496,18 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
 
+	// Gather the session statistics, totalling the sub-protocol traffic
+	info.Session.Duration = p.Duration().Seconds()
+	info.Session.Protocols = make(map[string]PeerTraffic)
+	for _, proto := range p.running {
+		traffic := proto.traffic.snapshot()
+		info.Session.Protocols[proto.Name] = traffic
+
+		info.Session.Traffic.MessagesIn += traffic.MessagesIn
+		info.Session.Traffic.MessagesOut += traffic.MessagesOut
+		info.Session.Traffic.BytesIn += traffic.BytesIn
+		info.Session.Traffic.BytesOut += traffic.BytesOut
+	}
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
 		protoInfo := interface{}("unknown")
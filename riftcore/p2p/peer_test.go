@@ -24,6 +24,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/cryptorift/riftcore/event"
 )
 
 var discard = Protocol{
@@ -147,6 +149,76 @@ func TestPeerDisconnect(t *testing.T) {
 	}
 }
 
+// Tests that the messages exchanged over sub-protocols are counted in the peer
+// infos and reported to the event feed.
+func TestPeerTraffic(t *testing.T) {
+	done := make(chan struct{})
+	proto := Protocol{
+		Name:   "a",
+		Length: 2,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 1, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := SendItems(rw, 0, "foo"); err != nil {
+				t.Error(err)
+			}
+			close(done)
+			<-peer.closed
+			return nil
+		},
+	}
+	fd1, fd2 := net.Pipe()
+	c1 := &conn{fd: fd1, transport: newTestTransport(randomID(), fd1), caps: []Cap{proto.cap()}}
+	c2 := &conn{fd: fd2, transport: newTestTransport(randomID(), fd2), caps: []Cap{proto.cap()}}
+	defer c2.close(errors.New("test done"))
+
+	var feed event.Feed
+	events := make(chan *PeerEvent, 2)
+	sub := feed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	peer := newPeer(c1, []Protocol{proto})
+	peer.events = &feed
+	go peer.run()
+	defer peer.Disconnect(DiscQuitting)
+
+	if err := SendItems(c2, baseProtocolLength+1, uint(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExpectMsg(c2, baseProtocolLength, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// Check the message events
+	for _, typ := range []PeerEventType{PeerEventTypeMsgRecv, PeerEventTypeMsgSend} {
+		select {
+		case ev := <-events:
+			if id := peer.ID(); ev.Type != typ || ev.Peer != id || ev.Protocol != "a" {
+				t.Errorf("event mismatch: have %s %x %s, want %s %x a", ev.Type, ev.Peer[:8], ev.Protocol, typ, id[:8])
+			}
+			if want := uint64(1); typ == PeerEventTypeMsgRecv && *ev.MsgCode != want {
+				t.Errorf("received message code mismatch: have %d, want %d", *ev.MsgCode, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s event timeout", typ)
+		}
+	}
+	// Check the traffic totals of the peer
+	info := peer.Info()
+	want := PeerTraffic{MessagesIn: 1, MessagesOut: 1, BytesIn: 2, BytesOut: 5}
+	if info.Session.Traffic != want {
+		t.Errorf("traffic mismatch: have %+v, want %+v", info.Session.Traffic, want)
+	}
+	if info.Session.Protocols["a"] != want {
+		t.Errorf("protocol traffic mismatch: have %+v, want %+v", info.Session.Protocols["a"], want)
+	}
+	if info.Session.Duration <= 0 {
+		t.Errorf("non-positive session duration %v", info.Session.Duration)
+	}
+}
+
 // This test is supposed to verify that Peer can reliably handle
 // multiple causes of disconnection occurring at the same time.
 func TestPeerDisconnectRace(t *testing.T) {
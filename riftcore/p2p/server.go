@@ -27,6 +27,7 @@ import (
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/mclock"
+	"github.com/cryptorift/riftcore/event"
 	"github.com/cryptorift/riftcore/log"
 	"github.com/cryptorift/riftcore/p2p/discover"
 	"github.com/cryptorift/riftcore/p2p/discv5"
@@ -130,10 +131,14 @@ type Config struct {
 
 	// If Dialer is set to a non-nil value, the given Dialer
 	// is used to dial outbound peer connections.
-	Dialer *net.Dialer `toml:"-"`
+	Dialer NodeDialer `toml:"-"`
 
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
+
+	// If EnableMsgEvents is set then the server will emit PeerEvents
+	// whenever a message is sent to or received from a peer
+	EnableMsgEvents bool `toml:",omitempty"`
 }
 
 // Server manages all peer connections.
@@ -166,6 +171,7 @@ type Server struct {
 	addpeer       chan *conn
 	delpeer       chan peerDrop
 	loopWG        sync.WaitGroup // loop, listenLoop
+	peerFeed      event.Feed
 }
 
 type peerOpFunc func(map[discover.NodeID]*Peer)
@@ -273,6 +279,14 @@ func (srv *Server) PeerCount() int {
 	return count
 }
 
+// SubscribeEvents subscribes the given channel to peer events: peers being added
+// or dropped and, if EnableMsgEvents is set, messages being sent or received over
+// their connections. Events are delivered synchronously from the peer goroutines,
+// subscribers must keep up with them not to stall the peers.
+func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
+	return srv.peerFeed.Subscribe(ch)
+}
+
 // AddPeer connects to the given node and maintains the connection until the
 // server is shut down. If the connection fails for any reason, the server will
 // attempt to reconnect the peer.
@@ -358,7 +372,7 @@ func (srv *Server) Start() (err error) {
 		srv.newTransport = newRLPX
 	}
 	if srv.Dialer == nil {
-		srv.Dialer = &net.Dialer{Timeout: defaultDialTimeout}
+		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
 	srv.quit = make(chan struct{})
 	srv.addpeer = make(chan *conn)
@@ -544,6 +558,11 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				// If message events are enabled, pass the peerFeed
+				// to the peer
+				if srv.EnableMsgEvents {
+					p.events = &srv.peerFeed
+				}
 				name := truncateName(c.name)
 				log.Debug("Adding p2p peer", "id", c.id, "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				peers[c.id] = p
@@ -671,6 +690,14 @@ func (srv *Server) listenLoop() {
 	}
 }
 
+// AcceptConn runs the handshakes on an inbound connection established outside
+// of the server's listener, e.g. an in-memory pipe of a network simulation, and
+// attempts to add it as a peer. It returns when the connection has been added as
+// a peer or the handshakes have failed.
+func (srv *Server) AcceptConn(fd net.Conn) {
+	srv.setupConn(fd, inboundConn, nil)
+}
+
 // setupConn runs the handshakes and attempts to add the connection
 // as a peer. It returns when the connection has been added as a peer
 // or the handshakes have failed.
@@ -755,7 +782,26 @@ func (srv *Server) runPeer(p *Peer) {
 	if srv.newPeerHook != nil {
 		srv.newPeerHook(p)
 	}
+	// Broadcast the peer addition
+	srv.peerFeed.Send(&PeerEvent{
+		Type:       PeerEventTypeAdd,
+		Peer:       p.ID(),
+		RemoteAddr: p.RemoteAddr().String(),
+	})
 	remoteRequested, err := p.run()
+
+	// Broadcast the peer drop along with the reason
+	drop := &PeerEvent{
+		Type:       PeerEventTypeDrop,
+		Peer:       p.ID(),
+		RemoteAddr: p.RemoteAddr().String(),
+		Requested:  remoteRequested,
+		Duration:   p.Duration().Seconds(),
+	}
+	if err != nil {
+		drop.Error = err.Error()
+	}
+	srv.peerFeed.Send(drop)
 	// Note: run waits for existing peers to be sent on srv.delpeer
 	// before returning, so this send should not select on srv.quit.
 	srv.delpeer <- peerDrop{p, err, remoteRequested}
@@ -18,10 +18,12 @@ package p2p
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
 	"math/rand"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -121,6 +123,54 @@ func TestServerListen(t *testing.T) {
 	}
 }
 
+// Tests that peer additions and drops are reported on the event feed.
+func TestServerPeerEvents(t *testing.T) {
+	remid := randomID()
+	srv := startTestServer(t, remid, nil)
+	defer srv.Stop()
+
+	events := make(chan *PeerEvent)
+	sub := srv.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type != PeerEventTypeAdd || ev.Peer != remid {
+			t.Errorf("add event mismatch: have %s %x, want %s %x", ev.Type, ev.Peer[:8], PeerEventTypeAdd, remid[:8])
+		}
+		if ev.RemoteAddr != conn.LocalAddr().String() {
+			t.Errorf("add event address mismatch: have %s, want %s", ev.RemoteAddr, conn.LocalAddr())
+		}
+		// Events are served over RPC, the peer must encode as a hex ID
+		enc, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatalf("failed to encode add event: %v", err)
+		}
+		if want := `"peer":"` + remid.String() + `"`; !strings.Contains(string(enc), want) {
+			t.Errorf("add event encoding mismatch: have %s, want %s", enc, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("add event timeout")
+	}
+	conn.Close()
+
+	select {
+	case ev := <-events:
+		if ev.Type != PeerEventTypeDrop || ev.Peer != remid {
+			t.Errorf("drop event mismatch: have %s %x, want %s %x", ev.Type, ev.Peer[:8], PeerEventTypeDrop, remid[:8])
+		}
+		if ev.Error == "" {
+			t.Error("drop event without reason")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drop event timeout")
+	}
+}
+
 func TestServerDial(t *testing.T) {
 	// run a one-shot TCP server to handle the connection.
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -0,0 +1,139 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/p2p"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// testService is a minimal node service running a protocol which simply keeps
+// its peers connected.
+type testService struct{}
+
+func (s *testService) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    "test",
+		Version: 1,
+		Length:  1,
+		Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+			}
+		},
+	}}
+}
+
+func (s *testService) APIs() []rpc.API         { return nil }
+func (s *testService) Start(*p2p.Server) error { return nil }
+func (s *testService) Stop() error             { return nil }
+
+var testServices = Services{
+	"test": func(ctx *ServiceContext) (node.Service, error) {
+		return &testService{}, nil
+	},
+}
+
+func init() {
+	// Register the test services so that exec'd child processes can boot them
+	RegisterServices(testServices)
+}
+
+func TestSimAdapter(t *testing.T) {
+	testAdapterConnect(t, NewSimAdapter(testServices))
+}
+
+func TestExecAdapter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-sim-exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testAdapterConnect(t, NewExecAdapter(dir))
+}
+
+// testAdapterConnect starts two nodes with the given adapter, connects them
+// and checks that the connection is reported as a peer event.
+func testAdapterConnect(t *testing.T, adapter NodeAdapter) {
+	var nodes []Node
+	for i := 0; i < 2; i++ {
+		config := RandomNodeConfig()
+		config.Services = []string{"test"}
+
+		node, err := adapter.NewNode(config)
+		if err != nil {
+			t.Fatalf("failed to create node %d: %v", i, err)
+		}
+		if err := node.Start(); err != nil {
+			t.Fatalf("failed to start node %d: %v", i, err)
+		}
+		defer node.Stop()
+		nodes = append(nodes, node)
+	}
+	client, err := nodes[0].Client()
+	if err != nil {
+		t.Fatalf("failed to get client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan *p2p.PeerEvent)
+	sub, err := client.Subscribe(ctx, "admin", events, "peerEvents")
+	if err != nil {
+		t.Fatalf("failed to subscribe to peer events: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := client.Call(nil, "admin_addPeer", string(nodes[1].Addr())); err != nil {
+		t.Fatalf("failed to add peer: %v", err)
+	}
+	want := nodes[1].NodeInfo().ID
+	for {
+		select {
+		case event := <-events:
+			if event.Type == p2p.PeerEventTypeAdd {
+				if event.Peer.String() != want {
+					t.Fatalf("peer mismatch: have %s, want %s", event.Peer, want)
+				}
+				var peers []*p2p.PeerInfo
+				if err := client.Call(&peers, "admin_peers"); err != nil {
+					t.Fatalf("failed to retrieve peers: %v", err)
+				}
+				if len(peers) != 1 || peers[0].ID != want {
+					t.Fatalf("peers mismatch: have %v, want %s", peers, want)
+				}
+				return
+			}
+		case err := <-sub.Err():
+			t.Fatalf("peer event subscription failed: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for peer to connect")
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/p2p"
+	"github.com/cryptorift/riftcore/p2p/discover"
+	"github.com/cryptorift/riftcore/rpc"
+	"github.com/docker/docker/pkg/reexec"
+)
+
+// envNodeConfig is the environment variable used to pass the node
+// configuration from the ExecNode to the child process.
+const envNodeConfig = "_P2P_NODE_CONFIG"
+
+// execStartTimeout is the amount of time the child process is given to boot
+// the node and report its RPC endpoint.
+const execStartTimeout = 10 * time.Second
+
+// execStopTimeout is the amount of time the child process is given to shut
+// down gracefully before it is killed.
+const execStopTimeout = 5 * time.Second
+
+func init() {
+	// Register a reexec function to start a devp2p node when the current
+	// binary is executed as "p2p-node"
+	reexec.Register("p2p-node", execP2PNode)
+}
+
+// ExecAdapter is a NodeAdapter which runs simulation nodes by executing the
+// current binary as a child process.
+//
+// An init hook is used so that the child process executes the node services
+// (rather than whatever the main() function would normally do), see the
+// execP2PNode function for more information.
+type ExecAdapter struct {
+	// BaseDir is the directory under which the data directories for each
+	// simulation node are created.
+	BaseDir string
+}
+
+// NewExecAdapter returns an ExecAdapter which stores node data in
+// subdirectories of the given base directory
+func NewExecAdapter(baseDir string) *ExecAdapter {
+	return &ExecAdapter{
+		BaseDir: baseDir,
+	}
+}
+
+// Name returns the name of the adapter for logging purposes
+func (e *ExecAdapter) Name() string {
+	return "exec-adapter"
+}
+
+// NewNode returns a new ExecNode using the given config
+func (e *ExecAdapter) NewNode(config *NodeConfig) (Node, error) {
+	if len(config.Services) == 0 {
+		return nil, errors.New("node must have at least one service")
+	}
+	for _, service := range config.Services {
+		if _, exists := serviceFuncs[service]; !exists {
+			return nil, fmt.Errorf("unknown node service %q", service)
+		}
+	}
+
+	// create the node directory using the first 12 characters of the ID
+	// to keep the data paths short
+	dir := filepath.Join(e.BaseDir, config.ID.String()[:12])
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating node directory: %s", err)
+	}
+
+	return &ExecNode{
+		ID:     config.ID,
+		Dir:    dir,
+		Config: config,
+	}, nil
+}
+
+// ExecNode starts a simulation node by exec'ing the current binary and
+// running the configured services
+type ExecNode struct {
+	ID     discover.NodeID
+	Dir    string
+	Config *NodeConfig
+	Cmd    *exec.Cmd
+	Info   *p2p.NodeInfo
+
+	lock   sync.RWMutex
+	client *rpc.Client
+}
+
+// Addr returns the node's enode URL
+func (n *ExecNode) Addr() []byte {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.Info == nil {
+		return nil
+	}
+	return []byte(n.Info.Enode)
+}
+
+// Client returns an rpc.Client which can be used to communicate with the
+// underlying services (it is set once the node has started)
+func (n *ExecNode) Client() (*rpc.Client, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.client == nil {
+		return nil, errors.New("node not started")
+	}
+	return n.client, nil
+}
+
+// Start exec's the node passing the ID and service as command line arguments
+// and the node config encoded as JSON in the _P2P_NODE_CONFIG environment
+// variable
+func (n *ExecNode) Start() (err error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.Cmd != nil {
+		return errors.New("already started")
+	}
+	defer func() {
+		if err != nil {
+			log.Error("node failed to start", "err", err)
+			n.stop()
+		}
+	}()
+
+	// encode the config
+	conf, err := json.Marshal(&execNodeConfig{Node: n.Config, DataDir: n.Dir})
+	if err != nil {
+		return fmt.Errorf("error generating node config: %s", err)
+	}
+
+	// start the node
+	cmd := reexec.Command("p2p-node", strings.Join(n.Config.Services, ","), n.ID.String())
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), envNodeConfig+"="+string(conf))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting node: %s", err)
+	}
+	n.Cmd = cmd
+
+	// wait for the child to report its RPC endpoint
+	statusc := make(chan *execNodeStatus, 1)
+	go func() {
+		status := new(execNodeStatus)
+		line, err := bufio.NewReader(stdout).ReadBytes('\n')
+		if err != nil {
+			status.Err = err.Error()
+		} else if err := json.Unmarshal(line, status); err != nil {
+			status.Err = err.Error()
+		}
+		statusc <- status
+	}()
+	var status *execNodeStatus
+	select {
+	case status = <-statusc:
+		if status.Err != "" {
+			return errors.New(status.Err)
+		}
+	case <-time.After(execStartTimeout):
+		return errors.New("timed out waiting for node to start")
+	}
+
+	// connect to the node's websocket RPC endpoint
+	ctx, cancel := context.WithTimeout(context.Background(), execStartTimeout)
+	defer cancel()
+	client, err := rpc.DialWebsocket(ctx, "ws://"+status.WSEndpoint, "")
+	if err != nil {
+		return fmt.Errorf("error dialing node RPC: %s", err)
+	}
+	n.client = client
+	n.Info = status.NodeInfo
+
+	return nil
+}
+
+// Stop stops the node by first sending an interrupt signal and then waiting
+// for the process to exit, killing it if it doesn't do so in time
+func (n *ExecNode) Stop() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.Cmd == nil {
+		return nil
+	}
+	return n.stop()
+}
+
+// stop terminates the child process, the lock must be held by the caller.
+func (n *ExecNode) stop() error {
+	if n.Cmd == nil {
+		return nil
+	}
+	defer func() {
+		n.Cmd = nil
+	}()
+
+	if n.client != nil {
+		n.client.Close()
+		n.client = nil
+	}
+	if err := n.Cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return n.Cmd.Process.Kill()
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- n.Cmd.Wait()
+	}()
+	select {
+	case err := <-waitErr:
+		return err
+	case <-time.After(execStopTimeout):
+		return n.Cmd.Process.Kill()
+	}
+}
+
+// NodeInfo returns information about the node
+func (n *ExecNode) NodeInfo() *p2p.NodeInfo {
+	n.lock.RLock()
+	client, info := n.client, n.Info
+	n.lock.RUnlock()
+
+	if client != nil {
+		fresh := new(p2p.NodeInfo)
+		if err := client.Call(fresh, "admin_nodeInfo"); err == nil {
+			return fresh
+		}
+	}
+	if info == nil {
+		info = &p2p.NodeInfo{ID: n.ID.String()}
+	}
+	return info
+}
+
+// execNodeConfig is used to serialize the node configuration so it can be
+// passed to the child process as a JSON encoded environment variable
+type execNodeConfig struct {
+	Node    *NodeConfig `json:"node"`
+	DataDir string      `json:"datadir"`
+}
+
+// execNodeStatus is reported by the child process on its standard output once
+// the node is running (or failed to start)
+type execNodeStatus struct {
+	WSEndpoint string        `json:"wsEndpoint,omitempty"`
+	NodeInfo   *p2p.NodeInfo `json:"nodeInfo,omitempty"`
+	Err        string        `json:"err,omitempty"`
+}
+
+// execP2PNode starts a devp2p node when the current binary is executed with
+// argv[0] being "p2p-node", reading the service / ID from argv[1] / argv[2]
+// and the node config from the _P2P_NODE_CONFIG environment variable
+func execP2PNode() {
+	stack, err := startExecNodeStack()
+	if err != nil {
+		json.NewEncoder(os.Stdout).Encode(&execNodeStatus{Err: err.Error()})
+		os.Exit(1)
+	}
+	json.NewEncoder(os.Stdout).Encode(&execNodeStatus{
+		WSEndpoint: stack.WSEndpoint(),
+		NodeInfo:   stack.Server().NodeInfo(),
+	})
+
+	// stop the stack if we get a SIGTERM signal
+	go func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGTERM, os.Interrupt)
+		defer signal.Stop(sigc)
+		<-sigc
+		log.Info("Received SIGTERM, shutting down...")
+		stack.Stop()
+	}()
+	stack.Wait()
+	os.Exit(0)
+}
+
+// startExecNodeStack assembles and starts the node described by the
+// _P2P_NODE_CONFIG environment variable.
+func startExecNodeStack() (*node.Node, error) {
+	// decode the config
+	confEnv := os.Getenv(envNodeConfig)
+	if confEnv == "" {
+		return nil, errors.New("missing " + envNodeConfig)
+	}
+	var conf execNodeConfig
+	if err := json.Unmarshal([]byte(confEnv), &conf); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", envNodeConfig, err)
+	}
+	// expose the admin API (and the services' own namespaces) over a
+	// websocket listening on a random local port
+	modules := append([]string{"admin", "net", "web3"}, conf.Node.Services...)
+	stack, err := node.New(&node.Config{
+		DataDir: conf.DataDir,
+		P2P: p2p.Config{
+			PrivateKey:      conf.Node.PrivateKey,
+			MaxPeers:        math.MaxInt32,
+			NoDiscovery:     true,
+			EnableMsgEvents: true,
+			ListenAddr:      "127.0.0.1:0",
+		},
+		WSHost:    "127.0.0.1",
+		WSOrigins: []string{"*"},
+		WSModules: modules,
+		NoUSB:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating node stack: %v", err)
+	}
+	// register the requested services
+	for _, name := range conf.Node.Services {
+		serviceFunc, exists := serviceFuncs[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown node service %q", name)
+		}
+		constructor := func(nodeCtx *node.ServiceContext) (node.Service, error) {
+			return serviceFunc(&ServiceContext{
+				Config:      conf.Node,
+				NodeContext: nodeCtx,
+			})
+		}
+		if err := stack.Register(constructor); err != nil {
+			return nil, fmt.Errorf("error registering service %q: %v", name, err)
+		}
+	}
+	if err := stack.Start(); err != nil {
+		return nil, fmt.Errorf("error starting node stack: %v", err)
+	}
+	return stack, nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/p2p"
+	"github.com/cryptorift/riftcore/p2p/discover"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// SimAdapter is a NodeAdapter which creates in-memory simulation nodes and
+// connects them using in-memory net.Pipe connections
+type SimAdapter struct {
+	mtx      sync.RWMutex
+	nodes    map[discover.NodeID]*SimNode
+	services map[string]ServiceFunc
+}
+
+// NewSimAdapter creates a SimAdapter which is capable of running in-memory
+// simulation nodes running any of the given services (the services to run on a
+// particular node are passed to the NewNode function in the NodeConfig)
+func NewSimAdapter(services map[string]ServiceFunc) *SimAdapter {
+	return &SimAdapter{
+		nodes:    make(map[discover.NodeID]*SimNode),
+		services: services,
+	}
+}
+
+// Name returns the name of the adapter for logging purposes
+func (s *SimAdapter) Name() string {
+	return "sim-adapter"
+}
+
+// NewNode returns a new SimNode using the given config
+func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// check a node with the ID doesn't already exist
+	id := config.ID
+	if _, exists := s.nodes[id]; exists {
+		return nil, fmt.Errorf("node already exists: %s", id)
+	}
+
+	// check the services are valid
+	if len(config.Services) == 0 {
+		return nil, errors.New("node must have at least one service")
+	}
+	for _, service := range config.Services {
+		if _, exists := s.services[service]; !exists {
+			return nil, fmt.Errorf("unknown node service %q", service)
+		}
+	}
+
+	n, err := node.New(&node.Config{
+		P2P: p2p.Config{
+			PrivateKey:      config.PrivateKey,
+			MaxPeers:        math.MaxInt32,
+			NoDiscovery:     true,
+			EnableMsgEvents: true,
+			Dialer:          s,
+		},
+		NoUSB: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	simNode := &SimNode{
+		ID:      id,
+		config:  config,
+		node:    n,
+		adapter: s,
+		running: make(map[string]node.Service),
+	}
+	for _, name := range config.Services {
+		if err := n.Register(simNode.serviceFunc(name)); err != nil {
+			return nil, err
+		}
+	}
+	s.nodes[id] = simNode
+	return simNode, nil
+}
+
+// Dial implements the p2p.NodeDialer interface by connecting to the node using
+// an in-memory net.Pipe connection
+func (s *SimAdapter) Dial(dest *discover.Node) (conn net.Conn, err error) {
+	node, ok := s.GetNode(dest.ID)
+	if !ok {
+		return nil, fmt.Errorf("unknown node: %s", dest.ID)
+	}
+	srv := node.Server()
+	if srv == nil {
+		return nil, fmt.Errorf("node not running: %s", dest.ID)
+	}
+	pipe1, pipe2 := net.Pipe()
+	go srv.AcceptConn(pipe1)
+	return pipe2, nil
+}
+
+// GetNode returns the node with the given ID if it exists
+func (s *SimAdapter) GetNode(id discover.NodeID) (*SimNode, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	node, ok := s.nodes[id]
+	return node, ok
+}
+
+// SimNode is an in-memory simulation node which connects to other nodes using
+// an in-memory net.Pipe connection (see SimAdapter.Dial), running devp2p
+// protocols directly over that pipe
+type SimNode struct {
+	lock    sync.RWMutex
+	ID      discover.NodeID
+	config  *NodeConfig
+	adapter *SimAdapter
+	node    *node.Node
+	running map[string]node.Service
+	client  *rpc.Client
+}
+
+// Addr returns the node's discovery address
+func (sn *SimNode) Addr() []byte {
+	return []byte(sn.Node().String())
+}
+
+// Node returns a discover.Node representing the SimNode
+func (sn *SimNode) Node() *discover.Node {
+	return discover.NewNode(sn.ID, net.IP{127, 0, 0, 1}, 30303, 30303)
+}
+
+// Client returns an rpc.Client which can be used to communicate with the
+// underlying services (it is set once the node has started)
+func (sn *SimNode) Client() (*rpc.Client, error) {
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	if sn.client == nil {
+		return nil, errors.New("node not started")
+	}
+	return sn.client, nil
+}
+
+// Start starts the underlying devp2p node along with its services
+func (sn *SimNode) Start() error {
+	if err := sn.node.Start(); err != nil {
+		return err
+	}
+
+	// create an in-process RPC client
+	client, err := sn.node.Attach()
+	if err != nil {
+		sn.node.Stop()
+		return err
+	}
+
+	sn.lock.Lock()
+	sn.client = client
+	sn.lock.Unlock()
+
+	return nil
+}
+
+// Stop closes the RPC client and stops the underlying devp2p node
+func (sn *SimNode) Stop() error {
+	sn.lock.Lock()
+	if sn.client != nil {
+		sn.client.Close()
+		sn.client = nil
+	}
+	sn.lock.Unlock()
+	return sn.node.Stop()
+}
+
+// Service returns a running service by name
+func (sn *SimNode) Service(name string) node.Service {
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	return sn.running[name]
+}
+
+// Services returns a copy of the underlying services
+func (sn *SimNode) Services() []node.Service {
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	services := make([]node.Service, 0, len(sn.running))
+	for _, service := range sn.running {
+		services = append(services, service)
+	}
+	return services
+}
+
+// Server returns the underlying p2p.Server
+func (sn *SimNode) Server() *p2p.Server {
+	return sn.node.Server()
+}
+
+// NodeInfo returns information about the node
+func (sn *SimNode) NodeInfo() *p2p.NodeInfo {
+	server := sn.Server()
+	if server == nil {
+		return &p2p.NodeInfo{
+			ID:    sn.ID.String(),
+			Enode: sn.Node().String(),
+		}
+	}
+	return server.NodeInfo()
+}
+
+// serviceFunc returns the constructor of the named service, recording the
+// instance it creates so it can be retrieved using Service.
+func (sn *SimNode) serviceFunc(name string) node.ServiceConstructor {
+	return func(nodeCtx *node.ServiceContext) (node.Service, error) {
+		ctx := &ServiceContext{
+			Config:      sn.config,
+			NodeContext: nodeCtx,
+		}
+		service, err := sn.adapter.services[name](ctx)
+		if err != nil {
+			return nil, err
+		}
+		sn.lock.Lock()
+		sn.running[name] = service
+		sn.lock.Unlock()
+		return service, nil
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/p2p/simulations/adapters"
+)
+
+// newTestServer creates an HTTP API server over a network of in-memory nodes
+// running the test service.
+func newTestServer(t *testing.T) (*Network, *httptest.Server, *Client) {
+	adapter := adapters.NewSimAdapter(adapters.Services{"test": newTestService})
+	network := NewNetwork(adapter, &NetworkConfig{DefaultService: "test"})
+	server := httptest.NewServer(NewServer(network))
+	return network, server, NewClient(server.URL)
+}
+
+func TestHTTPNetwork(t *testing.T) {
+	network, server, client := newTestServer(t)
+	defer server.Close()
+	defer network.Shutdown()
+
+	// Create and start a few nodes, addressing them both by ID and name
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateNode(nil); err != nil {
+			t.Fatalf("failed to create node %d: %v", i, err)
+		}
+	}
+	if _, err := client.CreateNode(&adapters.NodeConfig{Name: "node01"}); err == nil {
+		t.Fatalf("created node with duplicate name")
+	}
+	nodes, err := client.GetNodes()
+	if err != nil {
+		t.Fatalf("failed to get nodes: %v", err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("node count mismatch: have %d, want 3", len(nodes))
+	}
+	if err := client.StartNode(nodes[0].ID); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	if err := client.StartNetwork(); err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	if err := client.StartNode("node02"); err == nil {
+		t.Fatalf("started running node twice")
+	}
+	if _, err := client.GetNode("node04"); err == nil {
+		t.Fatalf("retrieved unknown node")
+	}
+	// Connect the nodes while streaming network events
+	events := make(chan *Event, 100)
+	sub, err := client.SubscribeNetwork(events, SubscribeOpts{Current: true})
+	if err != nil {
+		t.Fatalf("failed to subscribe to network events: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := client.ConnectNode("node01", "node02"); err != nil {
+		t.Fatalf("failed to connect nodes: %v", err)
+	}
+	if err := client.ConnectNode(nodes[1].ID, nodes[2].ID); err != nil {
+		t.Fatalf("failed to connect nodes: %v", err)
+	}
+	var (
+		current = 0
+		conns   = make(map[string]bool)
+		timeout = time.After(10 * time.Second)
+	)
+	for len(conns) < 2 {
+		select {
+		case event := <-events:
+			switch {
+			case event.Type == EventTypeNode && !event.Control:
+				if !event.Node.Up {
+					t.Errorf("node %s not reported up", event.Node.ID())
+				}
+				current++
+			case event.Type == EventTypeConn && !event.Control && event.Conn.Up:
+				conns[ConnLabel(event.Conn.One, event.Conn.Other)] = true
+			}
+		case err := <-sub.Err():
+			t.Fatalf("network event subscription failed: %v", err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for connections, have %d", len(conns))
+		}
+	}
+	if current != 3 {
+		t.Errorf("current node event count mismatch: have %d, want 3", current)
+	}
+	net, err := client.GetNetwork()
+	if err != nil {
+		t.Fatalf("failed to get network: %v", err)
+	}
+	if len(net.Nodes) != 3 || len(net.Conns) != 2 {
+		t.Fatalf("network mismatch: have %d nodes and %d conns, want 3 and 2", len(net.Nodes), len(net.Conns))
+	}
+	// Snapshot the network and restore it into a fresh one
+	snap, err := client.CreateSnapshot()
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	network2, server2, client2 := newTestServer(t)
+	defer server2.Close()
+	defer network2.Shutdown()
+
+	events2 := make(chan *Event, 100)
+	sub2 := network2.Events().Subscribe(events2)
+	defer sub2.Unsubscribe()
+
+	if err := client2.LoadSnapshot(snap); err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	restored := make(map[string]bool)
+	for _, conn := range snap.Conns {
+		restored[ConnLabel(conn.One, conn.Other)] = true
+	}
+	waitConns(t, events2, restored, true)
+
+	for _, node := range snap.Nodes {
+		info, err := client2.GetNode(node.Node.Config.Name)
+		if err != nil {
+			t.Fatalf("failed to get restored node %s: %v", node.Node.Config.Name, err)
+		}
+		if info.ID != node.Node.Config.ID.String() {
+			t.Errorf("restored node ID mismatch: have %s, want %s", info.ID, node.Node.Config.ID)
+		}
+	}
+	// Disconnect a restored connection and stop the network
+	if err := client2.DisconnectNode("node01", "node02"); err != nil {
+		t.Fatalf("failed to disconnect nodes: %v", err)
+	}
+	if err := client2.DisconnectNode("node01", "node03"); err == nil {
+		t.Fatalf("disconnected unconnected nodes")
+	}
+	if err := client2.StopNetwork(); err != nil {
+		t.Fatalf("failed to stop network: %v", err)
+	}
+}
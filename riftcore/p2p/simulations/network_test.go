@@ -0,0 +1,200 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/node"
+	"github.com/cryptorift/riftcore/p2p"
+	"github.com/cryptorift/riftcore/p2p/discover"
+	"github.com/cryptorift/riftcore/p2p/simulations/adapters"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+const (
+	pingMsgCode = iota
+	pongMsgCode
+)
+
+// testService runs a ping-pong protocol: each side pings its peer once it
+// connects and answers every ping it receives with a pong.
+type testService struct{}
+
+func newTestService(ctx *adapters.ServiceContext) (node.Service, error) {
+	return &testService{}, nil
+}
+
+func (s *testService) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    "test",
+		Version: 1,
+		Length:  2,
+		Run:     s.run,
+	}}
+}
+
+func (s *testService) run(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p2p.Send(rw, pingMsgCode, struct{}{})
+	}()
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		msg.Discard()
+		if msg.Code == pingMsgCode {
+			if err := p2p.Send(rw, pongMsgCode, struct{}{}); err != nil {
+				return err
+			}
+		}
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		default:
+		}
+	}
+}
+
+func (s *testService) APIs() []rpc.API         { return nil }
+func (s *testService) Start(*p2p.Server) error { return nil }
+func (s *testService) Stop() error             { return nil }
+
+// newTestNetwork creates a network of in-memory nodes running the test service.
+func newTestNetwork(t *testing.T, size int) (*Network, []discover.NodeID) {
+	adapter := adapters.NewSimAdapter(adapters.Services{"test": newTestService})
+	network := NewNetwork(adapter, &NetworkConfig{DefaultService: "test"})
+
+	ids := make([]discover.NodeID, size)
+	for i := range ids {
+		node, err := network.NewNode()
+		if err != nil {
+			t.Fatalf("failed to create node %d: %v", i, err)
+		}
+		ids[i] = node.ID()
+	}
+	return network, ids
+}
+
+// waitConns reads events until the given connections are reported with the
+// expected state.
+func waitConns(t *testing.T, events chan *Event, conns map[string]bool, up bool) {
+	waitEvents(t, events, func(event *Event) bool {
+		if event.Type == EventTypeConn && !event.Control && event.Conn.Up == up {
+			delete(conns, ConnLabel(event.Conn.One, event.Conn.Other))
+		}
+		return len(conns) == 0
+	})
+}
+
+// waitEvents feeds events to the given function until it reports being done.
+func waitEvents(t *testing.T, events chan *Event, done func(*Event) bool) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if done(event) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for network events")
+		}
+	}
+}
+
+func TestNetworkSimulation(t *testing.T) {
+	network, ids := newTestNetwork(t, 50)
+	defer network.Shutdown()
+
+	// The network blocks on delivering events, so buffer enough of them to
+	// cover establishing all connections before they are consumed
+	events := make(chan *Event, 20*len(ids))
+	sub := network.Events().Subscribe(events)
+	defer sub.Unsubscribe()
+
+	if err := network.StartAll(); err != nil {
+		t.Fatalf("failed to start nodes: %v", err)
+	}
+	// Connect the nodes into a ring and wait for all connections to come up.
+	// Both sides of a connection ping each other, which should be reported
+	// as sent and received messages.
+	ring := make(map[string]bool)
+	for i, id := range ids {
+		peer := ids[(i+1)%len(ids)]
+		if err := network.Connect(id, peer); err != nil {
+			t.Fatalf("failed to connect %v to %v: %v", id, peer, err)
+		}
+		ring[ConnLabel(id, peer)] = true
+	}
+	pings := make(map[string]bool)
+	waitEvents(t, events, func(event *Event) bool {
+		switch {
+		case event.Type == EventTypeConn && !event.Control && event.Conn.Up:
+			delete(ring, ConnLabel(event.Conn.One, event.Conn.Other))
+		case event.Type == EventTypeMsg && event.Msg.Received && event.Msg.Code == pingMsgCode:
+			if event.Msg.Protocol != "test" {
+				t.Fatalf("message protocol mismatch: have %s, want test", event.Msg.Protocol)
+			}
+			pings[event.Msg.One.String()+event.Msg.Other.String()] = true
+		}
+		return len(ring) == 0 && len(pings) == 2*len(ids)
+	})
+	for i, id := range ids {
+		peer := ids[(i+1)%len(ids)]
+		if conn := network.GetConn(peer, id); conn == nil || !conn.Up {
+			t.Errorf("connection %v-%v not up: %v", id, peer, conn)
+		}
+		if err := network.Connect(id, peer); err == nil {
+			t.Errorf("connection %v-%v established twice", id, peer)
+		}
+	}
+	// Drop a connection and check the snapshot reflects the topology
+	if err := network.Disconnect(ids[0], ids[1]); err != nil {
+		t.Fatalf("failed to disconnect: %v", err)
+	}
+	waitConns(t, events, map[string]bool{ConnLabel(ids[0], ids[1]): true}, false)
+
+	snap, err := network.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if len(snap.Nodes) != len(ids) {
+		t.Errorf("snapshot node count mismatch: have %d, want %d", len(snap.Nodes), len(ids))
+	}
+	up := 0
+	for _, conn := range snap.Conns {
+		if conn.Up {
+			up++
+		}
+	}
+	if up != len(ids)-1 {
+		t.Errorf("snapshot live connection count mismatch: have %d, want %d", up, len(ids)-1)
+	}
+	// Stopping a node should drop its remaining connection
+	if err := network.Stop(ids[5]); err != nil {
+		t.Fatalf("failed to stop node: %v", err)
+	}
+	waitConns(t, events, map[string]bool{ConnLabel(ids[5], ids[6]): true}, false)
+	if node := network.GetNode(ids[5]); node.Up {
+		t.Errorf("stopped node still up")
+	}
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/consensus/misc"
 	"github.com/cryptorift/riftcore/core"
 	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
@@ -43,7 +44,16 @@ import (
 	"github.com/cryptorift/riftcore/trie"
 )
 
-const defaultTraceTimeout = 5 * time.Second
+const (
+	// defaultTraceTimeout is the amount of time a single transaction can execute
+	// by default before being forcefully aborted.
+	defaultTraceTimeout = 5 * time.Second
+
+	// defaultTraceReexec is the number of blocks the tracer is willing to go back
+	// and re-execute to produce missing historical state necessary to run a
+	// specific trace.
+	defaultTraceReexec = uint64(128)
+)
 
 // PublicCryptoriftAPI provides an API to access CryptoRift full node-related
 // information.
@@ -349,49 +359,57 @@ func NewPrivateDebugAPI(config *params.ChainConfig, rift *CryptoRift) *PrivateDe
 }
 
 // BlockTraceResult is the returned value when replaying a block to check for
-// consensus results and full VM trace logs for all included transactions.
+// consensus results and full VM trace logs for all included transactions. If a
+// named or Javascript tracer was requested, the individual transaction results
+// are returned in Traces instead of the raw struct logs.
 type BlockTraceResult struct {
 	Validated  bool                  `json:"validated"`
 	StructLogs []riftapi.StructLogRes `json:"structLogs"`
+	Traces     []interface{}         `json:"traces,omitempty"`
 	Error      string                `json:"error"`
 }
 
-// TraceArgs holds extra parameters to trace functions
+// TraceArgs holds extra parameters to trace functions. Tracer is either the name
+// of a built-in tracer (callTracer, prestateTracer) or the source of a Javascript
+// tracer object. Reexec limits how many blocks may be re-executed to regenerate
+// a pruned historical state.
 type TraceArgs struct {
 	*vm.LogConfig
 	Tracer  *string
 	Timeout *string
+	Reexec  *uint64
+}
+
+// resultTracer is a tracer aggregating its own results while executing, as
+// opposed to the struct logger returning all the raw execution steps.
+type resultTracer interface {
+	vm.Tracer
+	GetResult() (interface{}, error)
 }
 
 // TraceBlock processes the given block'api RLP but does not import the block in to
 // the chain.
-func (api *PrivateDebugAPI) TraceBlock(blockRlp []byte, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlock(ctx context.Context, blockRlp []byte, config *TraceArgs) BlockTraceResult {
 	var block types.Block
 	err := rlp.Decode(bytes.NewReader(blockRlp), &block)
 	if err != nil {
 		return BlockTraceResult{Error: fmt.Sprintf("could not decode block: %v", err)}
 	}
-
-	validated, logs, err := api.traceBlock(&block, config)
-	return BlockTraceResult{
-		Validated:  validated,
-		StructLogs: riftapi.FormatLogs(logs),
-		Error:      formatError(err),
-	}
+	return api.traceBlock(ctx, &block, config)
 }
 
 // TraceBlockFromFile loads the block'api RLP from the given file name and attempts to
 // process it but does not import the block in to the chain.
-func (api *PrivateDebugAPI) TraceBlockFromFile(file string, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockFromFile(ctx context.Context, file string, config *TraceArgs) BlockTraceResult {
 	blockRlp, err := ioutil.ReadFile(file)
 	if err != nil {
 		return BlockTraceResult{Error: fmt.Sprintf("could not read file: %v", err)}
 	}
-	return api.TraceBlock(blockRlp, config)
+	return api.TraceBlock(ctx, blockRlp, config)
 }
 
 // TraceBlockByNumber processes the block by canonical block number.
-func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, config *TraceArgs) BlockTraceResult {
 	// Fetch the block that we aim to reprocess
 	var block *types.Block
 	switch blockNr {
@@ -407,62 +425,147 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *
 	if block == nil {
 		return BlockTraceResult{Error: fmt.Sprintf("block #%d not found", blockNr)}
 	}
-
-	validated, logs, err := api.traceBlock(block, config)
-	return BlockTraceResult{
-		Validated:  validated,
-		StructLogs: riftapi.FormatLogs(logs),
-		Error:      formatError(err),
-	}
+	return api.traceBlock(ctx, block, config)
 }
 
 // TraceBlockByHash processes the block by hash.
-func (api *PrivateDebugAPI) TraceBlockByHash(hash common.Hash, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockByHash(ctx context.Context, hash common.Hash, config *TraceArgs) BlockTraceResult {
 	// Fetch the block that we aim to reprocess
 	block := api.rift.BlockChain().GetBlockByHash(hash)
 	if block == nil {
 		return BlockTraceResult{Error: fmt.Sprintf("block #%x not found", hash)}
 	}
-
-	validated, logs, err := api.traceBlock(block, config)
-	return BlockTraceResult{
-		Validated:  validated,
-		StructLogs: riftapi.FormatLogs(logs),
-		Error:      formatError(err),
-	}
+	return api.traceBlock(ctx, block, config)
 }
 
-// traceBlock processes the given block but does not save the state.
-func (api *PrivateDebugAPI) traceBlock(block *types.Block, logConfig *vm.LogConfig) (bool, []vm.StructLog, error) {
+// traceBlock processes the given block on top of a snapshot of its parent state
+// but does not save the state. Without a tracer requested, the struct logs of
+// all the transactions are returned concatenated, otherwise each transaction is
+// traced individually with a fresh tracer instance.
+func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block, config *TraceArgs) BlockTraceResult {
 	// Validate and reprocess the block
 	var (
 		blockchain = api.rift.BlockChain()
 		validator  = blockchain.Validator()
 		processor  = blockchain.Processor()
 	)
-
-	structLogger := vm.NewStructLogger(logConfig)
-
-	config := vm.Config{
-		Debug:  true,
-		Tracer: structLogger,
+	parent := blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return BlockTraceResult{Error: fmt.Sprintf("block parent %x not found", block.ParentHash())}
 	}
 	if err := api.rift.engine.VerifyHeader(blockchain, block.Header(), true); err != nil {
-		return false, structLogger.StructLogs(), err
+		return BlockTraceResult{Error: formatError(err)}
 	}
-	statedb, err := blockchain.StateAt(blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1).Root())
+	statedb, _, err := api.computeStateDB(parent, traceReexec(config))
 	if err != nil {
-		return false, structLogger.StructLogs(), err
+		return BlockTraceResult{Error: formatError(err)}
 	}
+	var (
+		result   BlockTraceResult
+		receipts types.Receipts
+		usedGas  *big.Int
+	)
+	if config == nil || config.Tracer == nil {
+		var logConfig *vm.LogConfig
+		if config != nil {
+			logConfig = config.LogConfig
+		}
+		structLogger := vm.NewStructLogger(logConfig)
 
-	receipts, _, usedGas, err := processor.Process(block, statedb, config)
+		receipts, _, usedGas, err = processor.Process(block, statedb, vm.Config{Debug: true, Tracer: structLogger})
+		result.StructLogs = riftapi.FormatLogs(structLogger.StructLogs())
+	} else {
+		receipts, usedGas, result.Traces, err = api.traceTransactions(ctx, block, statedb, config)
+	}
 	if err != nil {
-		return false, structLogger.StructLogs(), err
+		result.Error = formatError(err)
+		return result
+	}
+	if err := validator.ValidateState(block, parent, statedb, receipts, usedGas); err != nil {
+		result.Error = formatError(err)
+		return result
+	}
+	result.Validated = true
+	return result
+}
+
+// traceTransactions executes all the transactions of a block on top of the given
+// state, tracing each of them with a new instance of the requested tracer. The
+// consensus engine's block finalization is applied to the state afterwards.
+func (api *PrivateDebugAPI) traceTransactions(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceArgs) (types.Receipts, *big.Int, []interface{}, error) {
+	var (
+		blockchain = api.rift.BlockChain()
+		header     = block.Header()
+		gp         = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas    = new(big.Int)
+		receipts   types.Receipts
+		traces     []interface{}
+	)
+	// Mutate the the state according to any hard-fork specs
+	if api.config.DAOForkSupport && api.config.DAOForkBlock != nil && api.config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	for i, tx := range block.Transactions() {
+		// Abort if the trace request was cancelled in between transactions
+		select {
+		case <-ctx.Done():
+			return nil, nil, traces, ctx.Err()
+		default:
+		}
+		tracer, cancel, err := api.newTracer(ctx, config)
+		if err != nil {
+			return nil, nil, traces, err
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, err := core.ApplyTransaction(api.config, blockchain, nil, gp, statedb, header, tx, usedGas, vm.Config{Debug: true, Tracer: tracer})
+		cancel()
+		if err != nil {
+			return nil, nil, traces, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
+		}
+		trace, err := tracer.(resultTracer).GetResult()
+		if err != nil {
+			return nil, nil, traces, fmt.Errorf("tx %x trace failed: %v", tx.Hash(), err)
+		}
+		receipts = append(receipts, receipt)
+		traces = append(traces, trace)
+	}
+	api.rift.engine.Finalize(blockchain, header, statedb, block.Transactions(), block.Uncles(), receipts)
+
+	return receipts, usedGas, traces, nil
+}
+
+// newTracer creates the tracer requested by the trace arguments, defaulting to
+// the struct logger. Javascript tracers are interrupted if the requested timeout
+// expires or ctx is cancelled; the returned function releases the watcher and
+// must always be called once tracing finished.
+func (api *PrivateDebugAPI) newTracer(ctx context.Context, config *TraceArgs) (vm.Tracer, context.CancelFunc, error) {
+	switch {
+	case config == nil:
+		return vm.NewStructLogger(nil), func() {}, nil
+	case config.Tracer == nil:
+		return vm.NewStructLogger(config.LogConfig), func() {}, nil
 	}
-	if err := validator.ValidateState(block, blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1), statedb, receipts, usedGas); err != nil {
-		return false, structLogger.StructLogs(), err
+	if tracer, ok := riftapi.NewNativeTracer(*config.Tracer); ok {
+		return tracer, func() {}, nil
+	}
+	timeout := defaultTraceTimeout
+	if config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, nil, err
+		}
+	}
+	tracer, err := riftapi.NewJavascriptTracer(*config.Tracer)
+	if err != nil {
+		return nil, nil, err
 	}
-	return true, structLogger.StructLogs(), nil
+	// Handle timeouts and RPC cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		tracer.Stop(&timeoutError{})
+	}()
+	return tracer, cancel, nil
 }
 
 // callmsg is the message type used for call transitions.
@@ -503,47 +606,31 @@ func (t *timeoutError) Error() string {
 // TraceTransaction returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
-	var tracer vm.Tracer
-	if config != nil && config.Tracer != nil {
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			var err error
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-
-		var err error
-		if tracer, err = riftapi.NewJavascriptTracer(*config.Tracer); err != nil {
-			return nil, err
-		}
-
-		// Handle timeouts and RPC cancellations
-		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
-			<-deadlineCtx.Done()
-			tracer.(*riftapi.JavascriptTracer).Stop(&timeoutError{})
-		}()
-		defer cancel()
-	} else if config == nil {
-		tracer = vm.NewStructLogger(nil)
-	} else {
-		tracer = vm.NewStructLogger(config.LogConfig)
-	}
-
 	// Retrieve the tx from the chain and the containing block
 	tx, blockHash, _, txIndex := core.GetTransaction(api.rift.ChainDb(), txHash)
 	if tx == nil {
 		return nil, fmt.Errorf("transaction %x not found", txHash)
 	}
-	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
+	msg, vmctx, statedb, err := api.computeTxEnv(blockHash, int(txIndex), traceReexec(config))
+	if err != nil {
+		return nil, err
+	}
+	return api.traceTx(ctx, msg, vmctx, statedb, config)
+}
+
+// traceTx configures a new tracer according to the provided configuration, and
+// executes the given message in the provided environment. The return value will
+// be tracer dependent.
+func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceArgs) (interface{}, error) {
+	tracer, cancel, err := api.newTracer(ctx, config)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
-	ret, gas, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
@@ -551,18 +638,20 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 	case *vm.StructLogger:
 		return &riftapi.ExecutionResult{
 			Gas:         gas,
+			Failed:      failed,
 			ReturnValue: fmt.Sprintf("%x", ret),
 			StructLogs:  riftapi.FormatLogs(tracer.StructLogs()),
 		}, nil
-	case *riftapi.JavascriptTracer:
+	case resultTracer:
 		return tracer.GetResult()
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
 }
 
-// computeTxEnv returns the execution environment of a certain transaction.
-func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
+// computeTxEnv returns the execution environment of a certain transaction,
+// re-executing at most reexec blocks if the parent state is not available.
+func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, reexec uint64) (core.Message, vm.Context, *state.StateDB, error) {
 	// Create the parent state.
 	block := api.rift.BlockChain().GetBlockByHash(blockHash)
 	if block == nil {
@@ -572,7 +661,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 	if parent == nil {
 		return nil, vm.Context{}, nil, fmt.Errorf("block parent %x not found", block.ParentHash())
 	}
-	statedb, err := api.rift.BlockChain().StateAt(parent.Root())
+	statedb, _, err := api.computeStateDB(parent, reexec)
 	if err != nil {
 		return nil, vm.Context{}, nil, err
 	}
@@ -590,7 +679,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 
 		vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{})
 		gp := new(core.GasPool).AddGas(tx.Gas())
-		_, _, err := core.ApplyMessage(vmenv, msg, gp)
+		_, _, _, err := core.ApplyMessage(vmenv, msg, gp)
 		if err != nil {
 			return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 		}
@@ -626,7 +715,7 @@ type storageEntry struct {
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex)
+	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex, defaultTraceReexec)
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/math"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/state"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
@@ -95,6 +96,15 @@ func (b *RiftApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc
 	return stateDb, header, err
 }
 
+func (b *RiftApiBackend) StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.rift.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	stateDb, err := b.rift.BlockChain().StateAt(header.Root)
+	return stateDb, header, err
+}
+
 func (b *RiftApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.rift.blockchain.GetBlockByHash(blockHash), nil
 }
@@ -151,6 +161,22 @@ func (b *RiftApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.rift.TxPool().Content()
 }
 
+func (b *RiftApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return b.rift.TxPool().ContentFrom(addr)
+}
+
+func (b *RiftApiBackend) TxPoolEvict(txHash common.Hash) bool {
+	return b.rift.TxPool().Evict(txHash)
+}
+
+func (b *RiftApiBackend) TxPoolEvictAccount(addr common.Address) int {
+	return b.rift.TxPool().EvictAccount(addr)
+}
+
+func (b *RiftApiBackend) SubscribeTxPoolEvent(ch chan<- core.TxPoolEvent) event.Subscription {
+	return b.rift.TxPool().SubscribeTxPoolEvent(ch)
+}
+
 func (b *RiftApiBackend) Downloader() *downloader.Downloader {
 	return b.rift.Downloader()
 }
@@ -174,3 +200,14 @@ func (b *RiftApiBackend) EventMux() *event.TypeMux {
 func (b *RiftApiBackend) AccountManager() *accounts.Manager {
 	return b.rift.AccountManager()
 }
+
+func (b *RiftApiBackend) BloomStatus() (uint64, uint64) {
+	sections, _, _ := b.rift.bloomIndexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
+func (b *RiftApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.rift.bloomRequests)
+	}
+}
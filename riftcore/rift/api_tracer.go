@@ -0,0 +1,404 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/consensus/misc"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/state"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// errTraceAborted is the internal marker of a chain trace stopped by its subscriber.
+var errTraceAborted = errors.New("chain trace aborted")
+
+// txTraceResult is the result of a single transaction trace.
+type txTraceResult struct {
+	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// chainTraceResult is the result of tracing all the transactions of a block,
+// pushed to the subscriber of a chain trace.
+type chainTraceResult struct {
+	Block  hexutil.Uint64   `json:"block"`
+	Hash   common.Hash      `json:"hash"`
+	Traces []*txTraceResult `json:"traces"`
+}
+
+// chainTraceTask represents a single block trace task when an entire chain is
+// being traced.
+type chainTraceTask struct {
+	statedb *state.StateDB   // Intermediate state prepped for tracing
+	block   *types.Block     // Block to trace the transactions from
+	rootref common.Hash      // Trie root reference held for this task
+	results []*txTraceResult // Trace results produced by the task
+}
+
+// TraceChain returns the structured logs created during the execution of EVM
+// between two blocks (inclusive) and streams them back over a subscription,
+// one notification per block, in chain order.
+func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *TraceArgs) (*rpc.Subscription, error) {
+	// Fetch the block interval that we want to trace. The pending block is not
+	// part of the chain yet, so the range is capped at the current head.
+	resolve := func(number rpc.BlockNumber) *types.Block {
+		if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+			return api.rift.blockchain.CurrentBlock()
+		}
+		return api.rift.blockchain.GetBlockByNumber(uint64(number))
+	}
+	from, to := resolve(start), resolve(end)
+
+	// Trace the chain if we've found all our blocks
+	if from == nil {
+		return nil, fmt.Errorf("starting block #%d not found", start)
+	}
+	if to == nil {
+		return nil, fmt.Errorf("end block #%d not found", end)
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("end block #%d precedes start block #%d", to.NumberU64(), from.NumberU64())
+	}
+	// The genesis block contains no transactions and has no parent state to
+	// trace from, start with its child instead
+	if from.NumberU64() == 0 {
+		if to.NumberU64() == 0 {
+			return nil, fmt.Errorf("no blocks to trace in range")
+		}
+		from = api.rift.blockchain.GetBlockByNumber(1)
+	}
+	return api.traceChain(ctx, from, to, config)
+}
+
+// traceChain configures a new tracer according to the provided configuration,
+// and executes all the transactions contained within. The return value will be
+// one item per transaction, dependent on the requested tracer.
+func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Block, config *TraceArgs) (*rpc.Subscription, error) {
+	// Tracing a chain is a **long** operation, only do with subscriptions
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	// Ensure we have a valid starting state before doing any work
+	reexec := traceReexec(config)
+	parent := api.rift.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent block #%d not found", start.NumberU64()-1)
+	}
+	statedb, database, err := api.computeStateDB(parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	sub := notifier.CreateSubscription()
+
+	// Closing the subscription or the connection aborts all the work
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		}
+		close(quit)
+	}()
+	// Execute all the transactions of the blocks concurrently
+	var (
+		blocks  = int(end.NumberU64() - start.NumberU64() + 1)
+		threads = runtime.NumCPU()
+		pend    = new(sync.WaitGroup)
+		tasks   = make(chan *chainTraceTask, threads)
+		results = make(chan *chainTraceTask, threads)
+	)
+	if threads > blocks {
+		threads = blocks
+	}
+	for th := 0; th < threads; th++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			// Fetch and execute the next block trace tasks, releasing the state
+			// of any tasks left over after an abort
+			for task := range tasks {
+				select {
+				case <-quit:
+					database.TrieDB().Dereference(task.rootref)
+					continue
+				default:
+				}
+				api.traceChainTask(ctx, task, config)
+
+				// Stream the result back to the ordering goroutine
+				select {
+				case results <- task:
+				case <-quit:
+					database.TrieDB().Dereference(task.rootref)
+				}
+			}
+		}()
+	}
+	// Start a goroutine to feed all the blocks into the tracers
+	go func() {
+		defer func() {
+			close(tasks)
+			pend.Wait()
+			close(results)
+		}()
+		var (
+			begin   = time.Now()
+			logged  time.Time
+			failed  error
+			triedb  = database.TrieDB()
+			proot   = parent.Root()
+			started = start.NumberU64()
+		)
+		// Hold our own reference to the running state, the database may be the
+		// live one of the chain, pruning anything not referenced
+		triedb.Reference(proot, common.Hash{})
+
+		for number := start.NumberU64(); number <= end.NumberU64(); number++ {
+			// Print progress logs if long enough time elapsed
+			if time.Since(logged) > 8*time.Second {
+				if number > started {
+					log.Info("Tracing chain segment", "start", started, "end", end.NumberU64(), "current", number, "elapsed", time.Since(begin))
+				}
+				logged = time.Now()
+			}
+			// Retrieve the next block to trace
+			block := api.rift.blockchain.GetBlockByNumber(number)
+			if block == nil {
+				failed = fmt.Errorf("block #%d not found", number)
+				break
+			}
+			// Send the block over to the concurrent tracers, retaining its parent
+			// state until the task is done with it
+			triedb.Reference(proot, common.Hash{})
+			task := &chainTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(block.Transactions()))}
+			select {
+			case tasks <- task:
+			case <-quit:
+				triedb.Dereference(task.rootref)
+				failed = errTraceAborted
+			}
+			if failed != nil {
+				break
+			}
+			// Generate the next state snapshot fast without tracing
+			if _, _, _, err := api.rift.blockchain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+				failed = err
+				break
+			}
+			// Finalize the state so any modifications are written to the trie
+			root, err := statedb.CommitTo(triedb, api.config.IsEIP158(block.Number()))
+			if err != nil {
+				failed = err
+				break
+			}
+			if statedb, err = state.New(root, database); err != nil {
+				failed = err
+				break
+			}
+			// Swap our own reference over to the new state root
+			triedb.Reference(root, common.Hash{})
+			triedb.Dereference(proot)
+			proot = root
+		}
+		triedb.Dereference(proot)
+
+		if failed != nil && failed != errTraceAborted {
+			log.Warn("Chain tracing failed", "start", started, "end", end.NumberU64(), "elapsed", time.Since(begin), "err", failed)
+		}
+	}()
+	// Keep reading the trace results and stream them to the user in order
+	go func() {
+		var (
+			done = make(map[uint64]*chainTraceTask)
+			next = start.NumberU64()
+		)
+		// Notifications are dropped until the subscription is delivered to the user
+		select {
+		case <-sub.Active():
+		case <-quit:
+		}
+		for res := range results {
+			// Release the state of all results if the trace was aborted
+			select {
+			case <-quit:
+				database.TrieDB().Dereference(res.rootref)
+				continue
+			default:
+			}
+			// Queue up next received result
+			done[res.block.NumberU64()] = res
+
+			// Stream completed traces to the user, aborting on the first error
+			for task, ok := done[next]; ok; task, ok = done[next] {
+				result := &chainTraceResult{
+					Block:  hexutil.Uint64(task.block.NumberU64()),
+					Hash:   task.block.Hash(),
+					Traces: task.results,
+				}
+				if err := notifier.Notify(sub.ID, result); err != nil {
+					log.Warn("Failed to stream chain trace", "block", next, "err", err)
+				}
+				database.TrieDB().Dereference(task.rootref)
+				delete(done, next)
+				next++
+			}
+		}
+		// Results queued behind a dropped one are never streamed, release them
+		for _, task := range done {
+			database.TrieDB().Dereference(task.rootref)
+		}
+		log.Info("Traced chain segment", "start", start.NumberU64(), "end", end.NumberU64(), "traced", next-start.NumberU64())
+	}()
+	return sub, nil
+}
+
+// traceChainTask traces all the transactions of a single block on top of its
+// parent state, storing the individual trace results within the task.
+func (api *PrivateDebugAPI) traceChainTask(ctx context.Context, task *chainTraceTask, config *TraceArgs) {
+	var (
+		blockchain = api.rift.BlockChain()
+		header     = task.block.Header()
+		signer     = types.MakeSigner(api.config, task.block.Number())
+	)
+	// Mutate the the state according to any hard-fork specs
+	if api.config.DAOForkSupport && api.config.DAOForkBlock != nil && api.config.DAOForkBlock.Cmp(task.block.Number()) == 0 {
+		misc.ApplyDAOHardFork(task.statedb)
+	}
+	for i, tx := range task.block.Transactions() {
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, header, blockchain, nil)
+
+		task.statedb.Prepare(tx.Hash(), task.block.Hash(), i)
+		res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+		if err != nil {
+			task.results[i] = &txTraceResult{Error: err.Error()}
+			log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
+
+			// The state is unusable for the rest of the block, fail those too
+			for j := i + 1; j < len(task.results); j++ {
+				task.results[j] = &txTraceResult{Error: fmt.Sprintf("preceding transaction %x failed", tx.Hash())}
+			}
+			break
+		}
+		// Update the state with pending changes, same as during block processing
+		if api.config.IsMetropolis(task.block.Number()) {
+			task.statedb.Finalise()
+		} else {
+			task.statedb.IntermediateRoot(api.config.IsEIP158(task.block.Number()))
+		}
+		task.results[i] = &txTraceResult{Result: res}
+	}
+	// Release the state as soon as possible, results may linger a while
+	task.statedb = nil
+}
+
+// computeStateDB retrieves the state database associated with a certain block,
+// along with the database backing it. If the chain has the state available, on
+// disk or in its live trie cache, that is used directly. Otherwise a number of
+// blocks are attempted to be re-executed to generate the desired state in a
+// throwaway database, the final state root being referenced within it.
+func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*state.StateDB, state.Database, error) {
+	// If we have the state fully available, use that
+	if statedb, err := api.rift.blockchain.StateAt(block.Root()); err == nil {
+		return statedb, api.rift.blockchain.StateCache(), nil
+	}
+	// Otherwise find the most recent block that has its state available
+	var (
+		database = state.NewDatabase(api.rift.ChainDb())
+		origin   = block
+		statedb  *state.StateDB
+		err      error
+	)
+	for i := uint64(0); i <= reexec; i++ {
+		if statedb, err = state.New(origin.Root(), database); err == nil {
+			break
+		}
+		if origin.NumberU64() == 0 {
+			break
+		}
+		if origin = api.rift.blockchain.GetBlock(origin.ParentHash(), origin.NumberU64()-1); origin == nil {
+			return nil, nil, fmt.Errorf("missing block #%d", block.NumberU64()-i-1)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("required historical state unavailable (reexec=%d)", reexec)
+	}
+	// State was available at historical point, regenerate
+	var (
+		start  = time.Now()
+		logged time.Time
+		triedb = database.TrieDB()
+		proot  common.Hash
+	)
+	for origin.NumberU64() < block.NumberU64() {
+		// Print progress logs if long enough time elapsed
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Regenerating historical state", "block", origin.NumberU64()+1, "target", block.NumberU64(), "elapsed", time.Since(start))
+			logged = time.Now()
+		}
+		// Retrieve the next block to regenerate and process it
+		next := api.rift.blockchain.GetBlockByNumber(origin.NumberU64() + 1)
+		if next == nil {
+			return nil, nil, fmt.Errorf("block #%d not found", origin.NumberU64()+1)
+		}
+		origin = next
+
+		if _, _, _, err := api.rift.blockchain.Processor().Process(origin, statedb, vm.Config{}); err != nil {
+			return nil, nil, err
+		}
+		// Finalize the state so any modifications are written to the trie
+		root, err := statedb.CommitTo(triedb, api.config.IsEIP158(origin.Number()))
+		if err != nil {
+			return nil, nil, err
+		}
+		if statedb, err = state.New(root, database); err != nil {
+			return nil, nil, err
+		}
+		triedb.Reference(root, common.Hash{})
+		if proot != (common.Hash{}) {
+			triedb.Dereference(proot)
+		}
+		proot = root
+	}
+	if proot != (common.Hash{}) {
+		log.Info("Historical state regenerated", "block", block.NumberU64(), "elapsed", time.Since(start), "size", triedb.Size())
+	}
+	return statedb, database, nil
+}
+
+// traceReexec returns the number of blocks the tracer may re-execute to
+// regenerate a missing historical state.
+func traceReexec(config *TraceArgs) uint64 {
+	if config != nil && config.Reexec != nil {
+		return *config.Reexec
+	}
+	return defaultTraceReexec
+}
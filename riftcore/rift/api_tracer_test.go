@@ -0,0 +1,212 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rift
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/consensus/rifthash"
+	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/core/vm"
+	"github.com/cryptorift/riftcore/event"
+	"github.com/cryptorift/riftcore/params"
+	"github.com/cryptorift/riftcore/riftdb"
+	"github.com/cryptorift/riftcore/rpc"
+)
+
+// newTracingChain creates a blockchain of the given length with a value transfer
+// in every block. The states are generated in a separate database so the chain
+// only holds them in memory.
+func newTracingChain(t *testing.T, n int) (*core.BlockChain, []*types.Block, *PrivateDebugAPI) {
+	var (
+		engine = rifthash.NewFaker()
+		gendb  = riftdb.NewMemDatabase
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	db, _ := gendb()
+	genesis := gspec.MustCommit(db)
+
+	tmpdb, _ := gendb()
+	gspec.MustCommit(tmpdb)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, tmpdb, n, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testBank), common.Address{0x01}, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), signer, testBankKey)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, engine, new(event.TypeMux), vm.Config{})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		chain.Stop()
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, blocks, NewPrivateDebugAPI(gspec.Config, &CryptoRift{blockchain: chain, chainDb: db, engine: engine})
+}
+
+// Tests that tracing a chain segment streams the per-block results in order,
+// regenerating the starting state if it's not available on disk.
+func TestTraceChain(t *testing.T) {
+	chain, blocks, api := newTracingChain(t, 8)
+	defer chain.Stop()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Trace a chain segment using the native call tracer
+	tracer := "callTracer"
+	checkChainTrace(t, client, blocks, 3, 7, &TraceArgs{Tracer: &tracer})
+}
+
+// Tests that recent states only held in memory by a pruning chain are used for
+// tracing, instead of attempting to regenerate them from the last state on disk.
+func TestTracePrunedState(t *testing.T) {
+	// Create a chain longer than the allowed re-execution depth
+	chain, blocks, api := newTracingChain(t, 16)
+	defer chain.Stop()
+
+	var (
+		tracer = "callTracer"
+		reexec = uint64(4)
+		config = &TraceArgs{Tracer: &tracer, Reexec: &reexec}
+	)
+	// Trace a single transaction and a full block
+	if _, err := api.TraceTransaction(context.Background(), blocks[12].Transactions()[0].Hash(), config); err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if result := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(14), config); result.Error != "" {
+		t.Fatalf("failed to trace block: %v", result.Error)
+	}
+	// Trace a chain segment
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	checkChainTrace(t, client, blocks, 13, 16, config)
+}
+
+// Tests that aborting a chain trace midway releases all the states it held in
+// the chain's trie cache, leaving nothing dangling once the chain is stopped.
+func TestTraceChainAbort(t *testing.T) {
+	chain, _, api := newTracingChain(t, 64)
+	defer chain.Stop()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	baseline := runtime.NumGoroutine()
+
+	// Start tracing the chain and abort after the first result
+	results := make(chan json.RawMessage)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(1), hexutil.Uint64(64), nil)
+	if err != nil {
+		t.Fatalf("failed to subscribe to chain trace: %v", err)
+	}
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatalf("chain trace timed out")
+	}
+	sub.Unsubscribe()
+
+	// Wait for the tracer to wind down and ensure all references are released
+	for i := 0; runtime.NumGoroutine() > baseline; i++ {
+		if i > 100 {
+			t.Fatalf("chain tracer didn't terminate: goroutines %d, want %d", runtime.NumGoroutine(), baseline)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	chain.Stop()
+	if size := chain.StateCache().TrieDB().Size(); size != 0 {
+		t.Fatalf("dangling trie nodes after aborted trace: %v", size)
+	}
+}
+
+// checkChainTrace traces the chain segment [start, end] via a subscription and
+// checks that each block contains a single successful value transfer.
+func checkChainTrace(t *testing.T, client *rpc.Client, blocks []*types.Block, start, end uint64, config *TraceArgs) {
+	results := make(chan json.RawMessage, 16)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(start), hexutil.Uint64(end), config)
+	if err != nil {
+		t.Fatalf("failed to subscribe to chain trace: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for number := start; number <= end; number++ {
+		select {
+		case blob := <-results:
+			var result struct {
+				Block  hexutil.Uint64
+				Hash   common.Hash
+				Traces []struct {
+					Result struct {
+						Type  string
+						From  common.Address
+						To    common.Address
+						Value *hexutil.Big
+					}
+					Error string
+				}
+			}
+			if err := json.Unmarshal(blob, &result); err != nil {
+				t.Fatalf("block %d: failed to decode trace: %v", number, err)
+			}
+			if uint64(result.Block) != number || result.Hash != blocks[number-1].Hash() {
+				t.Fatalf("block mismatch: have #%d [%x], want #%d [%x]", result.Block, result.Hash, number, blocks[number-1].Hash())
+			}
+			if len(result.Traces) != 1 {
+				t.Fatalf("block %d: trace count mismatch: have %d, want 1", number, len(result.Traces))
+			}
+			trace := result.Traces[0]
+			if trace.Error != "" {
+				t.Fatalf("block %d: trace failed: %v", number, trace.Error)
+			}
+			if trace.Result.Type != "CALL" || trace.Result.From != testBank || trace.Result.To != (common.Address{0x01}) || trace.Result.Value.ToInt().Int64() != 1000 {
+				t.Errorf("block %d: call frame mismatch: %+v", number, trace.Result)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("block %d: subscription failed: %v", number, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block %d: trace timeout", number)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -32,6 +33,7 @@ import (
 	"github.com/cryptorift/riftcore/consensus/clique"
 	"github.com/cryptorift/riftcore/consensus/rifthash"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/core/vm"
 	"github.com/cryptorift/riftcore/rift/downloader"
@@ -53,6 +55,7 @@ type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
+	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
 // CryptoRift implements the CryptoRift full node service.
@@ -69,6 +72,9 @@ type CryptoRift struct {
 	// DB interfaces
 	chainDb riftdb.Database // Block chain database
 
+	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
@@ -87,6 +93,7 @@ type CryptoRift struct {
 
 func (s *CryptoRift) AddLesServer(ls LesServer) {
 	s.lesServer = ls
+	ls.SetBloomBitsIndexer(s.bloomIndexer)
 }
 
 // New creates a new CryptoRift object (including the
@@ -104,6 +111,9 @@ func New(ctx *node.ServiceContext, config *Config) (*CryptoRift, error) {
 		return nil, err
 	}
 	stopDbUpgrade := upgradeDeduplicateData(chainDb)
+	if chainDb, err = CreateFreezerDB(ctx, config, chainDb, "chaindata"); err != nil {
+		return nil, err
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -121,11 +131,10 @@ func New(ctx *node.ServiceContext, config *Config) (*CryptoRift, error) {
 		networkId:      config.NetworkId,
 		gasPrice:       config.GasPrice,
 		riftbase:      config.Riftbase,
+		bloomRequests:  make(chan chan *bloombits.Retrieval),
+		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
 	}
 
-	if err := addMipmapBloomBins(chainDb); err != nil {
-		return nil, err
-	}
 	log.Info("Initialising CryptoRift protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
 	if !config.SkipBcVersionCheck {
@@ -136,8 +145,11 @@ func New(ctx *node.ServiceContext, config *Config) (*CryptoRift, error) {
 		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
 
-	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-	rift.blockchain, err = core.NewBlockChain(chainDb, rift.chainConfig, rift.engine, rift.eventMux, vmConfig)
+	var (
+		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+	)
+	rift.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, rift.chainConfig, rift.engine, rift.eventMux, vmConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +159,11 @@ func New(ctx *node.ServiceContext, config *Config) (*CryptoRift, error) {
 		rift.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	rift.bloomIndexer.Start(rift.blockchain.CurrentHeader(), rift.eventMux)
 
+	if config.TxPool.Journal != "" {
+		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
+	}
 	newPool := core.NewTxPool(config.TxPool, rift.chainConfig, rift.EventMux(), rift.blockchain.State, rift.blockchain.GasLimit)
 	rift.txPool = newPool
 
@@ -208,6 +224,23 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (riftdb.Dat
 	return db, nil
 }
 
+// CreateFreezerDB layers an ancient store for immutable chain segments below the
+// chain database. Ephemeral nodes keep all their chain data in memory.
+func CreateFreezerDB(ctx *node.ServiceContext, config *Config, db riftdb.Database, name string) (riftdb.Database, error) {
+	dir := ctx.ResolvePath(name)
+	if dir == "" {
+		return db, nil
+	}
+	freezer := config.DatabaseFreezer
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(dir, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = ctx.ResolvePath(freezer)
+	}
+	return core.NewDatabaseWithFreezer(db, freezer)
+}
+
 // CreateConsensusEngine creates the required type of consensus engine instance for an CryptoRift service
 func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig *params.ChainConfig, db riftdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
@@ -372,8 +405,13 @@ func (s *CryptoRift) Protocols() []p2p.Protocol {
 // Start implements node.Service, starting all internal goroutines needed by the
 // CryptoRift protocol implementation.
 func (s *CryptoRift) Start(srvr *p2p.Server) error {
+	// Start the bloom bits servicing goroutines
+	s.startBloomHandlers()
+
+	// Start the RPC service
 	s.netRPCService = riftapi.NewPublicNetAPI(srvr, s.NetVersion())
 
+	// Start the networking layer
 	s.protocolManager.Start()
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
@@ -387,6 +425,7 @@ func (s *CryptoRift) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -18,13 +18,17 @@ package rift
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"sync"
 
 	"github.com/cryptorift/riftcore"
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/hexutil"
 	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/event"
 	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/rift/filters"
 	"github.com/cryptorift/riftcore/rlp"
 	"github.com/cryptorift/riftcore/rpc"
 )
@@ -40,16 +44,28 @@ type ContractBackend struct {
 	eapi  *riftapi.PublicCryptoriftAPI        // Wrapper around the CryptoRift object to access metadata
 	bcapi *riftapi.PublicBlockChainAPI      // Wrapper around the blockchain to access chain data
 	txapi *riftapi.PublicTransactionPoolAPI // Wrapper around the transaction pool to access transaction data
+
+	filterBackend filters.Backend      // Backend to filter logs with (nil = filtering unsupported)
+	events        *filters.EventSystem // Event system to stream live logs through, created on demand
+	eventsOnce    sync.Once
 }
 
+// errFilteringUnsupported is returned by the log filtering methods if the API
+// backend the contract backend was created with cannot filter logs.
+var errFilteringUnsupported = errors.New("api backend does not support log filtering")
+
 // NewContractBackend creates a new native contract backend using an existing
 // Cryptorift object.
 func NewContractBackend(apiBackend riftapi.Backend) *ContractBackend {
-	return &ContractBackend{
+	backend := &ContractBackend{
 		eapi:  riftapi.NewPublicCryptoriftAPI(apiBackend),
 		bcapi: riftapi.NewPublicBlockChainAPI(apiBackend),
 		txapi: riftapi.NewPublicTransactionPoolAPI(apiBackend, new(riftapi.AddrLocker)),
 	}
+	if filterBackend, ok := apiBackend.(filters.Backend); ok {
+		backend.filterBackend = filterBackend
+	}
+	return backend
 }
 
 // CodeAt retrieves any code associated with the contract from the local API.
@@ -136,3 +152,76 @@ func (b *ContractBackend) SendTransaction(ctx context.Context, tx *types.Transac
 	_, err := b.txapi.SendRawTransaction(ctx, raw)
 	return err
 }
+
+// FilterLogs implements bind.ContractFilterer executing a log filter operation
+// against the local chain, returning all the results in one batch.
+func (b *ContractBackend) FilterLogs(ctx context.Context, query cryptorift.FilterQuery) ([]types.Log, error) {
+	if b.filterBackend == nil {
+		return nil, errFilteringUnsupported
+	}
+	// Initialize unset filter boundaries to run from genesis to chain head
+	from := int64(0)
+	if query.FromBlock != nil {
+		from = query.FromBlock.Int64()
+	}
+	to := int64(rpc.LatestBlockNumber)
+	if query.ToBlock != nil {
+		to = query.ToBlock.Int64()
+	}
+	filter := filters.New(b.filterBackend)
+	filter.SetBeginBlock(from)
+	filter.SetEndBlock(to)
+	filter.SetAddresses(query.Addresses)
+	filter.SetTopics(query.Topics)
+
+	logs, err := filter.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]types.Log, len(logs))
+	for i, log := range logs {
+		res[i] = *log
+	}
+	return res, nil
+}
+
+// SubscribeFilterLogs implements bind.ContractFilterer creating a background log
+// filtering operation on newly imported blocks, returning a subscription that
+// streams the found events. Logs are taken from the chain events of a full node,
+// light clients don't produce any.
+func (b *ContractBackend) SubscribeFilterLogs(ctx context.Context, query cryptorift.FilterQuery, ch chan<- types.Log) (cryptorift.Subscription, error) {
+	if b.filterBackend == nil {
+		return nil, errFilteringUnsupported
+	}
+	b.eventsOnce.Do(func() {
+		b.events = filters.NewEventSystem(b.filterBackend.EventMux(), b.filterBackend, false)
+	})
+	sink := make(chan []*types.Log)
+
+	sub, err := b.events.SubscribeLogs(filters.FilterCriteria(query), sink)
+	if err != nil {
+		return nil, err
+	}
+	// Since we're getting logs in batches, we need to flatten them into a plain stream
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case logs := <-sink:
+				for _, log := range logs {
+					select {
+					case ch <- *log:
+					case err := <-sub.Err():
+						return err
+					case <-quit:
+						return nil
+					}
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
@@ -22,6 +22,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/cryptorift/riftcore/common"
 	"github.com/cryptorift/riftcore/common/hexutil"
@@ -42,6 +43,8 @@ var DefaultConfig = Config{
 	NetworkId:            1,
 	LightPeers:           20,
 	DatabaseCache:        128,
+	TrieCache:            256,
+	TrieTimeout:          5 * time.Minute,
 	GasPrice:             big.NewInt(18 * params.Shannon),
 
 	TxPool: core.DefaultTxPoolConfig,
@@ -85,6 +88,10 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string // Directory of the ancient store (default = inside the chain database)
+	TrieCache          int
+	TrieTimeout        time.Duration
+	NoPruning          bool // Whether to disable pruning and flush everything to disk
 
 	// Mining-related options
 	Riftbase    common.Address `toml:",omitempty"`
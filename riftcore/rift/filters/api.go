@@ -52,7 +52,6 @@ type filter struct {
 // information related to the CryptoRift protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
 	backend   Backend
-	useMipMap bool
 	mux       *event.TypeMux
 	quit      chan struct{}
 	chainDb   riftdb.Database
@@ -65,7 +64,6 @@ type PublicFilterAPI struct {
 func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend:   backend,
-		useMipMap: !lightMode,
 		mux:       backend.EventMux(),
 		chainDb:   backend.ChainDb(),
 		events:    NewEventSystem(backend.EventMux(), backend, lightMode),
@@ -333,7 +331,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		crit.ToBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
 	}
 
-	filter := New(api.backend, api.useMipMap)
+	filter := New(api.backend)
 	filter.SetBeginBlock(crit.FromBlock.Int64())
 	filter.SetEndBlock(crit.ToBlock.Int64())
 	filter.SetAddresses(crit.Addresses)
@@ -373,7 +371,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		return nil, fmt.Errorf("filter not found")
 	}
 
-	filter := New(api.backend, api.useMipMap)
+	filter := New(api.backend)
 	if f.crit.FromBlock != nil {
 		filter.SetBeginBlock(f.crit.FromBlock.Int64())
 	} else {
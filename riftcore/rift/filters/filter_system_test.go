@@ -19,12 +19,15 @@ package filters
 import (
 	"context"
 	"math/big"
+	"math/rand"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/bitutil"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/event"
@@ -32,9 +35,13 @@ import (
 	"github.com/cryptorift/riftcore/rpc"
 )
 
+// testBloomBitsBlocks is the bloom bits section size used by the test backend.
+const testBloomBitsBlocks = 256
+
 type testBackend struct {
-	mux *event.TypeMux
-	db  riftdb.Database
+	mux      *event.TypeMux
+	db       riftdb.Database
+	sections uint64
 }
 
 func (b *testBackend) ChainDb() riftdb.Database {
@@ -63,6 +70,38 @@ func (b *testBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (t
 	return core.GetBlockReceipts(b.db, blockHash, num), nil
 }
 
+func (b *testBackend) BloomStatus() (uint64, uint64) {
+	return testBloomBitsBlocks, b.sections
+}
+
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	requests := make(chan chan *bloombits.Retrieval)
+
+	go session.Multiplex(16, 0, requests)
+	go func() {
+		for {
+			// Wait for a service request or a shutdown
+			select {
+			case <-ctx.Done():
+				return
+
+			case request := <-requests:
+				task := <-request
+
+				task.Bitsets = make([][]byte, len(task.Sections))
+				for i, section := range task.Sections {
+					if rand.Int()%4 != 0 { // Handle occasional missing deliveries
+						head := core.GetCanonicalHash(b.db, (section+1)*testBloomBitsBlocks-1)
+						bits, _ := core.GetBloomBits(b.db, task.Bit, section, head)
+						task.Bitsets[i], _ = bitutil.DecompressBytes(bits, testBloomBitsBlocks/8)
+					}
+				}
+				request <- task
+			}
+		}
+	}()
+}
+
 // TestBlockSubscription tests if a block subscription returns block hashes for posted chain events.
 // It creates multiple subscriptions:
 // - one at the start and should receive all posted chain events and a second (blockHashes)
@@ -74,7 +113,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		mux         = new(event.TypeMux)
 		db, _       = riftdb.NewMemDatabase()
-		backend     = &testBackend{mux, db}
+		backend     = &testBackend{mux, db, 0}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *core.BlockGen) {})
@@ -127,7 +166,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		mux     = new(event.TypeMux)
 		db, _   = riftdb.NewMemDatabase()
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		api     = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -177,7 +216,7 @@ func TestLogFilterCreation(t *testing.T) {
 	var (
 		mux     = new(event.TypeMux)
 		db, _   = riftdb.NewMemDatabase()
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		api     = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -222,7 +261,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		mux     = new(event.TypeMux)
 		db, _   = riftdb.NewMemDatabase()
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		api     = NewPublicFilterAPI(backend, false)
 	)
 
@@ -248,7 +287,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		mux     = new(event.TypeMux)
 		db, _   = riftdb.NewMemDatabase()
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		api     = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -356,7 +395,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		mux     = new(event.TypeMux)
 		db, _   = riftdb.NewMemDatabase()
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		api     = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -403,6 +442,8 @@ func TestPendingLogsSubscription(t *testing.T) {
 			{FilterCriteria{Addresses: []common.Address{{}, notUsedAddress}, Topics: [][]common.Hash{{}}}, []*types.Log{}, nil, nil},
 			// match logs based on addresses, ignore topics
 			{FilterCriteria{Addresses: []common.Address{firstAddr}}, append(convertLogs(allLogs[:2]), allLogs[5].Logs[3]), nil, nil},
+			// match logs based on addresses, an empty topic rule set being a wildcard
+			{FilterCriteria{Addresses: []common.Address{secondAddr}, Topics: [][]common.Hash{{}}}, convertLogs(allLogs[2:3]), nil, nil},
 			// match none due to no matching topics (match with address)
 			{FilterCriteria{Addresses: []common.Address{secondAddr}, Topics: [][]common.Hash{{notUsedTopic}}}, []*types.Log{}, nil, nil},
 			// match logs based on addresses and topics
@@ -24,7 +24,9 @@ import (
 	"testing"
 
 	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/bitutil"
 	"github.com/cryptorift/riftcore/core"
+	"github.com/cryptorift/riftcore/core/bloombits"
 	"github.com/cryptorift/riftcore/core/types"
 	"github.com/cryptorift/riftcore/crypto"
 	"github.com/cryptorift/riftcore/riftdb"
@@ -32,8 +34,32 @@ import (
 	"github.com/cryptorift/riftcore/params"
 )
 
+// indexBloomBits generates the rotated bloom bits of the first few sections of
+// the canonical chain, as the bloom bits chain indexer would.
+func indexBloomBits(t testing.TB, db riftdb.Database, sections uint64) {
+	for section := uint64(0); section < sections; section++ {
+		gen, err := bloombits.NewGenerator(uint(testBloomBitsBlocks))
+		if err != nil {
+			t.Fatalf("failed to create bloom generator: %v", err)
+		}
+		var head common.Hash
+		for i := uint64(0); i < testBloomBitsBlocks; i++ {
+			number := section*testBloomBitsBlocks + i
+
+			head = core.GetCanonicalHash(db, number)
+			if err := gen.AddBloom(uint(i), core.GetHeader(db, head, number).Bloom); err != nil {
+				t.Fatalf("failed to add bloom of block #%d: %v", number, err)
+			}
+		}
+		for bit := uint(0); bit < types.BloomBitLength; bit++ {
+			bits, _ := gen.Bitset(bit)
+			core.WriteBloomBits(db, bit, section, head, bitutil.CompressBytes(bits))
+		}
+	}
+}
+
 func makeReceipt(addr common.Address) *types.Receipt {
-	receipt := types.NewReceipt(nil, new(big.Int))
+	receipt := types.NewReceipt(nil, false, new(big.Int))
 	receipt.Logs = []*types.Log{
 		{Address: addr},
 	}
@@ -41,8 +67,8 @@ func makeReceipt(addr common.Address) *types.Receipt {
 	return receipt
 }
 
-func BenchmarkMipmaps(b *testing.B) {
-	dir, err := ioutil.TempDir("", "mipmap")
+func BenchmarkFilters(b *testing.B) {
+	dir, err := ioutil.TempDir("", "filtertest")
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -51,7 +77,7 @@ func BenchmarkMipmaps(b *testing.B) {
 	var (
 		db, _   = riftdb.NewLDBDatabase(dir, 0, 0)
 		mux     = new(event.TypeMux)
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2   = common.BytesToAddress([]byte("jeff"))
@@ -62,27 +88,21 @@ func BenchmarkMipmaps(b *testing.B) {
 
 	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
 	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 100010, func(i int, gen *core.BlockGen) {
-		var receipts types.Receipts
 		switch i {
 		case 2403:
 			receipt := makeReceipt(addr1)
-			receipts = types.Receipts{receipt}
 			gen.AddUncheckedReceipt(receipt)
 		case 1034:
 			receipt := makeReceipt(addr2)
-			receipts = types.Receipts{receipt}
 			gen.AddUncheckedReceipt(receipt)
 		case 34:
 			receipt := makeReceipt(addr3)
-			receipts = types.Receipts{receipt}
 			gen.AddUncheckedReceipt(receipt)
 		case 99999:
 			receipt := makeReceipt(addr4)
-			receipts = types.Receipts{receipt}
 			gen.AddUncheckedReceipt(receipt)
 
 		}
-		core.WriteMipmapBloom(db, uint64(i+1), receipts)
 	})
 	for i, block := range chain {
 		core.WriteBlock(db, block)
@@ -96,14 +116,16 @@ func BenchmarkMipmaps(b *testing.B) {
 			b.Fatal("error writing block receipts:", err)
 		}
 	}
-	b.ResetTimer()
-
-	filter := New(backend, true)
-	filter.SetAddresses([]common.Address{addr1, addr2, addr3, addr4})
-	filter.SetBeginBlock(0)
-	filter.SetEndBlock(-1)
+	backend.sections = uint64(len(chain)) / testBloomBitsBlocks
+	indexBloomBits(b, db, backend.sections)
 
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		filter := New(backend)
+		filter.SetAddresses([]common.Address{addr1, addr2, addr3, addr4})
+		filter.SetBeginBlock(0)
+		filter.SetEndBlock(-1)
+
 		logs, _ := filter.Find(context.Background())
 		if len(logs) != 4 {
 			b.Fatal("expected 4 logs, got", len(logs))
@@ -112,7 +134,7 @@ func BenchmarkMipmaps(b *testing.B) {
 }
 
 func TestFilters(t *testing.T) {
-	dir, err := ioutil.TempDir("", "mipmap")
+	dir, err := ioutil.TempDir("", "filtertest")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -121,7 +143,7 @@ func TestFilters(t *testing.T) {
 	var (
 		db, _   = riftdb.NewLDBDatabase(dir, 0, 0)
 		mux     = new(event.TypeMux)
-		backend = &testBackend{mux, db}
+		backend = &testBackend{mux, db, 0}
 		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr    = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -134,10 +156,9 @@ func TestFilters(t *testing.T) {
 
 	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
 	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 1000, func(i int, gen *core.BlockGen) {
-		var receipts types.Receipts
 		switch i {
 		case 1:
-			receipt := types.NewReceipt(nil, new(big.Int))
+			receipt := types.NewReceipt(nil, false, new(big.Int))
 			receipt.Logs = []*types.Log{
 				{
 					Address: addr,
@@ -145,9 +166,8 @@ func TestFilters(t *testing.T) {
 				},
 			}
 			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
 		case 2:
-			receipt := types.NewReceipt(nil, new(big.Int))
+			receipt := types.NewReceipt(nil, false, new(big.Int))
 			receipt.Logs = []*types.Log{
 				{
 					Address: addr,
@@ -155,9 +175,8 @@ func TestFilters(t *testing.T) {
 				},
 			}
 			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
 		case 998:
-			receipt := types.NewReceipt(nil, new(big.Int))
+			receipt := types.NewReceipt(nil, false, new(big.Int))
 			receipt.Logs = []*types.Log{
 				{
 					Address: addr,
@@ -165,9 +184,8 @@ func TestFilters(t *testing.T) {
 				},
 			}
 			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
 		case 999:
-			receipt := types.NewReceipt(nil, new(big.Int))
+			receipt := types.NewReceipt(nil, false, new(big.Int))
 			receipt.Logs = []*types.Log{
 				{
 					Address: addr,
@@ -175,12 +193,7 @@ func TestFilters(t *testing.T) {
 				},
 			}
 			gen.AddUncheckedReceipt(receipt)
-			receipts = types.Receipts{receipt}
 		}
-		// i is used as block number for the writes but since the i
-		// starts at 0 and block 0 (genesis) is already present increment
-		// by one
-		core.WriteMipmapBloom(db, uint64(i+1), receipts)
 	})
 	for i, block := range chain {
 		core.WriteBlock(db, block)
@@ -195,82 +208,90 @@ func TestFilters(t *testing.T) {
 		}
 	}
 
-	filter := New(backend, true)
-	filter.SetAddresses([]common.Address{addr})
-	filter.SetTopics([][]common.Hash{{hash1, hash2, hash3, hash4}})
-	filter.SetBeginBlock(0)
-	filter.SetEndBlock(-1)
+	// Run all the queries both with and without the bloom bits index covering
+	// the first few sections of the chain
+	indexBloomBits(t, db, 3)
 
-	logs, _ := filter.Find(context.Background())
-	if len(logs) != 4 {
-		t.Error("expected 4 log, got", len(logs))
-	}
+	for _, sections := range []uint64{0, 3} {
+		backend.sections = sections
 
-	filter = New(backend, true)
-	filter.SetAddresses([]common.Address{addr})
-	filter.SetTopics([][]common.Hash{{hash3}})
-	filter.SetBeginBlock(900)
-	filter.SetEndBlock(999)
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 1 {
-		t.Error("expected 1 log, got", len(logs))
-	}
-	if len(logs) > 0 && logs[0].Topics[0] != hash3 {
-		t.Errorf("expected log[0].Topics[0] to be %x, got %x", hash3, logs[0].Topics[0])
-	}
+		filter := New(backend)
+		filter.SetAddresses([]common.Address{addr})
+		filter.SetTopics([][]common.Hash{{hash1, hash2, hash3, hash4}})
+		filter.SetBeginBlock(0)
+		filter.SetEndBlock(-1)
 
-	filter = New(backend, true)
-	filter.SetAddresses([]common.Address{addr})
-	filter.SetTopics([][]common.Hash{{hash3}})
-	filter.SetBeginBlock(990)
-	filter.SetEndBlock(-1)
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 1 {
-		t.Error("expected 1 log, got", len(logs))
-	}
-	if len(logs) > 0 && logs[0].Topics[0] != hash3 {
-		t.Errorf("expected log[0].Topics[0] to be %x, got %x", hash3, logs[0].Topics[0])
-	}
+		logs, _ := filter.Find(context.Background())
+		if len(logs) != 4 {
+			t.Errorf("sections %d: expected 4 log, got %d", sections, len(logs))
+		}
 
-	filter = New(backend, true)
-	filter.SetTopics([][]common.Hash{{hash1, hash2}})
-	filter.SetBeginBlock(1)
-	filter.SetEndBlock(10)
+		filter = New(backend)
+		filter.SetAddresses([]common.Address{addr})
+		filter.SetTopics([][]common.Hash{{hash3}})
+		filter.SetBeginBlock(900)
+		filter.SetEndBlock(999)
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 1 {
+			t.Errorf("sections %d: expected 1 log, got %d", sections, len(logs))
+		}
+		if len(logs) > 0 && logs[0].Topics[0] != hash3 {
+			t.Errorf("sections %d: expected log[0].Topics[0] to be %x, got %x", sections, hash3, logs[0].Topics[0])
+		}
 
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 2 {
-		t.Error("expected 2 log, got", len(logs))
-	}
+		filter = New(backend)
+		filter.SetAddresses([]common.Address{addr})
+		filter.SetTopics([][]common.Hash{{hash3}})
+		filter.SetBeginBlock(990)
+		filter.SetEndBlock(-1)
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 1 {
+			t.Errorf("sections %d: expected 1 log, got %d", sections, len(logs))
+		}
+		if len(logs) > 0 && logs[0].Topics[0] != hash3 {
+			t.Errorf("sections %d: expected log[0].Topics[0] to be %x, got %x", sections, hash3, logs[0].Topics[0])
+		}
 
-	failHash := common.BytesToHash([]byte("fail"))
-	filter = New(backend, true)
-	filter.SetTopics([][]common.Hash{{failHash}})
-	filter.SetBeginBlock(0)
-	filter.SetEndBlock(-1)
+		filter = New(backend)
+		filter.SetTopics([][]common.Hash{{hash1, hash2}})
+		filter.SetBeginBlock(1)
+		filter.SetEndBlock(10)
 
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 0 {
-		t.Error("expected 0 log, got", len(logs))
-	}
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 2 {
+			t.Errorf("sections %d: expected 2 log, got %d", sections, len(logs))
+		}
 
-	failAddr := common.BytesToAddress([]byte("failmenow"))
-	filter = New(backend, true)
-	filter.SetAddresses([]common.Address{failAddr})
-	filter.SetBeginBlock(0)
-	filter.SetEndBlock(-1)
+		failHash := common.BytesToHash([]byte("fail"))
+		filter = New(backend)
+		filter.SetTopics([][]common.Hash{{failHash}})
+		filter.SetBeginBlock(0)
+		filter.SetEndBlock(-1)
 
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 0 {
-		t.Error("expected 0 log, got", len(logs))
-	}
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 0 {
+			t.Errorf("sections %d: expected 0 log, got %d", sections, len(logs))
+		}
 
-	filter = New(backend, true)
-	filter.SetTopics([][]common.Hash{{failHash}, {hash1}})
-	filter.SetBeginBlock(0)
-	filter.SetEndBlock(-1)
+		failAddr := common.BytesToAddress([]byte("failmenow"))
+		filter = New(backend)
+		filter.SetAddresses([]common.Address{failAddr})
+		filter.SetBeginBlock(0)
+		filter.SetEndBlock(-1)
 
-	logs, _ = filter.Find(context.Background())
-	if len(logs) != 0 {
-		t.Error("expected 0 log, got", len(logs))
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 0 {
+			t.Errorf("sections %d: expected 0 log, got %d", sections, len(logs))
+		}
+
+		filter = New(backend)
+		filter.SetTopics([][]common.Hash{{failHash}, {hash1}})
+		filter.SetBeginBlock(0)
+		filter.SetEndBlock(-1)
+
+		logs, _ = filter.Find(context.Background())
+		if len(logs) != 0 {
+			t.Errorf("sections %d: expected 0 log, got %d", sections, len(logs))
+		}
 	}
 }
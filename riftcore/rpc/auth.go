@@ -0,0 +1,150 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// allowedModulesKey is the request context key under which the set of modules
+// granted by an authentication token is stored.
+type allowedModulesKey struct{}
+
+// jwtClaims are the claims of the tokens authenticating RPC requests. Beside the
+// standard time based claims, a token may restrict the API modules accessible
+// through it.
+type jwtClaims struct {
+	jwt.StandardClaims
+	Modules []string `json:"modules,omitempty"`
+}
+
+// NewJWT creates an HS256 signed token authenticating RPC requests against an
+// endpoint protected with the same secret. If modules is non-empty, the token
+// only grants access to the listed API modules. A zero expiry creates a token
+// that never expires.
+func NewJWT(secret []byte, modules []string, expiry time.Duration) (string, error) {
+	claims := jwtClaims{Modules: modules}
+	claims.IssuedAt = time.Now().Unix()
+	if expiry > 0 {
+		claims.ExpiresAt = time.Now().Add(expiry).Unix()
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// NewJWTHandler wraps an HTTP or websocket RPC handler, requiring all requests
+// to carry an HS256 signed bearer token in their Authorization header. If the
+// token carries a module list, only those API modules may be called with it.
+func NewJWTHandler(secret []byte, handler http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, next: handler}
+}
+
+// jwtHandler is an http.Handler authenticating requests via JSON web tokens.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// ServeHTTP implements http.Handler, validating the bearer token of the request
+// before passing it on to the wrapped handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// CORS preflight requests can't carry credentials, let the wrapped handler answer
+	if r.Method == http.MethodOptions {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	claims, err := h.validate(r.Header.Get("Authorization"))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if len(claims.Modules) > 0 {
+		modules := make(map[string]bool)
+		for _, module := range claims.Modules {
+			modules[module] = true
+		}
+		r = r.WithContext(context.WithValue(r.Context(), allowedModulesKey{}, modules))
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// validate parses the bearer token from an Authorization header, verifying its
+// signature and time based claims.
+func (h *jwtHandler) validate(header string) (*jwtClaims, error) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	claims := new(jwtClaims)
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return h.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	return claims, nil
+}
+
+// moduleFilterCodec is a ServerCodec rejecting requests for API modules outside
+// of an allowed set, as if the methods did not exist.
+type moduleFilterCodec struct {
+	ServerCodec
+	modules map[string]bool
+}
+
+// ReadRequestHeaders implements ServerCodec, marking requests for disallowed
+// modules as invalid. Unsubscribe requests are let through, as they can only
+// cancel subscriptions created on the same connection.
+func (c *moduleFilterCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	reqs, batch, err := c.ServerCodec.ReadRequestHeaders()
+	for i := range reqs {
+		if reqs[i].isPubSub && strings.HasSuffix(reqs[i].method, unsubscribeMethodSuffix) {
+			continue
+		}
+		if reqs[i].err == nil && !c.modules[reqs[i].service] {
+			reqs[i].err = &methodNotFoundError{reqs[i].service, reqs[i].method}
+		}
+	}
+	return reqs, batch, err
+}
+
+// ModuleAllowed reports whether the authentication token of an HTTP request, if
+// any, grants access to the given module. It allows endpoints served outside of
+// the RPC server to honour module restricted tokens.
+func ModuleAllowed(ctx context.Context, module string) bool {
+	if modules, ok := ctx.Value(allowedModulesKey{}).(map[string]bool); ok {
+		return modules[module]
+	}
+	return true
+}
+
+// filterCodec restricts the codec to the modules granted in the request context,
+// if any restriction was set.
+func filterCodec(ctx context.Context, codec ServerCodec) ServerCodec {
+	if modules, ok := ctx.Value(allowedModulesKey{}).(map[string]bool); ok {
+		return &moduleFilterCodec{ServerCodec: codec, modules: modules}
+	}
+	return codec
+}
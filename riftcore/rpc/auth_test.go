@@ -0,0 +1,100 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwtRequest issues a raw JSON-RPC request against an HTTP endpoint, returning
+// the status code and the decoded response if the request was accepted.
+func jwtRequest(t *testing.T, url string, token string, method string) (int, *jsonErrResponse) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	result := new(jsonErrResponse)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+// Tests that JWT protected endpoints reject unauthenticated requests and only
+// grant access to the modules permitted by the tokens.
+func TestJWTHandler(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("calc", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	httpsrv := httptest.NewServer(NewJWTHandler(secret, server))
+	defer httpsrv.Close()
+
+	// Unauthenticated and forged requests must be rejected
+	if code, _ := jwtRequest(t, httpsrv.URL, "", "test_noArgsRets"); code != http.StatusUnauthorized {
+		t.Errorf("missing token: status mismatch: have %d, want %d", code, http.StatusUnauthorized)
+	}
+	forged, _ := NewJWT([]byte("invalid secret"), nil, 0)
+	if code, _ := jwtRequest(t, httpsrv.URL, forged, "test_noArgsRets"); code != http.StatusUnauthorized {
+		t.Errorf("forged token: status mismatch: have %d, want %d", code, http.StatusUnauthorized)
+	}
+	claims := jwtClaims{}
+	claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if code, _ := jwtRequest(t, httpsrv.URL, expired, "test_noArgsRets"); code != http.StatusUnauthorized {
+		t.Errorf("expired token: status mismatch: have %d, want %d", code, http.StatusUnauthorized)
+	}
+	// Unrestricted tokens should grant access to all modules
+	full, _ := NewJWT(secret, nil, time.Minute)
+	for _, method := range []string{"test_noArgsRets", "calc_noArgsRets"} {
+		if code, resp := jwtRequest(t, httpsrv.URL, full, method); code != http.StatusOK || resp.Error.Code != 0 {
+			t.Errorf("unrestricted token: %s failed: status %d, response %+v", method, code, resp)
+		}
+	}
+	// Restricted tokens should only grant access to the listed modules
+	limited, _ := NewJWT(secret, []string{"test"}, time.Minute)
+	if code, resp := jwtRequest(t, httpsrv.URL, limited, "test_noArgsRets"); code != http.StatusOK || resp.Error.Code != 0 {
+		t.Errorf("restricted token: allowed module failed: status %d, response %+v", code, resp)
+	}
+	if code, resp := jwtRequest(t, httpsrv.URL, limited, "calc_noArgsRets"); code != http.StatusOK || resp.Error.Code != (&methodNotFoundError{}).ErrorCode() {
+		t.Errorf("restricted token: disallowed module accessible: status %d, response %+v", code, resp)
+	}
+}
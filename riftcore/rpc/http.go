@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -136,18 +137,22 @@ func (t *httpReadWriteNopCloser) Close() error {
 	return nil
 }
 
-// NewHTTPServer creates a new HTTP RPC server around an API provider.
-//
-// Deprecated: Server implements http.Handler
-func NewHTTPServer(cors []string, srv *Server) *http.Server {
-	return &http.Server{Handler: newCorsHandler(srv, cors)}
+// NewHTTPServer creates a new HTTP server around an API provider, accepting cross
+// origin requests from the cors domains and requests addressed to one of the
+// vhosts. To accept requests with any Host header, pass "*".
+func NewHTTPServer(cors []string, vhosts []string, srv http.Handler) *http.Server {
+	return &http.Server{Handler: newVHostHandler(vhosts, newCorsHandler(srv, cors))}
 }
 
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.ContentLength > maxHTTPRequestContentLength {
+	limit := int64(maxHTTPRequestContentLength)
+	if srv.limits.MaxRequestSize > 0 {
+		limit = srv.limits.MaxRequestSize
+	}
+	if r.ContentLength > limit {
 		http.Error(w,
-			fmt.Sprintf("content length too large (%d>%d)", r.ContentLength, maxHTTPRequestContentLength),
+			fmt.Sprintf("content length too large (%d>%d)", r.ContentLength, limit),
 			http.StatusRequestEntityTooLarge)
 		return
 	}
@@ -156,12 +161,13 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// create a codec that reads direct from the request body until
 	// EOF and writes the response to w and order the server to process
 	// a single request.
-	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
+	body := http.MaxBytesReader(w, r.Body, limit)
+	codec := srv.limitCodec(r.RemoteAddr, filterCodec(r.Context(), NewJSONCodec(&httpReadWriteNopCloser{body, w})))
 	defer codec.Close()
 	srv.ServeSingleRequest(codec, OptionMethodInvocation)
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -175,3 +181,43 @@ func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
 	})
 	return c.Handler(srv)
 }
+
+// virtualHostHandler is an http.Handler rejecting requests with a Host header not
+// in the whitelist, preventing DNS rebinding attacks against the endpoint.
+type virtualHostHandler struct {
+	vhosts map[string]bool
+	next   http.Handler
+}
+
+// newVHostHandler wraps a handler with a Host header whitelist, unless any host
+// is accepted.
+func newVHostHandler(vhosts []string, next http.Handler) http.Handler {
+	whitelist := make(map[string]bool)
+	for _, host := range vhosts {
+		if host == "*" {
+			return next
+		}
+		whitelist[strings.ToLower(host)] = true
+	}
+	return &virtualHostHandler{vhosts: whitelist, next: next}
+}
+
+// ServeHTTP implements http.Handler, validating the Host header of the request.
+func (h *virtualHostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Requests without a Host header (HTTP/1.0) can't be rebound
+	if r.Host == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		// Either invalid (too many colons) or no port specified
+		host = r.Host
+	}
+	// Requests addressed to an IP address are not subject to DNS rebinding
+	if net.ParseIP(host) != nil || h.vhosts[strings.ToLower(host)] {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "invalid host specified", http.StatusForbidden)
+}
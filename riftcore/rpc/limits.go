@@ -0,0 +1,226 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limiterPruneThreshold is the number of tracked clients above which a rate
+// limiter drops the clients that have been idle long enough to be forgotten.
+const limiterPruneThreshold = 4096
+
+// Limits configures the resources a single client may consume from a server
+// exposed to remote connections. Zero values disable the respective limit.
+type Limits struct {
+	MaxRequestSize int64              // Maximum size of an HTTP request body in bytes (defaults to 128KB)
+	MaxBatchSize   int                // Maximum number of requests in a single batch
+	RequestRate    float64            // Requests per second allowed from a single client IP
+	RequestBurst   int                // Maximum requests a client may issue at once (defaults to the rate)
+	MethodRates    map[string]float64 // Requests per second allowed from a single client IP per method (e.g. rift_getLogs), bursting up to the rate
+}
+
+// SetLimits configures the request size, batch size and rate limits enforced on
+// the HTTP and websocket clients of the server. It must be called before the
+// server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits = limits
+	s.clientLimiter, s.methodLimiters = nil, nil
+
+	if limits.RequestRate > 0 {
+		s.clientLimiter = newRateLimiter(limits.RequestRate, limits.RequestBurst)
+	}
+	if len(limits.MethodRates) > 0 {
+		s.methodLimiters = make(map[string]*rateLimiter)
+		for method, rate := range limits.MethodRates {
+			if rate > 0 {
+				s.methodLimiters[method] = newRateLimiter(rate, 0)
+			}
+		}
+	}
+}
+
+// limitCodec restricts the codec of a remote client to the configured batch size
+// and request rates, if any limit was set.
+func (s *Server) limitCodec(remoteAddr string, codec ServerCodec) ServerCodec {
+	if s.limits.MaxBatchSize == 0 && s.clientLimiter == nil && len(s.methodLimiters) == 0 {
+		return codec
+	}
+	return &limitedCodec{ServerCodec: codec, server: s, client: clientIP(remoteAddr)}
+}
+
+// clientIP strips the port from the remote address of a client, as rate limits
+// are enforced per IP address.
+func clientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// NewLimitHandler wraps a plain HTTP handler exposed next to the RPC endpoints
+// with their request size and per client request rate limits. Batch and method
+// limits don't apply, every HTTP request counts as a single request.
+func NewLimitHandler(limits Limits, handler http.Handler) http.Handler {
+	h := &limitHandler{size: limits.MaxRequestSize, next: handler}
+	if limits.RequestRate > 0 {
+		h.limiter = newRateLimiter(limits.RequestRate, limits.RequestBurst)
+	}
+	return h
+}
+
+// limitHandler is an http.Handler rejecting requests over the size or rate limits.
+type limitHandler struct {
+	size    int64        // Maximum size of a request body, zero if unlimited
+	limiter *rateLimiter // Per client request rate limiter, nil if unlimited
+	next    http.Handler
+}
+
+// ServeHTTP implements http.Handler, passing requests within the limits on to
+// the wrapped handler.
+func (h *limitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.size > 0 {
+		if r.ContentLength > h.size {
+			http.Error(w, fmt.Sprintf("content length too large (%d>%d)", r.ContentLength, h.size), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, h.size)
+	}
+	if h.limiter != nil && !h.limiter.allow(clientIP(r.RemoteAddr), time.Now()) {
+		http.Error(w, "request rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// limitedCodec is a ServerCodec rejecting the requests of a client exceeding the
+// batch size or request rate limits of the server.
+type limitedCodec struct {
+	ServerCodec
+	server *Server
+	client string // IP address of the remote client
+}
+
+// ReadRequestHeaders implements ServerCodec, marking requests over the limits
+// of the server as failed.
+func (c *limitedCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	reqs, batch, err := c.ServerCodec.ReadRequestHeaders()
+	if err != nil {
+		return reqs, batch, err
+	}
+	if limit := c.server.limits.MaxBatchSize; limit > 0 && len(reqs) > limit {
+		err := &limitExceededError{fmt.Sprintf("batch too large (%d>%d)", len(reqs), limit)}
+		for i := range reqs {
+			reqs[i].err = err
+		}
+		return reqs, batch, nil
+	}
+	now := time.Now()
+	for i := range reqs {
+		if reqs[i].err != nil {
+			continue
+		}
+		if limiter := c.server.clientLimiter; limiter != nil && !limiter.allow(c.client, now) {
+			reqs[i].err = &limitExceededError{"request rate limit exceeded"}
+			continue
+		}
+		name := requestName(&reqs[i])
+		if limiter := c.server.methodLimiters[name]; limiter != nil && !limiter.allow(c.client, now) {
+			reqs[i].err = &limitExceededError{fmt.Sprintf("request rate limit of %s exceeded", name)}
+		}
+	}
+	return reqs, batch, nil
+}
+
+// requestName returns the method name of a request as issued by the client.
+func requestName(req *rpcRequest) string {
+	switch {
+	case req.isPubSub && req.service == "":
+		return req.method // unsubscribe, the method holds the full name
+	case req.isPubSub:
+		return req.service + subscribeMethodSuffix
+	default:
+		return req.service + serviceMethodSeparator + req.method
+	}
+}
+
+// bucket is the token bucket of a single client.
+type bucket struct {
+	tokens  float64   // Requests the client may currently issue
+	updated time.Time // Time when the tokens were last refilled
+}
+
+// rateLimiter is a token bucket rate limiter tracking the requests of individual
+// clients.
+type rateLimiter struct {
+	rate    float64            // Tokens refilled per second
+	burst   float64            // Maximum number of tokens a client may accumulate
+	buckets map[string]*bucket // Token buckets of the recently active clients
+	lock    sync.Mutex
+}
+
+// newRateLimiter creates a rate limiter allowing clients the given number of
+// requests per second. If burst is zero, the rate rounded up is used.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow refills the token bucket of a client and consumes a token from it if
+// there is any available.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= limiterPruneThreshold {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune drops the buckets of all clients that would have been refilled by now,
+// as they are indistinguishable from new clients.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
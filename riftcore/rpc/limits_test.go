@@ -0,0 +1,239 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLimitedTestServer creates an HTTP RPC server with the given limits, serving
+// the test service under the "test" and "calc" namespaces.
+func newLimitedTestServer(t *testing.T, limits Limits, vhosts []string) (*Server, *httptest.Server) {
+	server := NewServer()
+	server.SetLimits(limits)
+
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("calc", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	return server, httptest.NewServer(NewHTTPServer(nil, vhosts, server).Handler)
+}
+
+// postRequest sends a raw request body to an HTTP endpoint with the given Host
+// header, returning the status code and the raw response.
+func postRequest(t *testing.T, url string, host string, body string) (int, []byte) {
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if host != "" {
+		req.Host = host
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var blob json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&blob)
+	return resp.StatusCode, blob
+}
+
+// Tests that only requests addressed to whitelisted virtual hosts or IP addresses
+// are accepted.
+func TestVirtualHosts(t *testing.T) {
+	tests := []struct {
+		vhosts []string
+		host   string
+		code   int
+	}{
+		{[]string{"localhost"}, "localhost", http.StatusOK},
+		{[]string{"localhost"}, "LocalHost:8545", http.StatusOK},
+		{[]string{"localhost"}, "127.0.0.1:8545", http.StatusOK},
+		{[]string{"localhost"}, "[::1]:8545", http.StatusOK},
+		{[]string{"localhost"}, "evil.com", http.StatusForbidden},
+		{[]string{"localhost"}, "evil.com:8545", http.StatusForbidden},
+		{[]string{"localhost", "node.example.com"}, "node.example.com", http.StatusOK},
+		{nil, "localhost", http.StatusForbidden},
+		{[]string{"*"}, "evil.com", http.StatusOK},
+	}
+	for i, tt := range tests {
+		server, httpsrv := newLimitedTestServer(t, Limits{}, tt.vhosts)
+		code, _ := postRequest(t, httpsrv.URL, tt.host, `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`)
+		if code != tt.code {
+			t.Errorf("test %d: vhosts %v, host %s: status mismatch: have %d, want %d", i, tt.vhosts, tt.host, code, tt.code)
+		}
+		httpsrv.Close()
+		server.Stop()
+	}
+}
+
+// Tests that oversized request bodies are rejected, even without a declared length.
+func TestMaxRequestSize(t *testing.T) {
+	server, httpsrv := newLimitedTestServer(t, Limits{MaxRequestSize: 128}, []string{"*"})
+	defer server.Stop()
+	defer httpsrv.Close()
+
+	if code, _ := postRequest(t, httpsrv.URL, "", `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`); code != http.StatusOK {
+		t.Errorf("small request: status mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	large := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + strings.Repeat("x", 256) + `",1,{"S":""}]}`
+	if code, _ := postRequest(t, httpsrv.URL, "", large); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large request: status mismatch: have %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+	// Chunked bodies don't declare their length, they must fail decoding
+	req, _ := http.NewRequest("POST", httpsrv.URL, struct{ *strings.Reader }{strings.NewReader(large)})
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send chunked request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result jsonErrResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Error.Code == 0 {
+		t.Errorf("chunked large request: not rejected: response %+v (err %v)", result, err)
+	}
+}
+
+// Tests that batches above the size limit are rejected with an error per request.
+func TestMaxBatchSize(t *testing.T) {
+	server, httpsrv := newLimitedTestServer(t, Limits{MaxBatchSize: 2}, []string{"*"})
+	defer server.Stop()
+	defer httpsrv.Close()
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`
+	for size, fail := range map[int]bool{1: false, 2: false, 3: true} {
+		batch := "[" + strings.Repeat(request+",", size-1) + request + "]"
+		_, blob := postRequest(t, httpsrv.URL, "", batch)
+
+		var results []jsonErrResponse
+		if err := json.Unmarshal(blob, &results); err != nil {
+			t.Fatalf("batch %d: failed to decode response: %v", size, err)
+		}
+		if len(results) != size {
+			t.Fatalf("batch %d: response count mismatch: have %d, want %d", size, len(results), size)
+		}
+		for i, result := range results {
+			if failed := result.Error.Code == (&limitExceededError{}).ErrorCode(); failed != fail {
+				t.Errorf("batch %d, request %d: failure mismatch: have %v, want %v", size, i, failed, fail)
+			}
+		}
+	}
+}
+
+// Tests that clients are throttled to the configured total and per method rates.
+func TestRateLimits(t *testing.T) {
+	limits := Limits{
+		RequestRate:  1,
+		RequestBurst: 3,
+		MethodRates:  map[string]float64{"calc_noArgsRets": 0.001},
+	}
+	server, httpsrv := newLimitedTestServer(t, limits, []string{"*"})
+	defer server.Stop()
+	defer httpsrv.Close()
+
+	call := func(method string) int {
+		_, blob := postRequest(t, httpsrv.URL, "", `{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`)
+		var result jsonErrResponse
+		if err := json.Unmarshal(blob, &result); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return result.Error.Code
+	}
+	limited := (&limitExceededError{}).ErrorCode()
+
+	// The method limit only allows a single call, the client limit the burst
+	if code := call("calc_noArgsRets"); code != 0 {
+		t.Errorf("first method call failed: code %d", code)
+	}
+	if code := call("calc_noArgsRets"); code != limited {
+		t.Errorf("second method call: code mismatch: have %d, want %d", code, limited)
+	}
+	if code := call("test_noArgsRets"); code != 0 {
+		t.Errorf("burst call failed: code %d", code)
+	}
+	if code := call("test_noArgsRets"); code != limited {
+		t.Errorf("over burst call: code mismatch: have %d, want %d", code, limited)
+	}
+}
+
+// Tests that plain HTTP handlers are throttled and size limited like the RPC
+// endpoints.
+func TestLimitHandler(t *testing.T) {
+	handler := NewLimitHandler(Limits{MaxRequestSize: 16, RequestRate: 0.001, RequestBurst: 2}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := post(strings.Repeat("x", 32)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large request: status mismatch: have %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+	if code := post("small"); code != http.StatusOK {
+		t.Errorf("first request: status mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	if code := post("small"); code != http.StatusOK {
+		t.Errorf("burst request: status mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	if code := post("small"); code != http.StatusTooManyRequests {
+		t.Errorf("over burst request: status mismatch: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+// Tests that the rate limiter refills the buckets of clients over time and forgets
+// idle clients.
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, 0)
+	start := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !limiter.allow("a", start) {
+			t.Fatalf("burst request %d denied", i)
+		}
+	}
+	if limiter.allow("a", start) {
+		t.Fatalf("over burst request allowed")
+	}
+	if !limiter.allow("b", start) {
+		t.Fatalf("other client denied")
+	}
+	if !limiter.allow("a", start.Add(500*time.Millisecond)) {
+		t.Fatalf("refilled request denied")
+	}
+	if limiter.allow("a", start.Add(500*time.Millisecond)) {
+		t.Fatalf("over refill request allowed")
+	}
+	limiter.prune(start.Add(time.Second))
+	if _, ok := limiter.buckets["a"]; !ok {
+		t.Errorf("partially refilled client pruned")
+	}
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Errorf("refilled client not pruned")
+	}
+}
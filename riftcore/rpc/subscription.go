@@ -37,7 +37,8 @@ type ID string
 type Subscription struct {
 	ID        ID
 	namespace string
-	err       chan error // closed on unsubscribe
+	err       chan error    // closed on unsubscribe
+	active    chan struct{} // closed on activation
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -45,6 +46,13 @@ func (s *Subscription) Err() <-chan error {
 	return s.err
 }
 
+// Active returns a channel that is closed when the subscription is activated,
+// i.e. notifications are not dropped any more. Long running producers that
+// must not lose their first notifications can wait on it before sending.
+func (s *Subscription) Active() <-chan struct{} {
+	return s.active
+}
+
 // notifierKey is used to store a notifier within the connection context.
 type notifierKey struct{}
 
@@ -79,7 +87,7 @@ func NotifierFromContext(ctx context.Context) (*Notifier, bool) {
 // are dropped until the subscription is marked as active. This is done
 // by the RPC server after the subscription ID is send to the client.
 func (n *Notifier) CreateSubscription() *Subscription {
-	s := &Subscription{ID: NewID(), err: make(chan error)}
+	s := &Subscription{ID: NewID(), err: make(chan error), active: make(chan struct{})}
 	n.subMu.Lock()
 	n.inactive[s.ID] = s
 	n.subMu.Unlock()
@@ -132,5 +140,6 @@ func (n *Notifier) activate(id ID, namespace string) {
 		sub.namespace = namespace
 		n.active[id] = sub
 		delete(n.inactive, id)
+		close(sub.active)
 	}
 }
@@ -79,6 +79,10 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	limits         Limits                  // Resource limits of remote clients
+	clientLimiter  *rateLimiter            // Request rate limiter of remote clients, if enabled
+	methodLimiters map[string]*rateLimiter // Per method request rate limiters of remote clients
 }
 
 // rpcRequest represents a raw incoming RPC request
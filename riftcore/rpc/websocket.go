@@ -40,7 +40,8 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			codec := srv.limitCodec(conn.Request().RemoteAddr, filterCodec(conn.Request().Context(), NewJSONCodec(conn)))
+			srv.ServeCodec(codec, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
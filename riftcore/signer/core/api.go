@@ -0,0 +1,280 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package core implements an external transaction and data signer, holding the
+// account keys in a separate process from the node and requiring every signing
+// request to be approved by a user interface or a ruleset.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/rlp"
+)
+
+// ExternalAPIVersion is the version of the external signing API, which clients
+// can use to check compatibility with the signer.
+const ExternalAPIVersion = "1.0.0"
+
+// ErrRequestDenied is returned if a signing request was rejected by the user or
+// by the ruleset.
+var ErrRequestDenied = errors.New("request denied")
+
+// ExternalAPI defines the signing API exposed to the node and other clients over
+// RPC, under the "account" namespace.
+type ExternalAPI interface {
+	// List returns the accounts the user agreed to reveal to the caller.
+	List(ctx context.Context) ([]common.Address, error)
+
+	// SignTransaction signs a transaction after it has been approved.
+	SignTransaction(ctx context.Context, args SendTxArgs) (*riftapi.SignTransactionResult, error)
+
+	// SignData signs an arbitrary message after it has been approved.
+	SignData(ctx context.Context, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error)
+
+	// Version returns the version of the external signing API.
+	Version(ctx context.Context) (string, error)
+}
+
+// SignerUI is the interface through which signing requests are presented for
+// approval, either to a human or to an automated ruleset.
+type SignerUI interface {
+	// ApproveTx prompts the user for confirmation to sign a transaction. The user
+	// may modify the transaction before approving it.
+	ApproveTx(request *SignTxRequest) (SignTxResponse, error)
+
+	// ApproveSignData prompts the user for confirmation to sign a message.
+	ApproveSignData(request *SignDataRequest) (SignDataResponse, error)
+
+	// ApproveListing prompts the user for confirmation to list accounts. The list
+	// of accounts to reveal may be narrowed down by the user.
+	ApproveListing(request *ListRequest) (ListResponse, error)
+
+	// ShowError displays an error message to the user.
+	ShowError(message string)
+
+	// ShowInfo displays an informational message to the user.
+	ShowInfo(message string)
+
+	// OnApprovedTx notifies the UI about a transaction having been signed. The
+	// UI may use it to keep track of (e.g. to rate limit) approved transfers.
+	OnApprovedTx(tx riftapi.SignTransactionResult)
+
+	// OnSignerStartup is invoked when the signer boots, telling the UI about the
+	// endpoints it is reachable on.
+	OnSignerStartup(info StartupInfo)
+}
+
+// SignTxRequest contains a transaction signing request, along with any remarks
+// the signer made when validating it.
+type SignTxRequest struct {
+	Transaction SendTxArgs         `json:"transaction"`
+	Messages    ValidationMessages `json:"messages"`
+}
+
+// SignTxResponse is the UI's answer to a transaction signing request.
+type SignTxResponse struct {
+	Transaction SendTxArgs `json:"transaction"` // Possibly modified transaction to sign
+	Approved    bool       `json:"approved"`
+	Password    string     `json:"password"` // Optional password to unlock the signing key
+}
+
+// SignDataRequest contains a message signing request, containing both the raw
+// data and the hash that will actually be signed.
+type SignDataRequest struct {
+	Address common.Address `json:"address"`
+	Rawdata hexutil.Bytes  `json:"raw_data"`
+	Message string         `json:"message"`
+	Hash    hexutil.Bytes  `json:"hash"`
+}
+
+// SignDataResponse is the UI's answer to a message signing request.
+type SignDataResponse struct {
+	Approved bool   `json:"approved"`
+	Password string `json:"password"` // Optional password to unlock the signing key
+}
+
+// ListRequest contains an account listing request.
+type ListRequest struct {
+	Accounts []accounts.Account `json:"accounts"`
+}
+
+// ListResponse is the UI's answer to an account listing request, containing the
+// subset of accounts to reveal (nil denies the request).
+type ListResponse struct {
+	Accounts []accounts.Account `json:"accounts"`
+}
+
+// StartupInfo contains the details of the running signer.
+type StartupInfo struct {
+	Info map[string]interface{} `json:"info"`
+}
+
+// SignerAPI implements ExternalAPI, signing requests with the accounts of a local
+// account manager after they have been approved by a SignerUI.
+type SignerAPI struct {
+	chainID *big.Int
+	am      *accounts.Manager
+	UI      SignerUI
+}
+
+// NewSignerAPI creates a new API that signs with the accounts held by the given
+// account manager, using the provided UI to approve all requests.
+func NewSignerAPI(am *accounts.Manager, chainID int64, ui SignerUI) *SignerAPI {
+	return &SignerAPI{
+		chainID: big.NewInt(chainID),
+		am:      am,
+		UI:      ui,
+	}
+}
+
+// List returns the set of wallet addresses the user agreed to reveal.
+func (api *SignerAPI) List(ctx context.Context) ([]common.Address, error) {
+	var accs []accounts.Account
+	for _, wallet := range api.am.Wallets() {
+		accs = append(accs, wallet.Accounts()...)
+	}
+	result, err := api.UI.ApproveListing(&ListRequest{Accounts: accs})
+	if err != nil {
+		return nil, err
+	}
+	if result.Accounts == nil {
+		return nil, ErrRequestDenied
+	}
+	addresses := make([]common.Address, 0, len(result.Accounts))
+	for _, acc := range result.Accounts {
+		addresses = append(addresses, acc.Address)
+	}
+	return addresses, nil
+}
+
+// SignTransaction signs the given transaction with the key of its sender, after
+// the request has been approved. The UI may modify the transaction, so callers
+// should use the returned one rather than the one they submitted.
+func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs) (*riftapi.SignTransactionResult, error) {
+	req := &SignTxRequest{
+		Transaction: args,
+		Messages:    validateTransaction(&args),
+	}
+	result, err := api.UI.ApproveTx(req)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the (possibly modified) sender account
+	account := accounts.Account{Address: result.Transaction.From}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the transaction, unlocking the key if a password was supplied
+	var (
+		unsigned = result.Transaction.toTransaction()
+		signed   = unsigned
+	)
+	if result.Password != "" {
+		signed, err = wallet.SignTxWithPassphrase(account, result.Password, unsigned, api.chainID)
+	} else {
+		signed, err = wallet.SignTx(account, unsigned, api.chainID)
+	}
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	response := riftapi.SignTransactionResult{Raw: data, Tx: signed}
+
+	log.Info("Signed transaction", "hash", signed.Hash(), "from", account.Address)
+	api.UI.OnApprovedTx(response)
+
+	return &response, nil
+}
+
+// SignData signs an arbitrary message with the key of the given account, after
+// the request has been approved. The signature is calculated over
+//
+//   keccak256("\x19Cryptorift Signed Message:\n" + len(message) + message)
+//
+// and, as for the node's own signing methods, its V value will be 27 or 28.
+func (api *SignerAPI) SignData(ctx context.Context, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	hash, msg := SignHash(data)
+
+	req := &SignDataRequest{
+		Address: addr,
+		Rawdata: data,
+		Message: msg,
+		Hash:    hash,
+	}
+	result, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the requested signer and sign the hash
+	account := accounts.Account{Address: addr}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if result.Password != "" {
+		signature, err = wallet.SignHashWithPassphrase(account, result.Password, hash)
+	} else {
+		signature, err = wallet.SignHash(account, hash)
+	}
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+
+	return signature, nil
+}
+
+// Version returns the version of the external signing API.
+func (api *SignerAPI) Version(ctx context.Context) (string, error) {
+	return ExternalAPIVersion, nil
+}
+
+// SignHash is a helper function that calculates a hash for the given message
+// that can be safely used to calculate a signature from. It returns both the
+// hash and the prefixed message that was hashed.
+//
+// The hash is calculated as
+//
+//   keccak256("\x19Cryptorift Signed Message:\n"${message length}${message}).
+//
+// This gives context to the signed message and prevents signing of transactions.
+func SignHash(data []byte) ([]byte, string) {
+	msg := fmt.Sprintf("\x19Cryptorift Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg)), msg
+}
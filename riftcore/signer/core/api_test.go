@@ -0,0 +1,161 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/accounts/keystore"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core/types"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+)
+
+// headlessUI is a SignerUI answering all requests with preconfigured responses.
+type headlessUI struct {
+	approve  bool
+	password string
+	signed   int
+}
+
+func (ui *headlessUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	return SignTxResponse{Transaction: request.Transaction, Approved: ui.approve, Password: ui.password}, nil
+}
+
+func (ui *headlessUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	return SignDataResponse{Approved: ui.approve, Password: ui.password}, nil
+}
+
+func (ui *headlessUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	if !ui.approve {
+		return ListResponse{}, nil
+	}
+	return ListResponse{Accounts: request.Accounts}, nil
+}
+
+func (ui *headlessUI) ShowError(message string)                      {}
+func (ui *headlessUI) ShowInfo(message string)                       {}
+func (ui *headlessUI) OnApprovedTx(tx riftapi.SignTransactionResult) { ui.signed++ }
+func (ui *headlessUI) OnSignerStartup(info StartupInfo)              {}
+
+// newTestSigner creates a signer API backed by a temporary keystore containing
+// a single account.
+func newTestSigner(t *testing.T, ui SignerUI) (*SignerAPI, accounts.Account, func()) {
+	dir, err := ioutil.TempDir("", "signer-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary keystore: %v", err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("password")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	return NewSignerAPI(accounts.NewManager(ks), 1, ui), account, func() { os.RemoveAll(dir) }
+}
+
+// Tests that accounts are only revealed if the listing is approved.
+func TestSignerList(t *testing.T) {
+	ui := &headlessUI{approve: true}
+	api, account, cleanup := newTestSigner(t, ui)
+	defer cleanup()
+
+	addresses, err := api.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+	if len(addresses) != 1 || addresses[0] != account.Address {
+		t.Fatalf("account list mismatch: have %v, want [%x]", addresses, account.Address)
+	}
+	ui.approve = false
+	if _, err := api.List(context.Background()); err != ErrRequestDenied {
+		t.Fatalf("denied listing error mismatch: have %v, want %v", err, ErrRequestDenied)
+	}
+}
+
+// Tests that transactions are only signed if approved with the right password
+// and that the produced signature is valid for the configured chain.
+func TestSignerSignTransaction(t *testing.T) {
+	ui := &headlessUI{approve: true, password: "password"}
+	api, account, cleanup := newTestSigner(t, ui)
+	defer cleanup()
+
+	to := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	args := SendTxArgs{
+		From:     account.Address,
+		To:       &to,
+		Gas:      hexutil.Big(*big.NewInt(21000)),
+		GasPrice: hexutil.Big(*big.NewInt(1)),
+		Value:    hexutil.Big(*big.NewInt(1000)),
+		Nonce:    7,
+	}
+	res, err := api.SignTransaction(context.Background(), args)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), res.Tx)
+	if err != nil || from != account.Address {
+		t.Fatalf("signer mismatch: have %x (err %v), want %x", from, err, account.Address)
+	}
+	if res.Tx.Nonce() != 7 || *res.Tx.To() != to || res.Tx.Value().Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("signed transaction mismatch: %v", res.Tx)
+	}
+	if ui.signed != 1 {
+		t.Fatalf("approved transaction notifications mismatch: have %d, want %d", ui.signed, 1)
+	}
+	// Ensure wrong passwords and denials both fail
+	ui.password = "wrong"
+	if _, err := api.SignTransaction(context.Background(), args); err != keystore.ErrDecrypt {
+		t.Fatalf("wrong password error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	ui.approve, ui.password = false, "password"
+	if _, err := api.SignTransaction(context.Background(), args); err != ErrRequestDenied {
+		t.Fatalf("denied signing error mismatch: have %v, want %v", err, ErrRequestDenied)
+	}
+}
+
+// Tests that data signatures can be used to recover the signing account.
+func TestSignerSignData(t *testing.T) {
+	ui := &headlessUI{approve: true, password: "password"}
+	api, account, cleanup := newTestSigner(t, ui)
+	defer cleanup()
+
+	data := hexutil.Bytes("hello world")
+	signature, err := api.SignData(context.Background(), account.Address, data)
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	if signature[64] != 27 && signature[64] != 28 {
+		t.Fatalf("invalid signature V value: %d", signature[64])
+	}
+	signature[64] -= 27
+
+	hash, _ := SignHash(data)
+	pubkey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		t.Fatalf("failed to recover public key: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != account.Address {
+		t.Fatalf("recovered address mismatch: have %x, want %x", addr, account.Address)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/log"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// CommandlineUI is an interactive SignerUI, prompting the user on the terminal
+// the signer was started from to approve each request.
+type CommandlineUI struct {
+	in *bufio.Reader
+	mu sync.Mutex // Serializes prompts of concurrent requests
+}
+
+// NewCommandlineUI creates an interactive UI reading from standard input.
+func NewCommandlineUI() *CommandlineUI {
+	return &CommandlineUI{in: bufio.NewReader(os.Stdin)}
+}
+
+// readString reads a single line from stdin, trimming it of whitespace.
+func (ui *CommandlineUI) readString() string {
+	for {
+		fmt.Printf("> ")
+		text, err := ui.in.ReadString('\n')
+		if err != nil {
+			log.Crit("Failed to read user input", "err", err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			return text
+		}
+	}
+}
+
+// readPassword reads a single line from stdin without echoing it to the
+// terminal. An empty password is accepted to sign with an unlocked key.
+func (ui *CommandlineUI) readPassword() string {
+	fmt.Printf("Enter password to approve (empty if the key is unlocked):\n> ")
+	text, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		log.Crit("Failed to read password", "err", err)
+	}
+	fmt.Println()
+	return string(text)
+}
+
+// confirm asks the user to approve or deny an action.
+func (ui *CommandlineUI) confirm() bool {
+	fmt.Printf("Approve? [y/N]:\n")
+	return strings.ToLower(ui.readString()) == "y"
+}
+
+// showMessages prints the validation remarks attached to a request.
+func showMessages(msgs ValidationMessages) {
+	for _, m := range msgs {
+		fmt.Printf("* %s: %s\n", m.Severity, m.Message)
+	}
+}
+
+// ApproveTx implements SignerUI, prompting the user to approve a transaction.
+func (ui *CommandlineUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	fmt.Printf("--------- Transaction request -------------\n")
+	fmt.Printf("%v\n", request.Transaction)
+	showMessages(request.Messages)
+	fmt.Printf("-------------------------------------------\n")
+
+	if !ui.confirm() {
+		return SignTxResponse{Transaction: request.Transaction, Approved: false}, nil
+	}
+	return SignTxResponse{Transaction: request.Transaction, Approved: true, Password: ui.readPassword()}, nil
+}
+
+// ApproveSignData implements SignerUI, prompting the user to approve signing a
+// message.
+func (ui *CommandlineUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	fmt.Printf("-------- Sign data request --------------\n")
+	fmt.Printf("Account:  %s\n", request.Address.Hex())
+	fmt.Printf("Message:  %q\n", request.Message)
+	fmt.Printf("Raw data: %v\n", request.Rawdata)
+	fmt.Printf("Hash:     %v\n", request.Hash)
+	fmt.Printf("-------------------------------------------\n")
+
+	if !ui.confirm() {
+		return SignDataResponse{Approved: false}, nil
+	}
+	return SignDataResponse{Approved: true, Password: ui.readPassword()}, nil
+}
+
+// ApproveListing implements SignerUI, prompting the user to reveal the accounts
+// managed by the signer.
+func (ui *CommandlineUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	fmt.Printf("-------- List account request --------------\n")
+	fmt.Printf("A request has been made to list all accounts:\n")
+	for _, account := range request.Accounts {
+		fmt.Printf("  [x] %s (%s)\n", account.Address.Hex(), account.URL)
+	}
+	fmt.Printf("-------------------------------------------\n")
+
+	if !ui.confirm() {
+		return ListResponse{Accounts: nil}, nil
+	}
+	return ListResponse{Accounts: request.Accounts}, nil
+}
+
+// ShowError implements SignerUI, printing an error message.
+func (ui *CommandlineUI) ShowError(message string) {
+	fmt.Printf("ERROR: %v\n", message)
+}
+
+// ShowInfo implements SignerUI, printing an informational message.
+func (ui *CommandlineUI) ShowInfo(message string) {
+	fmt.Printf("Info: %v\n", message)
+}
+
+// OnApprovedTx implements SignerUI, printing the signed transaction.
+func (ui *CommandlineUI) OnApprovedTx(tx riftapi.SignTransactionResult) {
+	fmt.Printf("Transaction signed:\n%v\n", tx.Tx)
+}
+
+// OnSignerStartup implements SignerUI, printing the signer's endpoints.
+func (ui *CommandlineUI) OnSignerStartup(info StartupInfo) {
+	fmt.Printf("------- Signer info -------\n")
+	for k, v := range info.Info {
+		fmt.Printf("* %v : %v\n", k, v)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/core/types"
+)
+
+// SendTxArgs represents the arguments to sign a transaction. Opposed to the
+// node's own transaction arguments, all fields apart from the recipient and the
+// payload are mandatory, as the signer has no access to the chain to fill them.
+type SendTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Big     `json:"gas"`
+	GasPrice hexutil.Big     `json:"gasPrice"`
+	Value    hexutil.Big     `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+}
+
+// String implements fmt.Stringer, rendering the transaction arguments in a human
+// readable form for approval prompts.
+func (args SendTxArgs) String() string {
+	to := "<contract creation>"
+	if args.To != nil {
+		to = args.To.Hex()
+	}
+	return fmt.Sprintf("from: %s\nto: %s\nvalue: %v wei\ngas: %v\ngasprice: %v wei\nnonce: %d\ndata: %x",
+		args.From.Hex(), to, args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), uint64(args.Nonce), []byte(args.Data))
+}
+
+// toTransaction converts the arguments into an unsigned transaction.
+func (args *SendTxArgs) toTransaction() *types.Transaction {
+	var (
+		value    = new(big.Int).Set(args.Value.ToInt())
+		gas      = new(big.Int).Set(args.Gas.ToInt())
+		gasPrice = new(big.Int).Set(args.GasPrice.ToInt())
+	)
+	if args.To == nil {
+		return types.NewContractCreation(uint64(args.Nonce), value, gas, gasPrice, args.Data)
+	}
+	return types.NewTransaction(uint64(args.Nonce), *args.To, value, gas, gasPrice, args.Data)
+}
+
+// Severity levels of the validation messages attached to signing requests.
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityWarning  = "WARNING"
+	SeverityInfo     = "INFO"
+)
+
+// ValidationInfo is a single remark the signer made about a request, which the
+// user (or the rule engine) should take into account before approving it.
+type ValidationInfo struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationMessages is a collection of remarks about a signing request.
+type ValidationMessages []ValidationInfo
+
+func (vs *ValidationMessages) crit(msg string) {
+	*vs = append(*vs, ValidationInfo{SeverityCritical, msg})
+}
+
+func (vs *ValidationMessages) warn(msg string) {
+	*vs = append(*vs, ValidationInfo{SeverityWarning, msg})
+}
+
+func (vs *ValidationMessages) info(msg string) {
+	*vs = append(*vs, ValidationInfo{SeverityInfo, msg})
+}
+
+// validateTransaction inspects a transaction signing request for suspicious
+// parameters that the user should be made aware of.
+func validateTransaction(args *SendTxArgs) ValidationMessages {
+	var msgs ValidationMessages
+
+	if args.To == nil {
+		if len(args.Data) == 0 {
+			msgs.crit("Transaction will create a contract with empty code")
+		} else {
+			msgs.info("Transaction will create a contract")
+		}
+	} else {
+		if *args.To == (common.Address{}) {
+			msgs.crit("Transaction recipient is the zero address")
+		}
+		if len(args.Data) > 0 && len(args.Data) < 4 {
+			msgs.warn("Transaction data is not a valid method invocation (too short)")
+		}
+	}
+	if args.Gas.ToInt().Sign() == 0 {
+		msgs.crit("Transaction has no gas allowance")
+	}
+	if args.GasPrice.ToInt().Sign() == 0 {
+		msgs.warn("Transaction gas price is zero, it will likely not be mined")
+	}
+	return msgs
+}
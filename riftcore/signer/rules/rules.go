@@ -0,0 +1,194 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package rules implements a scriptable signer UI, deciding on signing requests
+// with a JavaScript ruleset and deferring undecided requests to another UI.
+//
+// A ruleset may define any of the following functions, each receiving the JSON
+// representation of the request and returning "Approve" or "Reject". Any other
+// return value (or a missing function) passes the request on to the next UI.
+//
+//   ApproveTx(request)
+//   ApproveSignData(request)
+//   ApproveListing(request)
+//
+// The bignumber.js library is available to rules as BigNumber, allowing numeric
+// checks on transaction values, e.g. spending limits. Quantities are passed in
+// their 0x prefixed hexadecimal JSON encoding, which has to be stripped before
+// parsing, e.g. new BigNumber(tx.value.slice(2), 16).
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cryptorift/riftcore/internal/jsre/deps"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/signer/core"
+	"github.com/robertkrimen/otto"
+)
+
+var (
+	// BigNumber_JS is the bignumber.js library preloaded into every rule VM.
+	BigNumber_JS = deps.MustAsset("bignumber.js")
+
+	// errRejected is returned if a request was explicitly rejected by the rules.
+	errRejected = errors.New("request rejected by rules")
+
+	// errUndecided is returned if the rules did not decide on a request.
+	errUndecided = errors.New("request undecided by rules")
+)
+
+// rulesetUI is a core.SignerUI that consults a JavaScript ruleset on all signing
+// requests, deferring any request the rules don't decide upon to the next UI.
+type rulesetUI struct {
+	next  core.SignerUI // UI to forward undecided requests to
+	rules string        // JavaScript source of the ruleset
+}
+
+// NewRuleEvaluator creates a rule based UI from the given JavaScript ruleset,
+// falling back to the next UI for undecided requests.
+func NewRuleEvaluator(next core.SignerUI, rules string) (core.SignerUI, error) {
+	ui := &rulesetUI{
+		next:  next,
+		rules: rules,
+	}
+	// Ensure the ruleset is at least syntactically valid
+	if _, err := ui.newVM(); err != nil {
+		return nil, err
+	}
+	return ui, nil
+}
+
+// newVM creates a fresh JavaScript VM with the ruleset loaded. Every request is
+// evaluated in a new VM so that rules cannot accumulate state between requests.
+func (r *rulesetUI) newVM() (*otto.Otto, error) {
+	vm := otto.New()
+
+	// Route console.log calls from the rules into the signer log
+	logger, _ := vm.Object("console = {}")
+	logger.Set("log", func(call otto.FunctionCall) otto.Value {
+		args := make([]interface{}, 0, len(call.ArgumentList))
+		for _, arg := range call.ArgumentList {
+			args = append(args, arg.String())
+		}
+		log.Info("Ruleset output", "msg", fmt.Sprint(args...))
+		return otto.UndefinedValue()
+	})
+	if _, err := vm.Run(string(BigNumber_JS)); err != nil {
+		return nil, fmt.Errorf("failed to load bignumber.js: %v", err)
+	}
+	if _, err := vm.Run(r.rules); err != nil {
+		return nil, fmt.Errorf("failed to load ruleset: %v", err)
+	}
+	return vm, nil
+}
+
+// checkApproval evaluates the named rule function with the given request,
+// returning whether the request was approved, rejected or left undecided.
+func (r *rulesetUI) checkApproval(rule string, request interface{}) (bool, error) {
+	vm, err := r.newVM()
+	if err != nil {
+		return false, err
+	}
+	if fn, err := vm.Get(rule); err != nil || !fn.IsFunction() {
+		return false, errUndecided
+	}
+	blob, err := json.Marshal(request)
+	if err != nil {
+		return false, err
+	}
+	result, err := vm.Run(fmt.Sprintf("%s(%s)", rule, blob))
+	if err != nil {
+		log.Warn("Ruleset evaluation failed", "rule", rule, "err", err)
+		return false, errUndecided
+	}
+	switch decision, _ := result.ToString(); decision {
+	case "Approve":
+		log.Info("Request approved by rules", "rule", rule)
+		return true, nil
+	case "Reject":
+		log.Info("Request rejected by rules", "rule", rule)
+		return false, errRejected
+	default:
+		return false, errUndecided
+	}
+}
+
+// ApproveTx implements core.SignerUI, deciding on a transaction signing request.
+func (r *rulesetUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	approved, err := r.checkApproval("ApproveTx", request)
+	switch err {
+	case nil:
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: approved}, nil
+	case errRejected:
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: false}, nil
+	}
+	return r.next.ApproveTx(request)
+}
+
+// ApproveSignData implements core.SignerUI, deciding on a message signing request.
+func (r *rulesetUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	approved, err := r.checkApproval("ApproveSignData", request)
+	switch err {
+	case nil:
+		return core.SignDataResponse{Approved: approved}, nil
+	case errRejected:
+		return core.SignDataResponse{Approved: false}, nil
+	}
+	return r.next.ApproveSignData(request)
+}
+
+// ApproveListing implements core.SignerUI, deciding on an account listing request.
+func (r *rulesetUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	approved, err := r.checkApproval("ApproveListing", request)
+	switch err {
+	case nil:
+		if approved {
+			return core.ListResponse{Accounts: request.Accounts}, nil
+		}
+		return core.ListResponse{}, nil
+	case errRejected:
+		return core.ListResponse{}, nil
+	}
+	return r.next.ApproveListing(request)
+}
+
+// ShowError implements core.SignerUI, forwarding the message to the next UI.
+func (r *rulesetUI) ShowError(message string) {
+	log.Error(message)
+	r.next.ShowError(message)
+}
+
+// ShowInfo implements core.SignerUI, forwarding the message to the next UI.
+func (r *rulesetUI) ShowInfo(message string) {
+	log.Info(message)
+	r.next.ShowInfo(message)
+}
+
+// OnApprovedTx implements core.SignerUI, forwarding the notification to the
+// next UI.
+func (r *rulesetUI) OnApprovedTx(tx riftapi.SignTransactionResult) {
+	r.next.OnApprovedTx(tx)
+}
+
+// OnSignerStartup implements core.SignerUI, forwarding the notification to the
+// next UI.
+func (r *rulesetUI) OnSignerStartup(info core.StartupInfo) {
+	r.next.OnSignerStartup(info)
+}
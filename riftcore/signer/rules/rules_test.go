@@ -0,0 +1,142 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptorift/riftcore/accounts"
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/common/hexutil"
+	"github.com/cryptorift/riftcore/internal/riftapi"
+	"github.com/cryptorift/riftcore/signer/core"
+)
+
+// forwardedUI is a core.SignerUI counting the requests forwarded to it, denying
+// all of them.
+type forwardedUI struct {
+	forwarded int
+}
+
+func (ui *forwardedUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	ui.forwarded++
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: false}, nil
+}
+
+func (ui *forwardedUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	ui.forwarded++
+	return core.SignDataResponse{Approved: false}, nil
+}
+
+func (ui *forwardedUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	ui.forwarded++
+	return core.ListResponse{}, nil
+}
+
+func (ui *forwardedUI) ShowError(message string)                      {}
+func (ui *forwardedUI) ShowInfo(message string)                       {}
+func (ui *forwardedUI) OnApprovedTx(tx riftapi.SignTransactionResult) {}
+func (ui *forwardedUI) OnSignerStartup(info core.StartupInfo)         {}
+
+// testRuleset approves small transfers to a whitelisted recipient, rejects all
+// contract creations and leaves everything else to the user.
+const testRuleset = `
+var whitelist = ["0x0000000000000000000000000000000000001337"];
+
+function ApproveTx(req) {
+	var tx = req.transaction;
+	if (tx.to === null) {
+		return "Reject";
+	}
+	var limit = new BigNumber("1000000000000000000");
+	if (whitelist.indexOf(tx.to.toLowerCase()) >= 0 && new BigNumber(tx.value.slice(2), 16).lte(limit)) {
+		return "Approve";
+	}
+}
+
+function ApproveListing(req) {
+	return "Approve";
+}
+`
+
+// Tests that the ruleset approves and rejects transactions based on the recipient
+// and value, forwarding undecided requests to the next UI.
+func TestRulesetApproveTx(t *testing.T) {
+	next := new(forwardedUI)
+	ui, err := NewRuleEvaluator(next, testRuleset)
+	if err != nil {
+		t.Fatalf("failed to create rule evaluator: %v", err)
+	}
+	var (
+		whitelisted = common.HexToAddress("0x0000000000000000000000000000000000001337")
+		stranger    = common.HexToAddress("0x000000000000000000000000000000000000dead")
+		ether       = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	)
+	tests := []struct {
+		to        *common.Address
+		value     *big.Int
+		approved  bool
+		forwarded int
+	}{
+		{&whitelisted, big.NewInt(1), true, 0},                         // Small transfer to whitelisted recipient
+		{&whitelisted, ether, true, 0},                                 // Transfer at the value limit
+		{&whitelisted, new(big.Int).Add(ether, common.Big1), false, 1}, // Transfer over the value limit
+		{&stranger, big.NewInt(1), false, 2},                           // Transfer to unknown recipient
+		{nil, big.NewInt(0), false, 2},                                 // Contract creation, rejected outright
+	}
+	for i, tt := range tests {
+		req := &core.SignTxRequest{
+			Transaction: core.SendTxArgs{
+				To:       tt.to,
+				Gas:      hexutil.Big(*big.NewInt(21000)),
+				GasPrice: hexutil.Big(*big.NewInt(1)),
+				Value:    hexutil.Big(*tt.value),
+			},
+		}
+		res, err := ui.ApproveTx(req)
+		if err != nil {
+			t.Fatalf("test %d: failed to evaluate request: %v", i, err)
+		}
+		if res.Approved != tt.approved {
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, res.Approved, tt.approved)
+		}
+		if next.forwarded != tt.forwarded {
+			t.Errorf("test %d: forwarded requests mismatch: have %d, want %d", i, next.forwarded, tt.forwarded)
+		}
+	}
+}
+
+// Tests that requests without a matching rule are forwarded to the next UI and
+// that invalid rulesets are rejected upfront.
+func TestRulesetUndefined(t *testing.T) {
+	next := new(forwardedUI)
+	ui, err := NewRuleEvaluator(next, testRuleset)
+	if err != nil {
+		t.Fatalf("failed to create rule evaluator: %v", err)
+	}
+	listing := &core.ListRequest{Accounts: []accounts.Account{{Address: common.HexToAddress("0x1337")}}}
+	if res, err := ui.ApproveListing(listing); err != nil || len(res.Accounts) != 1 || next.forwarded != 0 {
+		t.Errorf("listing not approved by rules: res %v, err %v, forwarded %d", res, err, next.forwarded)
+	}
+	if res, err := ui.ApproveSignData(&core.SignDataRequest{}); err != nil || res.Approved || next.forwarded != 1 {
+		t.Errorf("data signing not forwarded: res %v, err %v, forwarded %d", res, err, next.forwarded)
+	}
+	if _, err := NewRuleEvaluator(next, "function ApproveTx(req) {"); err == nil {
+		t.Errorf("invalid ruleset accepted")
+	}
+}
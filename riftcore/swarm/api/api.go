@@ -64,9 +64,9 @@ func NewApi(dpa *storage.DPA, dns Resolver) (self *Api) {
 }
 
 // to be used only in TEST
-func (self *Api) Upload(uploadDir, index string) (hash string, err error) {
+func (self *Api) Upload(uploadDir, index string, toEncrypt bool) (hash string, err error) {
 	fs := NewFileSystem(self)
-	hash, err = fs.Upload(uploadDir, index)
+	hash, err = fs.Upload(uploadDir, index, toEncrypt)
 	return hash, err
 }
 
@@ -75,8 +75,8 @@ func (self *Api) Retrieve(key storage.Key) storage.LazySectionReader {
 	return self.dpa.Retrieve(key)
 }
 
-func (self *Api) Store(data io.Reader, size int64, wg *sync.WaitGroup) (key storage.Key, err error) {
-	return self.dpa.Store(data, size, wg, nil)
+func (self *Api) Store(data io.Reader, size int64, wg *sync.WaitGroup, toEncrypt bool) (key storage.Key, err error) {
+	return self.dpa.Store(data, size, wg, nil, toEncrypt)
 }
 
 type ErrResolve error
@@ -113,16 +113,17 @@ func (self *Api) Resolve(uri *URI) (storage.Key, error) {
 }
 
 // Put provides singleton manifest creation on top of dpa store
-func (self *Api) Put(content, contentType string) (storage.Key, error) {
+// if toEncrypt is set, both the content and the manifest are encrypted
+func (self *Api) Put(content, contentType string, toEncrypt bool) (storage.Key, error) {
 	r := strings.NewReader(content)
 	wg := &sync.WaitGroup{}
-	key, err := self.dpa.Store(r, int64(len(content)), wg, nil)
+	key, err := self.dpa.Store(r, int64(len(content)), wg, nil, toEncrypt)
 	if err != nil {
 		return nil, err
 	}
 	manifest := fmt.Sprintf(`{"entries":[{"hash":"%v","contentType":"%s"}]}`, key, contentType)
 	r = strings.NewReader(manifest)
-	key, err = self.dpa.Store(r, int64(len(manifest)), wg, nil)
+	key, err = self.dpa.Store(r, int64(len(manifest)), wg, nil, toEncrypt)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +110,7 @@ func TestApiPut(t *testing.T) {
 		content := "hello"
 		exp := expResponse(content, "text/plain", 0)
 		// exp := expResponse([]byte(content), "text/plain", 0)
-		key, err := api.Put(content, exp.MimeType)
+		key, err := api.Put(content, exp.MimeType, false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -119,6 +119,22 @@ func TestApiPut(t *testing.T) {
 	})
 }
 
+func TestApiPutEncrypted(t *testing.T) {
+	testApi(t, func(api *Api) {
+		content := "hello"
+		exp := expResponse(content, "text/plain", 0)
+		key, err := api.Put(content, exp.MimeType, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !storage.IsEncryptedKey(key) {
+			t.Fatalf("expected encrypted manifest key, got %v", key)
+		}
+		resp := testGet(t, api, key.String(), "")
+		checkResponse(t, resp, exp)
+	})
+}
+
 // testResolver implements the Resolver interface and either returns the given
 // hash if it is set, or returns a "name not found" error
 type testResolver struct {
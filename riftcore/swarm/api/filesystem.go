@@ -42,11 +42,12 @@ func NewFileSystem(api *Api) *FileSystem {
 }
 
 // Upload replicates a local directory as a manifest file and uploads it
-// using dpa store
+// using dpa store, encrypting both the files and the manifest if toEncrypt
+// is set
 // TODO: localpath should point to a manifest
 //
 // DEPRECATED: Use the HTTP API instead
-func (self *FileSystem) Upload(lpath, index string) (string, error) {
+func (self *FileSystem) Upload(lpath, index string, toEncrypt bool) (string, error) {
 	var list []*manifestTrieEntry
 	localpath, err := filepath.Abs(filepath.Clean(lpath))
 	if err != nil {
@@ -113,7 +114,7 @@ func (self *FileSystem) Upload(lpath, index string) (string, error) {
 				stat, _ := f.Stat()
 				var hash storage.Key
 				wg := &sync.WaitGroup{}
-				hash, err = self.api.dpa.Store(f, stat.Size(), wg, nil)
+				hash, err = self.api.dpa.Store(f, stat.Size(), wg, nil, toEncrypt)
 				if hash != nil {
 					list[i].Hash = hash.String()
 				}
@@ -142,7 +143,8 @@ func (self *FileSystem) Upload(lpath, index string) (string, error) {
 	}
 
 	trie := &manifestTrie{
-		dpa: self.api.dpa,
+		dpa:     self.api.dpa,
+		encrypt: toEncrypt,
 	}
 	quitC := make(chan bool)
 	for i, entry := range list {
@@ -49,7 +49,7 @@ func readPath(t *testing.T, parts ...string) string {
 func TestApiDirUpload0(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem) {
 		api := fs.api
-		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "")
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "", false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -75,7 +75,7 @@ func TestApiDirUpload0(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		newbzzhash, err := fs.Upload(downloadDir, "")
+		newbzzhash, err := fs.Upload(downloadDir, "", false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -85,10 +85,51 @@ func TestApiDirUpload0(t *testing.T) {
 	})
 }
 
+func TestApiDirUploadEncrypted(t *testing.T) {
+	testFileSystem(t, func(fs *FileSystem) {
+		api := fs.api
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "", true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !storage.IsEncryptedKey(common.Hex2Bytes(bzzhash)) {
+			t.Fatalf("expected encrypted manifest key, got %v", bzzhash)
+		}
+		content := readPath(t, "testdata", "test0", "index.html")
+		resp := testGet(t, api, bzzhash, "index.html")
+		exp := expResponse(content, "text/html; charset=utf-8", 0)
+		checkResponse(t, resp, exp)
+
+		content = readPath(t, "testdata", "test0", "img", "logo.png")
+		resp = testGet(t, api, bzzhash, "img/logo.png")
+		exp = expResponse(content, "image/png", 0)
+		checkResponse(t, resp, exp)
+
+		// all entries, including the sub-manifests, must be encrypted
+		trie, err := loadManifest(api.dpa, common.Hex2Bytes(bzzhash), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, entry := range trie.entries {
+			if entry != nil && !storage.IsEncryptedKey(common.Hex2Bytes(entry.Hash)) {
+				t.Errorf("manifest entry %s not encrypted: %s", entry.Path, entry.Hash)
+			}
+		}
+		err = trie.listWithPrefix("", nil, func(entry *manifestTrieEntry, suffix string) {
+			if !storage.IsEncryptedKey(common.Hex2Bytes(entry.Hash)) {
+				t.Errorf("entry %s not encrypted: %s", suffix, entry.Hash)
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
 func TestApiDirUploadModify(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem) {
 		api := fs.api
-		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "")
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -106,7 +147,7 @@ func TestApiDirUploadModify(t *testing.T) {
 			return
 		}
 		wg := &sync.WaitGroup{}
-		hash, err := api.Store(bytes.NewReader(index), int64(len(index)), wg)
+		hash, err := api.Store(bytes.NewReader(index), int64(len(index)), wg, false)
 		wg.Wait()
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -148,7 +189,7 @@ func TestApiDirUploadModify(t *testing.T) {
 func TestApiDirUploadWithRootFile(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem) {
 		api := fs.api
-		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "index.html")
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "index.html", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -164,7 +205,7 @@ func TestApiDirUploadWithRootFile(t *testing.T) {
 func TestApiFileUpload(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem) {
 		api := fs.api
-		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0", "index.html"), "")
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0", "index.html"), "", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -180,7 +221,7 @@ func TestApiFileUpload(t *testing.T) {
 func TestApiFileUploadWithRootFile(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem) {
 		api := fs.api
-		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0", "index.html"), "index.html")
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0", "index.html"), "index.html", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
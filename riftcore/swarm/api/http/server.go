@@ -42,6 +42,9 @@ import (
 	"github.com/rs/cors"
 )
 
+// encryptAddr is the address to POST content to for storing it encrypted
+const encryptAddr = "encrypt"
+
 // ServerConfig is the basic configuration needed for the HTTP server and also
 // includes CORS settings.
 type ServerConfig struct {
@@ -88,18 +91,21 @@ type Request struct {
 
 // HandlePostRaw handles a POST request to a raw bzzr:/ URI, stores the request
 // body in swarm and returns the resulting storage key as a text/plain response
+// Posting to bzzr:/encrypt stores the body encrypted, in which case the key
+// also contains the decryption key
 func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 	if r.uri.Path != "" {
 		s.BadRequest(w, r, "raw POST request cannot contain a path")
 		return
 	}
+	toEncrypt := r.uri.Addr == encryptAddr
 
 	if r.Header.Get("Content-Length") == "" {
 		s.BadRequest(w, r, "missing Content-Length header in request")
 		return
 	}
 
-	key, err := s.api.Store(r.Body, r.ContentLength, nil)
+	key, err := s.api.Store(r.Body, r.ContentLength, nil, toEncrypt)
 	if err != nil {
 		s.Error(w, r, err)
 		return
@@ -116,6 +122,8 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 // (either a tar archive or multipart form), adds those files either to an
 // existing manifest or to a new manifest under <path> and returns the
 // resulting manifest hash as a text/plain response
+// Posting to bzz:/encrypt/<path> creates a new encrypted manifest, files added
+// to an existing encrypted manifest are always encrypted
 func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
@@ -124,14 +132,14 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	}
 
 	var key storage.Key
-	if r.uri.Addr != "" {
+	if r.uri.Addr != "" && r.uri.Addr != encryptAddr {
 		key, err = s.api.Resolve(r.uri)
 		if err != nil {
 			s.Error(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 			return
 		}
 	} else {
-		key, err = s.api.NewManifest()
+		key, err = s.api.NewManifest(r.uri.Addr == encryptAddr)
 		if err != nil {
 			s.Error(w, r, err)
 			return
@@ -56,14 +56,15 @@ type ManifestList struct {
 	Entries        []*ManifestEntry `json:"entries,omitempty"`
 }
 
-// NewManifest creates and stores a new, empty manifest
-func (a *Api) NewManifest() (storage.Key, error) {
+// NewManifest creates and stores a new, empty manifest, which is encrypted
+// along with everything added to it if toEncrypt is set
+func (a *Api) NewManifest(toEncrypt bool) (storage.Key, error) {
 	var manifest Manifest
 	data, err := json.Marshal(&manifest)
 	if err != nil {
 		return nil, err
 	}
-	return a.Store(bytes.NewReader(data), int64(len(data)), nil)
+	return a.Store(bytes.NewReader(data), int64(len(data)), nil, toEncrypt)
 }
 
 // ManifestWriter is used to add and remove entries from an underlying manifest
@@ -82,8 +83,9 @@ func (a *Api) NewManifestWriter(key storage.Key, quitC chan bool) (*ManifestWrit
 }
 
 // AddEntry stores the given data and adds the resulting key to the manifest
+// the data is encrypted if the manifest is
 func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key, error) {
-	key, err := m.api.Store(data, e.Size, nil)
+	key, err := m.api.Store(data, e.Size, nil, m.trie.encrypt)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +166,7 @@ type manifestTrie struct {
 	dpa     *storage.DPA
 	entries [257]*manifestTrieEntry // indexed by first character of basePath, entries[256] is the empty basePath entry
 	hash    storage.Key             // if hash != nil, it is stored
+	encrypt bool                    // if set, the trie is stored encrypted
 }
 
 func newManifestTrieEntry(entry *ManifestEntry, subtrie *manifestTrie) *manifestTrieEntry {
@@ -220,7 +223,8 @@ func readManifest(manifestReader storage.LazySectionReader, hash storage.Key, dp
 	log.Trace(fmt.Sprintf("Manifest %v has %d entries.", hash.Log(), len(man.Entries)))
 
 	trie = &manifestTrie{
-		dpa: dpa,
+		dpa:     dpa,
+		encrypt: storage.IsEncryptedKey(hash),
 	}
 	for _, entry := range man.Entries {
 		trie.addEntry(entry, quitC)
@@ -261,7 +265,8 @@ func (self *manifestTrie) addEntry(entry *manifestTrieEntry, quitC chan bool) {
 	commonPrefix := entry.Path[:cpl]
 
 	subtrie := &manifestTrie{
-		dpa: self.dpa,
+		dpa:     self.dpa,
+		encrypt: self.encrypt,
 	}
 	entry.Path = entry.Path[cpl:]
 	oldentry.Path = oldentry.Path[cpl:]
@@ -350,7 +355,7 @@ func (self *manifestTrie) recalcAndStore() error {
 
 	sr := bytes.NewReader(manifest)
 	wg := &sync.WaitGroup{}
-	key, err2 := self.dpa.Store(sr, int64(len(manifest)), wg, nil)
+	key, err2 := self.dpa.Store(sr, int64(len(manifest)), wg, nil, self.encrypt)
 	wg.Wait()
 	self.hash = key
 	return err2
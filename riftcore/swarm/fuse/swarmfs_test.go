@@ -62,7 +62,7 @@ func createTestFilesAndUploadToSwarm(t *testing.T, api *api.Api, files map[strin
 		fd.Close()
 	}
 
-	bzzhash, err := api.Upload(uploadDir, "")
+	bzzhash, err := api.Upload(uploadDir, "", false)
 	if err != nil {
 		t.Fatalf("Error uploading directory %v: %v", uploadDir, err)
 	}
@@ -48,6 +48,10 @@ data_{i} := size(subtree_{i}) || key_{j} || key_{j+1} .... || key_{j+n-1}
   key = hash(int64(size) + key(slice0) + key(slice1) + ...)
 
  The underlying hash function is configurable
+
+6 if content is encrypted, every chunk is encrypted with its own key before
+  hashing and the keys referencing it are extended with the decryption key
+  (see encryption.go), reducing the number of branches accordingly
 */
 
 const (
@@ -83,8 +87,10 @@ type TreeChunker struct {
 	hashFunc Hasher
 	// calculated
 	hashSize    int64 // self.hashFunc.New().Size()
+	refSize     int64 // hashSize, plus the key size if encrypting
 	chunkSize   int64 // hashSize* branches
 	workerCount int
+	encrypt     bool
 }
 
 func NewTreeChunker(params *ChunkerParams) (self *TreeChunker) {
@@ -92,11 +98,27 @@ func NewTreeChunker(params *ChunkerParams) (self *TreeChunker) {
 	self.hashFunc = MakeHashFunc(params.Hash)
 	self.branches = params.Branches
 	self.hashSize = int64(self.hashFunc().Size())
+	self.refSize = self.hashSize
 	self.chunkSize = self.hashSize * self.branches
 	self.workerCount = 1
 	return
 }
 
+// encrypted returns a copy of the chunker which encrypts the chunks it
+// splits and decrypts the ones it joins. Chunks keep their size, but fit
+// fewer of the longer encrypted references.
+func (self *TreeChunker) encrypted() *TreeChunker {
+	return &TreeChunker{
+		branches:    self.chunkSize / (self.hashSize + encryptionKeySize),
+		hashFunc:    self.hashFunc,
+		hashSize:    self.hashSize,
+		refSize:     self.hashSize + encryptionKeySize,
+		chunkSize:   self.chunkSize,
+		workerCount: 1,
+		encrypt:     true,
+	}
+}
+
 // func (self *TreeChunker) KeySize() int64 {
 // 	return self.hashSize
 // }
@@ -113,11 +135,14 @@ type hashJob struct {
 	parentWg *sync.WaitGroup
 }
 
-func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
+func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup, toEncrypt bool) (Key, error) {
 
 	if self.chunkSize <= 0 {
 		panic("chunker must be initialised")
 	}
+	if toEncrypt && !self.encrypt {
+		return self.encrypted().Split(data, size, chunkC, swg, wwg, true)
+	}
 
 	jobC := make(chan *hashJob, 2*processors)
 	wg := &sync.WaitGroup{}
@@ -139,7 +164,7 @@ func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, s
 		depth++
 	}
 
-	key := make([]byte, self.hashFunc().Size())
+	key := make([]byte, self.refSize)
 	// this waitgroup member is released after the root hash is calculated
 	wg.Add(1)
 	//launch actual recursive function passing the waitgroups
@@ -197,7 +222,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 	// intermediate chunk containing child nodes hashes
 	branchCnt := int64((size + treeSize - 1) / treeSize)
 
-	var chunk []byte = make([]byte, branchCnt*self.hashSize+8)
+	var chunk []byte = make([]byte, branchCnt*self.refSize+8)
 	var pos, i int64
 
 	binary.LittleEndian.PutUint64(chunk[0:8], uint64(size))
@@ -212,7 +237,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 			secSize = treeSize
 		}
 		// the hash of that data
-		subTreeKey := chunk[8+i*self.hashSize : 8+(i+1)*self.hashSize]
+		subTreeKey := chunk[8+i*self.refSize : 8+(i+1)*self.refSize]
 
 		childrenWg.Add(1)
 		self.split(depth-1, treeSize/self.branches, subTreeKey, data, secSize, jobC, chunkC, errC, quitC, childrenWg, swg, wwg)
@@ -251,7 +276,13 @@ func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC
 			}
 			// now we got the hashes in the chunk, then hash the chunks
 			hasher.Reset()
-			self.hashChunk(hasher, job, chunkC, swg)
+			if err := self.hashChunk(hasher, job, chunkC, swg); err != nil {
+				select {
+				case errC <- err:
+				case <-quitC:
+				}
+				return
+			}
 		case <-quitC:
 			return
 		}
@@ -261,18 +292,29 @@ func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC
 // The treeChunkers own Hash hashes together
 // - the size (of the subtree encoded in the Chunk)
 // - the Chunk, ie. the contents read from the input reader
-func (self *TreeChunker) hashChunk(hasher hash.Hash, job *hashJob, chunkC chan *Chunk, swg *sync.WaitGroup) {
-	hasher.Write(job.chunk)
+// If encrypting, the ciphertext is hashed instead and the key is appended to
+// the hash reported to the parent.
+func (self *TreeChunker) hashChunk(hasher hash.Hash, job *hashJob, chunkC chan *Chunk, swg *sync.WaitGroup) error {
+	data := job.chunk
+	var encKey []byte
+	if self.encrypt {
+		var err error
+		if data, encKey, err = encryptChunkData(job.chunk, self.chunkSize+8); err != nil {
+			return err
+		}
+	}
+	hasher.Write(data)
 	h := hasher.Sum(nil)
 	newChunk := &Chunk{
 		Key:   h,
-		SData: job.chunk,
+		SData: data,
 		Size:  job.size,
 		wg:    swg,
 	}
 
 	// report hash of this chunk one level up (keys corresponds to the proper subslice of the parent chunk)
 	copy(job.key, h)
+	copy(job.key[self.hashSize:], encKey)
 	// send off new chunk to storage
 	if chunkC != nil {
 		if swg != nil {
@@ -284,6 +326,7 @@ func (self *TreeChunker) hashChunk(hasher hash.Hash, job *hashJob, chunkC chan *
 	if chunkC != nil {
 		chunkC <- newChunk
 	}
+	return nil
 }
 
 // LazyChunkReader implements LazySectionReader
@@ -295,17 +338,24 @@ type LazyChunkReader struct {
 	chunkSize int64       // inherit from chunker
 	branches  int64       // inherit from chunker
 	hashSize  int64       // inherit from chunker
+	refSize   int64       // inherit from chunker
+	decrypt   bool        // inherit from chunker
 }
 
 // implements the Joiner interface
+// encrypted content is recognised by the length of its key
 func (self *TreeChunker) Join(key Key, chunkC chan *Chunk) LazySectionReader {
-
+	if int64(len(key)) == self.hashSize+encryptionKeySize && !self.encrypt {
+		return self.encrypted().Join(key, chunkC)
+	}
 	return &LazyChunkReader{
 		key:       key,
 		chunkC:    chunkC,
 		chunkSize: self.chunkSize,
 		branches:  self.branches,
 		hashSize:  self.hashSize,
+		refSize:   self.refSize,
+		decrypt:   self.encrypt,
 	}
 }
 
@@ -314,7 +364,7 @@ func (self *LazyChunkReader) Size(quitC chan bool) (n int64, err error) {
 	if self.chunk != nil {
 		return self.chunk.Size, nil
 	}
-	chunk := retrieve(self.key, self.chunkC, quitC)
+	chunk := self.retrieve(self.key, quitC)
 	if chunk == nil {
 		select {
 		case <-quitC:
@@ -416,8 +466,15 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 		}
 		wg.Add(1)
 		go func(j int64) {
-			childKey := chunk.SData[8+j*self.hashSize : 8+(j+1)*self.hashSize]
-			chunk := retrieve(childKey, self.chunkC, quitC)
+			if 8+(j+1)*self.refSize > int64(len(chunk.SData)) {
+				select {
+				case errC <- fmt.Errorf("chunk %v-%v malformed", off, off+treeSize):
+				case <-quitC:
+				}
+				return
+			}
+			childKey := chunk.SData[8+j*self.refSize : 8+(j+1)*self.refSize]
+			chunk := self.retrieve(childKey, quitC)
 			if chunk == nil {
 				select {
 				case errC <- fmt.Errorf("chunk %v-%v not found", off, off+treeSize):
@@ -433,6 +490,27 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 	} //for
 }
 
+// retrieve fetches the chunk referenced by ref, decrypting it if the content
+// is encrypted. The data of decrypted chunks is never shared with the stores.
+func (self *LazyChunkReader) retrieve(ref Key, quitC chan bool) *Chunk {
+	if !self.decrypt {
+		return retrieve(ref, self.chunkC, quitC)
+	}
+	chunk := retrieve(ref[:self.hashSize], self.chunkC, quitC)
+	if chunk == nil {
+		return nil
+	}
+	data, size, err := decryptChunkData(ref[self.hashSize:], chunk.SData, self.chunkSize)
+	if err != nil {
+		return nil
+	}
+	return &Chunk{
+		Key:   chunk.Key,
+		SData: data,
+		Size:  size,
+	}
+}
+
 // the helper method submits chunks for a key to a oueue (DPA) and
 // block until they time out or arrive
 // abort if quitC is readable
@@ -50,7 +50,7 @@ func (self *chunkerTester) checkChunks(t *testing.T, want int) {
 	}
 }
 
-func (self *chunkerTester) Split(chunker Splitter, data io.Reader, size int64, chunkC chan *Chunk, swg *sync.WaitGroup, expectedError error) (key Key) {
+func (self *chunkerTester) Split(chunker Splitter, data io.Reader, size int64, chunkC chan *Chunk, swg *sync.WaitGroup, toEncrypt bool, expectedError error) (key Key) {
 	// reset
 	self.chunks = make(map[string]*Chunk)
 
@@ -78,7 +78,7 @@ func (self *chunkerTester) Split(chunker Splitter, data io.Reader, size int64, c
 			}
 		}()
 	}
-	key, err := chunker.Split(data, size, chunkC, swg, nil)
+	key, err := chunker.Split(data, size, chunkC, swg, nil, toEncrypt)
 	if err != nil && expectedError == nil {
 		self.t.Fatalf("Split error: %v", err)
 	} else if expectedError != nil && (err == nil || err.Error() != expectedError.Error()) {
@@ -143,11 +143,11 @@ func testRandomBrokenData(splitter Splitter, n int, tester *chunkerTester) {
 	chunkC := make(chan *Chunk, 1000)
 	swg := &sync.WaitGroup{}
 
-	key := tester.Split(splitter, brokendata, int64(n), chunkC, swg, fmt.Errorf("Broken reader"))
+	key := tester.Split(splitter, brokendata, int64(n), chunkC, swg, false, fmt.Errorf("Broken reader"))
 	tester.t.Logf(" Key = %v\n", key)
 }
 
-func testRandomData(splitter Splitter, n int, tester *chunkerTester) {
+func testRandomData(splitter Splitter, n int, toEncrypt bool, tester *chunkerTester) {
 	if tester.inputs == nil {
 		tester.inputs = make(map[uint64][]byte)
 	}
@@ -163,8 +163,11 @@ func testRandomData(splitter Splitter, n int, tester *chunkerTester) {
 	chunkC := make(chan *Chunk, 1000)
 	swg := &sync.WaitGroup{}
 
-	key := tester.Split(splitter, data, int64(n), chunkC, swg, nil)
+	key := tester.Split(splitter, data, int64(n), chunkC, swg, toEncrypt, nil)
 	tester.t.Logf(" Key = %v\n", key)
+	if IsEncryptedKey(key) != toEncrypt {
+		tester.t.Fatalf("key length mismatch: have %d, encrypted %v", len(key), toEncrypt)
+	}
 
 	chunkC = make(chan *Chunk, 1000)
 	quitC := make(chan bool)
@@ -191,11 +194,47 @@ func TestRandomData(t *testing.T) {
 	tester := &chunkerTester{t: t}
 	chunker := NewTreeChunker(NewChunkerParams())
 	for _, s := range sizes {
-		testRandomData(chunker, s, tester)
+		testRandomData(chunker, s, false, tester)
+	}
+	pyramid := NewPyramidChunker(NewChunkerParams())
+	for _, s := range sizes {
+		testRandomData(pyramid, s, false, tester)
+	}
+}
+
+func TestRandomEncryptedData(t *testing.T) {
+	sizes := []int{1, 60, 83, 179, 253, 1024, 4095, 4096, 4097, 8191, 8192, 8193, 123456, 2345678}
+	tester := &chunkerTester{t: t}
+	chunker := NewTreeChunker(NewChunkerParams())
+	for _, s := range sizes {
+		testRandomData(chunker, s, true, tester)
 	}
 	pyramid := NewPyramidChunker(NewChunkerParams())
 	for _, s := range sizes {
-		testRandomData(pyramid, s, tester)
+		testRandomData(pyramid, s, true, tester)
+	}
+}
+
+// Tests that encrypted chunks don't leak the content or its size.
+func TestEncryptedChunks(t *testing.T) {
+	tester := &chunkerTester{t: t}
+	chunker := NewTreeChunker(NewChunkerParams())
+
+	input := bytes.Repeat([]byte("secret"), 1000)
+	chunkC := make(chan *Chunk, 1000)
+	swg := &sync.WaitGroup{}
+	key := tester.Split(chunker, bytes.NewReader(input), int64(len(input)), chunkC, swg, true, nil)
+
+	for _, chunk := range tester.chunks {
+		if len(chunk.SData) != int(chunker.chunkSize)+8 {
+			t.Errorf("chunk %v not padded: have %d bytes, want %d", chunk.Key.Log(), len(chunk.SData), chunker.chunkSize+8)
+		}
+		if bytes.Contains(chunk.SData, []byte("secretsecret")) {
+			t.Errorf("chunk %v contains plaintext", chunk.Key.Log())
+		}
+	}
+	if _, ok := tester.chunks[Key(key[:chunker.hashSize]).String()]; !ok {
+		t.Errorf("root chunk not stored under the hash part of the key")
 	}
 }
 
@@ -241,7 +280,7 @@ func benchmarkJoin(n int, t *testing.B) {
 		chunkC := make(chan *Chunk, 1000)
 		swg := &sync.WaitGroup{}
 
-		key := tester.Split(chunker, data, int64(n), chunkC, swg, nil)
+		key := tester.Split(chunker, data, int64(n), chunkC, swg, false, nil)
 		// t.StartTimer()
 		chunkC = make(chan *Chunk, 1000)
 		quitC := make(chan bool)
@@ -262,7 +301,7 @@ func benchmarkSplitTree(n int, t *testing.B) {
 		chunker := NewTreeChunker(NewChunkerParams())
 		tester := &chunkerTester{t: t}
 		data := testDataReader(n)
-		tester.Split(chunker, data, int64(n), nil, nil, nil)
+		tester.Split(chunker, data, int64(n), nil, nil, false, nil)
 	}
 	stats := new(runtime.MemStats)
 	runtime.ReadMemStats(stats)
@@ -275,7 +314,7 @@ func benchmarkSplitPyramid(n int, t *testing.B) {
 		splitter := NewPyramidChunker(NewChunkerParams())
 		tester := &chunkerTester{t: t}
 		data := testDataReader(n)
-		tester.Split(splitter, data, int64(n), nil, nil, nil)
+		tester.Split(splitter, data, int64(n), nil, nil, false, nil)
 	}
 	stats := new(runtime.MemStats)
 	runtime.ReadMemStats(stats)
@@ -97,8 +97,10 @@ func (self *DPA) Retrieve(key Key) LazySectionReader {
 
 // Public API. Main entry point for document storage directly. Used by the
 // FS-aware API and httpaccess
-func (self *DPA) Store(data io.Reader, size int64, swg *sync.WaitGroup, wwg *sync.WaitGroup) (key Key, err error) {
-	return self.Chunker.Split(data, size, self.storeC, swg, wwg)
+// If toEncrypt is set, the content is stored encrypted and can only be
+// retrieved with the returned key, which includes the decryption key.
+func (self *DPA) Store(data io.Reader, size int64, swg *sync.WaitGroup, wwg *sync.WaitGroup, toEncrypt bool) (key Key, err error) {
+	return self.Chunker.Split(data, size, self.storeC, swg, wwg, toEncrypt)
 }
 
 func (self *DPA) Start() {
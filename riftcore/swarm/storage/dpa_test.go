@@ -46,7 +46,7 @@ func TestDPArandom(t *testing.T) {
 
 	reader, slice := testDataReaderAndSlice(testDataSize)
 	wg := &sync.WaitGroup{}
-	key, err := dpa.Store(reader, testDataSize, wg, nil)
+	key, err := dpa.Store(reader, testDataSize, wg, nil, false)
 	if err != nil {
 		t.Errorf("Store error: %v", err)
 	}
@@ -82,6 +82,51 @@ func TestDPArandom(t *testing.T) {
 	}
 }
 
+func TestDPAEncrypted(t *testing.T) {
+	dbStore := initDbStore(t)
+	memStore := NewMemStore(dbStore, defaultCacheCapacity)
+	localStore := &LocalStore{
+		memStore,
+		dbStore,
+	}
+	dpa := &DPA{
+		Chunker:    NewTreeChunker(NewChunkerParams()),
+		ChunkStore: localStore,
+	}
+	dpa.Start()
+	defer dpa.Stop()
+
+	size := int64(0x100000)
+	reader, slice := testDataReaderAndSlice(int(size))
+	wg := &sync.WaitGroup{}
+	key, err := dpa.Store(reader, size, wg, nil, true)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wg.Wait()
+	if !IsEncryptedKey(key) {
+		t.Fatalf("key %x does not reference encrypted content", key)
+	}
+	// read back from the db, where chunk sizes are derived from the ciphertext
+	localStore.memStore = NewMemStore(dbStore, defaultCacheCapacity)
+
+	resultReader := dpa.Retrieve(key)
+	if n, err := resultReader.Size(nil); err != nil || n != size {
+		t.Fatalf("Size mismatch: have %d (%v), want %d", n, err, size)
+	}
+	resultSlice := make([]byte, len(slice))
+	n, err := resultReader.ReadAt(resultSlice, 0)
+	if err != io.EOF {
+		t.Errorf("Retrieve error: %v", err)
+	}
+	if n != len(slice) {
+		t.Errorf("Slice size error got %d, expected %d.", n, len(slice))
+	}
+	if !bytes.Equal(slice, resultSlice) {
+		t.Errorf("Comparison error.")
+	}
+}
+
 func TestDPA_capacity(t *testing.T) {
 	dbStore := initDbStore(t)
 	memStore := NewMemStore(dbStore, defaultCacheCapacity)
@@ -98,7 +143,7 @@ func TestDPA_capacity(t *testing.T) {
 	dpa.Start()
 	reader, slice := testDataReaderAndSlice(testDataSize)
 	wg := &sync.WaitGroup{}
-	key, err := dpa.Store(reader, testDataSize, wg, nil)
+	key, err := dpa.Store(reader, testDataSize, wg, nil, false)
 	if err != nil {
 		t.Errorf("Store error: %v", err)
 	}
@@ -0,0 +1,106 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+/*
+Encrypted content is chunked exactly like plain content, but before hashing,
+every chunk (including the size prefix) is padded with random bytes to the
+maximum chunk length and encrypted with AES-256-CTR under a freshly generated
+key. The chunk is stored under the hash of its ciphertext and referenced by
+
+  ref := hash(ciphertext) || key
+
+so intermediate chunks hold encrypted references of their children and the
+root reference is all that is needed to retrieve and decrypt the content. As
+every key is used exactly once, the counter always starts from zero.
+*/
+
+// encryptionKeySize is the length of the per chunk symmetric keys (AES-256).
+const encryptionKeySize = 32
+
+var errChunkTooShort = errors.New("encrypted chunk too short")
+
+// IsEncryptedKey reports whether key is a reference to encrypted content, i.e.
+// the hash of the root chunk followed by its decryption key.
+func IsEncryptedKey(key Key) bool {
+	return len(key) == len(ZeroKey)+encryptionKeySize
+}
+
+// encryptChunkData pads data with random bytes to length and encrypts it under
+// a newly generated key, returning the ciphertext along with the key.
+func encryptChunkData(data []byte, length int64) (ciphertext []byte, key []byte, err error) {
+	key = make([]byte, encryptionKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	plaintext := data
+	if padding := length - int64(len(data)); padding > 0 {
+		plaintext = make([]byte, length)
+		copy(plaintext, data)
+		if _, err = rand.Read(plaintext[len(data):]); err != nil {
+			return nil, nil, err
+		}
+	}
+	stream, err := newChunkStream(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext = make([]byte, len(plaintext))
+	stream.XORKeyStream(ciphertext, plaintext)
+	return ciphertext, key, nil
+}
+
+// decryptChunkData decrypts the data of an encrypted chunk into a new slice
+// and strips the random padding of content chunks. The returned size is the
+// size of the subtree encoded in the chunk.
+func decryptChunkData(key []byte, ciphertext []byte, chunkSize int64) (data []byte, size int64, err error) {
+	if len(ciphertext) < 8 {
+		return nil, 0, errChunkTooShort
+	}
+	stream, err := newChunkStream(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	// never decrypt in place, the ciphertext may be shared with the chunk stores
+	data = make([]byte, len(ciphertext))
+	stream.XORKeyStream(data, ciphertext)
+
+	size = int64(binary.LittleEndian.Uint64(data[0:8]))
+	if size <= chunkSize {
+		if 8+size > int64(len(data)) {
+			return nil, 0, errChunkTooShort
+		}
+		data = data[:8+size]
+	}
+	return data, size, nil
+}
+
+func newChunkStream(key []byte) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize)), nil
+}
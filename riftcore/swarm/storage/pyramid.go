@@ -23,8 +23,6 @@ import (
 	"math"
 	"strings"
 	"sync"
-
-	"github.com/cryptorift/riftcore/common"
 )
 
 const (
@@ -35,12 +33,14 @@ type Tree struct {
 	Chunks int64
 	Levels []map[int64]*Node
 	Lock   sync.RWMutex
+
+	err error // first error encountered while processing chunks
 }
 
 type Node struct {
 	Pending  int64
 	Size     uint64
-	Children []common.Hash
+	Children []Key
 	Last     bool
 }
 
@@ -63,8 +63,10 @@ type PyramidChunker struct {
 	hashFunc    Hasher
 	chunkSize   int64
 	hashSize    int64
+	refSize     int64
 	branches    int64
 	workerCount int
+	encrypt     bool
 }
 
 func NewPyramidChunker(params *ChunkerParams) (self *PyramidChunker) {
@@ -72,12 +74,30 @@ func NewPyramidChunker(params *ChunkerParams) (self *PyramidChunker) {
 	self.hashFunc = MakeHashFunc(params.Hash)
 	self.branches = params.Branches
 	self.hashSize = int64(self.hashFunc().Size())
+	self.refSize = self.hashSize
 	self.chunkSize = self.hashSize * self.branches
 	self.workerCount = 1
 	return
 }
 
-func (self *PyramidChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
+// encrypted returns a copy of the chunker producing the same tree of encrypted
+// chunks as the TreeChunker does.
+func (self *PyramidChunker) encrypted() *PyramidChunker {
+	return &PyramidChunker{
+		hashFunc:    self.hashFunc,
+		chunkSize:   self.chunkSize,
+		hashSize:    self.hashSize,
+		refSize:     self.hashSize + encryptionKeySize,
+		branches:    self.chunkSize / (self.hashSize + encryptionKeySize),
+		workerCount: 1,
+		encrypt:     true,
+	}
+}
+
+func (self *PyramidChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup, toEncrypt bool) (Key, error) {
+	if toEncrypt && !self.encrypt {
+		return self.encrypted().Split(data, size, chunkC, swg, wwg, true)
+	}
 
 	chunks := (size + self.chunkSize - 1) / self.chunkSize
 	depth := int(math.Ceil(math.Log(float64(chunks))/math.Log(float64(self.branches)))) + 1
@@ -123,6 +143,9 @@ func (self *PyramidChunker) Split(data io.Reader, size int64, chunkC chan *Chunk
 	close(tasks)
 	pend.Wait()
 
+	if results.err != nil {
+		return nil, results.err
+	}
 	key := results.Levels[0][0].Children[0][:]
 	return key, nil
 }
@@ -140,20 +163,30 @@ func (self *PyramidChunker) processor(pend, swg *sync.WaitGroup, tasks chan *Tas
 		for depth >= 0 {
 			// New chunk received, reset the hasher and start processing
 			hasher.Reset()
-			if node == nil { // Leaf node, hash the data chunk
-				hasher.Write(task.Data)
-			} else { // Internal node, hash the children
+			if node != nil { // Internal node, concatenate the children
 				size = node.Size
-				data = make([]byte, hasher.Size()*len(node.Children)+8)
+				data = make([]byte, self.refSize*int64(len(node.Children))+8)
 				binary.LittleEndian.PutUint64(data[:8], size)
 
-				hasher.Write(data[:8])
-				for i, hash := range node.Children {
-					copy(data[i*hasher.Size()+8:], hash[:])
-					hasher.Write(hash[:])
+				for i, ref := range node.Children {
+					copy(data[int64(i)*self.refSize+8:], ref)
+				}
+			}
+			var encKey []byte
+			if self.encrypt {
+				var err error
+				if data, encKey, err = encryptChunkData(data, self.chunkSize+8); err != nil {
+					results.Lock.Lock()
+					if results.err == nil {
+						results.err = err
+					}
+					results.Lock.Unlock()
+					break
 				}
 			}
+			hasher.Write(data)
 			hash := hasher.Sum(nil)
+			ref := append(hash, encKey...)
 			last := task.Last || (node != nil) && node.Last
 			// Insert the subresult into the memoization tree
 			results.Lock.Lock()
@@ -163,7 +196,7 @@ func (self *PyramidChunker) processor(pend, swg *sync.WaitGroup, tasks chan *Tas
 				if task.Index/pow == results.Chunks/pow {
 					pending = (results.Chunks + pow/self.branches - 1) / (pow / self.branches) % self.branches
 				}
-				node = &Node{pending, 0, make([]common.Hash, pending), last}
+				node = &Node{pending, 0, make([]Key, pending), last}
 				results.Levels[depth][task.Index/pow] = node
 			}
 			node.Pending--
@@ -171,7 +204,7 @@ func (self *PyramidChunker) processor(pend, swg *sync.WaitGroup, tasks chan *Tas
 			if last {
 				node.Last = true
 			}
-			copy(node.Children[i][:], hash)
+			node.Children[i] = ref
 			node.Size += size
 			left := node.Pending
 			if chunkC != nil {
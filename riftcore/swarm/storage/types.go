@@ -108,8 +108,13 @@ func (key Key) MarshalJSON() (out []byte, err error) {
 
 func (key *Key) UnmarshalJSON(value []byte) error {
 	s := string(value)
-	*key = make([]byte, 32)
 	h := common.Hex2Bytes(s[1 : len(s)-1])
+	// references to encrypted content are longer than a hash, don't truncate them
+	if len(h) > len(ZeroKey) {
+		*key = h
+		return nil
+	}
+	*key = make([]byte, len(ZeroKey))
 	copy(*key, h)
 	return nil
 }
@@ -189,8 +194,9 @@ type Splitter interface {
 	   wg is a Waitgroup (can be nil) that can be used to block until the local storage finishes
 	   The caller gets returned an error channel, if an error is encountered during splitting, it is fed to errC error channel.
 	   A closed error signals process completion at which point the key can be considered final if there were no errors.
+	   If toEncrypt is set, the chunks are encrypted and the returned key also carries the decryption key of the root chunk.
 	*/
-	Split(io.Reader, int64, chan *Chunk, *sync.WaitGroup, *sync.WaitGroup) (Key, error)
+	Split(io.Reader, int64, chan *Chunk, *sync.WaitGroup, *sync.WaitGroup, bool) (Key, error)
 }
 
 type Joiner interface {
@@ -0,0 +1,351 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/log"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+// idealBatchSize is the amount of data to accumulate in a database batch before
+// it is flushed to disk during a commit.
+const idealBatchSize = 100 * 1024
+
+// LeafCallback is a callback type invoked when a trie operation reaches a leaf
+// node. It's used by state sync and commit to allow handling external references
+// between account and storage tries.
+type LeafCallback func(leaf []byte, parent common.Hash) error
+
+// cachedNode is all the information we know about a single cached trie node in
+// the memory database write layer.
+type cachedNode struct {
+	blob     []byte                 // Cached data block of the trie node
+	parents  int                    // Number of live nodes referencing this one
+	children map[common.Hash]uint16 // Children referenced by this nodes
+}
+
+// NodeDatabase is an intermediate write layer between the trie data structures
+// and the disk database. The aim is to accumulate trie writes in-memory and only
+// periodically flush a couple tries to disk, garbage collecting the remainder.
+//
+// Trie nodes are tracked with reference counts: a node is kept alive as long as
+// a parent node or an external reference (e.g. a block's state root) points to
+// it. Loose blobs that are not part of any trie (contract code, preimages) can
+// be stored via Put and are written to disk on the next Commit.
+type NodeDatabase struct {
+	diskdb riftdb.Database // Persistent storage for matured trie nodes
+
+	nodes     map[common.Hash]*cachedNode // Data and references relationships of trie nodes
+	blobs     map[string][]byte           // Loose data blobs not tracked by reference counts
+	nodesSize common.StorageSize          // Storage size of the nodes cache
+	blobsSize common.StorageSize          // Storage size of the loose blobs cache
+
+	gctime  time.Duration      // Time spent on garbage collection since last commit
+	gcnodes uint64             // Nodes garbage collected since last commit
+	gcsize  common.StorageSize // Data storage garbage collected since last commit
+
+	lock sync.RWMutex
+}
+
+// NewNodeDatabase creates a new trie node database to store ephemeral trie
+// content before it's written out to disk or garbage collected.
+func NewNodeDatabase(diskdb riftdb.Database) *NodeDatabase {
+	return &NodeDatabase{
+		diskdb: diskdb,
+		nodes: map[common.Hash]*cachedNode{
+			{}: {children: make(map[common.Hash]uint16)},
+		},
+		blobs: make(map[string][]byte),
+	}
+}
+
+// DiskDB retrieves the persistent storage backing the trie node database.
+func (db *NodeDatabase) DiskDB() riftdb.Database {
+	return db.diskdb
+}
+
+// Get retrieves a trie node or loose blob from memory, falling back to the
+// persistent database if it's not cached.
+func (db *NodeDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	if len(key) == common.HashLength {
+		if node := db.nodes[common.BytesToHash(key)]; node != nil {
+			db.lock.RUnlock()
+			return node.blob, nil
+		}
+	}
+	if blob, ok := db.blobs[string(key)]; ok {
+		db.lock.RUnlock()
+		return blob, nil
+	}
+	db.lock.RUnlock()
+
+	return db.diskdb.Get(key)
+}
+
+// Put stores a loose data blob (contract code, hash preimage) in the memory
+// database. Loose blobs are not reference counted, they are written to disk
+// together with the next committed trie.
+func (db *NodeDatabase) Put(key, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.blobs[string(key)]; ok {
+		return nil
+	}
+	db.blobs[string(key)] = common.CopyBytes(value)
+	db.blobsSize += common.StorageSize(len(key) + len(value))
+	return nil
+}
+
+// insert inserts a collapsed trie node into the memory database, tracking the
+// references to its hashed children. This method assumes the lock is held.
+func (db *NodeDatabase) insert(hash common.Hash, blob []byte, n node) {
+	// If the node's already cached, skip
+	if _, ok := db.nodes[hash]; ok {
+		return
+	}
+	db.nodes[hash] = &cachedNode{
+		blob:     common.CopyBytes(blob),
+		children: make(map[common.Hash]uint16),
+	}
+	db.nodesSize += common.StorageSize(common.HashLength + len(blob))
+
+	// Track all direct parent->child node references
+	switch n := n.(type) {
+	case *shortNode:
+		if child, ok := n.Val.(hashNode); ok {
+			db.reference(common.BytesToHash(child), hash)
+		}
+	case *fullNode:
+		for i := 0; i < 16; i++ {
+			if child, ok := n.Children[i].(hashNode); ok {
+				db.reference(common.BytesToHash(child), hash)
+			}
+		}
+	}
+}
+
+// Nodes retrieves the hashes of all the nodes cached within the memory database.
+// This method is extremely expensive and should only be used to validate internal
+// states in test code.
+func (db *NodeDatabase) Nodes() []common.Hash {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var hashes = make([]common.Hash, 0, len(db.nodes))
+	for hash := range db.nodes {
+		if hash != (common.Hash{}) { // Special case for "root" references/nodes
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// Reference adds a new reference from a parent node to a child node. The zero
+// parent hash denotes an external reference, such as a block's state root.
+func (db *NodeDatabase) Reference(child common.Hash, parent common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.reference(child, parent)
+}
+
+// reference is the private locked version of Reference.
+func (db *NodeDatabase) reference(child common.Hash, parent common.Hash) {
+	// If the node does not exist, it's a node pulled from disk, skip
+	node, ok := db.nodes[child]
+	if !ok {
+		return
+	}
+	// If the parent was already flushed, there's nothing to keep alive
+	owner, ok := db.nodes[parent]
+	if !ok {
+		return
+	}
+	// If the reference already exists, only duplicate for roots
+	if _, ok = owner.children[child]; ok && parent != (common.Hash{}) {
+		return
+	}
+	node.parents++
+	owner.children[child]++
+}
+
+// Dereference removes an existing external reference to a trie root, garbage
+// collecting any nodes that are not referenced any more.
+func (db *NodeDatabase) Dereference(root common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	nodes, storage, start := len(db.nodes), db.nodesSize, time.Now()
+	db.dereference(root, common.Hash{})
+
+	db.gcnodes += uint64(nodes - len(db.nodes))
+	db.gcsize += storage - db.nodesSize
+	db.gctime += time.Since(start)
+
+	log.Debug("Dereferenced trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+}
+
+// dereference is the private locked version of Dereference.
+func (db *NodeDatabase) dereference(child common.Hash, parent common.Hash) {
+	// Dereference the parent-child
+	owner := db.nodes[parent]
+
+	if owner.children[child] > 1 {
+		owner.children[child]--
+	} else {
+		delete(owner.children, child)
+	}
+	// If the child does not exist, it's a previously committed node.
+	node, ok := db.nodes[child]
+	if !ok {
+		return
+	}
+	// If there are no more references to the child, delete it and cascade
+	node.parents--
+	if node.parents == 0 {
+		for hash := range node.children {
+			db.dereference(hash, child)
+		}
+		delete(db.nodes, child)
+		db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+	}
+}
+
+// Commit iterates over all the children of a particular node, writes them out
+// to disk, forcefully tearing down all references in both directions.
+//
+// As a side effect, all pending loose blobs will be flushed to disk too.
+func (db *NodeDatabase) Commit(root common.Hash, report bool) error {
+	// Create a database batch to flush persistent data out. It is important that
+	// outside code doesn't see an inconsistent state (referenced data removed from
+	// memory cache during commit but not yet in persistent storage). This is ensured
+	// by only uncaching existing data when the database write finalizes.
+	db.lock.RLock()
+
+	start := time.Now()
+	batch := db.diskdb.NewBatch()
+	size := 0
+
+	// Move all of the accumulated loose blobs into a write batch
+	for key, blob := range db.blobs {
+		if err := batch.Put([]byte(key), blob); err != nil {
+			db.lock.RUnlock()
+			return err
+		}
+		if size += len(key) + len(blob); size >= idealBatchSize {
+			if err := batch.Write(); err != nil {
+				db.lock.RUnlock()
+				return err
+			}
+			batch, size = db.diskdb.NewBatch(), 0
+		}
+	}
+	// Move the trie itself into the batch, flushing if enough data is accumulated
+	nodes, storage := len(db.nodes), db.nodesSize
+	if err := db.commit(root, &batch, &size); err != nil {
+		log.Error("Failed to commit trie from trie database", "err", err)
+		db.lock.RUnlock()
+		return err
+	}
+	// Write batch ready, unlock for readers during persistence
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write trie to disk", "err", err)
+		db.lock.RUnlock()
+		return err
+	}
+	db.lock.RUnlock()
+
+	// Write successful, clear out the flushed data
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.blobs = make(map[string][]byte)
+	db.blobsSize = 0
+
+	db.uncache(root)
+
+	logger := log.Info
+	if !report {
+		logger = log.Debug
+	}
+	logger("Persisted trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+
+	// Reset the garbage collection statistics
+	db.gcnodes, db.gcsize, db.gctime = 0, 0, 0
+
+	return nil
+}
+
+// commit is the private locked version of Commit.
+func (db *NodeDatabase) commit(hash common.Hash, batch *riftdb.Batch, size *int) error {
+	// If the node does not exist, it's a previously committed node
+	node, ok := db.nodes[hash]
+	if !ok {
+		return nil
+	}
+	for child := range node.children {
+		if err := db.commit(child, batch, size); err != nil {
+			return err
+		}
+	}
+	if err := (*batch).Put(hash[:], node.blob); err != nil {
+		return err
+	}
+	// If we've reached an optimal batch size, commit and start over
+	if *size += common.HashLength + len(node.blob); *size >= idealBatchSize {
+		if err := (*batch).Write(); err != nil {
+			return err
+		}
+		*batch, *size = db.diskdb.NewBatch(), 0
+	}
+	return nil
+}
+
+// uncache is the post-processing step of a commit operation where the already
+// persisted trie is removed from the cache. The reason behind the two-phase
+// commit is to ensure consistent data availability while moving from memory
+// to disk.
+func (db *NodeDatabase) uncache(hash common.Hash) {
+	// If the node does not exist, we're done on this path
+	node, ok := db.nodes[hash]
+	if !ok {
+		return
+	}
+	// Otherwise uncache the node's subtries and remove the node itself too
+	for child := range node.children {
+		db.uncache(child)
+	}
+	delete(db.nodes, hash)
+	db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+}
+
+// Size returns the current storage size of the memory cache in front of the
+// persistent database layer.
+func (db *NodeDatabase) Size() common.StorageSize {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.nodesSize + db.blobsSize
+}
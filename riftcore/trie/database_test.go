@@ -0,0 +1,130 @@
+// Copyright 2017 The CryptoRift Authors
+// This file is part of the riftcore library.
+//
+// The riftcore library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The riftcore library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the riftcore library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/riftdb"
+)
+
+// makeTestNodeDatabaseTrie creates a trie with some arbitrary content inside a
+// fresh node database, committing it into the memory cache.
+func makeTestNodeDatabaseTrie(triedb *NodeDatabase, n byte) (common.Hash, map[string][]byte) {
+	trie, _ := New(common.Hash{}, triedb)
+
+	content := make(map[string][]byte)
+	for i := byte(0); i < n; i++ {
+		key, val := common.LeftPadBytes([]byte{1, i}, 32), common.LeftPadBytes([]byte{i}, 32)
+		content[string(key)] = val
+		trie.Update(key, val)
+	}
+	root, _ := trie.CommitTo(triedb)
+	return root, content
+}
+
+// checkNodeDatabaseTrie verifies that a trie can be fully resolved from the
+// given database and that it contains the expected content.
+func checkNodeDatabaseTrie(t *testing.T, db Database, root common.Hash, content map[string][]byte) {
+	trie, err := New(root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie %x: %v", root, err)
+	}
+	for key, val := range content {
+		if have, err := trie.TryGet([]byte(key)); err != nil || !bytes.Equal(have, val) {
+			t.Fatalf("trie %x: entry %x mismatch: have %x, want %x, err %v", root, key, have, val, err)
+		}
+	}
+}
+
+// Tests that dereferencing a trie root garbage collects all the nodes that are
+// not referenced by any other live trie.
+func TestNodeDatabaseDereference(t *testing.T) {
+	diskdb, _ := riftdb.NewMemDatabase()
+	triedb := NewNodeDatabase(diskdb)
+
+	// Create two overlapping tries and keep both alive
+	root1, content1 := makeTestNodeDatabaseTrie(triedb, 100)
+	root2, content2 := makeTestNodeDatabaseTrie(triedb, 120)
+
+	triedb.Reference(root1, common.Hash{})
+	triedb.Reference(root2, common.Hash{})
+
+	if len(diskdb.Keys()) != 0 {
+		t.Fatalf("trie nodes leaked to disk: %d", len(diskdb.Keys()))
+	}
+	checkNodeDatabaseTrie(t, triedb, root1, content1)
+	checkNodeDatabaseTrie(t, triedb, root2, content2)
+
+	nodes := len(triedb.Nodes())
+
+	// Drop the first trie and ensure the second one is intact
+	triedb.Dereference(root1)
+	if len(triedb.Nodes()) >= nodes {
+		t.Errorf("no nodes garbage collected: have %d, before %d", len(triedb.Nodes()), nodes)
+	}
+	checkNodeDatabaseTrie(t, triedb, root2, content2)
+
+	if _, err := New(root1, triedb); err == nil {
+		t.Errorf("dereferenced trie %x still accessible", root1)
+	}
+	// Drop the second trie too and ensure everything's gone
+	triedb.Dereference(root2)
+	if n := len(triedb.Nodes()); n != 0 {
+		t.Errorf("dangling nodes after full dereference: %d", n)
+	}
+	if size := triedb.Size(); size != 0 {
+		t.Errorf("dangling storage after full dereference: %v", size)
+	}
+}
+
+// Tests that committing a trie flushes it and any loose blobs to disk, removing
+// them from the memory cache.
+func TestNodeDatabaseCommit(t *testing.T) {
+	diskdb, _ := riftdb.NewMemDatabase()
+	triedb := NewNodeDatabase(diskdb)
+
+	root, content := makeTestNodeDatabaseTrie(triedb, 100)
+	triedb.Reference(root, common.Hash{})
+
+	blob := []byte("loose blob")
+	if err := triedb.Put([]byte("blobkey"), blob); err != nil {
+		t.Fatalf("failed to insert loose blob: %v", err)
+	}
+	if have, _ := triedb.Get([]byte("blobkey")); !bytes.Equal(have, blob) {
+		t.Fatalf("loose blob mismatch: have %x, want %x", have, blob)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	if n := len(triedb.Nodes()); n != 0 {
+		t.Errorf("nodes left in memory after commit: %d", n)
+	}
+	if size := triedb.Size(); size != 0 {
+		t.Errorf("storage left in memory after commit: %v", size)
+	}
+	// Ensure everything is accessible purely from disk
+	checkNodeDatabaseTrie(t, diskdb, root, content)
+	if have, _ := diskdb.Get([]byte("blobkey")); !bytes.Equal(have, blob) {
+		t.Errorf("persisted loose blob mismatch: have %x, want %x", have, blob)
+	}
+	// Dereferencing a committed trie must not touch the disk
+	triedb.Dereference(root)
+	checkNodeDatabaseTrie(t, diskdb, root, content)
+}
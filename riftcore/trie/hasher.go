@@ -30,6 +30,7 @@ type hasher struct {
 	tmp                  *bytes.Buffer
 	sha                  hash.Hash
 	cachegen, cachelimit uint16
+	onleaf               LeafCallback
 }
 
 // hashers live in a global pool.
@@ -39,9 +40,9 @@ var hasherPool = sync.Pool{
 	},
 }
 
-func newHasher(cachegen, cachelimit uint16) *hasher {
+func newHasher(cachegen, cachelimit uint16, onleaf LeafCallback) *hasher {
 	h := hasherPool.Get().(*hasher)
-	h.cachegen, h.cachelimit = cachegen, cachelimit
+	h.cachegen, h.cachelimit, h.onleaf = cachegen, cachelimit, onleaf
 	return h
 }
 
@@ -166,8 +167,36 @@ func (h *hasher) store(n node, db DatabaseWriter, force bool) (node, error) {
 		h.sha.Write(h.tmp.Bytes())
 		hash = hashNode(h.sha.Sum(nil))
 	}
-	if db != nil {
-		return hash, db.Put(hash, h.tmp.Bytes())
+	if db == nil {
+		return hash, nil
+	}
+	// Trie nodes pooled in a node database are tracked with their references,
+	// anything else is simply written out to the backing store.
+	if triedb, ok := db.(*NodeDatabase); ok {
+		triedb.lock.Lock()
+		triedb.insert(common.BytesToHash(hash), h.tmp.Bytes(), n)
+		triedb.lock.Unlock()
+	} else if err := db.Put(hash, h.tmp.Bytes()); err != nil {
+		return hash, err
+	}
+	// Track external references from account->storage trie
+	if h.onleaf != nil {
+		switch n := n.(type) {
+		case *shortNode:
+			if child, ok := n.Val.(valueNode); ok {
+				if err := h.onleaf(child, common.BytesToHash(hash)); err != nil {
+					return hash, err
+				}
+			}
+		case *fullNode:
+			for i := 0; i < 16; i++ {
+				if child, ok := n.Children[i].(valueNode); ok {
+					if err := h.onleaf(child, common.BytesToHash(hash)); err != nil {
+						return hash, err
+					}
+				}
+			}
+		}
 	}
 	return hash, nil
 }
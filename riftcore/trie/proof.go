@@ -67,7 +67,7 @@ func (t *Trie) Prove(key []byte) []rlp.RawValue {
 			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
 		}
 	}
-	hasher := newHasher(0, 0)
+	hasher := newHasher(0, 0, nil)
 	proof := make([]rlp.RawValue, 0, len(nodes))
 	for i, n := range nodes {
 		// Don't bother checking for errors here since hasher panics
@@ -124,6 +124,36 @@ func VerifyProof(rootHash common.Hash, key []byte, proof []rlp.RawValue) (value
 	return nil, errors.New("unexpected end of proof")
 }
 
+// VerifyProofDb checks a merkle proof whose nodes are looked up by hash in the
+// given database instead of being listed in order. This allows verifying many
+// keys against a single deduplicated set of proof nodes. VerifyProofDb returns
+// an error if a node on the path to key is missing or invalid.
+func VerifyProofDb(rootHash common.Hash, key []byte, proofDb DatabaseReader) (value []byte, err error) {
+	key = keybytesToHex(key)
+	wantHash := rootHash.Bytes()
+	for i := 0; ; i++ {
+		buf, _ := proofDb.Get(wantHash)
+		if buf == nil {
+			return nil, fmt.Errorf("proof node %d (hash %064x) missing", i, wantHash)
+		}
+		n, err := decodeNode(wantHash, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %d: %v", i, err)
+		}
+		keyrest, cld := get(n, key)
+		switch cld := cld.(type) {
+		case nil:
+			// The trie doesn't contain the key.
+			return nil, nil
+		case hashNode:
+			key = keyrest
+			wantHash = cld
+		case valueNode:
+			return cld, nil
+		}
+	}
+}
+
 func get(tn node, key []byte) ([]byte, node) {
 	for {
 		switch n := tn.(type) {
@@ -24,6 +24,8 @@ import (
 	"time"
 
 	"github.com/cryptorift/riftcore/common"
+	"github.com/cryptorift/riftcore/crypto"
+	"github.com/cryptorift/riftcore/riftdb"
 	"github.com/cryptorift/riftcore/rlp"
 )
 
@@ -83,6 +85,36 @@ func TestVerifyBadProof(t *testing.T) {
 	}
 }
 
+func TestProofDb(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+
+	// Merge the proofs of all keys into a single deduplicated node set
+	proofDb, _ := riftdb.NewMemDatabase()
+	for _, kv := range vals {
+		for _, node := range trie.Prove(kv.k) {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+	}
+	for _, kv := range vals {
+		val, err := VerifyProofDb(root, kv.k, proofDb)
+		if err != nil {
+			t.Fatalf("VerifyProofDb error for key %x: %v", kv.k, err)
+		}
+		if !bytes.Equal(val, kv.v) {
+			t.Fatalf("VerifyProofDb returned wrong value for key %x: got %x, want %x", kv.k, val, kv.v)
+		}
+	}
+	// A node set missing the root must not verify anything
+	emptyDb, _ := riftdb.NewMemDatabase()
+	for _, kv := range vals {
+		if _, err := VerifyProofDb(root, kv.k, emptyDb); err == nil {
+			t.Fatalf("expected proof to fail for key %x", kv.k)
+		}
+		break
+	}
+}
+
 // mutateByte changes one byte in b.
 func mutateByte(b []byte) {
 	for r := mrand.Intn(len(b)); ; {